@@ -19,17 +19,24 @@ import (
 	"html/template"
 	"io"
 	"log"
+	"maps"
 	"net/http"
+	"net/url"
+	"slices"
 	"strings"
 	"time"
 
 	"cirello.io/alreadyread/pkg/bookmarks"
+	"cirello.io/alreadyread/pkg/bookmarks/importer"
+	"cirello.io/alreadyread/pkg/bookmarks/linkhealth"
 )
 
 var (
 	//go:embed newLink.html
 	newLinkTPL string
-	newLink    = template.Must(template.New("newLink").Funcs(template.FuncMap{}).Parse(newLinkTPL))
+	newLink    = template.Must(template.New("newLink").Funcs(template.FuncMap{
+		"join": strings.Join,
+	}).Parse(newLinkTPL))
 )
 
 func RenderNewLink(w io.Writer, bookmark *bookmarks.Bookmark) {
@@ -40,12 +47,38 @@ func RenderNewLink(w io.Writer, bookmark *bookmarks.Bookmark) {
 	}
 }
 
+var (
+	//go:embed editLink.html
+	editLinkTPL string
+	editLink    = template.Must(template.New("editLink").Funcs(template.FuncMap{
+		"join": strings.Join,
+	}).Parse(editLinkTPL))
+)
+
+// RenderEditLink renders the form that replaces a bookmark card while it is
+// being edited.
+func RenderEditLink(w io.Writer, bookmark *bookmarks.Bookmark) {
+	if err := editLink.Execute(w, bookmark); err != nil {
+		log.Println("cannot render edit link form:", err)
+		if rw, ok := w.(http.ResponseWriter); ok {
+			http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+	}
+}
+
 var (
 	//go:embed linkTable.html
 	linkTableTPL string
 	linkTable    = template.Must(template.New("linkTable").Funcs(template.FuncMap{
 		"prettyTime":     func(t time.Time) string { return t.Format("Jan _2 2006") },
-		"httpStatusCode": func(code int64) string { return http.StatusText(int(code)) },
+		"unixTime":       func(sec int64) time.Time { return time.Unix(sec, 0) },
+		"httpStatusCode": bookmarks.StatusText,
+		"highlight": func(snippet string) template.HTML {
+			snippet = template.HTMLEscapeString(snippet)
+			snippet = strings.ReplaceAll(snippet, bookmarks.SnippetMatchStart, "<mark>")
+			snippet = strings.ReplaceAll(snippet, bookmarks.SnippetMatchEnd, "</mark>")
+			return template.HTML(snippet)
+		},
 		"slugify": func(s string) string {
 			s = strings.TrimSpace(s)
 			s = strings.ToLower(s)
@@ -57,7 +90,10 @@ var (
 	}).Parse(linkTableTPL))
 )
 
-func RenderLinkTable(w io.Writer, list []*bookmarks.Bookmark, page int, previousLastDate string) {
+// RenderLinkTable renders the bookmarks grouped by date. When next is not nil,
+// the table ends with an infinite scroll trigger that loads the page
+// described by the query parameters in next.
+func RenderLinkTable(w io.Writer, list []*bookmarks.Bookmark, next url.Values, previousLastDate string) {
 	type dateGroup struct {
 		Date  string
 		Links []*bookmarks.Bookmark
@@ -66,7 +102,7 @@ func RenderLinkTable(w io.Writer, list []*bookmarks.Bookmark, page int, previous
 		idx    = make(map[string]*dateGroup)
 		groups = make([]string, 0)
 		p      struct {
-			NextPage         int
+			NextPage         string
 			PreviousLastDate string
 			Links            []*dateGroup
 		}
@@ -86,7 +122,11 @@ func RenderLinkTable(w io.Writer, list []*bookmarks.Bookmark, page int, previous
 		p.Links = append(p.Links, idx[g])
 	}
 	p.PreviousLastDate = previousLastDate
-	p.NextPage = page + 1
+	if next != nil && len(groups) > 0 {
+		next = maps.Clone(next)
+		next.Set("lastDate", groups[len(groups)-1])
+		p.NextPage = "?" + next.Encode()
+	}
 	if err := linkTable.Execute(w, p); err != nil {
 		log.Println("cannot render link table:", err)
 		if rw, ok := w.(http.ResponseWriter); ok {
@@ -95,6 +135,138 @@ func RenderLinkTable(w io.Writer, list []*bookmarks.Bookmark, page int, previous
 	}
 }
 
+// RenderLink renders a single bookmark card, as found in the link table.
+func RenderLink(w io.Writer, bookmark *bookmarks.Bookmark) {
+	if err := linkTable.ExecuteTemplate(w, "link", bookmark); err != nil {
+		log.Println("cannot render link:", err)
+		if rw, ok := w.(http.ResponseWriter); ok {
+			http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+	}
+}
+
+var (
+	//go:embed linkHistory.html
+	linkHistoryTPL string
+	linkHistory    = template.Must(template.New("linkHistory").Funcs(template.FuncMap{
+		"checkTime":      func(t time.Time) string { return t.Format("Jan _2 2006 15:04") },
+		"unixTime":       func(sec int64) time.Time { return time.Unix(sec, 0) },
+		"httpStatusCode": bookmarks.StatusText,
+	}).Parse(linkHistoryTPL))
+)
+
+// RenderLinkHistory renders the link health panel of a bookmark: its uptime,
+// a timeline of the checks, oldest first, and the details of each check.
+func RenderLinkHistory(w io.Writer, history *bookmarks.LinkHistory) {
+	timeline := slices.Clone(history.Checks)
+	slices.Reverse(timeline)
+	err := linkHistory.Execute(w, struct {
+		*bookmarks.LinkHistory
+		Timeline []*bookmarks.LinkCheck
+	}{history, timeline})
+	if err != nil {
+		log.Println("cannot render link history:", err)
+		if rw, ok := w.(http.ResponseWriter); ok {
+			http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+	}
+}
+
+var (
+	//go:embed admin.html
+	adminTPL string
+	admin    = template.Must(template.New("admin").Funcs(template.FuncMap{
+		"checkTime": func(t time.Time) string { return t.Format("Jan _2 2006 15:04") },
+	}).Parse(adminTPL))
+)
+
+// RenderAdmin renders the server administration panel, which tells how the
+// link checker is doing and lets the administrator start it right away.
+func RenderAdmin(w io.Writer, status linkhealth.Status) {
+	if err := admin.Execute(w, status); err != nil {
+		log.Println("cannot render admin panel:", err)
+		if rw, ok := w.(http.ResponseWriter); ok {
+			http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+	}
+}
+
+var (
+	//go:embed deadFilters.html
+	deadFiltersTPL string
+	deadFilters    = template.Must(template.New("deadFilters").Parse(deadFiltersTPL))
+)
+
+// RenderDeadFilters renders the links that narrow the dead bookmarks down by
+// how their links fail, with the selected one marked as current.
+func RenderDeadFilters(w io.Writer, selected bookmarks.DeadFilter) {
+	err := deadFilters.Execute(w, struct {
+		Selected                    bookmarks.DeadFilter
+		AllDead, SoftDead, HardDead bookmarks.DeadFilter
+	}{selected, bookmarks.AllDead, bookmarks.SoftDead, bookmarks.HardDead})
+	if err != nil {
+		log.Println("cannot render dead bookmark filters:", err)
+		if rw, ok := w.(http.ResponseWriter); ok {
+			http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+	}
+}
+
+var (
+	//go:embed tags.html
+	tagsTPL string
+	tags    = template.Must(template.New("tags").Parse(tagsTPL))
+)
+
+func RenderTags(w io.Writer, list []*bookmarks.Tag) {
+	if err := tags.Execute(w, list); err != nil {
+		log.Println("cannot render tags:", err)
+		if rw, ok := w.(http.ResponseWriter); ok {
+			http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+	}
+}
+
+var (
+	//go:embed import.html
+	importTPL  string
+	importForm = template.Must(template.New("import").Parse(importTPL))
+)
+
+// RenderImport renders the bookmark file upload form, preceded by the report
+// of the previous import, if any, and followed by the export links. The form
+// offers the given format first.
+func RenderImport(w io.Writer, report *bookmarks.ImportReport, format importer.Format) {
+	err := importForm.Execute(w, struct {
+		Report   *bookmarks.ImportReport
+		Formats  []importer.Format
+		Selected importer.Format
+	}{report, importer.Formats, format})
+	if err != nil {
+		log.Println("cannot render import form:", err)
+		if rw, ok := w.(http.ResponseWriter); ok {
+			http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+	}
+}
+
+var (
+	//go:embed error.html
+	errorTPL string
+	errorMsg = template.Must(template.New("error").Parse(errorTPL))
+)
+
+// RenderError renders a message explaining why the request could not be
+// fulfilled.
+func RenderError(w io.Writer, msg string) {
+	if err := errorMsg.Execute(w, msg); err != nil {
+		log.Println("cannot render error message:", err)
+		if rw, ok := w.(http.ResponseWriter); ok {
+			http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+	}
+}
+
 var (
 	//go:embed index.html
 	indexTPL string
@@ -105,12 +277,15 @@ const EmptyContainer template.HTML = ""
 
 const NoTitle = ""
 
-func RenderIndex(w io.Writer, path string, headerPageName string, container template.HTML) {
+// RenderIndex renders the page layout around the container. When account is
+// not empty, the page offers a way to log out.
+func RenderIndex(w io.Writer, path string, headerPageName string, container template.HTML, account string) {
 	err := index.Execute(w, struct {
 		Path           string
 		HeaderPageName string
 		Container      template.HTML
-	}{Path: path, HeaderPageName: headerPageName, Container: container})
+		Account        string
+	}{Path: path, HeaderPageName: headerPageName, Container: container, Account: account})
 	if err != nil {
 		log.Println("cannot render index:", err)
 		if rw, ok := w.(http.ResponseWriter); ok {
@@ -119,3 +294,50 @@ func RenderIndex(w io.Writer, path string, headerPageName string, container temp
 		return
 	}
 }
+
+var (
+	//go:embed login.html
+	loginTPL string
+	login    = template.Must(template.New("login").Parse(loginTPL))
+)
+
+// RenderLogin renders the login page. When msg is not empty, it is shown as
+// the reason why the previous attempt failed. The page links to the signup
+// form if signupOpen is set.
+func RenderLogin(w io.Writer, next, username, msg string, signupOpen bool) {
+	err := login.Execute(w, struct {
+		Next       string
+		Username   string
+		Message    string
+		SignupOpen bool
+	}{Next: next, Username: username, Message: msg, SignupOpen: signupOpen})
+	if err != nil {
+		log.Println("cannot render login:", err)
+		if rw, ok := w.(http.ResponseWriter); ok {
+			http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+	}
+}
+
+var (
+	//go:embed signup.html
+	signupTPL string
+	signup    = template.Must(template.New("signup").Parse(signupTPL))
+)
+
+// RenderSignup renders the signup page. When msg is not empty, it is shown as
+// the reason why the previous attempt failed.
+func RenderSignup(w io.Writer, next, username, msg string, minPasswordLength int) {
+	err := signup.Execute(w, struct {
+		Next              string
+		Username          string
+		Message           string
+		MinPasswordLength int
+	}{Next: next, Username: username, Message: msg, MinPasswordLength: minPasswordLength})
+	if err != nil {
+		log.Println("cannot render signup:", err)
+		if rw, ok := w.(http.ResponseWriter); ok {
+			http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+	}
+}
@@ -18,10 +18,14 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
+	"time"
 
 	"cirello.io/alreadyread/pkg/bookmarks"
+	"cirello.io/alreadyread/pkg/bookmarks/importer"
+	"cirello.io/alreadyread/pkg/bookmarks/linkhealth"
 )
 
 func TestRenderNewLink(t *testing.T) {
@@ -53,10 +57,39 @@ func TestRenderNewLink(t *testing.T) {
 	})
 }
 
+func TestRenderEditLink(t *testing.T) {
+	t.Run("badWriter", func(t *testing.T) {
+		brw := &badResponseWriter{}
+		RenderEditLink(brw, nil)
+		if brw.recordedStatusCode != http.StatusInternalServerError {
+			t.Fatal("unexpected status code:", brw.recordedStatusCode)
+		}
+	})
+	t.Run("good", func(t *testing.T) {
+		const (
+			expectedURL   = "%FIND-URL%"
+			expectedTitle = "%FIND-TITLE%"
+		)
+		rw := httptest.NewRecorder()
+		RenderEditLink(rw, &bookmarks.Bookmark{
+			ID:    1,
+			URL:   expectedURL,
+			Title: expectedTitle,
+			Tags:  []string{"a", "b"},
+		})
+		body := rw.Body.String()
+		for _, pattern := range []string{expectedURL, expectedTitle, "a, b", `data-hx-put="/bookmarks/1"`} {
+			if !strings.Contains(body, pattern) {
+				t.Error("cannot find pattern:", pattern)
+			}
+		}
+	})
+}
+
 func TestRenderLinkTable(t *testing.T) {
 	t.Run("badWriter", func(t *testing.T) {
 		brw := &badResponseWriter{}
-		RenderLinkTable(brw, nil, -1, "")
+		RenderLinkTable(brw, nil, nil, "")
 		if brw.recordedStatusCode != http.StatusInternalServerError {
 			t.Fatal("unexpected status code:", brw.recordedStatusCode)
 		}
@@ -75,7 +108,7 @@ func TestRenderLinkTable(t *testing.T) {
 				Title:            expectedTitle,
 				LastStatusReason: expectedReason,
 			},
-		}, -1, "")
+		}, nil, "")
 		body := rw.Body.String()
 		if !strings.Contains(body, expectedURL) {
 			t.Error("cannot find URL pattern")
@@ -87,6 +120,37 @@ func TestRenderLinkTable(t *testing.T) {
 			t.Error("cannot find last status reason pattern")
 		}
 	})
+	t.Run("nextPage", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		RenderLinkTable(rw, []*bookmarks.Bookmark{
+			{ID: 1, URL: "https://example.com", BumpDate: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		}, url.Values{"page": {"2"}, "term": {"a&b"}}, "")
+		body := rw.Body.String()
+		if !strings.Contains(body, `hx-get="?lastDate=Jan&#43;&#43;2&#43;2024&amp;page=2&amp;term=a%26b"`) {
+			t.Error("cannot find next page trigger:", body)
+		}
+	})
+	t.Run("lastPage", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		RenderLinkTable(rw, nil, url.Values{"page": {"2"}}, "")
+		if strings.Contains(rw.Body.String(), "hx-trigger") {
+			t.Error("unexpected next page trigger")
+		}
+	})
+	t.Run("snippet", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		RenderLinkTable(rw, []*bookmarks.Bookmark{
+			{
+				ID:      1,
+				URL:     "https://example.com",
+				Snippet: "<b>" + bookmarks.SnippetMatchStart + "match" + bookmarks.SnippetMatchEnd,
+			},
+		}, nil, "")
+		body := rw.Body.String()
+		if !strings.Contains(body, "&lt;b&gt;<mark>match</mark>") {
+			t.Error("cannot find highlighted snippet")
+		}
+	})
 	t.Run("badStatusCode", func(t *testing.T) {
 		const (
 			expectedURL   = "%FIND-URL%"
@@ -100,7 +164,7 @@ func TestRenderLinkTable(t *testing.T) {
 				Title:          expectedTitle,
 				LastStatusCode: http.StatusInternalServerError,
 			},
-		}, -1, "")
+		}, nil, "")
 		body := rw.Body.String()
 		if !strings.Contains(body, expectedURL) {
 			t.Error("cannot find URL pattern")
@@ -114,10 +178,269 @@ func TestRenderLinkTable(t *testing.T) {
 	})
 }
 
+func TestRenderLink(t *testing.T) {
+	t.Run("badWriter", func(t *testing.T) {
+		brw := &badResponseWriter{}
+		RenderLink(brw, &bookmarks.Bookmark{})
+		if brw.recordedStatusCode != http.StatusInternalServerError {
+			t.Fatal("unexpected status code:", brw.recordedStatusCode)
+		}
+	})
+	t.Run("good", func(t *testing.T) {
+		const (
+			expectedURL = "%FIND-URL%"
+			expectedTag = "find-tag"
+		)
+		rw := httptest.NewRecorder()
+		RenderLink(rw, &bookmarks.Bookmark{
+			ID:   1,
+			URL:  expectedURL,
+			Tags: []string{expectedTag},
+		})
+		body := rw.Body.String()
+		if !strings.Contains(body, expectedURL) {
+			t.Error("cannot find URL pattern")
+		}
+		if !strings.Contains(body, "/tags/"+expectedTag) {
+			t.Error("cannot find tag link")
+		}
+		if strings.Contains(body, "action=move") {
+			t.Error("unexpected button to follow redirect")
+		}
+	})
+	t.Run("dead", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		RenderLink(rw, &bookmarks.Bookmark{
+			ID:             1,
+			URL:            "%FIND-URL%",
+			LastStatusCode: http.StatusNotFound,
+			FailureCount:   4,
+			FailingSince:   time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC).Unix(),
+			Dead:           true,
+		})
+		body := rw.Body.String()
+		if !strings.Contains(body, "dead, failing since Jan  2 2024") || !strings.Contains(body, "4 failed checks") {
+			t.Error("cannot find failure history")
+		}
+	})
+	t.Run("blocked", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		RenderLink(rw, &bookmarks.Bookmark{ID: 1, URL: "%FIND-URL%", LastStatusCode: bookmarks.StatusBlockedByRobots})
+		body := rw.Body.String()
+		if !strings.Contains(body, "robots.txt does not allow it") || strings.Contains(body, "⚠️") {
+			t.Error("cannot find robots.txt notice")
+		}
+	})
+	t.Run("subtitle", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		RenderLink(rw, &bookmarks.Bookmark{ID: 1, URL: "%FIND-URL%", Metadata: bookmarks.Metadata{SiteName: "%FIND-SITE%", Author: "%FIND-AUTHOR%"}})
+		if body := rw.Body.String(); !strings.Contains(body, "%FIND-SITE% · by %FIND-AUTHOR%") {
+			t.Error("cannot find subtitle")
+		}
+	})
+	t.Run("privateAddress", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		RenderLink(rw, &bookmarks.Bookmark{ID: 1, URL: "%FIND-URL%", LastStatusCode: bookmarks.StatusPrivateAddress})
+		body := rw.Body.String()
+		if !strings.Contains(body, "leads to a private address") || strings.Contains(body, "⚠️") {
+			t.Error("cannot find private address notice")
+		}
+	})
+	t.Run("softFailure", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		RenderLink(rw, &bookmarks.Bookmark{ID: 1, URL: "%FIND-URL%", LastStatusCode: bookmarks.StatusSoftFailure, LastStatusReason: "parked domain"})
+		body := rw.Body.String()
+		if !strings.Contains(body, "not the bookmarked page - parked domain") || strings.Contains(body, "⚠️") {
+			t.Error("cannot find soft failure notice")
+		}
+	})
+	t.Run("moved", func(t *testing.T) {
+		const expectedFinalURL = "%FIND-FINAL-URL%"
+		rw := httptest.NewRecorder()
+		RenderLink(rw, &bookmarks.Bookmark{
+			ID:            1,
+			URL:           "%FIND-URL%",
+			FinalURL:      expectedFinalURL,
+			RedirectCount: 3,
+
+			PermanentRedirect: true,
+		})
+		body := rw.Body.String()
+		if !strings.Contains(body, expectedFinalURL) || !strings.Contains(body, "3 redirects") {
+			t.Error("cannot find where the link moved to")
+		}
+		if !strings.Contains(body, "/bookmarks/1?action=move") {
+			t.Error("cannot find button to follow redirect")
+		}
+	})
+	t.Run("movedTemporarily", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		RenderLink(rw, &bookmarks.Bookmark{
+			ID:            1,
+			URL:           "%FIND-URL%",
+			FinalURL:      "%FIND-FINAL-URL%",
+			RedirectCount: 1,
+		})
+		body := rw.Body.String()
+		if !strings.Contains(body, "temporarily") {
+			t.Error("cannot find that the link moved temporarily")
+		}
+		if strings.Contains(body, "action=move") {
+			t.Error("unexpected button to follow a temporary redirect")
+		}
+	})
+}
+
+func TestRenderLinkHistory(t *testing.T) {
+	t.Run("badWriter", func(t *testing.T) {
+		brw := &badResponseWriter{}
+		RenderLinkHistory(brw, &bookmarks.LinkHistory{Bookmark: &bookmarks.Bookmark{}})
+		if brw.recordedStatusCode != http.StatusInternalServerError {
+			t.Fatal("unexpected status code:", brw.recordedStatusCode)
+		}
+	})
+	t.Run("good", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		RenderLinkHistory(rw, &bookmarks.LinkHistory{
+			Bookmark: &bookmarks.Bookmark{ID: 1},
+			Checks: []*bookmarks.LinkCheck{
+				{CheckedAt: 2, Code: http.StatusServiceUnavailable, Reason: "%FIND-REASON%"},
+				{CheckedAt: 1, Code: http.StatusOK},
+			},
+			Uptime: 50,
+		})
+		body := rw.Body.String()
+		if !strings.Contains(body, `id="history-1"`) || !strings.Contains(body, "50.0% uptime over the last 2 checks") {
+			t.Error("cannot find uptime")
+		}
+		if !strings.Contains(body, "%FIND-REASON%") {
+			t.Error("cannot find check reason")
+		}
+		if strings.Index(body, "🟩") > strings.Index(body, "🟥") {
+			t.Error("timeline is not oldest first")
+		}
+	})
+	t.Run("neverChecked", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		RenderLinkHistory(rw, &bookmarks.LinkHistory{Bookmark: &bookmarks.Bookmark{ID: 1}})
+		if !strings.Contains(rw.Body.String(), "not been checked yet") {
+			t.Error("cannot find empty history message")
+		}
+	})
+}
+
+func TestRenderAdmin(t *testing.T) {
+	started := time.Date(2023, 5, 6, 7, 0, 0, 0, time.UTC)
+	t.Run("badWriter", func(t *testing.T) {
+		brw := &badResponseWriter{}
+		RenderAdmin(brw, linkhealth.Status{})
+		if brw.recordedStatusCode != http.StatusInternalServerError {
+			t.Fatal("unexpected status code:", brw.recordedStatusCode)
+		}
+	})
+	tests := []struct {
+		name     string
+		status   linkhealth.Status
+		want     string
+		disabled bool
+	}{
+		{"neverRan", linkhealth.Status{}, "not been checked yet", false},
+		{"running", linkhealth.Status{Running: true, Last: linkhealth.Run{StartedAt: started}}, "Checking links since May  6 2023 07:00", true},
+		{"pending", linkhealth.Status{Pending: true}, "starts as soon as possible", true},
+		{"interrupted", linkhealth.Status{Last: linkhealth.Run{StartedAt: started}}, "was interrupted", false},
+		{"failed", linkhealth.Status{Last: linkhealth.Run{StartedAt: started, FinishedAt: started.Add(time.Minute), Err: "%FIND-ERROR%"}}, "It failed: %FIND-ERROR%", false},
+		{"next", linkhealth.Status{Next: started.Add(time.Hour)}, "Next run at May  6 2023 08:00", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			RenderAdmin(rw, tt.status)
+			body := rw.Body.String()
+			if !strings.Contains(body, tt.want) {
+				t.Errorf("cannot find %q in %s", tt.want, body)
+			}
+			if got := strings.Contains(body, " disabled>"); got != tt.disabled {
+				t.Errorf("button disabled = %v, want %v", got, tt.disabled)
+			}
+		})
+	}
+}
+
+func TestRenderTags(t *testing.T) {
+	t.Run("badWriter", func(t *testing.T) {
+		brw := &badResponseWriter{}
+		RenderTags(brw, nil)
+		if brw.recordedStatusCode != http.StatusInternalServerError {
+			t.Fatal("unexpected status code:", brw.recordedStatusCode)
+		}
+	})
+	t.Run("good", func(t *testing.T) {
+		const expectedTag = "find-tag"
+		rw := httptest.NewRecorder()
+		RenderTags(rw, []*bookmarks.Tag{{Name: expectedTag, Count: 42}})
+		body := rw.Body.String()
+		if !strings.Contains(body, "#"+expectedTag) {
+			t.Error("cannot find tag pattern")
+		}
+		if !strings.Contains(body, "42") {
+			t.Error("cannot find tag count")
+		}
+	})
+}
+
+func TestRenderImport(t *testing.T) {
+	t.Run("badWriter", func(t *testing.T) {
+		brw := &badResponseWriter{}
+		RenderImport(brw, nil, importer.Netscape)
+		if brw.recordedStatusCode != http.StatusInternalServerError {
+			t.Fatal("unexpected status code:", brw.recordedStatusCode)
+		}
+	})
+	t.Run("form", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		RenderImport(rw, nil, importer.Pinboard)
+		body := rw.Body.String()
+		if !strings.Contains(body, `type="file"`) {
+			t.Error("cannot find upload form")
+		}
+		if !strings.Contains(body, `href="/export/jsonl"`) {
+			t.Error("cannot find export links")
+		}
+		if !strings.Contains(body, `<option value="pinboard" selected>`) || strings.Count(body, "<option") != len(importer.Formats) {
+			t.Error("cannot find format selection")
+		}
+		if strings.Contains(body, "import-report") {
+			t.Error("unexpected import report")
+		}
+	})
+	t.Run("report", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		RenderImport(rw, &bookmarks.ImportReport{
+			Created:    3,
+			Duplicates: 2,
+			Failures:   []bookmarks.ImportFailure{{URL: "javascript:%FIND-URL%", Reason: "%FIND-REASON%"}},
+		}, importer.Netscape)
+		body := rw.Body.String()
+		if !strings.Contains(body, "3 created, 2 skipped as duplicates, 1 failed.") {
+			t.Error("cannot find import counts")
+		}
+		if !strings.Contains(body, "%FIND-URL%") || !strings.Contains(body, "%FIND-REASON%") {
+			t.Error("cannot find import failure")
+		}
+	})
+	t.Run("dryRun", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		RenderImport(rw, &bookmarks.ImportReport{DryRun: true, Created: 3}, importer.Netscape)
+		if body := rw.Body.String(); !strings.Contains(body, "nothing was imported: 3 would be created") {
+			t.Error("cannot find dry run report")
+		}
+	})
+}
+
 func TestRenderIndex(t *testing.T) {
 	t.Run("badWriter", func(t *testing.T) {
 		brw := &badResponseWriter{}
-		RenderIndex(brw, "/", "", "")
+		RenderIndex(brw, "/", "", "", "")
 		if brw.recordedStatusCode != http.StatusInternalServerError {
 			t.Fatal("unexpected status code:", brw.recordedStatusCode)
 		}
@@ -125,11 +448,73 @@ func TestRenderIndex(t *testing.T) {
 	t.Run("good", func(t *testing.T) {
 		const expectedPattern = "%FIND-ME%"
 		rw := httptest.NewRecorder()
-		RenderIndex(rw, "/", "", expectedPattern)
+		RenderIndex(rw, "/", "", expectedPattern, "")
 		body := rw.Body.String()
 		if !strings.Contains(body, expectedPattern) {
 			t.Error("cannot find pattern")
 		}
+		if strings.Contains(body, "/logout") {
+			t.Error("unexpected logout button")
+		}
+	})
+	t.Run("account", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		RenderIndex(rw, "/", "", "", "laptop")
+		if !strings.Contains(rw.Body.String(), `action="/logout"`) {
+			t.Error("cannot find logout button")
+		}
+	})
+}
+
+func TestRenderLogin(t *testing.T) {
+	t.Run("badWriter", func(t *testing.T) {
+		brw := &badResponseWriter{}
+		RenderLogin(brw, "", "", "", false)
+		if brw.recordedStatusCode != http.StatusInternalServerError {
+			t.Fatal("unexpected status code:", brw.recordedStatusCode)
+		}
+	})
+	t.Run("good", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		RenderLogin(rw, "/inbox?cursor=a&b", "%FIND-USER%", "%FIND-ME%", false)
+		body := rw.Body.String()
+		if !strings.Contains(body, "%FIND-ME%") || !strings.Contains(body, "%FIND-USER%") {
+			t.Error("cannot find message or username")
+		}
+		if !strings.Contains(body, `value="/inbox?cursor=a&amp;b"`) {
+			t.Error("cannot find escaped next page")
+		}
+		if strings.Contains(body, `href="/signup`) {
+			t.Error("unexpected signup link")
+		}
+	})
+	t.Run("signupOpen", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		RenderLogin(rw, "/inbox", "", "", true)
+		if !strings.Contains(rw.Body.String(), `href="/signup?next=%2finbox"`) {
+			t.Error("cannot find signup link:", rw.Body.String())
+		}
+	})
+}
+
+func TestRenderSignup(t *testing.T) {
+	t.Run("badWriter", func(t *testing.T) {
+		brw := &badResponseWriter{}
+		RenderSignup(brw, "", "", "", 8)
+		if brw.recordedStatusCode != http.StatusInternalServerError {
+			t.Fatal("unexpected status code:", brw.recordedStatusCode)
+		}
+	})
+	t.Run("good", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		RenderSignup(rw, "/inbox", "%FIND-USER%", "%FIND-ME%", 8)
+		body := rw.Body.String()
+		if !strings.Contains(body, "%FIND-ME%") || !strings.Contains(body, "%FIND-USER%") {
+			t.Error("cannot find message or username")
+		}
+		if !strings.Contains(body, `action="/signup"`) || !strings.Contains(body, `minlength="8"`) {
+			t.Error("cannot find signup form")
+		}
 	})
 }
 
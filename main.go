@@ -15,6 +15,7 @@
 package main // import "cirello.io/alreadyread"
 
 import (
+	"bufio"
 	"context"
 	"database/sql"
 	"flag"
@@ -24,10 +25,16 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strings"
+	"text/tabwriter"
 	"time"
 
+	"cirello.io/alreadyread/pkg/auth"
 	"cirello.io/alreadyread/pkg/bookmarks"
+	"cirello.io/alreadyread/pkg/bookmarks/export"
+	"cirello.io/alreadyread/pkg/bookmarks/importer"
+	"cirello.io/alreadyread/pkg/bookmarks/linkhealth"
 	"cirello.io/alreadyread/pkg/bookmarks/sqliterepo"
 	"cirello.io/alreadyread/pkg/bookmarks/url"
 	"cirello.io/alreadyread/pkg/web"
@@ -36,11 +43,41 @@ import (
 	_ "modernc.org/sqlite" // SQLite3 driver
 )
 
+// Schedules of the maintenance tasks of the database, as cron expressions.
+const (
+	vacuumSchedule       = "0 */6 * * *"
+	historyPruneSchedule = "30 3 * * *"
+)
+
 var (
 	dbFN           = flag.String("db", envOrDefault("ALREADYREAD_DB", "bookmarks.db"), "database filename")
 	bind           = flag.String("bind", envOrDefault("ALREADYREAD_LISTEN", ":8080"), "bind address for the server")
 	allowedOrigins = flag.String("allowedOrigins", envOrDefault("ALREADYREAD_ALLOWEDORIGINS", "localhost:8080"), "comma-separated value for allowed origins")
 	scanDeadLinks  = flag.Bool("scanDeadLinks", false, "scan dead links")
+	deadFailures   = flag.Int("deadAfterFailures", bookmarks.DefaultDeadAfterFailures, "number of consecutive failed checks before a link is declared dead")
+	deadAfter      = flag.Duration("deadAfter", bookmarks.DefaultDeadAfter, "how long a link must keep failing before it is declared dead")
+	checkSchedule  = flag.String("linkHealthSchedule", envOrDefault("ALREADYREAD_LINKHEALTHSCHEDULE", linkhealth.DefaultSchedule), "cron expression of when the links that are due get checked")
+	checkWorkers   = flag.Int("checkWorkers", bookmarks.DefaultLinkCheckWorkers, "number of links checked at a time")
+	hostWorkers    = flag.Int("hostWorkers", bookmarks.DefaultHostWorkers, "number of links on the same host checked at a time")
+	userAgent      = flag.String("userAgent", envOrDefault("ALREADYREAD_USERAGENT", url.DefaultUserAgent), "User-Agent of the link checker; its name, up to the first slash, picks the robots.txt rules that apply")
+	hostDelay      = flag.Duration("hostDelay", bookmarks.DefaultHostDelay, "how long to wait between two requests to the same host when checking links")
+	connectTimeout = flag.Duration("connectTimeout", url.DefaultConnectTimeout, "how long the link checker waits for a host to accept the connection")
+	readTimeout    = flag.Duration("readTimeout", url.DefaultReadTimeout, "how long the link checker waits for a host to send anything, and to send a whole response")
+	checkTimeout   = flag.Duration("checkTimeout", url.DefaultTotalTimeout, "how long a link check may take, redirects and robots.txt included")
+	maxTitleBytes  = flag.Int64("maxTitleBytes", url.DefaultMaxTitleBytes, "how much of a page the link checker reads looking for its title")
+	allowHosts     = flag.String("allowHosts", envOrDefault("ALREADYREAD_ALLOWHOSTS", ""), "comma-separated hostnames, IP addresses or CIDR networks of private hosts that the link checker may reach")
+	keepHistory    = flag.Duration("keepHistory", bookmarks.DefaultHistoryRetention, "how long the link check history is kept")
+	pageSize       = flag.Int("pageSize", bookmarks.DefaultPageSize, "number of bookmarks listed per page")
+	requireAuth    = flag.Bool("auth", envOrDefault("ALREADYREAD_AUTH", "false") == "true", "require users to log in to use the server")
+	allowSignup    = flag.Bool("allowSignup", envOrDefault("ALREADYREAD_ALLOWSIGNUP", "false") == "true", "let anyone sign up, not only the first user")
+	mintToken      = flag.String("mintToken", "", "mint an access token with the given name for -user and exit")
+	tokenUser      = flag.String("user", "", "username that owns the token minted with -mintToken, or the bookmarks imported with -import or exported with -export")
+	importFile     = flag.String("import", "", "import the bookmarks in the given file and exit")
+	importFormat   = flag.String("importFormat", string(importer.Netscape), "format of the file given to -import: netscape (bookmarks.html), pocket, instapaper, pinboard, firefox (places.sqlite) or chrome (Bookmarks)")
+	dryRun         = flag.Bool("dryRun", false, "with -import, only report what would be imported")
+	exportFile     = flag.String("export", "", "export all bookmarks to the given file and exit; the extension picks the format: .html (Netscape bookmark file), .jsonl or .csv")
+	revokeToken    = flag.Int64("revokeToken", 0, "revoke the access token with the given ID and exit")
+	listTokens     = flag.Bool("listTokens", false, "list access tokens and exit")
 )
 
 func main() {
@@ -56,15 +93,6 @@ func main() {
 		return
 	}
 	db.SetMaxOpenConns(1)
-	lHTTP, err := net.Listen("tcp", *bind)
-	if err != nil {
-		log.Println("cannot bind port:", err)
-		return
-	}
-	go func() {
-		<-ctx.Done()
-		lHTTP.Close()
-	}()
 
 	repository := sqliterepo.New(db)
 	if err := repository.Bootstrap(); err != nil {
@@ -72,7 +100,111 @@ func main() {
 		return
 	}
 
-	bookmarks := bookmarks.New(repository, url.NewChecker())
+	authService := auth.New(repository, auth.WithSignup(*allowSignup))
+	switch {
+	case *mintToken != "":
+		user, err := authService.User(*tokenUser)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		token, secret, err := authService.Mint(user.ID, *mintToken)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		fmt.Printf("token %d (%s) for %s: %s\n", token.ID, token.Name, user.Username, secret)
+		fmt.Println("store it safely, it will not be shown again")
+		return
+	case *revokeToken != 0:
+		if err := authService.Revoke(*revokeToken); err != nil {
+			log.Println(err)
+			return
+		}
+		fmt.Println("token revoked")
+		return
+	case *listTokens:
+		tokens, err := authService.Tokens()
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tUSER ID\tNAME\tCREATED")
+		for _, token := range tokens {
+			fmt.Fprintf(tw, "%d\t%d\t%s\t%s\n", token.ID, token.UserID, token.Name, token.CreatedAt.Format(time.DateTime))
+		}
+		tw.Flush()
+		return
+	}
+
+	users, err := authService.Users()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	// Without authentication, the server works on the bookmarks of the
+	// first user, if any.
+	var defaultOwner int64
+	if len(users) > 0 {
+		defaultOwner = users[0].ID
+	}
+
+	ownerID := defaultOwner
+	if *tokenUser != "" && (*importFile != "" || *exportFile != "") {
+		user, err := authService.User(*tokenUser)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		ownerID = user.ID
+	}
+
+	checker := url.NewChecker(
+		url.WithUserAgent(*userAgent),
+		url.WithTimeouts(*connectTimeout, *readTimeout, *checkTimeout),
+		url.WithMaxTitleBytes(*maxTitleBytes),
+		url.WithAllowedHosts(strings.Split(*allowHosts, ",")...),
+	)
+	bookmarks := bookmarks.New(repository, checker,
+		bookmarks.WithPageSize(*pageSize),
+		bookmarks.WithDeadLinkPolicy(*deadFailures, *deadAfter),
+		bookmarks.WithLinkCheckWorkers(*checkWorkers, *hostWorkers),
+		bookmarks.WithHostDelay(*hostDelay),
+	)
+	if *exportFile != "" {
+		if err := exportBookmarks(bookmarks, ownerID, *exportFile); err != nil {
+			log.Println(err)
+		}
+		return
+	}
+	if *importFile != "" {
+		format, err := importer.ParseFormat(*importFormat)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		list, err := importer.ParseFile(format, *importFile)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		report, err := bookmarks.Import(ownerID, list, *dryRun)
+		if err != nil {
+			log.Println(err)
+		}
+		if report != nil {
+			for _, failure := range report.Failures {
+				fmt.Printf("failed: %s: %s\n", failure.URL, failure.Reason)
+			}
+			if report.DryRun {
+				fmt.Printf("dry run, nothing was imported: %d would be created, %d skipped as duplicates, %d failed\n", report.Created, report.Duplicates, len(report.Failures))
+			} else {
+				fmt.Printf("%d created, %d skipped as duplicates, %d failed\n", report.Created, report.Duplicates, len(report.Failures))
+			}
+		}
+		return
+	}
 	if *scanDeadLinks {
 		err := bookmarks.RefreshExpiredLinks(ctx)
 		if err != nil {
@@ -83,7 +215,32 @@ func main() {
 		return
 	}
 
-	webserver := web.New(bookmarks, url.NewChecker(), strings.Split(*allowedOrigins, ","))
+	linkHealth, err := linkhealth.New(bookmarks, repository, *checkSchedule)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	webOpts := []web.Option{web.WithLinkHealth(linkHealth)}
+	if *requireAuth {
+		if len(users) == 0 {
+			log.Println("authentication is enabled but there are no users, sign up at /signup")
+		}
+		webOpts = append(webOpts, web.WithAuth(authService))
+	} else {
+		webOpts = append(webOpts, web.WithDefaultOwner(defaultOwner))
+	}
+	webserver := web.New(bookmarks, checker, strings.Split(*allowedOrigins, ","), webOpts...)
+
+	lHTTP, err := net.Listen("tcp", *bind)
+	if err != nil {
+		log.Println("cannot bind port:", err)
+		return
+	}
+	go func() {
+		<-ctx.Done()
+		lHTTP.Close()
+	}()
 
 	svr := oversight.New(
 		oversight.WithLogger(log.Default()),
@@ -95,7 +252,31 @@ func main() {
 				Restart: oversight.Permanent(),
 				Start: func(ctx context.Context) error {
 					err := repository.Vacuum(ctx)
-					t, _ := gronx.NextTickAfter("0 */6 * * *", time.Now(), false)
+					t, _ := gronx.NextTickAfter(vacuumSchedule, time.Now(), false)
+					select {
+					case <-time.After(time.Until(t)):
+						return err
+					case <-ctx.Done():
+						return ctx.Err()
+					}
+				},
+				Shutdown: oversight.Infinity(),
+			},
+			oversight.ChildProcessSpecification{
+				Name:     "linkHealth",
+				Restart:  oversight.Permanent(),
+				Start:    linkHealth.Run,
+				Shutdown: oversight.Infinity(),
+			},
+			oversight.ChildProcessSpecification{
+				Name:    "linkHistoryPruning",
+				Restart: oversight.Permanent(),
+				Start: func(ctx context.Context) error {
+					pruned, err := bookmarks.PruneHistory(*keepHistory)
+					if pruned > 0 {
+						log.Println("linkHistoryPruning: deleted", pruned, "link checks")
+					}
+					t, _ := gronx.NextTickAfter(historyPruneSchedule, time.Now(), false)
 					select {
 					case <-time.After(time.Until(t)):
 						return err
@@ -135,6 +316,35 @@ func main() {
 	}
 }
 
+// exportBookmarks writes all bookmarks of the owner to the file, in the format
+// named by its extension.
+func exportBookmarks(b *bookmarks.Bookmarks, ownerID int64, fn string) error {
+	format, err := export.ParseFormat(strings.TrimPrefix(filepath.Ext(fn), "."))
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(fn)
+	if err != nil {
+		return fmt.Errorf("cannot create export file: %w", err)
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	enc, err := export.NewEncoder(format, w)
+	if err != nil {
+		return err
+	}
+	if err := b.Walk(ownerID, enc.Encode); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("cannot write export file: %w", err)
+	}
+	return f.Close()
+}
+
 func envOrDefault(name string, defaultValue string) string {
 	if v := os.Getenv(name); v != "" {
 		return v
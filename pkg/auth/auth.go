@@ -0,0 +1,339 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package auth implements user accounts, along with the access tokens and
+// browser sessions that identify them.
+package auth // import "cirello.io/alreadyread/pkg/auth"
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// SessionTTL is how long a browser session lasts.
+const SessionTTL = 30 * 24 * time.Hour
+
+// tokenPrefix makes tokens easy to recognize, for instance by secret
+// scanners.
+const tokenPrefix = "ar_"
+
+// MinPasswordLength is the shortest password accepted at signup.
+const MinPasswordLength = 8
+
+const maxUsernameLength = 64
+
+var (
+	// ErrUnauthorized is returned when the credentials are unknown or
+	// expired.
+	ErrUnauthorized = errors.New("unauthorized")
+
+	// ErrSignupClosed is returned when new users cannot sign up.
+	ErrSignupClosed = errors.New("signup is closed")
+
+	// ErrUsernameTaken is returned when signing up with a username that is
+	// already in use.
+	ErrUsernameTaken = errors.New("username is already taken")
+
+	// ErrInvalidUsername is returned when signing up with an empty or
+	// overly long username, or one with spaces or control characters.
+	ErrInvalidUsername = errors.New("invalid username")
+
+	// ErrWeakPassword is returned when signing up with a password shorter
+	// than MinPasswordLength.
+	ErrWeakPassword = fmt.Errorf("password must have at least %d characters", MinPasswordLength)
+)
+
+var errEmptyTokenName = errors.New("token name cannot be empty")
+
+// Auth manages users, mints their tokens and checks the credentials presented
+// to the server.
+type Auth struct {
+	repository  Repository
+	allowSignup bool
+	now         func() time.Time
+
+	// dummyHash is checked against when logging in as an unknown user, so
+	// that response times do not reveal which usernames exist.
+	dummyHash func() string
+}
+
+// Option configures the authentication service.
+type Option func(*Auth)
+
+// WithSignup lets anyone create a user account. Otherwise, only the first
+// user can sign up.
+func WithSignup(allow bool) Option {
+	return func(a *Auth) {
+		a.allowSignup = allow
+	}
+}
+
+// New creates an authentication service.
+func New(repository Repository, opts ...Option) *Auth {
+	a := &Auth{
+		repository: repository,
+		now:        time.Now,
+		dummyHash: sync.OnceValue(func() string {
+			h, _ := hashPassword(rand.Text())
+			return h
+		}),
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// SignUp creates a new user. The first user to sign up takes over the
+// bookmarks and tokens created before user accounts existed.
+func (a *Auth) SignUp(username, password string) (*User, error) {
+	username = strings.TrimSpace(username)
+	if !validUsername(username) {
+		return nil, ErrInvalidUsername
+	}
+	if len(password) < MinPasswordLength {
+		return nil, ErrWeakPassword
+	}
+	if open, err := a.SignupOpen(); err != nil {
+		return nil, err
+	} else if !open {
+		return nil, ErrSignupClosed
+	}
+	if _, err := a.repository.UserByUsername(username); err == nil {
+		return nil, ErrUsernameTaken
+	} else if !errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("cannot load user: %w", err)
+	}
+	passwordHash, err := hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	user := &User{
+		Username:     username,
+		PasswordHash: passwordHash,
+		CreatedAt:    a.now(),
+	}
+	// Whether this is the first user is decided by the repository, as the
+	// user is stored, so that concurrent signups cannot both be first.
+	err = a.repository.InsertFirstUser(user)
+	if errors.Is(err, ErrSignupClosed) && a.allowSignup {
+		err = a.repository.InsertUser(user)
+	}
+	if errors.Is(err, ErrSignupClosed) {
+		return nil, ErrSignupClosed
+	} else if err != nil {
+		return nil, fmt.Errorf("cannot store user: %w", err)
+	}
+	return user, nil
+}
+
+// SignupOpen reports whether SignUp would accept new users.
+func (a *Auth) SignupOpen() (bool, error) {
+	if a.allowSignup {
+		return true, nil
+	}
+	users, err := a.repository.Users()
+	if err != nil {
+		return false, fmt.Errorf("cannot load users: %w", err)
+	}
+	return len(users) == 0, nil
+}
+
+func validUsername(username string) bool {
+	if username == "" || len(username) > maxUsernameLength {
+		return false
+	}
+	for _, r := range username {
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// User loads the user with the given username.
+func (a *Auth) User(username string) (*User, error) {
+	user, err := a.repository.UserByUsername(strings.TrimSpace(username))
+	if err != nil {
+		return nil, fmt.Errorf("cannot load user: %w", err)
+	}
+	return user, nil
+}
+
+// Users lists all users, in order of creation.
+func (a *Auth) Users() ([]*User, error) {
+	users, err := a.repository.Users()
+	if err != nil {
+		return nil, fmt.Errorf("cannot load users: %w", err)
+	}
+	return users, nil
+}
+
+// IsAdmin reports whether the user administers the server. The
+// administrator is the first user to sign up, who also took over the
+// bookmarks created before user accounts existed.
+func (a *Auth) IsAdmin(user *User) (bool, error) {
+	users, err := a.repository.Users()
+	if err != nil {
+		return false, fmt.Errorf("cannot load users: %w", err)
+	}
+	return len(users) > 0 && user != nil && users[0].ID == user.ID, nil
+}
+
+// Mint creates a new token with the given name for the user. The returned
+// secret is not stored and cannot be recovered later.
+func (a *Auth) Mint(userID int64, name string) (*Token, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", errEmptyTokenName
+	}
+	secret := tokenPrefix + rand.Text()
+	token := &Token{
+		UserID:    userID,
+		Name:      name,
+		Hash:      hash(secret),
+		CreatedAt: a.now(),
+	}
+	if err := a.repository.InsertToken(token); err != nil {
+		return nil, "", fmt.Errorf("cannot store token: %w", err)
+	}
+	return token, secret, nil
+}
+
+// Revoke deletes the token.
+func (a *Auth) Revoke(id int64) error {
+	if err := a.repository.DeleteToken(id); err != nil {
+		return fmt.Errorf("cannot revoke token: %w", err)
+	}
+	return nil
+}
+
+// Tokens lists all tokens.
+func (a *Auth) Tokens() ([]*Token, error) {
+	tokens, err := a.repository.Tokens()
+	if err != nil {
+		return nil, fmt.Errorf("cannot load tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+// Authenticate returns the owner of the token that matches the secret.
+// Tokens minted before user accounts existed are rejected until the first
+// user signs up and adopts them.
+func (a *Auth) Authenticate(secret string) (*User, error) {
+	if secret == "" {
+		return nil, ErrUnauthorized
+	}
+	token, err := a.repository.TokenByHash(hash(secret))
+	if errors.Is(err, ErrNotFound) {
+		return nil, ErrUnauthorized
+	} else if err != nil {
+		return nil, fmt.Errorf("cannot load token: %w", err)
+	}
+	return a.userByID(token.UserID)
+}
+
+// Login opens a browser session for the user. The returned session secret is
+// meant to be stored in a cookie.
+func (a *Auth) Login(username, password string) (*Session, string, error) {
+	user, err := a.repository.UserByUsername(strings.TrimSpace(username))
+	if errors.Is(err, ErrNotFound) {
+		_, _ = checkPassword(a.dummyHash(), password)
+		return nil, "", ErrUnauthorized
+	} else if err != nil {
+		return nil, "", fmt.Errorf("cannot load user: %w", err)
+	}
+	ok, err := checkPassword(user.PasswordHash, password)
+	if err != nil {
+		return nil, "", fmt.Errorf("cannot check password: %w", err)
+	} else if !ok {
+		return nil, "", ErrUnauthorized
+	}
+	now := a.now()
+	if err := a.repository.DeleteExpiredSessions(now); err != nil {
+		return nil, "", fmt.Errorf("cannot clean up sessions: %w", err)
+	}
+	secret := rand.Text()
+	session := &Session{
+		Hash:      hash(secret),
+		UserID:    user.ID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(SessionTTL),
+	}
+	if err := a.repository.InsertSession(session); err != nil {
+		return nil, "", fmt.Errorf("cannot store session: %w", err)
+	}
+	return session, secret, nil
+}
+
+// Session returns the user logged in with the given session secret.
+func (a *Auth) Session(secret string) (*User, error) {
+	if secret == "" {
+		return nil, ErrUnauthorized
+	}
+	session, err := a.repository.SessionByHash(hash(secret))
+	if errors.Is(err, ErrNotFound) {
+		return nil, ErrUnauthorized
+	} else if err != nil {
+		return nil, fmt.Errorf("cannot load session: %w", err)
+	}
+	if !a.now().Before(session.ExpiresAt) {
+		return nil, ErrUnauthorized
+	}
+	return a.userByID(session.UserID)
+}
+
+func (a *Auth) userByID(id int64) (*User, error) {
+	user, err := a.repository.UserByID(id)
+	if errors.Is(err, ErrNotFound) {
+		return nil, ErrUnauthorized
+	} else if err != nil {
+		return nil, fmt.Errorf("cannot load user: %w", err)
+	}
+	return user, nil
+}
+
+// Logout ends the session with the given secret.
+func (a *Auth) Logout(secret string) error {
+	if err := a.repository.DeleteSession(hash(secret)); err != nil {
+		return fmt.Errorf("cannot end session: %w", err)
+	}
+	return nil
+}
+
+func hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the authenticated user.
+func NewContext(ctx context.Context, user *User) context.Context {
+	return context.WithValue(ctx, contextKey{}, user)
+}
+
+// FromContext returns the authenticated user carried by ctx, if any.
+func FromContext(ctx context.Context) (*User, bool) {
+	user, ok := ctx.Value(contextKey{}).(*User)
+	return user, ok
+}
@@ -0,0 +1,417 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	passwordIterations = 1000
+	os.Exit(m.Run())
+}
+
+// newUserRepository keeps users and sessions in memory.
+func newUserRepository() *RepositoryMock {
+	var users []*User
+	sessions := make(map[string]*Session)
+	insertUser := func(user *User) error {
+		user.ID = int64(len(users) + 1)
+		users = append(users, user)
+		return nil
+	}
+	return &RepositoryMock{
+		UsersFunc:      func() ([]*User, error) { return users, nil },
+		InsertUserFunc: insertUser,
+		InsertFirstUserFunc: func(user *User) error {
+			if len(users) > 0 {
+				return ErrSignupClosed
+			}
+			return insertUser(user)
+		},
+		UserByIDFunc: func(id int64) (*User, error) {
+			for _, user := range users {
+				if user.ID == id {
+					return user, nil
+				}
+			}
+			return nil, ErrNotFound
+		},
+		UserByUsernameFunc: func(username string) (*User, error) {
+			for _, user := range users {
+				if user.Username == username {
+					return user, nil
+				}
+			}
+			return nil, ErrNotFound
+		},
+		DeleteExpiredSessionsFunc: func(time.Time) error { return nil },
+		InsertSessionFunc: func(session *Session) error {
+			sessions[session.Hash] = session
+			return nil
+		},
+		SessionByHashFunc: func(h string) (*Session, error) {
+			session, ok := sessions[h]
+			if !ok {
+				return nil, ErrNotFound
+			}
+			return session, nil
+		},
+		DeleteSessionFunc: func(h string) error {
+			delete(sessions, h)
+			return nil
+		},
+	}
+}
+
+func TestAuth_SignUp(t *testing.T) {
+	repository := newUserRepository()
+	a := New(repository)
+	tests := []struct {
+		name     string
+		username string
+		password string
+		wantErr  error
+	}{
+		{"emptyUsername", " ", "password", ErrInvalidUsername},
+		{"spacedUsername", "al ice", "password", ErrInvalidUsername},
+		{"longUsername", strings.Repeat("a", maxUsernameLength+1), "password", ErrInvalidUsername},
+		{"shortPassword", "alice", "short", ErrWeakPassword},
+		{"first", " alice ", "password", nil},
+		{"closed", "bob", "password", ErrSignupClosed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := a.SignUp(tt.username, tt.password)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Auth.SignUp() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if user.ID != 1 || user.Username != "alice" || user.PasswordHash == tt.password {
+				t.Errorf("unexpected user: %#v", user)
+			}
+		})
+	}
+	if calls := repository.InsertFirstUserCalls(); len(calls) != 1 || calls[0].User.ID != 1 {
+		t.Error("first user was not stored as such:", calls)
+	}
+	if open, err := a.SignupOpen(); err != nil || open {
+		t.Error("signup should be closed:", open, err)
+	}
+
+	a = New(repository, WithSignup(true))
+	if open, err := a.SignupOpen(); err != nil || !open {
+		t.Error("signup should be open:", open, err)
+	}
+	if _, err := a.SignUp("alice", "password"); !errors.Is(err, ErrUsernameTaken) {
+		t.Error("unexpected error for taken username:", err)
+	}
+	user, err := a.SignUp("bob", "password")
+	if err != nil || user.ID != 2 {
+		t.Fatal("cannot sign up second user:", user, err)
+	}
+	if calls := repository.InsertUserCalls(); len(calls) != 1 || calls[0].User.ID != 2 {
+		t.Error("later users must not be stored as the first one:", calls)
+	}
+
+	t.Run("badDB", func(t *testing.T) {
+		errDB := errors.New("bad DB")
+		if _, err := New(&RepositoryMock{UsersFunc: func() ([]*User, error) { return nil, errDB }}).SignUp("alice", "password"); !errors.Is(err, errDB) {
+			t.Error("unexpected error:", err)
+		}
+		repository := newUserRepository()
+		repository.InsertFirstUserFunc = func(*User) error { return errDB }
+		if _, err := New(repository).SignUp("alice", "password"); !errors.Is(err, errDB) {
+			t.Error("unexpected error:", err)
+		}
+	})
+	t.Run("raced", func(t *testing.T) {
+		// Another user was stored after the signup was found open.
+		repository := newUserRepository()
+		repository.InsertFirstUserFunc = func(*User) error { return ErrSignupClosed }
+		if _, err := New(repository).SignUp("alice", "password"); !errors.Is(err, ErrSignupClosed) {
+			t.Error("unexpected error:", err)
+		}
+		if calls := repository.InsertUserCalls(); len(calls) != 0 {
+			t.Error("user stored while signup is closed:", calls)
+		}
+	})
+}
+
+func TestAuth_Mint(t *testing.T) {
+	errDB := errors.New("bad DB")
+	tests := []struct {
+		name       string
+		tokenName  string
+		repository Repository
+		wantErr    error
+	}{
+		{"emptyName", " ", &RepositoryMock{}, errEmptyTokenName},
+		{"badDB", "laptop", &RepositoryMock{InsertTokenFunc: func(*Token) error { return errDB }}, errDB},
+		{"good", "laptop", &RepositoryMock{InsertTokenFunc: func(token *Token) error {
+			token.ID = 1
+			return nil
+		}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, secret, err := New(tt.repository).Mint(42, tt.tokenName)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Auth.Mint() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !strings.HasPrefix(secret, tokenPrefix) {
+				t.Error("unexpected secret format:", secret)
+			}
+			if token.ID != 1 || token.UserID != 42 || token.Name != tt.tokenName || token.Hash != hash(secret) || token.Hash == secret {
+				t.Errorf("unexpected token: %#v", token)
+			}
+		})
+	}
+}
+
+func TestAuth_Authenticate(t *testing.T) {
+	errDB := errors.New("bad DB")
+	const secret = "ar_secret"
+	const orphanSecret = "ar_orphan"
+	owner := &User{ID: 1, Username: "alice"}
+	tokenByHash := func(h string) (*Token, error) {
+		switch h {
+		case hash(secret):
+			return &Token{ID: 1, UserID: owner.ID, Name: "laptop", Hash: h}, nil
+		case hash(orphanSecret):
+			return &Token{ID: 2, Name: "old", Hash: h}, nil
+		}
+		return nil, ErrNotFound
+	}
+	userByID := func(id int64) (*User, error) {
+		if id != owner.ID {
+			return nil, ErrNotFound
+		}
+		return owner, nil
+	}
+	tests := []struct {
+		name       string
+		secret     string
+		repository Repository
+		want       *User
+		wantErr    error
+	}{
+		{"empty", "", &RepositoryMock{}, nil, ErrUnauthorized},
+		{"unknown", "ar_other", &RepositoryMock{TokenByHashFunc: tokenByHash}, nil, ErrUnauthorized},
+		{"orphan", orphanSecret, &RepositoryMock{TokenByHashFunc: tokenByHash, UserByIDFunc: userByID}, nil, ErrUnauthorized},
+		{"badDB", secret, &RepositoryMock{TokenByHashFunc: func(string) (*Token, error) { return nil, errDB }}, nil, errDB},
+		{"good", secret, &RepositoryMock{TokenByHashFunc: tokenByHash, UserByIDFunc: userByID}, owner, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(tt.repository).Authenticate(tt.secret)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Auth.Authenticate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Auth.Authenticate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuth_sessions(t *testing.T) {
+	repository := newUserRepository()
+	now := time.Now()
+	a := New(repository)
+	a.now = func() time.Time { return now }
+	user, err := a.SignUp("alice", "password")
+	if err != nil {
+		t.Fatal("cannot sign up:", err)
+	}
+
+	for _, credentials := range [][2]string{{"alice", "wrong password"}, {"bob", "password"}} {
+		if _, _, err := a.Login(credentials[0], credentials[1]); !errors.Is(err, ErrUnauthorized) {
+			t.Fatal("unexpected error for bad credentials:", credentials, err)
+		}
+	}
+	session, secret, err := a.Login(" alice ", "password")
+	if err != nil {
+		t.Fatal("cannot login:", err)
+	}
+	if session.UserID != user.ID || !session.ExpiresAt.Equal(now.Add(SessionTTL)) || session.Hash != hash(secret) {
+		t.Fatalf("unexpected session: %#v", session)
+	}
+	if got, err := a.Session(secret); err != nil || got != user {
+		t.Fatal("cannot load session:", got, err)
+	}
+	if _, err := a.Session(""); !errors.Is(err, ErrUnauthorized) {
+		t.Error("unexpected error for empty session:", err)
+	}
+
+	a.now = func() time.Time { return now.Add(SessionTTL) }
+	if _, err := a.Session(secret); !errors.Is(err, ErrUnauthorized) {
+		t.Error("unexpected error for expired session:", err)
+	}
+	a.now = func() time.Time { return now }
+
+	if err := a.Logout(secret); err != nil {
+		t.Fatal("cannot logout:", err)
+	}
+	if _, err := a.Session(secret); !errors.Is(err, ErrUnauthorized) {
+		t.Error("unexpected error after logout:", err)
+	}
+
+	t.Run("deletedUser", func(t *testing.T) {
+		_, secret, err := a.Login("alice", "password")
+		if err != nil {
+			t.Fatal("cannot login:", err)
+		}
+		userByID := repository.UserByIDFunc
+		defer func() { repository.UserByIDFunc = userByID }()
+		repository.UserByIDFunc = func(int64) (*User, error) { return nil, ErrNotFound }
+		if _, err := a.Session(secret); !errors.Is(err, ErrUnauthorized) {
+			t.Error("unexpected error for deleted user:", err)
+		}
+	})
+	t.Run("badDB", func(t *testing.T) {
+		errDB := errors.New("bad DB")
+		repository.DeleteExpiredSessionsFunc = func(time.Time) error { return errDB }
+		if _, _, err := a.Login("alice", "password"); !errors.Is(err, errDB) {
+			t.Error("unexpected error:", err)
+		}
+		repository.SessionByHashFunc = func(string) (*Session, error) { return nil, errDB }
+		if _, err := a.Session("secret"); !errors.Is(err, errDB) {
+			t.Error("unexpected error:", err)
+		}
+		repository.DeleteSessionFunc = func(string) error { return errDB }
+		if err := a.Logout("secret"); !errors.Is(err, errDB) {
+			t.Error("unexpected error:", err)
+		}
+	})
+}
+
+func Test_checkPassword(t *testing.T) {
+	encoded, err := hashPassword("password")
+	if err != nil {
+		t.Fatal("cannot hash password:", err)
+	}
+	if other, _ := hashPassword("password"); other == encoded {
+		t.Error("password hashes must be salted")
+	}
+	tests := []struct {
+		name     string
+		encoded  string
+		password string
+		want     bool
+		wantErr  error
+	}{
+		{"good", encoded, "password", true, nil},
+		{"wrong", encoded, "Password", false, nil},
+		{"unknownScheme", "bcrypt$10$salt$hash", "password", false, errMalformedPasswordHash},
+		{"badIterations", "pbkdf2-sha256$0$c2FsdA$aGFzaA", "password", false, errMalformedPasswordHash},
+		{"badSalt", "pbkdf2-sha256$1000$!!!$aGFzaA", "password", false, errMalformedPasswordHash},
+		{"emptyHash", "pbkdf2-sha256$1000$c2FsdA$", "password", false, errMalformedPasswordHash},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := checkPassword(tt.encoded, tt.password)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("checkPassword() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("checkPassword() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuth_IsAdmin(t *testing.T) {
+	repository := newUserRepository()
+	a := New(repository, WithSignup(true))
+	alice, err := a.SignUp("alice", "password")
+	if err != nil {
+		t.Fatal("cannot sign up:", err)
+	}
+	bob, err := a.SignUp("bob", "password")
+	if err != nil {
+		t.Fatal("cannot sign up:", err)
+	}
+	tests := []struct {
+		name string
+		user *User
+		want bool
+	}{
+		{"first", alice, true},
+		{"second", bob, false},
+		{"nobody", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := a.IsAdmin(tt.user); err != nil || got != tt.want {
+				t.Errorf("Auth.IsAdmin() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+	t.Run("badDB", func(t *testing.T) {
+		errDB := errors.New("bad DB")
+		if _, err := New(&RepositoryMock{UsersFunc: func() ([]*User, error) { return nil, errDB }}).IsAdmin(alice); !errors.Is(err, errDB) {
+			t.Error("unexpected error:", err)
+		}
+	})
+}
+
+func TestAuth_Revoke(t *testing.T) {
+	repository := &RepositoryMock{DeleteTokenFunc: func(id int64) error {
+		if id != 1 {
+			return ErrNotFound
+		}
+		return nil
+	}}
+	a := New(repository)
+	if err := a.Revoke(1); err != nil {
+		t.Error("cannot revoke token:", err)
+	}
+	if err := a.Revoke(2); !errors.Is(err, ErrNotFound) {
+		t.Error("unexpected error for unknown token:", err)
+	}
+}
+
+func TestAuth_Tokens(t *testing.T) {
+	errDB := errors.New("bad DB")
+	if _, err := New(&RepositoryMock{TokensFunc: func() ([]*Token, error) { return nil, errDB }}).Tokens(); !errors.Is(err, errDB) {
+		t.Error("unexpected error:", err)
+	}
+	want := []*Token{{ID: 1}}
+	got, err := New(&RepositoryMock{TokensFunc: func() ([]*Token, error) { return want, nil }}).Tokens()
+	if err != nil || len(got) != 1 || got[0] != want[0] {
+		t.Error("unexpected tokens:", got, err)
+	}
+}
+
+func TestContext(t *testing.T) {
+	if _, ok := FromContext(context.Background()); ok {
+		t.Error("unexpected user in empty context")
+	}
+	user := &User{ID: 1}
+	if got, ok := FromContext(NewContext(context.Background(), user)); !ok || got != user {
+		t.Error("cannot find user in context")
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned when the requested user, token or session does not
+// exist.
+var ErrNotFound = errors.New("not found")
+
+//go:generate go tool moq -out repository_mocks_test.go . Repository
+//go:generate go tool moq -pkg web -out ../web/authrepository_mocks_test.go . Repository:AuthRepositoryMock
+type Repository interface {
+	// DeleteExpiredSessions removes the sessions that expired before the
+	// given instant.
+	DeleteExpiredSessions(before time.Time) error
+
+	// DeleteSession removes the session with the given hash.
+	DeleteSession(hash string) error
+
+	// DeleteToken removes the token. It returns ErrNotFound if there is no
+	// such token.
+	DeleteToken(id int64) error
+
+	// InsertSession stores a new session.
+	InsertSession(*Session) error
+
+	// InsertFirstUser stores a new user, setting its ID, and hands the
+	// bookmarks and tokens created before user accounts existed over to
+	// it. It returns ErrSignupClosed, and stores nothing, if there is
+	// already a user.
+	InsertFirstUser(*User) error
+
+	// InsertToken stores a new token, setting its ID.
+	InsertToken(*Token) error
+
+	// InsertUser stores a new user, setting its ID.
+	InsertUser(*User) error
+
+	// SessionByHash loads the session with the given hash.
+	SessionByHash(hash string) (*Session, error)
+
+	// TokenByHash loads the token with the given hash.
+	TokenByHash(hash string) (*Token, error)
+
+	// TokenByID loads the token with the given ID.
+	TokenByID(id int64) (*Token, error)
+
+	// Tokens lists all tokens.
+	Tokens() ([]*Token, error)
+
+	// UserByID loads the user with the given ID.
+	UserByID(id int64) (*User, error)
+
+	// UserByUsername loads the user with the given username.
+	UserByUsername(username string) (*User, error)
+
+	// Users lists all users, in order of creation.
+	Users() ([]*User, error)
+}
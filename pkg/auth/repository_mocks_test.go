@@ -0,0 +1,633 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// Ensure, that RepositoryMock does implement Repository.
+// If this is not the case, regenerate this file with moq.
+var _ Repository = &RepositoryMock{}
+
+// RepositoryMock is a mock implementation of Repository.
+//
+//	func TestSomethingThatUsesRepository(t *testing.T) {
+//
+//		// make and configure a mocked Repository
+//		mockedRepository := &RepositoryMock{
+//			DeleteExpiredSessionsFunc: func(before time.Time) error {
+//				panic("mock out the DeleteExpiredSessions method")
+//			},
+//			DeleteSessionFunc: func(hash string) error {
+//				panic("mock out the DeleteSession method")
+//			},
+//			DeleteTokenFunc: func(id int64) error {
+//				panic("mock out the DeleteToken method")
+//			},
+//			InsertFirstUserFunc: func(user *User) error {
+//				panic("mock out the InsertFirstUser method")
+//			},
+//			InsertSessionFunc: func(session *Session) error {
+//				panic("mock out the InsertSession method")
+//			},
+//			InsertTokenFunc: func(token *Token) error {
+//				panic("mock out the InsertToken method")
+//			},
+//			InsertUserFunc: func(user *User) error {
+//				panic("mock out the InsertUser method")
+//			},
+//			SessionByHashFunc: func(hash string) (*Session, error) {
+//				panic("mock out the SessionByHash method")
+//			},
+//			TokenByHashFunc: func(hash string) (*Token, error) {
+//				panic("mock out the TokenByHash method")
+//			},
+//			TokenByIDFunc: func(id int64) (*Token, error) {
+//				panic("mock out the TokenByID method")
+//			},
+//			TokensFunc: func() ([]*Token, error) {
+//				panic("mock out the Tokens method")
+//			},
+//			UserByIDFunc: func(id int64) (*User, error) {
+//				panic("mock out the UserByID method")
+//			},
+//			UserByUsernameFunc: func(username string) (*User, error) {
+//				panic("mock out the UserByUsername method")
+//			},
+//			UsersFunc: func() ([]*User, error) {
+//				panic("mock out the Users method")
+//			},
+//		}
+//
+//		// use mockedRepository in code that requires Repository
+//		// and then make assertions.
+//
+//	}
+type RepositoryMock struct {
+	// DeleteExpiredSessionsFunc mocks the DeleteExpiredSessions method.
+	DeleteExpiredSessionsFunc func(before time.Time) error
+
+	// DeleteSessionFunc mocks the DeleteSession method.
+	DeleteSessionFunc func(hash string) error
+
+	// DeleteTokenFunc mocks the DeleteToken method.
+	DeleteTokenFunc func(id int64) error
+
+	// InsertFirstUserFunc mocks the InsertFirstUser method.
+	InsertFirstUserFunc func(user *User) error
+
+	// InsertSessionFunc mocks the InsertSession method.
+	InsertSessionFunc func(session *Session) error
+
+	// InsertTokenFunc mocks the InsertToken method.
+	InsertTokenFunc func(token *Token) error
+
+	// InsertUserFunc mocks the InsertUser method.
+	InsertUserFunc func(user *User) error
+
+	// SessionByHashFunc mocks the SessionByHash method.
+	SessionByHashFunc func(hash string) (*Session, error)
+
+	// TokenByHashFunc mocks the TokenByHash method.
+	TokenByHashFunc func(hash string) (*Token, error)
+
+	// TokenByIDFunc mocks the TokenByID method.
+	TokenByIDFunc func(id int64) (*Token, error)
+
+	// TokensFunc mocks the Tokens method.
+	TokensFunc func() ([]*Token, error)
+
+	// UserByIDFunc mocks the UserByID method.
+	UserByIDFunc func(id int64) (*User, error)
+
+	// UserByUsernameFunc mocks the UserByUsername method.
+	UserByUsernameFunc func(username string) (*User, error)
+
+	// UsersFunc mocks the Users method.
+	UsersFunc func() ([]*User, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// DeleteExpiredSessions holds details about calls to the DeleteExpiredSessions method.
+		DeleteExpiredSessions []struct {
+			// Before is the before argument value.
+			Before time.Time
+		}
+		// DeleteSession holds details about calls to the DeleteSession method.
+		DeleteSession []struct {
+			// Hash is the hash argument value.
+			Hash string
+		}
+		// DeleteToken holds details about calls to the DeleteToken method.
+		DeleteToken []struct {
+			// ID is the id argument value.
+			ID int64
+		}
+		// InsertFirstUser holds details about calls to the InsertFirstUser method.
+		InsertFirstUser []struct {
+			// User is the user argument value.
+			User *User
+		}
+		// InsertSession holds details about calls to the InsertSession method.
+		InsertSession []struct {
+			// Session is the session argument value.
+			Session *Session
+		}
+		// InsertToken holds details about calls to the InsertToken method.
+		InsertToken []struct {
+			// Token is the token argument value.
+			Token *Token
+		}
+		// InsertUser holds details about calls to the InsertUser method.
+		InsertUser []struct {
+			// User is the user argument value.
+			User *User
+		}
+		// SessionByHash holds details about calls to the SessionByHash method.
+		SessionByHash []struct {
+			// Hash is the hash argument value.
+			Hash string
+		}
+		// TokenByHash holds details about calls to the TokenByHash method.
+		TokenByHash []struct {
+			// Hash is the hash argument value.
+			Hash string
+		}
+		// TokenByID holds details about calls to the TokenByID method.
+		TokenByID []struct {
+			// ID is the id argument value.
+			ID int64
+		}
+		// Tokens holds details about calls to the Tokens method.
+		Tokens []struct {
+		}
+		// UserByID holds details about calls to the UserByID method.
+		UserByID []struct {
+			// ID is the id argument value.
+			ID int64
+		}
+		// UserByUsername holds details about calls to the UserByUsername method.
+		UserByUsername []struct {
+			// Username is the username argument value.
+			Username string
+		}
+		// Users holds details about calls to the Users method.
+		Users []struct {
+		}
+	}
+	lockDeleteExpiredSessions sync.RWMutex
+	lockDeleteSession         sync.RWMutex
+	lockDeleteToken           sync.RWMutex
+	lockInsertFirstUser       sync.RWMutex
+	lockInsertSession         sync.RWMutex
+	lockInsertToken           sync.RWMutex
+	lockInsertUser            sync.RWMutex
+	lockSessionByHash         sync.RWMutex
+	lockTokenByHash           sync.RWMutex
+	lockTokenByID             sync.RWMutex
+	lockTokens                sync.RWMutex
+	lockUserByID              sync.RWMutex
+	lockUserByUsername        sync.RWMutex
+	lockUsers                 sync.RWMutex
+}
+
+// DeleteExpiredSessions calls DeleteExpiredSessionsFunc.
+func (mock *RepositoryMock) DeleteExpiredSessions(before time.Time) error {
+	if mock.DeleteExpiredSessionsFunc == nil {
+		panic("RepositoryMock.DeleteExpiredSessionsFunc: method is nil but Repository.DeleteExpiredSessions was just called")
+	}
+	callInfo := struct {
+		Before time.Time
+	}{
+		Before: before,
+	}
+	mock.lockDeleteExpiredSessions.Lock()
+	mock.calls.DeleteExpiredSessions = append(mock.calls.DeleteExpiredSessions, callInfo)
+	mock.lockDeleteExpiredSessions.Unlock()
+	return mock.DeleteExpiredSessionsFunc(before)
+}
+
+// DeleteExpiredSessionsCalls gets all the calls that were made to DeleteExpiredSessions.
+// Check the length with:
+//
+//	len(mockedRepository.DeleteExpiredSessionsCalls())
+func (mock *RepositoryMock) DeleteExpiredSessionsCalls() []struct {
+	Before time.Time
+} {
+	var calls []struct {
+		Before time.Time
+	}
+	mock.lockDeleteExpiredSessions.RLock()
+	calls = mock.calls.DeleteExpiredSessions
+	mock.lockDeleteExpiredSessions.RUnlock()
+	return calls
+}
+
+// DeleteSession calls DeleteSessionFunc.
+func (mock *RepositoryMock) DeleteSession(hash string) error {
+	if mock.DeleteSessionFunc == nil {
+		panic("RepositoryMock.DeleteSessionFunc: method is nil but Repository.DeleteSession was just called")
+	}
+	callInfo := struct {
+		Hash string
+	}{
+		Hash: hash,
+	}
+	mock.lockDeleteSession.Lock()
+	mock.calls.DeleteSession = append(mock.calls.DeleteSession, callInfo)
+	mock.lockDeleteSession.Unlock()
+	return mock.DeleteSessionFunc(hash)
+}
+
+// DeleteSessionCalls gets all the calls that were made to DeleteSession.
+// Check the length with:
+//
+//	len(mockedRepository.DeleteSessionCalls())
+func (mock *RepositoryMock) DeleteSessionCalls() []struct {
+	Hash string
+} {
+	var calls []struct {
+		Hash string
+	}
+	mock.lockDeleteSession.RLock()
+	calls = mock.calls.DeleteSession
+	mock.lockDeleteSession.RUnlock()
+	return calls
+}
+
+// DeleteToken calls DeleteTokenFunc.
+func (mock *RepositoryMock) DeleteToken(id int64) error {
+	if mock.DeleteTokenFunc == nil {
+		panic("RepositoryMock.DeleteTokenFunc: method is nil but Repository.DeleteToken was just called")
+	}
+	callInfo := struct {
+		ID int64
+	}{
+		ID: id,
+	}
+	mock.lockDeleteToken.Lock()
+	mock.calls.DeleteToken = append(mock.calls.DeleteToken, callInfo)
+	mock.lockDeleteToken.Unlock()
+	return mock.DeleteTokenFunc(id)
+}
+
+// DeleteTokenCalls gets all the calls that were made to DeleteToken.
+// Check the length with:
+//
+//	len(mockedRepository.DeleteTokenCalls())
+func (mock *RepositoryMock) DeleteTokenCalls() []struct {
+	ID int64
+} {
+	var calls []struct {
+		ID int64
+	}
+	mock.lockDeleteToken.RLock()
+	calls = mock.calls.DeleteToken
+	mock.lockDeleteToken.RUnlock()
+	return calls
+}
+
+// InsertFirstUser calls InsertFirstUserFunc.
+func (mock *RepositoryMock) InsertFirstUser(user *User) error {
+	if mock.InsertFirstUserFunc == nil {
+		panic("RepositoryMock.InsertFirstUserFunc: method is nil but Repository.InsertFirstUser was just called")
+	}
+	callInfo := struct {
+		User *User
+	}{
+		User: user,
+	}
+	mock.lockInsertFirstUser.Lock()
+	mock.calls.InsertFirstUser = append(mock.calls.InsertFirstUser, callInfo)
+	mock.lockInsertFirstUser.Unlock()
+	return mock.InsertFirstUserFunc(user)
+}
+
+// InsertFirstUserCalls gets all the calls that were made to InsertFirstUser.
+// Check the length with:
+//
+//	len(mockedRepository.InsertFirstUserCalls())
+func (mock *RepositoryMock) InsertFirstUserCalls() []struct {
+	User *User
+} {
+	var calls []struct {
+		User *User
+	}
+	mock.lockInsertFirstUser.RLock()
+	calls = mock.calls.InsertFirstUser
+	mock.lockInsertFirstUser.RUnlock()
+	return calls
+}
+
+// InsertSession calls InsertSessionFunc.
+func (mock *RepositoryMock) InsertSession(session *Session) error {
+	if mock.InsertSessionFunc == nil {
+		panic("RepositoryMock.InsertSessionFunc: method is nil but Repository.InsertSession was just called")
+	}
+	callInfo := struct {
+		Session *Session
+	}{
+		Session: session,
+	}
+	mock.lockInsertSession.Lock()
+	mock.calls.InsertSession = append(mock.calls.InsertSession, callInfo)
+	mock.lockInsertSession.Unlock()
+	return mock.InsertSessionFunc(session)
+}
+
+// InsertSessionCalls gets all the calls that were made to InsertSession.
+// Check the length with:
+//
+//	len(mockedRepository.InsertSessionCalls())
+func (mock *RepositoryMock) InsertSessionCalls() []struct {
+	Session *Session
+} {
+	var calls []struct {
+		Session *Session
+	}
+	mock.lockInsertSession.RLock()
+	calls = mock.calls.InsertSession
+	mock.lockInsertSession.RUnlock()
+	return calls
+}
+
+// InsertToken calls InsertTokenFunc.
+func (mock *RepositoryMock) InsertToken(token *Token) error {
+	if mock.InsertTokenFunc == nil {
+		panic("RepositoryMock.InsertTokenFunc: method is nil but Repository.InsertToken was just called")
+	}
+	callInfo := struct {
+		Token *Token
+	}{
+		Token: token,
+	}
+	mock.lockInsertToken.Lock()
+	mock.calls.InsertToken = append(mock.calls.InsertToken, callInfo)
+	mock.lockInsertToken.Unlock()
+	return mock.InsertTokenFunc(token)
+}
+
+// InsertTokenCalls gets all the calls that were made to InsertToken.
+// Check the length with:
+//
+//	len(mockedRepository.InsertTokenCalls())
+func (mock *RepositoryMock) InsertTokenCalls() []struct {
+	Token *Token
+} {
+	var calls []struct {
+		Token *Token
+	}
+	mock.lockInsertToken.RLock()
+	calls = mock.calls.InsertToken
+	mock.lockInsertToken.RUnlock()
+	return calls
+}
+
+// InsertUser calls InsertUserFunc.
+func (mock *RepositoryMock) InsertUser(user *User) error {
+	if mock.InsertUserFunc == nil {
+		panic("RepositoryMock.InsertUserFunc: method is nil but Repository.InsertUser was just called")
+	}
+	callInfo := struct {
+		User *User
+	}{
+		User: user,
+	}
+	mock.lockInsertUser.Lock()
+	mock.calls.InsertUser = append(mock.calls.InsertUser, callInfo)
+	mock.lockInsertUser.Unlock()
+	return mock.InsertUserFunc(user)
+}
+
+// InsertUserCalls gets all the calls that were made to InsertUser.
+// Check the length with:
+//
+//	len(mockedRepository.InsertUserCalls())
+func (mock *RepositoryMock) InsertUserCalls() []struct {
+	User *User
+} {
+	var calls []struct {
+		User *User
+	}
+	mock.lockInsertUser.RLock()
+	calls = mock.calls.InsertUser
+	mock.lockInsertUser.RUnlock()
+	return calls
+}
+
+// SessionByHash calls SessionByHashFunc.
+func (mock *RepositoryMock) SessionByHash(hash string) (*Session, error) {
+	if mock.SessionByHashFunc == nil {
+		panic("RepositoryMock.SessionByHashFunc: method is nil but Repository.SessionByHash was just called")
+	}
+	callInfo := struct {
+		Hash string
+	}{
+		Hash: hash,
+	}
+	mock.lockSessionByHash.Lock()
+	mock.calls.SessionByHash = append(mock.calls.SessionByHash, callInfo)
+	mock.lockSessionByHash.Unlock()
+	return mock.SessionByHashFunc(hash)
+}
+
+// SessionByHashCalls gets all the calls that were made to SessionByHash.
+// Check the length with:
+//
+//	len(mockedRepository.SessionByHashCalls())
+func (mock *RepositoryMock) SessionByHashCalls() []struct {
+	Hash string
+} {
+	var calls []struct {
+		Hash string
+	}
+	mock.lockSessionByHash.RLock()
+	calls = mock.calls.SessionByHash
+	mock.lockSessionByHash.RUnlock()
+	return calls
+}
+
+// TokenByHash calls TokenByHashFunc.
+func (mock *RepositoryMock) TokenByHash(hash string) (*Token, error) {
+	if mock.TokenByHashFunc == nil {
+		panic("RepositoryMock.TokenByHashFunc: method is nil but Repository.TokenByHash was just called")
+	}
+	callInfo := struct {
+		Hash string
+	}{
+		Hash: hash,
+	}
+	mock.lockTokenByHash.Lock()
+	mock.calls.TokenByHash = append(mock.calls.TokenByHash, callInfo)
+	mock.lockTokenByHash.Unlock()
+	return mock.TokenByHashFunc(hash)
+}
+
+// TokenByHashCalls gets all the calls that were made to TokenByHash.
+// Check the length with:
+//
+//	len(mockedRepository.TokenByHashCalls())
+func (mock *RepositoryMock) TokenByHashCalls() []struct {
+	Hash string
+} {
+	var calls []struct {
+		Hash string
+	}
+	mock.lockTokenByHash.RLock()
+	calls = mock.calls.TokenByHash
+	mock.lockTokenByHash.RUnlock()
+	return calls
+}
+
+// TokenByID calls TokenByIDFunc.
+func (mock *RepositoryMock) TokenByID(id int64) (*Token, error) {
+	if mock.TokenByIDFunc == nil {
+		panic("RepositoryMock.TokenByIDFunc: method is nil but Repository.TokenByID was just called")
+	}
+	callInfo := struct {
+		ID int64
+	}{
+		ID: id,
+	}
+	mock.lockTokenByID.Lock()
+	mock.calls.TokenByID = append(mock.calls.TokenByID, callInfo)
+	mock.lockTokenByID.Unlock()
+	return mock.TokenByIDFunc(id)
+}
+
+// TokenByIDCalls gets all the calls that were made to TokenByID.
+// Check the length with:
+//
+//	len(mockedRepository.TokenByIDCalls())
+func (mock *RepositoryMock) TokenByIDCalls() []struct {
+	ID int64
+} {
+	var calls []struct {
+		ID int64
+	}
+	mock.lockTokenByID.RLock()
+	calls = mock.calls.TokenByID
+	mock.lockTokenByID.RUnlock()
+	return calls
+}
+
+// Tokens calls TokensFunc.
+func (mock *RepositoryMock) Tokens() ([]*Token, error) {
+	if mock.TokensFunc == nil {
+		panic("RepositoryMock.TokensFunc: method is nil but Repository.Tokens was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockTokens.Lock()
+	mock.calls.Tokens = append(mock.calls.Tokens, callInfo)
+	mock.lockTokens.Unlock()
+	return mock.TokensFunc()
+}
+
+// TokensCalls gets all the calls that were made to Tokens.
+// Check the length with:
+//
+//	len(mockedRepository.TokensCalls())
+func (mock *RepositoryMock) TokensCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockTokens.RLock()
+	calls = mock.calls.Tokens
+	mock.lockTokens.RUnlock()
+	return calls
+}
+
+// UserByID calls UserByIDFunc.
+func (mock *RepositoryMock) UserByID(id int64) (*User, error) {
+	if mock.UserByIDFunc == nil {
+		panic("RepositoryMock.UserByIDFunc: method is nil but Repository.UserByID was just called")
+	}
+	callInfo := struct {
+		ID int64
+	}{
+		ID: id,
+	}
+	mock.lockUserByID.Lock()
+	mock.calls.UserByID = append(mock.calls.UserByID, callInfo)
+	mock.lockUserByID.Unlock()
+	return mock.UserByIDFunc(id)
+}
+
+// UserByIDCalls gets all the calls that were made to UserByID.
+// Check the length with:
+//
+//	len(mockedRepository.UserByIDCalls())
+func (mock *RepositoryMock) UserByIDCalls() []struct {
+	ID int64
+} {
+	var calls []struct {
+		ID int64
+	}
+	mock.lockUserByID.RLock()
+	calls = mock.calls.UserByID
+	mock.lockUserByID.RUnlock()
+	return calls
+}
+
+// UserByUsername calls UserByUsernameFunc.
+func (mock *RepositoryMock) UserByUsername(username string) (*User, error) {
+	if mock.UserByUsernameFunc == nil {
+		panic("RepositoryMock.UserByUsernameFunc: method is nil but Repository.UserByUsername was just called")
+	}
+	callInfo := struct {
+		Username string
+	}{
+		Username: username,
+	}
+	mock.lockUserByUsername.Lock()
+	mock.calls.UserByUsername = append(mock.calls.UserByUsername, callInfo)
+	mock.lockUserByUsername.Unlock()
+	return mock.UserByUsernameFunc(username)
+}
+
+// UserByUsernameCalls gets all the calls that were made to UserByUsername.
+// Check the length with:
+//
+//	len(mockedRepository.UserByUsernameCalls())
+func (mock *RepositoryMock) UserByUsernameCalls() []struct {
+	Username string
+} {
+	var calls []struct {
+		Username string
+	}
+	mock.lockUserByUsername.RLock()
+	calls = mock.calls.UserByUsername
+	mock.lockUserByUsername.RUnlock()
+	return calls
+}
+
+// Users calls UsersFunc.
+func (mock *RepositoryMock) Users() ([]*User, error) {
+	if mock.UsersFunc == nil {
+		panic("RepositoryMock.UsersFunc: method is nil but Repository.Users was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockUsers.Lock()
+	mock.calls.Users = append(mock.calls.Users, callInfo)
+	mock.lockUsers.Unlock()
+	return mock.UsersFunc()
+}
+
+// UsersCalls gets all the calls that were made to Users.
+// Check the length with:
+//
+//	len(mockedRepository.UsersCalls())
+func (mock *RepositoryMock) UsersCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockUsers.RLock()
+	calls = mock.calls.Users
+	mock.lockUsers.RUnlock()
+	return calls
+}
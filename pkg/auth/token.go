@@ -0,0 +1,37 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import "time"
+
+// Token is a credential that grants a user access to the API. Only the hash
+// of its secret is stored.
+type Token struct {
+	ID        int64     `db:"id" json:"id"`
+	UserID    int64     `db:"user_id" json:"user_id"`
+	Name      string    `db:"name" json:"name"`
+	Hash      string    `db:"hash" json:"-"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// Session is a browser login obtained by presenting a username and password.
+// Only the hash of its secret is stored; the secret itself lives in the
+// session cookie.
+type Session struct {
+	Hash      string    `db:"hash" json:"-"`
+	UserID    int64     `db:"user_id" json:"user_id"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	ExpiresAt time.Time `db:"expires_at" json:"expires_at"`
+}
@@ -16,25 +16,94 @@ package bookmarks // import "cirello.io/alreadyread/pkg/bookmarks"
 
 import (
 	"fmt"
+	"net/http"
 	"time"
 )
 
 // Bookmark stores the basic information of a web URL.
 type Bookmark struct {
-	ID               int64     `db:"id" json:"id"`
-	URL              string    `db:"url" json:"url"`
-	LastStatusCode   int64     `db:"last_status_code" json:"last_status_code"`
-	LastStatusCheck  int64     `db:"last_status_check" json:"last_status_check"`
-	LastStatusReason string    `db:"last_status_reason" json:"last_status_reason"`
-	Title            string    `db:"title" json:"title"`
-	CreatedAt        time.Time `db:"created_at" json:"created_at"`
-	Inbox            Inbox     `db:"inbox" json:"inbox"`
-	Description      string    `db:"description" json:"description"`
-	BumpDate         time.Time `db:"bump_date" json:"bump_date"`
-
-	Host string `db:"-" json:"host"`
+	ID                int64     `db:"id" json:"id"`
+	URL               string    `db:"url" json:"url"`
+	LastStatusCode    int64     `db:"last_status_code" json:"last_status_code"`
+	LastStatusCheck   int64     `db:"last_status_check" json:"last_status_check"`
+	LastStatusReason  string    `db:"last_status_reason" json:"last_status_reason"`
+	Title             string    `db:"title" json:"title"`
+	CreatedAt         time.Time `db:"created_at" json:"created_at"`
+	Inbox             Inbox     `db:"inbox" json:"inbox"`
+	Description       string    `db:"description" json:"description"`
+	BumpDate          time.Time `db:"bump_date" json:"bump_date"`
+	OwnerID           int64     `db:"owner_id" json:"-"`
+	FinalURL          string    `db:"final_url" json:"final_url"`
+	RedirectCount     int64     `db:"redirect_count" json:"redirect_count"`
+	PermanentRedirect bool      `db:"permanent_redirect" json:"permanent_redirect"`
+	FailureCount      int64     `db:"failure_count" json:"failure_count"`
+	FailingSince      int64     `db:"failing_since" json:"failing_since"`
+	Dead              bool      `db:"dead" json:"dead"`
+	NextCheck         int64     `db:"next_check" json:"next_check"`
+	PageTitle         string    `db:"page_title" json:"-"`
+	Metadata          Metadata  `db:"-" json:"metadata"`
+
+	Host    string   `db:"-" json:"host"`
+	Tags    []string `db:"-" json:"tags"`
+	Snippet string   `db:"-" json:"-"`
+}
+
+// Moved reports whether the last check found that the link redirects
+// elsewhere.
+func (b *Bookmark) Moved() bool {
+	return b.FinalURL != "" && b.FinalURL != b.URL
+}
+
+// MovedPermanently reports whether the last check found that the link
+// redirects elsewhere for good, with 301 or 308 redirects only. Temporary
+// redirects, such as to a login page, do not mean the link has moved.
+func (b *Bookmark) MovedPermanently() bool {
+	return b.Moved() && b.PermanentRedirect
+}
+
+// Blocked reports whether robots.txt kept the link from being checked the
+// last time.
+func (b *Bookmark) Blocked() bool {
+	return b.LastStatusCode == StatusBlockedByRobots
+}
+
+// PrivateAddress reports whether the link was not checked the last time
+// because it leads to a private address.
+func (b *Bookmark) PrivateAddress() bool {
+	return b.LastStatusCode == StatusPrivateAddress
+}
+
+// SoftFailure reports whether the link loaded the last time, but not the page
+// that was bookmarked.
+func (b *Bookmark) SoftFailure() bool {
+	return b.LastStatusCode == StatusSoftFailure
+}
+
+// setCheckResult records the outcome of a link check. FinalURL is only kept
+// if the redirects led somewhere else, and the metadata and the title of the
+// page are only replaced when the page could be read.
+func (b *Bookmark) setCheckResult(r CheckResult) {
+	b.Title, b.LastStatusCheck, b.LastStatusCode, b.LastStatusReason = r.Title, r.When, r.Code, r.Reason
+	b.FinalURL, b.RedirectCount, b.PermanentRedirect = r.FinalURL(), int64(len(r.Redirects)), r.PermanentRedirect
+	if b.FinalURL == b.URL {
+		b.FinalURL, b.PermanentRedirect = "", false
+	}
+	if r.Code == http.StatusOK {
+		b.Metadata, b.PageTitle = r.Metadata, r.PageTitle
+	}
 }
 
+// resetHealth forgets past link check failures, as when the link is new.
+func (b *Bookmark) resetHealth() {
+	b.FailureCount, b.FailingSince, b.Dead = 0, 0, false
+}
+
+// Delimiters of the matched terms in Bookmark.Snippet.
+const (
+	SnippetMatchStart = "\x02"
+	SnippetMatchEnd   = "\x03"
+)
+
 type Inbox int64
 
 const (
@@ -14,7 +14,11 @@
 
 package bookmarks
 
-import "testing"
+import (
+	"net/http"
+	"testing"
+	"time"
+)
 
 func TestParseInbox(t *testing.T) {
 	type args struct {
@@ -43,3 +47,91 @@ func TestParseInbox(t *testing.T) {
 		})
 	}
 }
+
+func TestParseDeadFilter(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    DeadFilter
+		wantErr bool
+	}{
+		{"", AllDead, false},
+		{"soft", SoftDead, false},
+		{"hard", HardDead, false},
+		{"invalid", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseDeadFilter(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseDeadFilter() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParseDeadFilter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBookmark_setCheckResult(t *testing.T) {
+	tests := []struct {
+		name          string
+		redirects     []string
+		wantFinalURL  string
+		wantRedirects int64
+		wantMoved     bool
+	}{
+		{"noRedirects", nil, "", 0, false},
+		{"moved", []string{"https://example.com/", "https://example.org/"}, "https://example.org/", 2, true},
+		{"backToItself", []string{"http://example.com/login", "http://example.com"}, "", 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Bookmark{URL: "http://example.com", FinalURL: "http://example.com/stale"}
+			b.setCheckResult(CheckResult{Title: "title", When: 1, Code: 200, Redirects: tt.redirects})
+			if b.Title != "title" || b.LastStatusCheck != 1 || b.LastStatusCode != 200 {
+				t.Errorf("check result not recorded: %#v", b)
+			}
+			if b.FinalURL != tt.wantFinalURL || b.RedirectCount != tt.wantRedirects || b.Moved() != tt.wantMoved {
+				t.Errorf("unexpected redirect state: %q %d %v", b.FinalURL, b.RedirectCount, b.Moved())
+			}
+		})
+	}
+}
+
+func TestBookmark_setCheckResult_metadata(t *testing.T) {
+	b := &Bookmark{URL: "http://example.com"}
+	b.setCheckResult(CheckResult{When: 1, Code: http.StatusOK, PageTitle: "Example Page", Metadata: Metadata{SiteName: "Example"}})
+	if b.Metadata.SiteName != "Example" || b.PageTitle != "Example Page" {
+		t.Fatal("metadata not recorded:", b.PageTitle, b.Metadata)
+	}
+	b.setCheckResult(CheckResult{When: 2, Code: StatusSoftFailure, PageTitle: "Not Found"})
+	if b.Metadata.SiteName != "Example" || b.PageTitle != "Example Page" {
+		t.Error("metadata lost to a failed check:", b.PageTitle, b.Metadata)
+	}
+	b.setCheckResult(CheckResult{When: 3, Code: http.StatusOK})
+	if b.Metadata != (Metadata{}) || b.PageTitle != "" {
+		t.Error("stale metadata kept:", b.PageTitle, b.Metadata)
+	}
+}
+
+func TestMetadata_Subtitle(t *testing.T) {
+	published := time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC).Unix()
+	tests := []struct {
+		name     string
+		metadata Metadata
+		want     string
+	}{
+		{"empty", Metadata{Title: "ignored"}, ""},
+		{"siteName", Metadata{SiteName: "Example"}, "Example"},
+		{"author", Metadata{Author: "Jane Doe"}, "by Jane Doe"},
+		{"all", Metadata{SiteName: "Example", Author: "Jane Doe", PublishedAt: published}, "Example · by Jane Doe · Mar  5 2024"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.metadata.Subtitle(); got != tt.want {
+				t.Errorf("Subtitle() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
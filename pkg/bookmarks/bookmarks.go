@@ -20,6 +20,8 @@ import (
 	"fmt"
 	"log"
 	"net/url"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 )
@@ -27,13 +29,43 @@ import (
 type Bookmarks struct {
 	repository Repository
 	urlChecker URLChecker
+	pageSize   int
+
+	deadAfterFailures int
+	deadAfter         time.Duration
+
+	checkWorkers int
+	hostWorkers  int
+	hostDelay    time.Duration
+}
+
+func New(repository Repository, urlChecker URLChecker, opts ...Option) *Bookmarks {
+	b := &Bookmarks{
+		repository:        repository,
+		urlChecker:        urlChecker,
+		pageSize:          DefaultPageSize,
+		deadAfterFailures: DefaultDeadAfterFailures,
+		deadAfter:         DefaultDeadAfter,
+		checkWorkers:      DefaultLinkCheckWorkers,
+		hostWorkers:       DefaultHostWorkers,
+		hostDelay:         DefaultHostDelay,
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
 }
 
-func New(repository Repository, urlChecker URLChecker) *Bookmarks {
-	return &Bookmarks{
-		repository: repository,
-		urlChecker: urlChecker,
+// PageSize is how many bookmarks are listed per page.
+func (b *Bookmarks) PageSize() int {
+	return b.limit()
+}
+
+func (b *Bookmarks) limit() int {
+	if b.pageSize <= 0 {
+		return DefaultPageSize
 	}
+	return b.pageSize
 }
 
 var (
@@ -52,7 +84,7 @@ func (b *Bookmarks) isSetup() error {
 }
 
 var (
-	errNilBookmark = fmt.Errorf("cannot insert nil bookmark")
+	errNilBookmark = fmt.Errorf("cannot store nil bookmark")
 )
 
 type BadURLError struct {
@@ -72,7 +104,9 @@ func (b BadURLError) Is(target error) bool {
 	return errors.As(target, &errBadURL)
 }
 
-func (b *Bookmarks) Insert(bookmark *Bookmark) error {
+// Insert stores a new bookmark owned by the given user. Its link is checked
+// first, unless the context is canceled.
+func (b *Bookmarks) Insert(ctx context.Context, ownerID int64, bookmark *Bookmark) error {
 	if err := b.isSetup(); err != nil {
 		return fmt.Errorf("cannot begin inserting bookmark: %w", err)
 	}
@@ -82,26 +116,111 @@ func (b *Bookmarks) Insert(bookmark *Bookmark) error {
 	if _, err := url.Parse(bookmark.URL); err != nil {
 		return &BadURLError{cause: err}
 	}
-	bookmark.Title, bookmark.LastStatusCheck, bookmark.LastStatusCode, bookmark.LastStatusReason = b.urlChecker.Check(bookmark.URL, bookmark.Title)
+	bookmark.OwnerID = ownerID
+	bookmark.Inbox = NewLink
+	bookmark.resetHealth()
+	result := b.urlChecker.Check(ctx, CheckRequest{URL: bookmark.URL, Title: bookmark.Title})
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("cannot check link: %w", err)
+	}
+	b.recordCheck(bookmark, result)
 	if err := b.repository.Insert(bookmark); err != nil {
 		return fmt.Errorf("cannot insert bookmark: %w", err)
 	}
+	for _, tag := range bookmark.Tags {
+		if tag = NormalizeTag(tag); tag == "" {
+			continue
+		}
+		if err := b.repository.AddTag(ownerID, bookmark.ID, tag); err != nil {
+			return fmt.Errorf("cannot tag bookmark: %w", err)
+		}
+	}
+	// The bookmark is saved by now, a missing first check in its history
+	// does not warrant failing the insert.
+	if err := b.repository.AddLinkCheck(newLinkCheck(bookmark.ID, result)); err != nil {
+		log.Println("cannot record link check of bookmark", bookmark.ID, ":", err)
+	}
 	return nil
 }
 
-func (b *Bookmarks) DeleteByID(id int64) error {
-	if err := b.repository.DeleteByID(id); err != nil {
+// Edit updates the title, URL, description and tags of an existing bookmark,
+// preserving everything else. The link is checked again if its URL changes.
+func (b *Bookmarks) Edit(ctx context.Context, ownerID int64, edited *Bookmark) (*Bookmark, error) {
+	if err := b.isSetup(); err != nil {
+		return nil, fmt.Errorf("cannot begin editing bookmark: %w", err)
+	}
+	if edited == nil {
+		return nil, errNilBookmark
+	}
+	if _, err := url.Parse(edited.URL); err != nil {
+		return nil, &BadURLError{cause: err}
+	}
+	bookmark, err := b.repository.GetByID(ownerID, edited.ID)
+	if err != nil {
+		return nil, fmt.Errorf("cannot find bookmark: %w", err)
+	}
+	urlChanged := bookmark.URL != edited.URL
+	bookmark.URL = edited.URL
+	bookmark.Title = edited.Title
+	bookmark.Description = edited.Description
+	if urlChanged {
+		bookmark.resetHealth()
+		// What was read of the old page, such as its canonical URL,
+		// does not describe the new one.
+		bookmark.PageTitle, bookmark.Metadata = "", Metadata{}
+	}
+	if urlChanged || bookmark.Title == "" {
+		result := b.urlChecker.Check(ctx, CheckRequest{URL: bookmark.URL, Title: bookmark.Title, PageTitle: bookmark.PageTitle})
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("cannot check link: %w", err)
+		}
+		b.recordCheck(bookmark, result)
+	}
+	if err := b.repository.Update(bookmark); err != nil {
+		return nil, fmt.Errorf("cannot store bookmark: %w", err)
+	}
+	tags := ParseTags(strings.Join(edited.Tags, ","))
+	for _, tag := range bookmark.Tags {
+		if slices.Contains(tags, tag) {
+			continue
+		}
+		if err := b.repository.RemoveTag(ownerID, bookmark.ID, tag); err != nil {
+			return nil, fmt.Errorf("cannot untag bookmark: %w", err)
+		}
+	}
+	for _, tag := range tags {
+		if slices.Contains(bookmark.Tags, tag) {
+			continue
+		}
+		if err := b.repository.AddTag(ownerID, bookmark.ID, tag); err != nil {
+			return nil, fmt.Errorf("cannot tag bookmark: %w", err)
+		}
+	}
+	bookmark.Tags = tags
+	return bookmark, nil
+}
+
+func (b *Bookmarks) GetByID(ownerID, id int64) (*Bookmark, error) {
+	bookmark, err := b.repository.GetByID(ownerID, id)
+	if err != nil {
+		return nil, fmt.Errorf("cannot find bookmark: %w", err)
+	}
+	return bookmark, nil
+}
+
+func (b *Bookmarks) DeleteByID(ownerID, id int64) error {
+	if err := b.repository.DeleteByID(ownerID, id); err != nil {
 		return fmt.Errorf("cannot delete bookmark: %w", err)
 	}
 	return nil
 }
 
-func (b *Bookmarks) UpdateInbox(id int64, inbox string) error {
+func (b *Bookmarks) UpdateInbox(ownerID, id int64, inbox string) error {
 	parsedInbox, err := ParseInbox(inbox)
 	if err != nil {
 		return fmt.Errorf("cannot parse inbox: %w", err)
 	}
-	bookmark, err := b.repository.GetByID(id)
+	bookmark, err := b.repository.GetByID(ownerID, id)
 	if err != nil {
 		return fmt.Errorf("cannot find bookmark: %w", err)
 	}
@@ -112,8 +231,8 @@ func (b *Bookmarks) UpdateInbox(id int64, inbox string) error {
 	return nil
 }
 
-func (b *Bookmarks) Bump(id int64) error {
-	bookmark, err := b.repository.GetByID(id)
+func (b *Bookmarks) Bump(ownerID, id int64) error {
+	bookmark, err := b.repository.GetByID(ownerID, id)
 	if err != nil {
 		return fmt.Errorf("cannot find bookmark: %w", err)
 	}
@@ -124,81 +243,221 @@ func (b *Bookmarks) Bump(id int64) error {
 	return nil
 }
 
-func (b *Bookmarks) Inbox(page int) ([]*Bookmark, error) {
-	list, err := b.repository.Inbox(page)
+func (b *Bookmarks) Inbox(ownerID int64, cursor Cursor) ([]*Bookmark, Cursor, error) {
+	list, next, err := b.repository.Inbox(ownerID, cursor, b.limit())
 	if err != nil {
-		return nil, fmt.Errorf("cannot load bookmarks inbox: %w", err)
+		return nil, "", fmt.Errorf("cannot load bookmarks inbox: %w", err)
 	}
-	return list, nil
+	return list, next, nil
 }
 
-func (b *Bookmarks) Duplicated(page int) ([]*Bookmark, error) {
-	list, err := b.repository.Duplicated(page)
+func (b *Bookmarks) Duplicated(ownerID int64, cursor Cursor) ([]*Bookmark, Cursor, error) {
+	list, next, err := b.repository.Duplicated(ownerID, cursor, b.limit())
 	if err != nil {
-		return nil, fmt.Errorf("cannot load duplicated bookmarks: %w", err)
+		return nil, "", fmt.Errorf("cannot load duplicated bookmarks: %w", err)
 	}
-	return list, nil
+	return list, next, nil
 }
 
-func (b *Bookmarks) Dead(page int) ([]*Bookmark, error) {
-	list, err := b.repository.Dead(page)
+// DeadFilter narrows dead bookmarks down by how their links fail.
+type DeadFilter string
+
+// Dead bookmark filters.
+const (
+	// AllDead keeps every dead bookmark.
+	AllDead DeadFilter = ""
+	// SoftDead keeps the links that load, but not the page that was
+	// bookmarked (StatusSoftFailure).
+	SoftDead DeadFilter = "soft"
+	// HardDead keeps the links that fail outright.
+	HardDead DeadFilter = "hard"
+)
+
+// ParseDeadFilter reads a dead bookmark filter. An empty string keeps every
+// dead bookmark.
+func ParseDeadFilter(v string) (DeadFilter, error) {
+	switch f := DeadFilter(v); f {
+	case AllDead, SoftDead, HardDead:
+		return f, nil
+	}
+	return "", fmt.Errorf("invalid dead bookmark filter: %s", v)
+}
+
+// Dead lists the bookmarks whose links were declared dead, narrowed down by
+// the filter.
+func (b *Bookmarks) Dead(ownerID int64, filter DeadFilter, cursor Cursor) ([]*Bookmark, Cursor, error) {
+	list, next, err := b.repository.Dead(ownerID, filter, cursor, b.limit())
 	if err != nil {
-		return nil, fmt.Errorf("cannot load dead bookmarks: %w", err)
+		return nil, "", fmt.Errorf("cannot load dead bookmarks: %w", err)
 	}
-	return list, nil
+	return list, next, nil
 }
 
-func (b *Bookmarks) All(page int) ([]*Bookmark, error) {
-	list, err := b.repository.All(page)
+func (b *Bookmarks) Moved(ownerID int64, cursor Cursor) ([]*Bookmark, Cursor, error) {
+	list, next, err := b.repository.Moved(ownerID, cursor, b.limit())
 	if err != nil {
-		return nil, fmt.Errorf("cannot load all bookmarks: %w", err)
+		return nil, "", fmt.Errorf("cannot load moved bookmarks: %w", err)
 	}
-	return list, nil
+	return list, next, nil
 }
 
-func (b *Bookmarks) Search(term string) ([]*Bookmark, error) {
-	list, err := b.repository.Search(term)
+var (
+	// ErrNotMoved is returned when following the redirect of a bookmark
+	// whose link does not redirect anywhere, or only temporarily.
+	ErrNotMoved = errors.New("bookmark link has not moved permanently")
+
+	// ErrDuplicateURL is returned when following the redirect of a
+	// bookmark would make it a duplicate of another bookmark of the owner.
+	ErrDuplicateURL = errors.New("new link is already bookmarked")
+)
+
+// FollowRedirect rewrites the URL of a moved bookmark to where its link
+// redirects to. Only permanent redirects are followed, and only if the owner
+// has not bookmarked the new URL already.
+func (b *Bookmarks) FollowRedirect(ownerID, id int64) (*Bookmark, error) {
+	bookmark, err := b.repository.GetByID(ownerID, id)
+	if err != nil {
+		return nil, fmt.Errorf("cannot find bookmark: %w", err)
+	}
+	if !bookmark.MovedPermanently() {
+		return nil, ErrNotMoved
+	}
+	if exists, err := b.repository.HasURL(ownerID, bookmark.FinalURL); err != nil {
+		return nil, fmt.Errorf("cannot look for duplicates: %w", err)
+	} else if exists {
+		return nil, ErrDuplicateURL
+	}
+	bookmark.URL, bookmark.FinalURL, bookmark.RedirectCount, bookmark.PermanentRedirect = bookmark.FinalURL, "", 0, false
+	if u, err := url.Parse(bookmark.URL); err == nil {
+		bookmark.Host = u.Host
+	}
+	if err := b.repository.Update(bookmark); err != nil {
+		return nil, fmt.Errorf("cannot store bookmark: %w", err)
+	}
+	return bookmark, nil
+}
+
+func (b *Bookmarks) All(ownerID int64, cursor Cursor) ([]*Bookmark, Cursor, error) {
+	list, next, err := b.repository.All(ownerID, cursor, b.limit())
+	if err != nil {
+		return nil, "", fmt.Errorf("cannot load all bookmarks: %w", err)
+	}
+	return list, next, nil
+}
+
+// Walk calls fn for every bookmark of the owner, oldest first. Bookmarks are
+// read from the repository as they are needed, so that exports of large
+// collections do not have to fit in memory.
+func (b *Bookmarks) Walk(ownerID int64, fn func(*Bookmark) error) error {
+	if err := b.repository.Walk(ownerID, fn); err != nil {
+		return fmt.Errorf("cannot walk bookmarks: %w", err)
+	}
+	return nil
+}
+
+// ErrEmptyTag is returned when tagging a bookmark with a tag that is empty
+// once normalized.
+var ErrEmptyTag = errors.New("empty tag")
+
+func (b *Bookmarks) AddTag(ownerID, id int64, tag string) error {
+	tag = NormalizeTag(tag)
+	if tag == "" {
+		return ErrEmptyTag
+	}
+	if err := b.repository.AddTag(ownerID, id, tag); err != nil {
+		return fmt.Errorf("cannot tag bookmark: %w", err)
+	}
+	return nil
+}
+
+func (b *Bookmarks) RemoveTag(ownerID, id int64, tag string) error {
+	if err := b.repository.RemoveTag(ownerID, id, NormalizeTag(tag)); err != nil {
+		return fmt.Errorf("cannot untag bookmark: %w", err)
+	}
+	return nil
+}
+
+func (b *Bookmarks) Tags(ownerID int64) ([]*Tag, error) {
+	tags, err := b.repository.Tags(ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load tags: %w", err)
+	}
+	return tags, nil
+}
+
+func (b *Bookmarks) ByTag(ownerID int64, tag string, cursor Cursor) ([]*Bookmark, Cursor, error) {
+	list, next, err := b.repository.ByTag(ownerID, NormalizeTag(tag), cursor, b.limit())
+	if err != nil {
+		return nil, "", fmt.Errorf("cannot load tagged bookmarks: %w", err)
+	}
+	return list, next, nil
+}
+
+// Search finds one page of bookmarks matching the search expression, along
+// with the total number of matches. Malformed expressions are reported with a
+// *QuerySyntaxError.
+func (b *Bookmarks) Search(ownerID int64, term string, page int) ([]*Bookmark, int, error) {
+	query, err := ParseQuery(term)
 	if err != nil {
-		return nil, fmt.Errorf("cannot search bookmarks: %w", err)
+		return nil, 0, err
 	}
-	return list, nil
+	list, total, err := b.repository.Search(ownerID, query, page, b.limit())
+	if err != nil {
+		return nil, 0, fmt.Errorf("cannot search bookmarks: %w", err)
+	}
+	return list, total, nil
 }
 
+// RefreshExpiredLinks checks the links that are due. Links on the same host
+// are checked a few at a time and spaced out, and hosts that ask for a break
+// with Retry-After, or with a Crawl-delay in robots.txt, get it. When the
+// context is canceled, it stops right away and leaves the links not yet
+// checked for the next run.
 func (b *Bookmarks) RefreshExpiredLinks(ctx context.Context) error {
 	expiredBookmarks, err := b.repository.Expired()
 	if err != nil {
 		return fmt.Errorf("cannot load expired bookmarks: %w", err)
 	}
 
-	bookmarkCh := make(chan *Bookmark)
+	workers, perHost, delay := b.linkCheckPolicy()
+	queue := newHostQueue(expiredBookmarks, perHost, delay)
 	var (
 		wg        sync.WaitGroup
 		muAllErrs sync.Mutex
 		allErrs   error
 	)
-	for i := 0; i < 4; i++ {
+	for range workers {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			for bookmark := range bookmarkCh {
+			for bookmark := queue.next(ctx); bookmark != nil; bookmark = queue.next(ctx) {
 				log.Println("linkHealth:", bookmark.ID, bookmark.URL)
-				bookmark.Title, bookmark.LastStatusCheck, bookmark.LastStatusCode, bookmark.LastStatusReason = b.urlChecker.Check(bookmark.URL, bookmark.Title)
-				if err := b.repository.Update(bookmark); err != nil {
+				result := b.urlChecker.Check(ctx, CheckRequest{URL: bookmark.URL, Title: bookmark.Title, PageTitle: bookmark.PageTitle, Pacer: queue.pace(bookmark)})
+				queue.done(bookmark, max(result.RetryAfter, result.CrawlDelay))
+				if ctx.Err() != nil {
+					// The check was cut short, it is not a failure of the link.
+					return
+				}
+				if len(result.Redirects) > 0 {
+					log.Println("linkHealth:", bookmark.ID, "redirects:", strings.Join(result.Redirects, " -> "))
+				}
+				b.recordCheck(bookmark, result)
+				if result.Failed() && !result.RateLimited() {
+					log.Println("linkHealth:", bookmark.ID, "failed", bookmark.FailureCount, "consecutive checks, dead:", bookmark.Dead)
+				}
+				err := b.repository.Update(bookmark)
+				if err == nil {
+					err = b.repository.AddLinkCheck(newLinkCheck(bookmark.ID, result))
+				}
+				// Bookmarks deleted while being checked are not an error.
+				if err != nil && !errors.Is(err, ErrNotFound) {
 					muAllErrs.Lock()
 					allErrs = errors.Join(allErrs, err)
 					muAllErrs.Unlock()
 				}
-				time.Sleep(1 * time.Second)
 			}
 		}()
 	}
-	for _, bookmark := range expiredBookmarks {
-		if ctx.Err() != nil {
-			break
-		}
-		bookmarkCh <- bookmark
-	}
-	close(bookmarkCh)
 	wg.Wait()
 
 	return allErrs
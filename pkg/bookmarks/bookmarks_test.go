@@ -20,6 +20,7 @@ import (
 	"net/http"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestBadURLError(t *testing.T) {
@@ -77,18 +78,200 @@ func TestBookmarks_Insert(t *testing.T) {
 		{"badSetup/missingURLChecker", fields{&RepositoryMock{}, nil}, args{&Bookmark{}}, errBookmarksURLCheckerNotSet},
 		{"missingBookmark", fields{&RepositoryMock{}, &URLCheckerMock{}}, args{nil}, errNilBookmark},
 		{"badURL", fields{&RepositoryMock{}, &URLCheckerMock{}}, args{&Bookmark{URL: "://"}}, &BadURLError{}},
-		{"badDB", fields{&RepositoryMock{InsertFunc: func(*Bookmark) error { return errExpectedDBError }}, &URLCheckerMock{CheckFunc: func(_, _ string) (string, int64, int64, string) { return "", 0, 0, "" }}}, args{&Bookmark{URL: "http://example.org"}}, errExpectedDBError},
-		{"good", fields{&RepositoryMock{InsertFunc: func(*Bookmark) error { return nil }}, &URLCheckerMock{CheckFunc: func(_, _ string) (string, int64, int64, string) { return "", 0, 0, "" }}}, args{&Bookmark{URL: "http://example.org"}}, nil},
+		{"badDB", fields{&RepositoryMock{InsertFunc: func(*Bookmark) error { return errExpectedDBError }}, &URLCheckerMock{CheckFunc: func(context.Context, CheckRequest) CheckResult { return CheckResult{} }}}, args{&Bookmark{URL: "http://example.org"}}, errExpectedDBError},
+		{"badDB/linkCheck", fields{&RepositoryMock{InsertFunc: func(*Bookmark) error { return nil }, AddLinkCheckFunc: func(*LinkCheck) error { return errExpectedDBError }, AddTagFunc: func(int64, int64, string) error { return nil }}, &URLCheckerMock{CheckFunc: func(context.Context, CheckRequest) CheckResult { return CheckResult{} }}}, args{&Bookmark{URL: "http://example.org", Tags: []string{"tag"}}}, nil},
+		{"good", fields{&RepositoryMock{InsertFunc: func(*Bookmark) error { return nil }, AddLinkCheckFunc: func(*LinkCheck) error { return nil }}, &URLCheckerMock{CheckFunc: func(context.Context, CheckRequest) CheckResult { return CheckResult{} }}}, args{&Bookmark{URL: "http://example.org"}}, nil},
+		{"badDB/tags", fields{&RepositoryMock{InsertFunc: func(*Bookmark) error { return nil }, AddLinkCheckFunc: func(*LinkCheck) error { return nil }, AddTagFunc: func(int64, int64, string) error { return errExpectedDBError }}, &URLCheckerMock{CheckFunc: func(context.Context, CheckRequest) CheckResult { return CheckResult{} }}}, args{&Bookmark{URL: "http://example.org", Tags: []string{"tag"}}}, errExpectedDBError},
+		{"good/tags", fields{&RepositoryMock{InsertFunc: func(*Bookmark) error { return nil }, AddLinkCheckFunc: func(*LinkCheck) error { return nil }, AddTagFunc: func(int64, int64, string) error { return nil }}, &URLCheckerMock{CheckFunc: func(context.Context, CheckRequest) CheckResult { return CheckResult{} }}}, args{&Bookmark{URL: "http://example.org", Tags: []string{"tag", " "}}}, nil},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			b := New(tt.fields.repository, tt.fields.urlChecker)
-			if err := b.Insert(tt.args.bookmark); (err != nil) && !errors.Is(err, tt.expectedError) {
+			if err := b.Insert(context.TODO(), 1, tt.args.bookmark); (err != nil) && !errors.Is(err, tt.expectedError) {
 				t.Errorf("Bookmarks.Insert() error = %v, wantErr %v", err, tt.expectedError)
 				return
 			}
 		})
 	}
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.TODO())
+		cancel()
+		repository := &RepositoryMock{}
+		urlChecker := &URLCheckerMock{CheckFunc: func(context.Context, CheckRequest) CheckResult {
+			return CheckResult{Code: http.StatusServiceUnavailable, Reason: "context canceled"}
+		}}
+		if err := New(repository, urlChecker).Insert(ctx, 1, &Bookmark{URL: "http://example.org"}); !errors.Is(err, context.Canceled) {
+			t.Error("unexpected error:", err)
+		}
+		if len(repository.InsertCalls()) != 0 {
+			t.Error("bookmark stored without checking its link")
+		}
+	})
+	t.Run("linkCheckNotRecorded", func(t *testing.T) {
+		repository := &RepositoryMock{
+			InsertFunc:       func(*Bookmark) error { return nil },
+			AddTagFunc:       func(int64, int64, string) error { return nil },
+			AddLinkCheckFunc: func(*LinkCheck) error { return errExpectedDBError },
+		}
+		urlChecker := &URLCheckerMock{CheckFunc: func(context.Context, CheckRequest) CheckResult { return CheckResult{} }}
+		if err := New(repository, urlChecker).Insert(context.TODO(), 1, &Bookmark{URL: "http://example.org", Tags: []string{"a", "b"}}); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		if calls := repository.AddTagCalls(); len(calls) != 2 {
+			t.Error("bookmark not fully tagged:", calls)
+		}
+	})
+}
+
+func TestBookmarks_owner(t *testing.T) {
+	const ownerID = 42
+	repository := &RepositoryMock{
+		InsertFunc:       func(*Bookmark) error { return nil },
+		AddLinkCheckFunc: func(*LinkCheck) error { return nil },
+		AddTagFunc:       func(int64, int64, string) error { return nil },
+		GetByIDFunc: func(ownerID, id int64) (*Bookmark, error) {
+			return &Bookmark{ID: id, OwnerID: ownerID}, nil
+		},
+		UpdateFunc: func(*Bookmark) error { return nil },
+		WalkFunc:   func(int64, func(*Bookmark) error) error { return nil },
+	}
+	b := New(repository, &URLCheckerMock{CheckFunc: func(_ context.Context, link CheckRequest) CheckResult { return CheckResult{Title: link.Title} }})
+	bookmark := &Bookmark{URL: "http://example.com", Tags: []string{"tag"}}
+	if err := b.Insert(context.TODO(), ownerID, bookmark); err != nil {
+		t.Fatal("cannot insert bookmark:", err)
+	}
+	if bookmark.OwnerID != ownerID || repository.InsertCalls()[0].Bookmark.OwnerID != ownerID {
+		t.Error("owner not set on insert:", bookmark.OwnerID)
+	}
+	if calls := repository.AddTagCalls(); len(calls) != 1 || calls[0].OwnerID != ownerID {
+		t.Error("tag added without owner:", calls)
+	}
+	if err := b.Bump(ownerID, 1); err != nil {
+		t.Fatal("cannot bump bookmark:", err)
+	}
+	if calls := repository.GetByIDCalls(); len(calls) != 1 || calls[0].OwnerID != ownerID {
+		t.Error("bookmark loaded without owner:", calls)
+	}
+	if calls := repository.UpdateCalls(); len(calls) != 1 || calls[0].Bookmark.OwnerID != ownerID {
+		t.Error("bookmark stored without owner:", calls)
+	}
+	if err := b.Walk(ownerID, func(*Bookmark) error { return nil }); err != nil {
+		t.Fatal("cannot walk bookmarks:", err)
+	}
+	if calls := repository.WalkCalls(); len(calls) != 1 || calls[0].OwnerID != ownerID {
+		t.Error("bookmarks walked without owner:", calls)
+	}
+}
+
+func TestBookmarks_Edit(t *testing.T) {
+	errDB := errors.New("bad DB")
+	checked := false
+	urlChecker := &URLCheckerMock{CheckFunc: func(_ context.Context, link CheckRequest) CheckResult {
+		checked = true
+		return CheckResult{Title: link.Title, When: 1, Code: http.StatusOK}
+	}}
+	newRepository := func() *RepositoryMock {
+		return &RepositoryMock{
+			GetByIDFunc: func(_, id int64) (*Bookmark, error) {
+				return &Bookmark{ID: id, URL: "http://example.com", Title: "title", Tags: []string{"old", "kept"}}, nil
+			},
+			UpdateFunc:    func(*Bookmark) error { return nil },
+			AddTagFunc:    func(int64, int64, string) error { return nil },
+			RemoveTagFunc: func(int64, int64, string) error { return nil },
+		}
+	}
+	t.Run("badSetup", func(t *testing.T) {
+		if _, err := New(nil, nil).Edit(context.TODO(), 1, &Bookmark{}); !errors.Is(err, errBookmarksRepositoryNotSet) {
+			t.Fatal("unexpected error:", err)
+		}
+	})
+	t.Run("missingBookmark", func(t *testing.T) {
+		if _, err := New(newRepository(), urlChecker).Edit(context.TODO(), 1, nil); !errors.Is(err, errNilBookmark) {
+			t.Fatal("unexpected error:", err)
+		}
+	})
+	t.Run("badURL", func(t *testing.T) {
+		if _, err := New(newRepository(), urlChecker).Edit(context.TODO(), 1, &Bookmark{ID: 1, URL: "://"}); !errors.Is(err, &BadURLError{}) {
+			t.Fatal("unexpected error:", err)
+		}
+	})
+	t.Run("badDB/GetByID", func(t *testing.T) {
+		repository := newRepository()
+		repository.GetByIDFunc = func(int64, int64) (*Bookmark, error) { return nil, errDB }
+		if _, err := New(repository, urlChecker).Edit(context.TODO(), 1, &Bookmark{ID: 1}); !errors.Is(err, errDB) {
+			t.Fatal("unexpected error:", err)
+		}
+	})
+	t.Run("badDB/Update", func(t *testing.T) {
+		repository := newRepository()
+		repository.UpdateFunc = func(*Bookmark) error { return errDB }
+		if _, err := New(repository, urlChecker).Edit(context.TODO(), 1, &Bookmark{ID: 1, URL: "http://example.com", Title: "title"}); !errors.Is(err, errDB) {
+			t.Fatal("unexpected error:", err)
+		}
+	})
+	t.Run("sameURL", func(t *testing.T) {
+		checked = false
+		repository := newRepository()
+		got, err := New(repository, urlChecker).Edit(context.TODO(), 1, &Bookmark{ID: 1, URL: "http://example.com", Title: "new title", Tags: []string{"kept", "new"}})
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		if checked {
+			t.Error("URL checked although it did not change")
+		}
+		if got.Title != "new title" {
+			t.Error("title not updated:", got.Title)
+		}
+		if l := len(repository.RemoveTagCalls()); l != 1 || repository.RemoveTagCalls()[0].Tag != "old" {
+			t.Error("unexpected tag removals:", repository.RemoveTagCalls())
+		}
+		if l := len(repository.AddTagCalls()); l != 1 || repository.AddTagCalls()[0].Tag != "new" {
+			t.Error("unexpected tag additions:", repository.AddTagCalls())
+		}
+	})
+	t.Run("newURL", func(t *testing.T) {
+		checked = false
+		got, err := New(newRepository(), urlChecker).Edit(context.TODO(), 1, &Bookmark{ID: 1, URL: "http://example.org", Title: "title"})
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		if !checked {
+			t.Error("URL not checked after change")
+		}
+		if got.URL != "http://example.org" || got.LastStatusCode != http.StatusOK {
+			t.Error("bookmark not updated:", got)
+		}
+	})
+	t.Run("newURL/oldPage", func(t *testing.T) {
+		repository := newRepository()
+		repository.GetByIDFunc = func(_, id int64) (*Bookmark, error) {
+			return &Bookmark{ID: id, URL: "http://example.com", Title: "title", PageTitle: "Example", Metadata: Metadata{CanonicalURL: "http://example.com/"}}, nil
+		}
+		urlChecker := &URLCheckerMock{CheckFunc: func(context.Context, CheckRequest) CheckResult {
+			return CheckResult{Title: "title", When: 1, Code: http.StatusServiceUnavailable}
+		}}
+		got, err := New(repository, urlChecker).Edit(context.TODO(), 1, &Bookmark{ID: 1, URL: "http://example.org", Title: "title"})
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		if calls := urlChecker.CheckCalls(); len(calls) != 1 || calls[0].Link.PageTitle != "" {
+			t.Error("new URL checked against the old page:", calls)
+		}
+		if got.PageTitle != "" || got.Metadata != (Metadata{}) {
+			t.Error("metadata of the old page kept:", got.PageTitle, got.Metadata)
+		}
+	})
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.TODO())
+		cancel()
+		repository := newRepository()
+		if _, err := New(repository, urlChecker).Edit(ctx, 1, &Bookmark{ID: 1, URL: "http://example.org", Title: "title"}); !errors.Is(err, context.Canceled) {
+			t.Error("unexpected error:", err)
+		}
+		if len(repository.UpdateCalls()) != 0 {
+			t.Error("bookmark updated without checking its link")
+		}
+	})
 }
 
 func TestBookmarks_DeleteByID(t *testing.T) {
@@ -105,7 +288,7 @@ func TestBookmarks_DeleteByID(t *testing.T) {
 			"badDelete",
 			args{
 				repository: &RepositoryMock{
-					DeleteByIDFunc: func(int64) error {
+					DeleteByIDFunc: func(int64, int64) error {
 						return errors.New("mocked error")
 					},
 				},
@@ -116,7 +299,7 @@ func TestBookmarks_DeleteByID(t *testing.T) {
 			"goodDelete",
 			args{
 				repository: &RepositoryMock{
-					DeleteByIDFunc: func(int64) error {
+					DeleteByIDFunc: func(int64, int64) error {
 						return nil
 					},
 				},
@@ -126,7 +309,7 @@ func TestBookmarks_DeleteByID(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := New(tt.args.repository, nil).DeleteByID(tt.args.id); (err != nil) != tt.wantErr {
+			if err := New(tt.args.repository, nil).DeleteByID(1, tt.args.id); (err != nil) != tt.wantErr {
 				t.Errorf("DeleteByID() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -150,13 +333,13 @@ func TestBookmarks_UpdateInbox(t *testing.T) {
 		wantErr bool
 	}{
 		{"badInbox", fields{}, args{0, "bad"}, true},
-		{"badDB/Get", fields{repository: &RepositoryMock{GetByIDFunc: func(int64) (*Bookmark, error) { return nil, errDB }}}, args{0, "new"}, true},
-		{"badDB/Update", fields{repository: &RepositoryMock{GetByIDFunc: func(int64) (*Bookmark, error) { return foundBookmark, nil }, UpdateFunc: func(*Bookmark) error { return errDB }}}, args{foundBookmark.ID, "new"}, true},
+		{"badDB/Get", fields{repository: &RepositoryMock{GetByIDFunc: func(int64, int64) (*Bookmark, error) { return nil, errDB }}}, args{0, "new"}, true},
+		{"badDB/Update", fields{repository: &RepositoryMock{GetByIDFunc: func(int64, int64) (*Bookmark, error) { return foundBookmark, nil }, UpdateFunc: func(*Bookmark) error { return errDB }}}, args{foundBookmark.ID, "new"}, true},
 		{
 			"done",
 			fields{
 				repository: &RepositoryMock{
-					GetByIDFunc: func(int64) (*Bookmark, error) {
+					GetByIDFunc: func(int64, int64) (*Bookmark, error) {
 						return foundBookmark, nil
 					},
 					UpdateFunc: func(bookmark *Bookmark) error {
@@ -176,7 +359,7 @@ func TestBookmarks_UpdateInbox(t *testing.T) {
 			b := &Bookmarks{
 				repository: tt.fields.repository,
 			}
-			if err := b.UpdateInbox(tt.args.id, tt.args.inbox); (err != nil) != tt.wantErr {
+			if err := b.UpdateInbox(1, tt.args.id, tt.args.inbox); (err != nil) != tt.wantErr {
 				t.Errorf("Bookmarks.UpdateInbox() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -195,17 +378,17 @@ func TestBookmarks_Inbox(t *testing.T) {
 		want    []*Bookmark
 		wantErr bool
 	}{
-		{"badDB", fields{repository: &RepositoryMock{InboxFunc: func(int) ([]*Bookmark, error) { return nil, errDB }}}, nil, true},
-		{"nilResult", fields{repository: &RepositoryMock{InboxFunc: func(int) ([]*Bookmark, error) { return nil, nil }}}, nil, false},
-		{"emptyResult", fields{repository: &RepositoryMock{InboxFunc: func(int) ([]*Bookmark, error) { return []*Bookmark{}, nil }}}, []*Bookmark{}, false},
-		{"good", fields{repository: &RepositoryMock{InboxFunc: func(int) ([]*Bookmark, error) { return []*Bookmark{foundBookmark}, nil }}}, []*Bookmark{foundBookmark}, false},
+		{"badDB", fields{repository: &RepositoryMock{InboxFunc: func(int64, Cursor, int) ([]*Bookmark, Cursor, error) { return nil, "", errDB }}}, nil, true},
+		{"nilResult", fields{repository: &RepositoryMock{InboxFunc: func(int64, Cursor, int) ([]*Bookmark, Cursor, error) { return nil, "", nil }}}, nil, false},
+		{"emptyResult", fields{repository: &RepositoryMock{InboxFunc: func(int64, Cursor, int) ([]*Bookmark, Cursor, error) { return []*Bookmark{}, "", nil }}}, []*Bookmark{}, false},
+		{"good", fields{repository: &RepositoryMock{InboxFunc: func(int64, Cursor, int) ([]*Bookmark, Cursor, error) { return []*Bookmark{foundBookmark}, "", nil }}}, []*Bookmark{foundBookmark}, false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			b := &Bookmarks{
 				repository: tt.fields.repository,
 			}
-			got, err := b.Inbox(0)
+			got, _, err := b.Inbox(1, "")
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Bookmarks.Inbox() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -229,17 +412,17 @@ func TestBookmarks_Duplicated(t *testing.T) {
 		want    []*Bookmark
 		wantErr bool
 	}{
-		{"badDB", fields{repository: &RepositoryMock{DuplicatedFunc: func(int) ([]*Bookmark, error) { return nil, errDB }}}, nil, true},
-		{"nilResult", fields{repository: &RepositoryMock{DuplicatedFunc: func(int) ([]*Bookmark, error) { return nil, nil }}}, nil, false},
-		{"emptyResult", fields{repository: &RepositoryMock{DuplicatedFunc: func(int) ([]*Bookmark, error) { return []*Bookmark{}, nil }}}, []*Bookmark{}, false},
-		{"good", fields{repository: &RepositoryMock{DuplicatedFunc: func(int) ([]*Bookmark, error) { return []*Bookmark{foundBookmark}, nil }}}, []*Bookmark{foundBookmark}, false},
+		{"badDB", fields{repository: &RepositoryMock{DuplicatedFunc: func(int64, Cursor, int) ([]*Bookmark, Cursor, error) { return nil, "", errDB }}}, nil, true},
+		{"nilResult", fields{repository: &RepositoryMock{DuplicatedFunc: func(int64, Cursor, int) ([]*Bookmark, Cursor, error) { return nil, "", nil }}}, nil, false},
+		{"emptyResult", fields{repository: &RepositoryMock{DuplicatedFunc: func(int64, Cursor, int) ([]*Bookmark, Cursor, error) { return []*Bookmark{}, "", nil }}}, []*Bookmark{}, false},
+		{"good", fields{repository: &RepositoryMock{DuplicatedFunc: func(int64, Cursor, int) ([]*Bookmark, Cursor, error) { return []*Bookmark{foundBookmark}, "", nil }}}, []*Bookmark{foundBookmark}, false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			b := &Bookmarks{
 				repository: tt.fields.repository,
 			}
-			got, err := b.Duplicated(0)
+			got, _, err := b.Duplicated(1, "")
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Bookmarks.Duplicated() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -263,17 +446,19 @@ func TestBookmarks_Dead(t *testing.T) {
 		want    []*Bookmark
 		wantErr bool
 	}{
-		{"badDB", fields{repository: &RepositoryMock{DeadFunc: func(int) ([]*Bookmark, error) { return nil, errDB }}}, nil, true},
-		{"nilResult", fields{repository: &RepositoryMock{DeadFunc: func(int) ([]*Bookmark, error) { return nil, nil }}}, nil, false},
-		{"emptyResult", fields{repository: &RepositoryMock{DeadFunc: func(int) ([]*Bookmark, error) { return []*Bookmark{}, nil }}}, []*Bookmark{}, false},
-		{"good", fields{repository: &RepositoryMock{DeadFunc: func(int) ([]*Bookmark, error) { return []*Bookmark{foundBookmark}, nil }}}, []*Bookmark{foundBookmark}, false},
+		{"badDB", fields{repository: &RepositoryMock{DeadFunc: func(int64, DeadFilter, Cursor, int) ([]*Bookmark, Cursor, error) { return nil, "", errDB }}}, nil, true},
+		{"nilResult", fields{repository: &RepositoryMock{DeadFunc: func(int64, DeadFilter, Cursor, int) ([]*Bookmark, Cursor, error) { return nil, "", nil }}}, nil, false},
+		{"emptyResult", fields{repository: &RepositoryMock{DeadFunc: func(int64, DeadFilter, Cursor, int) ([]*Bookmark, Cursor, error) { return []*Bookmark{}, "", nil }}}, []*Bookmark{}, false},
+		{"good", fields{repository: &RepositoryMock{DeadFunc: func(int64, DeadFilter, Cursor, int) ([]*Bookmark, Cursor, error) {
+			return []*Bookmark{foundBookmark}, "", nil
+		}}}, []*Bookmark{foundBookmark}, false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			b := &Bookmarks{
 				repository: tt.fields.repository,
 			}
-			got, err := b.Dead(0)
+			got, _, err := b.Dead(1, AllDead, "")
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Bookmarks.Dead() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -285,6 +470,92 @@ func TestBookmarks_Dead(t *testing.T) {
 	}
 }
 
+func TestBookmarks_Moved(t *testing.T) {
+	errDB := errors.New("DB error")
+	foundBookmark := &Bookmark{ID: 1, Title: "title", URL: "http://url.com", FinalURL: "https://url.com/"}
+	type fields struct {
+		repository Repository
+	}
+	tests := []struct {
+		name    string
+		fields  fields
+		want    []*Bookmark
+		wantErr bool
+	}{
+		{"badDB", fields{repository: &RepositoryMock{MovedFunc: func(int64, Cursor, int) ([]*Bookmark, Cursor, error) { return nil, "", errDB }}}, nil, true},
+		{"nilResult", fields{repository: &RepositoryMock{MovedFunc: func(int64, Cursor, int) ([]*Bookmark, Cursor, error) { return nil, "", nil }}}, nil, false},
+		{"emptyResult", fields{repository: &RepositoryMock{MovedFunc: func(int64, Cursor, int) ([]*Bookmark, Cursor, error) { return []*Bookmark{}, "", nil }}}, []*Bookmark{}, false},
+		{"good", fields{repository: &RepositoryMock{MovedFunc: func(int64, Cursor, int) ([]*Bookmark, Cursor, error) { return []*Bookmark{foundBookmark}, "", nil }}}, []*Bookmark{foundBookmark}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Bookmarks{
+				repository: tt.fields.repository,
+			}
+			got, _, err := b.Moved(1, "")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Bookmarks.Moved() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Bookmarks.Moved() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBookmarks_FollowRedirect(t *testing.T) {
+	const ownerID = 42
+	stored := map[int64]*Bookmark{
+		1: {ID: 1, OwnerID: ownerID, URL: "http://example.com", FinalURL: "https://example.com/", RedirectCount: 2, PermanentRedirect: true, Host: "example.com", LastStatusCode: http.StatusOK},
+		2: {ID: 2, OwnerID: ownerID, URL: "https://example.com/"},
+		4: {ID: 4, OwnerID: ownerID, URL: "https://example.com/private", FinalURL: "https://example.com/login", RedirectCount: 1},
+		5: {ID: 5, OwnerID: ownerID, URL: "https://example.com/old", FinalURL: "https://example.com/taken", RedirectCount: 1, PermanentRedirect: true},
+	}
+	repository := &RepositoryMock{
+		GetByIDFunc: func(_, id int64) (*Bookmark, error) {
+			if bookmark, ok := stored[id]; ok {
+				copied := *bookmark
+				return &copied, nil
+			}
+			return nil, ErrNotFound
+		},
+		UpdateFunc: func(*Bookmark) error { return nil },
+		HasURLFunc: func(_ int64, url string) (bool, error) { return url == "https://example.com/taken", nil },
+	}
+	b := New(repository, nil)
+	bookmark, err := b.FollowRedirect(ownerID, 1)
+	if err != nil {
+		t.Fatal("cannot follow redirect:", err)
+	}
+	if bookmark.URL != "https://example.com/" || bookmark.FinalURL != "" || bookmark.RedirectCount != 0 || bookmark.PermanentRedirect || bookmark.LastStatusCode != http.StatusOK {
+		t.Errorf("unexpected bookmark: %#v", bookmark)
+	}
+	if calls := repository.UpdateCalls(); len(calls) != 1 || calls[0].Bookmark.URL != "https://example.com/" {
+		t.Error("bookmark not stored:", calls)
+	}
+	if _, err := b.FollowRedirect(ownerID, 2); !errors.Is(err, ErrNotMoved) {
+		t.Error("expected error missing:", err)
+	}
+	if _, err := b.FollowRedirect(ownerID, 4); !errors.Is(err, ErrNotMoved) {
+		t.Error("temporary redirect followed:", err)
+	}
+	if _, err := b.FollowRedirect(ownerID, 5); !errors.Is(err, ErrDuplicateURL) {
+		t.Error("redirect to a bookmarked URL followed:", err)
+	}
+	if calls := repository.UpdateCalls(); len(calls) != 1 {
+		t.Error("bookmark stored despite errors:", calls)
+	}
+	if _, err := b.FollowRedirect(ownerID, 3); !errors.Is(err, ErrNotFound) {
+		t.Error("expected error missing:", err)
+	}
+	errDB := errors.New("bad DB")
+	repository.UpdateFunc = func(*Bookmark) error { return errDB }
+	if _, err := b.FollowRedirect(ownerID, 1); !errors.Is(err, errDB) {
+		t.Error("expected error missing:", err)
+	}
+}
+
 func TestBookmarks_All(t *testing.T) {
 	errDB := errors.New("DB error")
 	foundBookmark := &Bookmark{ID: 1, Title: "title", URL: "http://url.com"}
@@ -297,17 +568,17 @@ func TestBookmarks_All(t *testing.T) {
 		want    []*Bookmark
 		wantErr bool
 	}{
-		{"badDB", fields{repository: &RepositoryMock{AllFunc: func(int) ([]*Bookmark, error) { return nil, errDB }}}, nil, true},
-		{"nilResult", fields{repository: &RepositoryMock{AllFunc: func(int) ([]*Bookmark, error) { return nil, nil }}}, nil, false},
-		{"emptyResult", fields{repository: &RepositoryMock{AllFunc: func(int) ([]*Bookmark, error) { return []*Bookmark{}, nil }}}, []*Bookmark{}, false},
-		{"good", fields{repository: &RepositoryMock{AllFunc: func(int) ([]*Bookmark, error) { return []*Bookmark{foundBookmark}, nil }}}, []*Bookmark{foundBookmark}, false},
+		{"badDB", fields{repository: &RepositoryMock{AllFunc: func(int64, Cursor, int) ([]*Bookmark, Cursor, error) { return nil, "", errDB }}}, nil, true},
+		{"nilResult", fields{repository: &RepositoryMock{AllFunc: func(int64, Cursor, int) ([]*Bookmark, Cursor, error) { return nil, "", nil }}}, nil, false},
+		{"emptyResult", fields{repository: &RepositoryMock{AllFunc: func(int64, Cursor, int) ([]*Bookmark, Cursor, error) { return []*Bookmark{}, "", nil }}}, []*Bookmark{}, false},
+		{"good", fields{repository: &RepositoryMock{AllFunc: func(int64, Cursor, int) ([]*Bookmark, Cursor, error) { return []*Bookmark{foundBookmark}, "", nil }}}, []*Bookmark{foundBookmark}, false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			b := &Bookmarks{
 				repository: tt.fields.repository,
 			}
-			got, err := b.All(0)
+			got, _, err := b.All(1, "")
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Bookmarks.All() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -329,23 +600,25 @@ func TestBookmarks_Search(t *testing.T) {
 		term string
 	}
 	tests := []struct {
-		name    string
-		fields  fields
-		args    args
-		want    []*Bookmark
-		wantErr bool
+		name      string
+		fields    fields
+		args      args
+		want      []*Bookmark
+		wantTotal int
+		wantErr   bool
 	}{
-		{"badDB", fields{repository: &RepositoryMock{SearchFunc: func(string) ([]*Bookmark, error) { return nil, errDB }}}, args{}, nil, true},
-		{"nilResult", fields{repository: &RepositoryMock{SearchFunc: func(string) ([]*Bookmark, error) { return nil, nil }}}, args{}, nil, false},
-		{"emptyResult", fields{repository: &RepositoryMock{SearchFunc: func(string) ([]*Bookmark, error) { return []*Bookmark{}, nil }}}, args{}, []*Bookmark{}, false},
-		{"good", fields{repository: &RepositoryMock{SearchFunc: func(string) ([]*Bookmark, error) { return []*Bookmark{foundBookmark}, nil }}}, args{}, []*Bookmark{foundBookmark}, false},
+		{"badDB", fields{repository: &RepositoryMock{SearchFunc: func(int64, *Query, int, int) ([]*Bookmark, int, error) { return nil, 0, errDB }}}, args{}, nil, 0, true},
+		{"nilResult", fields{repository: &RepositoryMock{SearchFunc: func(int64, *Query, int, int) ([]*Bookmark, int, error) { return nil, 0, nil }}}, args{}, nil, 0, false},
+		{"emptyResult", fields{repository: &RepositoryMock{SearchFunc: func(int64, *Query, int, int) ([]*Bookmark, int, error) { return []*Bookmark{}, 0, nil }}}, args{}, []*Bookmark{}, 0, false},
+		{"good", fields{repository: &RepositoryMock{SearchFunc: func(int64, *Query, int, int) ([]*Bookmark, int, error) { return []*Bookmark{foundBookmark}, 1, nil }}}, args{}, []*Bookmark{foundBookmark}, 1, false},
+		{"badQuery", fields{repository: &RepositoryMock{}}, args{`"unterminated`}, nil, 0, true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			b := &Bookmarks{
 				repository: tt.fields.repository,
 			}
-			got, err := b.Search(tt.args.term)
+			got, total, err := b.Search(1, tt.args.term, 0)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Bookmarks.Search() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -353,6 +626,120 @@ func TestBookmarks_Search(t *testing.T) {
 			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("Bookmarks.Search() = %v, want %v", got, tt.want)
 			}
+			if total != tt.wantTotal {
+				t.Errorf("Bookmarks.Search() total = %v, want %v", total, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestBookmarks_AddTag(t *testing.T) {
+	errDB := errors.New("DB error")
+	tests := []struct {
+		name       string
+		repository Repository
+		tag        string
+		wantErr    error
+	}{
+		{"emptyTag", &RepositoryMock{}, " ", ErrEmptyTag},
+		{"badDB", &RepositoryMock{AddTagFunc: func(int64, int64, string) error { return errDB }}, "tag", errDB},
+		{"good", &RepositoryMock{AddTagFunc: func(_, _ int64, tag string) error {
+			if tag != "read-later" {
+				t.Error("tag not normalized:", tag)
+			}
+			return nil
+		}}, "Read Later", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Bookmarks{
+				repository: tt.repository,
+			}
+			if err := b.AddTag(1, 1, tt.tag); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Bookmarks.AddTag() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBookmarks_RemoveTag(t *testing.T) {
+	errDB := errors.New("DB error")
+	tests := []struct {
+		name       string
+		repository Repository
+		wantErr    error
+	}{
+		{"badDB", &RepositoryMock{RemoveTagFunc: func(int64, int64, string) error { return errDB }}, errDB},
+		{"good", &RepositoryMock{RemoveTagFunc: func(int64, int64, string) error { return nil }}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Bookmarks{
+				repository: tt.repository,
+			}
+			if err := b.RemoveTag(1, 1, "tag"); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Bookmarks.RemoveTag() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBookmarks_Tags(t *testing.T) {
+	errDB := errors.New("DB error")
+	foundTag := &Tag{Name: "tag", Count: 1}
+	tests := []struct {
+		name       string
+		repository Repository
+		want       []*Tag
+		wantErr    bool
+	}{
+		{"badDB", &RepositoryMock{TagsFunc: func(int64) ([]*Tag, error) { return nil, errDB }}, nil, true},
+		{"good", &RepositoryMock{TagsFunc: func(int64) ([]*Tag, error) { return []*Tag{foundTag}, nil }}, []*Tag{foundTag}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Bookmarks{
+				repository: tt.repository,
+			}
+			got, err := b.Tags(1)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Bookmarks.Tags() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Bookmarks.Tags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBookmarks_ByTag(t *testing.T) {
+	errDB := errors.New("DB error")
+	foundBookmark := &Bookmark{ID: 1, Title: "title", URL: "http://url.com", Tags: []string{"tag"}}
+	tests := []struct {
+		name       string
+		repository Repository
+		want       []*Bookmark
+		wantErr    bool
+	}{
+		{"badDB", &RepositoryMock{ByTagFunc: func(int64, string, Cursor, int) ([]*Bookmark, Cursor, error) { return nil, "", errDB }}, nil, true},
+		{"good", &RepositoryMock{ByTagFunc: func(int64, string, Cursor, int) ([]*Bookmark, Cursor, error) {
+			return []*Bookmark{foundBookmark}, "", nil
+		}}, []*Bookmark{foundBookmark}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Bookmarks{
+				repository: tt.repository,
+			}
+			got, _, err := b.ByTag(1, "Tag", "")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Bookmarks.ByTag() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Bookmarks.ByTag() = %v, want %v", got, tt.want)
+			}
 		})
 	}
 }
@@ -385,8 +772,8 @@ func TestBookmarks_RefreshExpiredLinks(t *testing.T) {
 		}
 		const expectedTitle = "title"
 		urlchecker := &URLCheckerMock{
-			CheckFunc: func(_, _ string) (string, int64, int64, string) {
-				return expectedTitle, 0, 0, ""
+			CheckFunc: func(context.Context, CheckRequest) CheckResult {
+				return CheckResult{Title: expectedTitle}
 			},
 		}
 		b := New(repository, urlchecker)
@@ -410,8 +797,8 @@ func TestBookmarks_RefreshExpiredLinks(t *testing.T) {
 		}
 		const expectedTitle = "title"
 		urlchecker := &URLCheckerMock{
-			CheckFunc: func(_, _ string) (string, int64, int64, string) {
-				return expectedTitle, 0, 0, ""
+			CheckFunc: func(context.Context, CheckRequest) CheckResult {
+				return CheckResult{Title: expectedTitle}
 			},
 		}
 		b := New(repository, urlchecker)
@@ -420,6 +807,68 @@ func TestBookmarks_RefreshExpiredLinks(t *testing.T) {
 			t.Fatal("unexpected error:", err)
 		}
 	})
+	t.Run("redirects", func(t *testing.T) {
+		foundBookmarks := []*Bookmark{{ID: 1, URL: "http://example.com", FinalURL: "http://example.com/stale"}}
+		repository := &RepositoryMock{
+			ExpiredFunc: func() ([]*Bookmark, error) {
+				return foundBookmarks, nil
+			},
+			UpdateFunc: func(*Bookmark) error {
+				return nil
+			},
+			AddLinkCheckFunc: func(*LinkCheck) error {
+				return nil
+			},
+		}
+		urlchecker := &URLCheckerMock{
+			CheckFunc: func(_ context.Context, link CheckRequest) CheckResult {
+				return CheckResult{Title: link.Title, Code: http.StatusOK, Redirects: []string{"https://example.com", "https://example.com/"}}
+			},
+		}
+		if err := New(repository, urlchecker).RefreshExpiredLinks(context.TODO()); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		updated := repository.UpdateCalls()[0].Bookmark
+		if updated.FinalURL != "https://example.com/" || updated.RedirectCount != 2 || !updated.Moved() {
+			t.Errorf("redirects not recorded: %#v", updated)
+		}
+	})
+	t.Run("failures", func(t *testing.T) {
+		foundBookmarks := []*Bookmark{
+			{ID: 1, URL: "https://example.com/flaky"},
+			{ID: 2, URL: "https://example.com/dead", LastStatusCode: http.StatusNotFound, FailureCount: 5, FailingSince: 1, Dead: true},
+		}
+		repository := &RepositoryMock{
+			ExpiredFunc: func() ([]*Bookmark, error) {
+				return foundBookmarks, nil
+			},
+			UpdateFunc: func(*Bookmark) error {
+				return nil
+			},
+			AddLinkCheckFunc: func(*LinkCheck) error {
+				return nil
+			},
+		}
+		now := time.Now().Unix()
+		urlchecker := &URLCheckerMock{
+			CheckFunc: func(_ context.Context, link CheckRequest) CheckResult {
+				if link.URL == "https://example.com/dead" {
+					return CheckResult{Title: link.Title, When: now, Code: http.StatusOK}
+				}
+				return CheckResult{Title: link.Title, When: now, Code: http.StatusServiceUnavailable}
+			},
+		}
+		if err := New(repository, urlchecker).RefreshExpiredLinks(context.TODO()); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		flaky, recovered := foundBookmarks[0], foundBookmarks[1]
+		if flaky.FailureCount != 1 || flaky.FailingSince != now || flaky.Dead || flaky.NextCheck <= now {
+			t.Errorf("failure not recorded: %#v", flaky)
+		}
+		if recovered.FailureCount != 0 || recovered.Dead || recovered.NextCheck <= now {
+			t.Errorf("dead link not restored: %#v", recovered)
+		}
+	})
 	t.Run("good", func(t *testing.T) {
 		foundBookmarks := []*Bookmark{{ID: 1, URL: "https://example.com"}}
 		repository := &RepositoryMock{
@@ -429,11 +878,14 @@ func TestBookmarks_RefreshExpiredLinks(t *testing.T) {
 			UpdateFunc: func(*Bookmark) error {
 				return nil
 			},
+			AddLinkCheckFunc: func(*LinkCheck) error {
+				return nil
+			},
 		}
 		const expectedTitle = "title"
 		urlchecker := &URLCheckerMock{
-			CheckFunc: func(_, _ string) (string, int64, int64, string) {
-				return expectedTitle, 0, 0, ""
+			CheckFunc: func(context.Context, CheckRequest) CheckResult {
+				return CheckResult{Title: expectedTitle}
 			},
 		}
 		b := New(repository, urlchecker)
@@ -441,5 +893,58 @@ func TestBookmarks_RefreshExpiredLinks(t *testing.T) {
 		if err != nil {
 			t.Fatal("unexpected error:", err)
 		}
+		if calls := repository.AddLinkCheckCalls(); len(calls) != 1 || calls[0].LinkCheck.BookmarkID != 1 {
+			t.Error("link check not recorded:", calls)
+		}
 	})
+	t.Run("badDB/linkCheck", func(t *testing.T) {
+		errDB := errors.New("bad DB")
+		repository := &RepositoryMock{
+			ExpiredFunc: func() ([]*Bookmark, error) {
+				return []*Bookmark{{ID: 1, URL: "https://example.com"}}, nil
+			},
+			UpdateFunc: func(*Bookmark) error {
+				return nil
+			},
+			AddLinkCheckFunc: func(*LinkCheck) error {
+				return errDB
+			},
+		}
+		urlchecker := &URLCheckerMock{
+			CheckFunc: func(_ context.Context, link CheckRequest) CheckResult {
+				return CheckResult{Title: link.Title}
+			},
+		}
+		if err := New(repository, urlchecker).RefreshExpiredLinks(context.TODO()); !errors.Is(err, errDB) {
+			t.Fatal("unexpected error:", err)
+		}
+	})
+}
+
+func TestWithPageSize(t *testing.T) {
+	var gotLimit int
+	repository := &RepositoryMock{InboxFunc: func(_ int64, _ Cursor, limit int) ([]*Bookmark, Cursor, error) {
+		gotLimit = limit
+		return nil, "", nil
+	}}
+	tests := []struct {
+		name string
+		opts []Option
+		want int
+	}{
+		{"default", nil, DefaultPageSize},
+		{"custom", []Option{WithPageSize(10)}, 10},
+		{"invalid", []Option{WithPageSize(-1)}, DefaultPageSize},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := New(repository, &URLCheckerMock{}, tt.opts...)
+			if _, _, err := b.Inbox(1, ""); err != nil {
+				t.Fatal(err)
+			}
+			if gotLimit != tt.want {
+				t.Errorf("limit = %v, want %v", gotLimit, tt.want)
+			}
+		})
+	}
 }
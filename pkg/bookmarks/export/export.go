@@ -0,0 +1,174 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package export writes bookmarks to files that other applications, or
+// alreadyread itself, can read back.
+package export // import "cirello.io/alreadyread/pkg/bookmarks/export"
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"cirello.io/alreadyread/pkg/bookmarks"
+	"cirello.io/alreadyread/pkg/bookmarks/netscape"
+)
+
+// Format names a supported export file format. It doubles as the file
+// extension.
+type Format string
+
+// Supported export formats.
+const (
+	// Netscape is the bookmarks.html format that browsers import.
+	Netscape Format = "html"
+	// JSONLines writes every field of each bookmark as one JSON object per
+	// line.
+	JSONLines Format = "jsonl"
+	// CSV writes one row per bookmark, after a header row.
+	CSV Format = "csv"
+)
+
+// ErrUnknownFormat is returned when the requested format is not supported.
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// ParseFormat validates the name of an export format.
+func ParseFormat(v string) (Format, error) {
+	switch f := Format(strings.ToLower(v)); f {
+	case Netscape, JSONLines, CSV:
+		return f, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownFormat, v)
+}
+
+// ContentType is the MIME type of files in the format.
+func (f Format) ContentType() string {
+	switch f {
+	case Netscape:
+		return "text/html; charset=utf-8"
+	case JSONLines:
+		return "application/jsonl; charset=utf-8"
+	case CSV:
+		return "text/csv; charset=utf-8"
+	}
+	return "application/octet-stream"
+}
+
+// Encoder writes bookmarks one at a time. Close must be called after the
+// last bookmark to complete the file.
+type Encoder interface {
+	Encode(*bookmarks.Bookmark) error
+	Close() error
+}
+
+// NewEncoder returns an encoder that writes to w in the given format.
+func NewEncoder(f Format, w io.Writer) (Encoder, error) {
+	switch f {
+	case Netscape:
+		return netscape.NewEncoder(w), nil
+	case JSONLines:
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		return &jsonLinesEncoder{enc: enc}, nil
+	case CSV:
+		return &csvEncoder{w: csv.NewWriter(w)}, nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, f)
+}
+
+type jsonLinesEncoder struct {
+	enc *json.Encoder
+}
+
+func (e *jsonLinesEncoder) Encode(bookmark *bookmarks.Bookmark) error {
+	if err := e.enc.Encode(bookmark); err != nil {
+		return fmt.Errorf("cannot write bookmark: %w", err)
+	}
+	return nil
+}
+
+func (e *jsonLinesEncoder) Close() error {
+	return nil
+}
+
+// csvHeader names the columns written by csvEncoder.
+var csvHeader = []string{"id", "url", "title", "description", "tags", "inbox", "created_at", "bump_date", "last_status_code", "last_status_check", "last_status_reason", "final_url", "redirect_count"}
+
+type csvEncoder struct {
+	w       *csv.Writer
+	started bool
+}
+
+func (e *csvEncoder) start() error {
+	if e.started {
+		return nil
+	}
+	e.started = true
+	if err := e.w.Write(csvHeader); err != nil {
+		return fmt.Errorf("cannot write CSV header: %w", err)
+	}
+	return nil
+}
+
+func (e *csvEncoder) Encode(bookmark *bookmarks.Bookmark) error {
+	if err := e.start(); err != nil {
+		return err
+	}
+	inbox := "read"
+	if bookmark.Inbox == bookmarks.NewLink {
+		inbox = "new"
+	}
+	err := e.w.Write([]string{
+		strconv.FormatInt(bookmark.ID, 10),
+		bookmark.URL,
+		bookmark.Title,
+		bookmark.Description,
+		strings.Join(bookmark.Tags, ","),
+		inbox,
+		formatTime(bookmark.CreatedAt),
+		formatTime(bookmark.BumpDate),
+		strconv.FormatInt(bookmark.LastStatusCode, 10),
+		strconv.FormatInt(bookmark.LastStatusCheck, 10),
+		bookmark.LastStatusReason,
+		bookmark.FinalURL,
+		strconv.FormatInt(bookmark.RedirectCount, 10),
+	})
+	if err != nil {
+		return fmt.Errorf("cannot write bookmark: %w", err)
+	}
+	return nil
+}
+
+func (e *csvEncoder) Close() error {
+	if err := e.start(); err != nil {
+		return err
+	}
+	e.w.Flush()
+	if err := e.w.Error(); err != nil {
+		return fmt.Errorf("cannot write CSV file: %w", err)
+	}
+	return nil
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,144 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"cirello.io/alreadyread/pkg/bookmarks"
+)
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Format
+		wantErr bool
+	}{
+		{"html", Netscape, false},
+		{"JSONL", JSONLines, false},
+		{"csv", CSV, false},
+		{"", "", true},
+		{"xml", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseFormat(tt.in)
+			if (err != nil) != tt.wantErr || (err != nil && !errors.Is(err, ErrUnknownFormat)) {
+				t.Fatalf("ParseFormat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseFormat() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if _, err := NewEncoder("xml", &strings.Builder{}); !errors.Is(err, ErrUnknownFormat) {
+		t.Error("expected error missing:", err)
+	}
+}
+
+var testBookmarks = []*bookmarks.Bookmark{
+	{
+		ID:               1,
+		URL:              "https://go.dev/?a=1&b=2",
+		Title:            "The Go Programming Language",
+		Description:      "line one\nline \"two\"",
+		Tags:             []string{"go", "reference"},
+		CreatedAt:        time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+		BumpDate:         time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Inbox:            bookmarks.NewLink,
+		LastStatusCode:   404,
+		LastStatusCheck:  1700000000,
+		LastStatusReason: "404 Not Found",
+		FinalURL:         "https://go.dev/new",
+		RedirectCount:    2,
+		Host:             "go.dev",
+	},
+	{
+		ID:        2,
+		URL:       "https://example.com/",
+		CreatedAt: time.Date(2023, 1, 3, 0, 0, 0, 0, time.UTC),
+		BumpDate:  time.Date(2023, 1, 3, 0, 0, 0, 0, time.UTC),
+		Inbox:     bookmarks.Read,
+		Host:      "example.com",
+	},
+}
+
+func encodeAll(t *testing.T, f Format, list []*bookmarks.Bookmark) string {
+	t.Helper()
+	var sb strings.Builder
+	enc, err := NewEncoder(f, &sb)
+	if err != nil {
+		t.Fatal("cannot create encoder:", err)
+	}
+	for _, bookmark := range list {
+		if err := enc.Encode(bookmark); err != nil {
+			t.Fatal("cannot encode bookmark:", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal("cannot close encoder:", err)
+	}
+	return sb.String()
+}
+
+func TestJSONLines(t *testing.T) {
+	lines := strings.Split(strings.TrimSuffix(encodeAll(t, JSONLines, testBookmarks), "\n"), "\n")
+	if len(lines) != len(testBookmarks) {
+		t.Fatalf("unexpected line count: %d", len(lines))
+	}
+	for i, line := range lines {
+		got := &bookmarks.Bookmark{}
+		if err := json.Unmarshal([]byte(line), got); err != nil {
+			t.Fatal("cannot decode line:", err)
+		}
+		if !reflect.DeepEqual(got, testBookmarks[i]) {
+			t.Errorf("bookmark changed in round trip:\ngot  %#v\nwant %#v", got, testBookmarks[i])
+		}
+	}
+	if !strings.Contains(lines[0], "a=1&b=2") {
+		t.Error("URL should not be escaped:", lines[0])
+	}
+}
+
+func TestCSV(t *testing.T) {
+	records, err := csv.NewReader(strings.NewReader(encodeAll(t, CSV, testBookmarks))).ReadAll()
+	if err != nil {
+		t.Fatal("cannot read CSV file:", err)
+	}
+	want := [][]string{
+		csvHeader,
+		{"1", "https://go.dev/?a=1&b=2", "The Go Programming Language", "line one\nline \"two\"", "go,reference", "new", "2023-01-02T03:04:05Z", "2024-01-02T03:04:05Z", "404", "1700000000", "404 Not Found", "https://go.dev/new", "2"},
+		{"2", "https://example.com/", "", "", "", "read", "2023-01-03T00:00:00Z", "2023-01-03T00:00:00Z", "0", "0", "", "", "0"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("unexpected CSV file:\ngot  %q\nwant %q", records, want)
+	}
+	if got := encodeAll(t, CSV, nil); got != strings.Join(csvHeader, ",")+"\n" {
+		t.Errorf("unexpected empty CSV file: %q", got)
+	}
+}
+
+func TestNetscape(t *testing.T) {
+	got := encodeAll(t, Netscape, testBookmarks)
+	if !strings.HasPrefix(got, "<!DOCTYPE NETSCAPE-Bookmark-file-1>") || !strings.Contains(got, `HREF="https://go.dev/?a=1&amp;b=2"`) {
+		t.Errorf("unexpected bookmark file:\n%s", got)
+	}
+}
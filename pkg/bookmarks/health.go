@@ -0,0 +1,93 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmarks
+
+import "time"
+
+// Working links are checked again once a week. Failing links are checked
+// again a day after they first fail, and then twice as long after each
+// further failure, so that short outages are noticed soon after they end
+// without bothering hosts that are gone for good.
+const (
+	recheckInterval     = 7 * 24 * time.Hour
+	firstFailureBackoff = 24 * time.Hour
+	maxFailureBackoff   = 30 * 24 * time.Hour
+)
+
+// blockedRecheckInterval is how long links blocked by robots.txt wait before
+// the link checker asks again whether it may check them. Rules seldom
+// change, and a blocked link is neither working nor failing. The same goes
+// for links to private addresses, until the server allows them.
+const blockedRecheckInterval = 30 * 24 * time.Hour
+
+// recordCheck stores the outcome of a link check in the bookmark, and
+// schedules the next one. A link is only declared dead once it failed
+// enough consecutive checks over a long enough period, and it stays dead
+// until a check succeeds again. Links blocked by robots.txt or leading to
+// private addresses are neither: they are just asked about again much later.
+// Hosts that ask to be left alone for a while are not failing either, and
+// their links are checked again once the wait is over.
+func (b *Bookmarks) recordCheck(bookmark *Bookmark, r CheckResult) {
+	bookmark.setCheckResult(r)
+	if r.Blocked() || r.PrivateAddress() {
+		bookmark.resetHealth()
+		bookmark.NextCheck = r.When + int64(blockedRecheckInterval/time.Second)
+		return
+	}
+	if r.RateLimited() {
+		// The host is busy, not gone: the link is as healthy as it was.
+		wait := r.RetryAfter
+		if wait <= 0 {
+			wait = firstFailureBackoff
+		}
+		bookmark.NextCheck = r.When + int64(wait/time.Second)
+		return
+	}
+	if !r.Failed() {
+		bookmark.resetHealth()
+		bookmark.NextCheck = r.When + int64(recheckInterval/time.Second)
+		return
+	}
+	if bookmark.FailureCount == 0 {
+		bookmark.FailingSince = r.When
+	}
+	bookmark.FailureCount++
+	failures, period := b.deadLinkPolicy()
+	if bookmark.FailureCount >= int64(failures) && r.When-bookmark.FailingSince >= int64(period/time.Second) {
+		bookmark.Dead = true
+	}
+	bookmark.NextCheck = r.When + int64(failureBackoff(bookmark.FailureCount)/time.Second)
+}
+
+func (b *Bookmarks) deadLinkPolicy() (failures int, period time.Duration) {
+	failures, period = b.deadAfterFailures, b.deadAfter
+	if failures <= 0 {
+		failures = DefaultDeadAfterFailures
+	}
+	if period < 0 {
+		period = DefaultDeadAfter
+	}
+	return failures, period
+}
+
+// failureBackoff is how long to wait before checking again a link that
+// failed the given number of consecutive checks.
+func failureBackoff(failures int64) time.Duration {
+	backoff := firstFailureBackoff
+	for i := int64(1); i < failures && backoff < maxFailureBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxFailureBackoff)
+}
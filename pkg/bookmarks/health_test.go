@@ -0,0 +1,130 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmarks
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBookmarks_recordCheck(t *testing.T) {
+	const day = int64(24 * time.Hour / time.Second)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Unix()
+	type check struct {
+		day          int64
+		code         int64
+		wantFailures int64
+		wantDead     bool
+		wantNext     int64 // in days after the check
+	}
+	tests := []struct {
+		name   string
+		opts   []Option
+		checks []check
+	}{
+		{"healthy", nil, []check{
+			{0, http.StatusOK, 0, false, 7},
+			{7, http.StatusOK, 0, false, 7},
+		}},
+		{"briefOutage", nil, []check{
+			{0, http.StatusServiceUnavailable, 1, false, 1},
+			{1, http.StatusServiceUnavailable, 2, false, 2},
+			{3, http.StatusOK, 0, false, 7},
+		}},
+		{"dead", nil, []check{
+			{0, http.StatusNotFound, 1, false, 1},
+			{1, http.StatusNotFound, 2, false, 2},
+			{3, http.StatusNotFound, 3, false, 4},
+			{7, http.StatusNotFound, 4, true, 8},
+			{15, http.StatusNotFound, 5, true, 16},
+			{31, http.StatusNotFound, 6, true, 30},
+			{61, http.StatusOK, 0, false, 7},
+		}},
+		{"blocked", nil, []check{
+			{0, http.StatusNotFound, 1, false, 1},
+			{1, StatusBlockedByRobots, 0, false, 30},
+			{31, StatusBlockedByRobots, 0, false, 30},
+		}},
+		{"privateAddress", nil, []check{
+			{0, http.StatusNotFound, 1, false, 1},
+			{1, StatusPrivateAddress, 0, false, 30},
+		}},
+		{"softFailure", nil, []check{
+			{0, StatusSoftFailure, 1, false, 1},
+			{1, StatusSoftFailure, 2, false, 2},
+			{3, http.StatusOK, 0, false, 7},
+		}},
+		{"tooManyRequests", nil, []check{
+			{0, http.StatusNotFound, 1, false, 1},
+			{1, http.StatusTooManyRequests, 1, false, 1},
+			{2, http.StatusNotFound, 2, false, 2},
+		}},
+		{"customPolicy", []Option{WithDeadLinkPolicy(2, 0)}, []check{
+			{0, http.StatusNotFound, 1, false, 1},
+			{1, http.StatusNotFound, 2, true, 2},
+		}},
+		{"invalidPolicy", []Option{WithDeadLinkPolicy(0, -1)}, []check{
+			{0, http.StatusNotFound, 1, false, 1},
+			{1, http.StatusNotFound, 2, false, 2},
+			{3, http.StatusNotFound, 3, false, 4},
+			{7, http.StatusNotFound, 4, true, 8},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := New(nil, nil, tt.opts...)
+			bookmark := &Bookmark{URL: "https://example.com"}
+			for _, c := range tt.checks {
+				when := start + c.day*day
+				b.recordCheck(bookmark, CheckResult{When: when, Code: c.code})
+				if bookmark.FailureCount != c.wantFailures || bookmark.Dead != c.wantDead || bookmark.NextCheck != when+c.wantNext*day {
+					t.Fatalf("day %d: unexpected health: failures=%d dead=%v next=%+d days", c.day, bookmark.FailureCount, bookmark.Dead, (bookmark.NextCheck-when)/day)
+				}
+				if c.wantFailures > 0 && bookmark.FailingSince != start {
+					t.Fatalf("day %d: unexpected start of failures: %v", c.day, bookmark.FailingSince)
+				} else if c.wantFailures == 0 && bookmark.FailingSince != 0 {
+					t.Fatalf("day %d: failures not forgotten: %v", c.day, bookmark.FailingSince)
+				}
+			}
+		})
+	}
+	t.Run("retryAfter", func(t *testing.T) {
+		for _, code := range []int64{http.StatusTooManyRequests, http.StatusServiceUnavailable} {
+			bookmark := &Bookmark{URL: "https://example.com", FailureCount: 1, FailingSince: start}
+			New(nil, nil).recordCheck(bookmark, CheckResult{When: start + day, Code: code, RetryAfter: 3 * 24 * time.Hour})
+			if bookmark.FailureCount != 1 || bookmark.FailingSince != start || bookmark.NextCheck != start+4*day {
+				t.Errorf("%d: Retry-After not honored: failures=%d next=%+d days", code, bookmark.FailureCount, (bookmark.NextCheck-start-day)/day)
+			}
+		}
+	})
+	t.Run("repeatedTooManyRequests", func(t *testing.T) {
+		b := New(nil, nil, WithDeadLinkPolicy(1, 0))
+		bookmark := &Bookmark{URL: "https://example.com"}
+		for i := range int64(100) {
+			b.recordCheck(bookmark, CheckResult{When: start + i*day, Code: http.StatusTooManyRequests, RetryAfter: time.Hour})
+			if bookmark.Dead || bookmark.FailureCount != 0 {
+				t.Fatalf("check %d: rate limited link counted as failing: failures=%d dead=%v", i, bookmark.FailureCount, bookmark.Dead)
+			}
+		}
+	})
+	t.Run("deadAndRateLimited", func(t *testing.T) {
+		bookmark := &Bookmark{URL: "https://example.com", FailureCount: 4, FailingSince: start, Dead: true}
+		New(nil, nil).recordCheck(bookmark, CheckResult{When: start + 7*day, Code: http.StatusTooManyRequests})
+		if bookmark.FailureCount != 4 || !bookmark.Dead || bookmark.NextCheck != start+8*day {
+			t.Errorf("dead link changed by a 429: failures=%d dead=%v next=%+d days", bookmark.FailureCount, bookmark.Dead, (bookmark.NextCheck-start-7*day)/day)
+		}
+	})
+}
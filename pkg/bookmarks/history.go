@@ -0,0 +1,92 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmarks
+
+import (
+	"fmt"
+	"time"
+)
+
+// LinkCheck is one entry of the link health history of a bookmark.
+type LinkCheck struct {
+	BookmarkID int64  `db:"bookmark_id" json:"bookmark_id"`
+	CheckedAt  int64  `db:"checked_at" json:"checked_at"`
+	Code       int64  `db:"code" json:"code"`
+	Reason     string `db:"reason" json:"reason"`
+}
+
+// Failed reports whether the link did not work.
+func (c *LinkCheck) Failed() bool {
+	return CheckResult{Code: c.Code}.Failed()
+}
+
+func newLinkCheck(bookmarkID int64, r CheckResult) *LinkCheck {
+	return &LinkCheck{BookmarkID: bookmarkID, CheckedAt: r.When, Code: r.Code, Reason: r.Reason}
+}
+
+// LinkHistory is the recent link health history of a bookmark.
+type LinkHistory struct {
+	Bookmark *Bookmark
+	// Checks are the latest link checks, newest first.
+	Checks []*LinkCheck
+	// Uptime is the percentage of Checks that succeeded.
+	Uptime float64
+}
+
+// historySize is how many link checks History reports.
+const historySize = 100
+
+// History loads the latest link checks of a bookmark.
+func (b *Bookmarks) History(ownerID, id int64) (*LinkHistory, error) {
+	if b.repository == nil {
+		return nil, fmt.Errorf("cannot begin loading link history: %w", errBookmarksRepositoryNotSet)
+	}
+	bookmark, err := b.repository.GetByID(ownerID, id)
+	if err != nil {
+		return nil, fmt.Errorf("cannot find bookmark: %w", err)
+	}
+	checks, err := b.repository.LinkChecks(ownerID, id, historySize)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load link history: %w", err)
+	}
+	history := &LinkHistory{Bookmark: bookmark, Checks: checks}
+	if len(checks) > 0 {
+		var ok int
+		for _, check := range checks {
+			if !check.Failed() {
+				ok++
+			}
+		}
+		history.Uptime = 100 * float64(ok) / float64(len(checks))
+	}
+	return history, nil
+}
+
+// DefaultHistoryRetention is how long link checks are kept unless the
+// caller of PruneHistory says otherwise.
+const DefaultHistoryRetention = 365 * 24 * time.Hour
+
+// PruneHistory deletes the link checks older than maxAge, and reports how
+// many were deleted.
+func (b *Bookmarks) PruneHistory(maxAge time.Duration) (int64, error) {
+	if b.repository == nil {
+		return 0, fmt.Errorf("cannot begin pruning link history: %w", errBookmarksRepositoryNotSet)
+	}
+	pruned, err := b.repository.PruneLinkChecks(time.Now().Add(-maxAge))
+	if err != nil {
+		return 0, fmt.Errorf("cannot prune link history: %w", err)
+	}
+	return pruned, nil
+}
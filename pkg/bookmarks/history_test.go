@@ -0,0 +1,111 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmarks
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBookmarks_History(t *testing.T) {
+	const ownerID = 42
+	checks := []*LinkCheck{
+		{BookmarkID: 1, CheckedAt: 4, Code: http.StatusOK},
+		{BookmarkID: 1, CheckedAt: 3, Code: http.StatusServiceUnavailable},
+		{BookmarkID: 1, CheckedAt: 2, Code: http.StatusOK},
+	}
+	repository := &RepositoryMock{
+		GetByIDFunc: func(_, id int64) (*Bookmark, error) {
+			if id != 1 {
+				return nil, ErrNotFound
+			}
+			return &Bookmark{ID: id}, nil
+		},
+		LinkChecksFunc: func(_, id int64, limit int) ([]*LinkCheck, error) {
+			if limit != historySize {
+				t.Error("unexpected limit:", limit)
+			}
+			return checks, nil
+		},
+	}
+	history, err := New(repository, nil).History(ownerID, 1)
+	if err != nil {
+		t.Fatal("cannot load history:", err)
+	}
+	if history.Bookmark.ID != 1 || len(history.Checks) != 3 || math.Abs(history.Uptime-200.0/3) > 0.001 {
+		t.Errorf("unexpected history: %#v", history)
+	}
+	if calls := repository.LinkChecksCalls(); calls[0].OwnerID != ownerID || calls[0].ID != 1 {
+		t.Error("unexpected repository call:", calls)
+	}
+	t.Run("notFound", func(t *testing.T) {
+		if _, err := New(repository, nil).History(ownerID, 2); !errors.Is(err, ErrNotFound) {
+			t.Error("unexpected error:", err)
+		}
+	})
+	t.Run("neverChecked", func(t *testing.T) {
+		checks = nil
+		history, err := New(repository, nil).History(ownerID, 1)
+		if err != nil || history.Uptime != 0 || len(history.Checks) != 0 {
+			t.Errorf("unexpected history: %#v %v", history, err)
+		}
+	})
+	t.Run("badSetup", func(t *testing.T) {
+		if _, err := New(nil, nil).History(ownerID, 1); !errors.Is(err, errBookmarksRepositoryNotSet) {
+			t.Error("unexpected error:", err)
+		}
+	})
+	t.Run("badDB", func(t *testing.T) {
+		errDB := errors.New("bad DB")
+		repository := &RepositoryMock{
+			GetByIDFunc:    func(_, id int64) (*Bookmark, error) { return &Bookmark{ID: id}, nil },
+			LinkChecksFunc: func(int64, int64, int) ([]*LinkCheck, error) { return nil, errDB },
+		}
+		if _, err := New(repository, nil).History(ownerID, 1); !errors.Is(err, errDB) {
+			t.Error("unexpected error:", err)
+		}
+	})
+}
+
+func TestBookmarks_PruneHistory(t *testing.T) {
+	repository := &RepositoryMock{
+		PruneLinkChecksFunc: func(time.Time) (int64, error) { return 10, nil },
+	}
+	pruned, err := New(repository, nil).PruneHistory(24 * time.Hour)
+	if err != nil || pruned != 10 {
+		t.Fatal("unexpected result:", pruned, err)
+	}
+	before := repository.PruneLinkChecksCalls()[0].Before
+	if d := time.Since(before); d < 24*time.Hour || d > 25*time.Hour {
+		t.Error("unexpected cutoff:", before)
+	}
+	t.Run("badSetup", func(t *testing.T) {
+		if _, err := New(nil, nil).PruneHistory(time.Hour); !errors.Is(err, errBookmarksRepositoryNotSet) {
+			t.Error("unexpected error:", err)
+		}
+	})
+	t.Run("badDB", func(t *testing.T) {
+		errDB := errors.New("bad DB")
+		repository := &RepositoryMock{
+			PruneLinkChecksFunc: func(time.Time) (int64, error) { return 0, errDB },
+		}
+		if _, err := New(repository, nil).PruneHistory(time.Hour); !errors.Is(err, errDB) {
+			t.Error("unexpected error:", err)
+		}
+	})
+}
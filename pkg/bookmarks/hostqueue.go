@@ -0,0 +1,229 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmarks
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxHostPause is the longest a run of the link checker waits for a host
+// that asked to be left alone. Links on hosts that ask for longer are left
+// for the next run.
+const maxHostPause = 5 * time.Minute
+
+func (b *Bookmarks) linkCheckPolicy() (workers, perHost int, delay time.Duration) {
+	workers, perHost, delay = b.checkWorkers, b.hostWorkers, b.hostDelay
+	if workers <= 0 {
+		workers = DefaultLinkCheckWorkers
+	}
+	if perHost <= 0 {
+		perHost = DefaultHostWorkers
+	}
+	if delay < 0 {
+		delay = DefaultHostDelay
+	}
+	return workers, perHost, delay
+}
+
+// hostQueue hands out the links to check so that every host gets at most
+// perHost requests at a time. Requests to a host start at least delay apart,
+// and at least delay after the previous one ended. Hosts take turns, so that
+// a host with many links does not hold up the others.
+type hostQueue struct {
+	perHost int
+	delay   time.Duration
+	now     func() time.Time
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+	order []string      // hosts with links left, in turn order
+	wake  chan struct{} // closed when a host frees up
+}
+
+type hostState struct {
+	pending []*Bookmark
+	active  int
+	readyAt time.Time
+}
+
+func newHostQueue(list []*Bookmark, perHost int, delay time.Duration) *hostQueue {
+	q := &hostQueue{
+		perHost: perHost,
+		delay:   delay,
+		now:     time.Now,
+		hosts:   make(map[string]*hostState),
+		wake:    make(chan struct{}),
+	}
+	for _, bookmark := range list {
+		host := linkHost(bookmark.URL)
+		h, ok := q.hosts[host]
+		if !ok {
+			h = &hostState{}
+			q.hosts[host] = h
+			q.order = append(q.order, host)
+		}
+		h.pending = append(h.pending, bookmark)
+	}
+	return q
+}
+
+// linkHost is the host that serves the link. Links that cannot be parsed
+// share a single host.
+func linkHost(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// next waits until a link can be checked, and returns it. It returns nil
+// once there are no links left, or when the context is canceled.
+func (q *hostQueue) next(ctx context.Context) *Bookmark {
+	for ctx.Err() == nil {
+		q.mu.Lock()
+		bookmark, wait, wake := q.take()
+		q.mu.Unlock()
+		if bookmark != nil || wake == nil {
+			return bookmark
+		}
+		var (
+			timer   *time.Timer
+			timeout <-chan time.Time
+		)
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			timeout = timer.C
+		}
+		select {
+		case <-ctx.Done():
+		case <-timeout:
+		case <-wake:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+	return nil
+}
+
+// take returns the next link of the first host that is ready. Otherwise, it
+// returns how long until a host is ready, if known, and a channel closed
+// when a host frees up. Both are empty if there are no links left.
+func (q *hostQueue) take() (*Bookmark, time.Duration, <-chan struct{}) {
+	now := q.now()
+	var wait time.Duration
+	for i, host := range q.order {
+		h := q.hosts[host]
+		if h.active >= q.perHost {
+			continue
+		}
+		if d := h.readyAt.Sub(now); d > 0 {
+			if wait == 0 || d < wait {
+				wait = d
+			}
+			continue
+		}
+		bookmark := h.pending[0]
+		h.pending = h.pending[1:]
+		h.active++
+		h.readyAt = now.Add(q.delay)
+		q.order = slices.Delete(q.order, i, i+1)
+		if len(h.pending) > 0 {
+			q.order = append(q.order, host)
+		}
+		return bookmark, 0, nil
+	}
+	if len(q.order) == 0 {
+		return nil, 0, nil
+	}
+	return nil, wait, q.wake
+}
+
+// done tells that the check of the link is over. The host is not sent
+// another request before the delay, or before the pause it asked for. If
+// the pause is too long, the links left on the host are skipped.
+func (q *hostQueue) done(bookmark *Bookmark, pause time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	host := linkHost(bookmark.URL)
+	h := q.hosts[host]
+	h.active--
+	if pause > maxHostPause && len(h.pending) > 0 {
+		log.Println("linkHealth:", host, "asked to wait", pause, "skipping", len(h.pending), "links until the next run")
+		h.pending = nil
+		q.order = slices.DeleteFunc(q.order, func(v string) bool { return v == host })
+	}
+	h.readyAt = later(h.readyAt, q.now().Add(max(q.delay, pause)))
+	close(q.wake)
+	q.wake = make(chan struct{})
+}
+
+// pace returns the pacer of the check of the link, with which the requests
+// of the check wait their turn on the host like checks do. The first request
+// goes at once, as the link was just handed out.
+func (q *hostQueue) pace(bookmark *Bookmark) HostPacer {
+	return &hostTurn{queue: q, host: linkHost(bookmark.URL)}
+}
+
+type hostTurn struct {
+	queue   *hostQueue
+	host    string
+	started atomic.Bool
+}
+
+func (t *hostTurn) Wait(ctx context.Context) error {
+	if t.started.CompareAndSwap(false, true) {
+		return nil
+	}
+	return t.queue.wait(ctx, t.host)
+}
+
+// wait waits until the host may be sent another request.
+func (q *hostQueue) wait(ctx context.Context, host string) error {
+	for {
+		q.mu.Lock()
+		h := q.hosts[host]
+		now := q.now()
+		d := h.readyAt.Sub(now)
+		if d <= 0 {
+			h.readyAt = now.Add(q.delay)
+			q.mu.Unlock()
+			return nil
+		}
+		q.mu.Unlock()
+		timer := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
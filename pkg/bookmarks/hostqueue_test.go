@@ -0,0 +1,254 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmarks
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_linkHost(t *testing.T) {
+	tests := []struct {
+		link string
+		want string
+	}{
+		{"https://Example.com/a", "example.com"},
+		{"https://example.com:8080/b", "example.com"},
+		{"http://[::1]/c", "::1"},
+		{"://", ""},
+	}
+	for _, tt := range tests {
+		if got := linkHost(tt.link); got != tt.want {
+			t.Errorf("linkHost(%q) = %q, want %q", tt.link, got, tt.want)
+		}
+	}
+}
+
+func TestBookmarks_linkCheckPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        []Option
+		wantWorkers int
+		wantPerHost int
+		wantDelay   time.Duration
+	}{
+		{"default", nil, DefaultLinkCheckWorkers, DefaultHostWorkers, DefaultHostDelay},
+		{"custom", []Option{WithLinkCheckWorkers(8, 2), WithHostDelay(0)}, 8, 2, 0},
+		{"invalid", []Option{WithLinkCheckWorkers(0, -1), WithHostDelay(-1)}, DefaultLinkCheckWorkers, DefaultHostWorkers, DefaultHostDelay},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workers, perHost, delay := New(nil, nil, tt.opts...).linkCheckPolicy()
+			if workers != tt.wantWorkers || perHost != tt.wantPerHost || delay != tt.wantDelay {
+				t.Errorf("linkCheckPolicy() = %v, %v, %v", workers, perHost, delay)
+			}
+		})
+	}
+}
+
+// hostLog records when each host was sent requests, and how many it had to
+// serve at once.
+type hostLog struct {
+	mu             sync.Mutex
+	active         map[string]int
+	maxActive      map[string]int
+	starts, ends   map[string][]time.Time
+	checked        []string
+	retryAfter     map[string]time.Duration
+	retryAfterOnce map[string]bool
+}
+
+func newHostLog() *hostLog {
+	return &hostLog{
+		active:         make(map[string]int),
+		maxActive:      make(map[string]int),
+		starts:         make(map[string][]time.Time),
+		ends:           make(map[string][]time.Time),
+		retryAfter:     make(map[string]time.Duration),
+		retryAfterOnce: make(map[string]bool),
+	}
+}
+
+func (l *hostLog) check(_ context.Context, req CheckRequest) CheckResult {
+	host := linkHost(req.URL)
+	l.mu.Lock()
+	l.active[host]++
+	l.maxActive[host] = max(l.maxActive[host], l.active[host])
+	l.starts[host] = append(l.starts[host], time.Now())
+	l.checked = append(l.checked, req.URL)
+	l.mu.Unlock()
+	time.Sleep(5 * time.Millisecond)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.active[host]--
+	l.ends[host] = append(l.ends[host], time.Now())
+	if pause, ok := l.retryAfter[host]; ok && !l.retryAfterOnce[host] {
+		l.retryAfterOnce[host] = true
+		return CheckResult{Title: req.Title, Code: http.StatusTooManyRequests, RetryAfter: pause}
+	}
+	return CheckResult{Title: req.Title, Code: http.StatusOK}
+}
+
+func (l *hostLog) minGap(host string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	gap := time.Duration(-1)
+	for i := 1; i < len(l.starts[host]); i++ {
+		if d := l.starts[host][i].Sub(l.ends[host][i-1]); gap < 0 || d < gap {
+			gap = d
+		}
+	}
+	return gap
+}
+
+func refreshRepository(list []*Bookmark) *RepositoryMock {
+	return &RepositoryMock{
+		ExpiredFunc:      func() ([]*Bookmark, error) { return list, nil },
+		UpdateFunc:       func(*Bookmark) error { return nil },
+		AddLinkCheckFunc: func(*LinkCheck) error { return nil },
+	}
+}
+
+func TestBookmarks_RefreshExpiredLinks_politeness(t *testing.T) {
+	const delay = 20 * time.Millisecond
+	t.Run("perHost", func(t *testing.T) {
+		list := []*Bookmark{
+			{ID: 1, URL: "https://a.example.com/1"},
+			{ID: 2, URL: "https://a.example.com/2"},
+			{ID: 3, URL: "https://a.example.com/3"},
+			{ID: 4, URL: "https://a.example.com/4"},
+			{ID: 5, URL: "https://b.example.com/1"},
+			{ID: 6, URL: "https://b.example.com/2"},
+		}
+		l := newHostLog()
+		b := New(refreshRepository(list), &URLCheckerMock{CheckFunc: l.check}, WithLinkCheckWorkers(4, 1), WithHostDelay(delay))
+		if err := b.RefreshExpiredLinks(context.Background()); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		if len(l.checked) != len(list) {
+			t.Fatal("unexpected checks:", l.checked)
+		}
+		for _, host := range []string{"a.example.com", "b.example.com"} {
+			if l.maxActive[host] != 1 {
+				t.Error(host, "served concurrent requests:", l.maxActive[host])
+			}
+			if gap := l.minGap(host); gap < delay {
+				t.Error(host, "requests too close:", gap)
+			}
+		}
+		if l.checked[1] != "https://b.example.com/1" {
+			t.Error("hosts did not take turns:", l.checked)
+		}
+	})
+	t.Run("requestsOfACheck", func(t *testing.T) {
+		list := []*Bookmark{
+			{ID: 1, URL: "https://a.example.com/1"},
+			{ID: 2, URL: "https://a.example.com/2"},
+		}
+		var (
+			mu       sync.Mutex
+			requests []time.Time
+		)
+		// Each check sends a request for robots.txt, the page and a probe.
+		urlChecker := &URLCheckerMock{CheckFunc: func(ctx context.Context, req CheckRequest) CheckResult {
+			for range 3 {
+				if err := req.Pacer.Wait(ctx); err != nil {
+					t.Error("unexpected error:", err)
+				}
+				mu.Lock()
+				requests = append(requests, time.Now())
+				mu.Unlock()
+			}
+			return CheckResult{Title: req.Title, Code: http.StatusOK}
+		}}
+		b := New(refreshRepository(list), urlChecker, WithLinkCheckWorkers(2, 2), WithHostDelay(delay))
+		if err := b.RefreshExpiredLinks(context.Background()); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		if len(requests) != 6 {
+			t.Fatal("unexpected requests:", len(requests))
+		}
+		slices.SortFunc(requests, func(a, b time.Time) int { return a.Compare(b) })
+		// Requests are timed once they got their turn, a little later.
+		for i := 1; i < len(requests); i++ {
+			if gap := requests[i].Sub(requests[i-1]); gap < delay*3/4 {
+				t.Error("requests too close:", gap)
+			}
+		}
+	})
+	t.Run("retryAfter", func(t *testing.T) {
+		const pause = 100 * time.Millisecond
+		list := []*Bookmark{
+			{ID: 1, URL: "https://a.example.com/1"},
+			{ID: 2, URL: "https://a.example.com/2"},
+		}
+		l := newHostLog()
+		l.retryAfter["a.example.com"] = pause
+		b := New(refreshRepository(list), &URLCheckerMock{CheckFunc: l.check}, WithHostDelay(0))
+		if err := b.RefreshExpiredLinks(context.Background()); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		if gap := l.minGap("a.example.com"); gap < pause {
+			t.Error("Retry-After not honored:", gap)
+		}
+	})
+	t.Run("longRetryAfter", func(t *testing.T) {
+		list := []*Bookmark{
+			{ID: 1, URL: "https://a.example.com/1"},
+			{ID: 2, URL: "https://a.example.com/2"},
+			{ID: 3, URL: "https://b.example.com/1"},
+		}
+		l := newHostLog()
+		l.retryAfter["a.example.com"] = 2 * maxHostPause
+		b := New(refreshRepository(list), &URLCheckerMock{CheckFunc: l.check}, WithLinkCheckWorkers(1, 1), WithHostDelay(0))
+		if err := b.RefreshExpiredLinks(context.Background()); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		if len(l.checked) != 2 || l.checked[0] != "https://a.example.com/1" || l.checked[1] != "https://b.example.com/1" {
+			t.Error("links on a host asking for a long pause must be skipped:", l.checked)
+		}
+	})
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		list := []*Bookmark{
+			{ID: 1, URL: "https://a.example.com/1"},
+			{ID: 2, URL: "https://a.example.com/2"},
+		}
+		l := newHostLog()
+		urlChecker := &URLCheckerMock{CheckFunc: func(ctx context.Context, req CheckRequest) CheckResult {
+			defer cancel()
+			return l.check(ctx, req)
+		}}
+		b := New(refreshRepository(list), urlChecker, WithHostDelay(time.Hour))
+		done := make(chan error, 1)
+		go func() { done <- b.RefreshExpiredLinks(ctx) }()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("link checker did not stop when canceled")
+		}
+		if len(l.checked) != 1 {
+			t.Error("unexpected checks:", l.checked)
+		}
+	})
+}
@@ -0,0 +1,110 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmarks
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// ImportReport tells what happened to each link of an import. In dry runs,
+// it tells what would have happened.
+type ImportReport struct {
+	DryRun     bool
+	Created    int
+	Duplicates int
+	Failures   []ImportFailure
+}
+
+// ImportFailure explains why a link could not be imported.
+type ImportFailure struct {
+	URL    string
+	Reason string
+}
+
+// Import stores bookmarks read from another service. Unlike Insert, it keeps
+// their creation date and inbox state, and it does not check the links right
+// away: they are checked later along with the expired ones. Links the owner
+// already bookmarked are skipped. Problems with individual links are
+// reported, and only storage errors stop the import. A dry run stores
+// nothing, but reports what would be stored.
+func (b *Bookmarks) Import(ownerID int64, list []*Bookmark, dryRun bool) (*ImportReport, error) {
+	if b.repository == nil {
+		return nil, fmt.Errorf("cannot begin importing bookmarks: %w", errBookmarksRepositoryNotSet)
+	}
+	report := &ImportReport{DryRun: dryRun}
+	seen := make(map[string]bool)
+	now := time.Now()
+	for _, bookmark := range list {
+		if err := validImportURL(bookmark.URL); err != nil {
+			report.Failures = append(report.Failures, ImportFailure{URL: bookmark.URL, Reason: err.Error()})
+			continue
+		}
+		if seen[bookmark.URL] {
+			report.Duplicates++
+			continue
+		}
+		seen[bookmark.URL] = true
+		exists, err := b.repository.HasURL(ownerID, bookmark.URL)
+		if err != nil {
+			return report, fmt.Errorf("cannot check for duplicated bookmark: %w", err)
+		} else if exists {
+			report.Duplicates++
+			continue
+		}
+		if dryRun {
+			report.Created++
+			continue
+		}
+		bookmark.ID = 0
+		bookmark.OwnerID = ownerID
+		bookmark.LastStatusCode, bookmark.LastStatusCheck, bookmark.LastStatusReason = 0, 0, ""
+		bookmark.FinalURL, bookmark.RedirectCount = "", 0
+		bookmark.resetHealth()
+		bookmark.NextCheck = 0
+		if bookmark.CreatedAt.IsZero() || bookmark.CreatedAt.After(now) {
+			bookmark.CreatedAt = now
+		}
+		if err := b.repository.Insert(bookmark); err != nil {
+			return report, fmt.Errorf("cannot insert bookmark: %w", err)
+		}
+		for _, tag := range bookmark.Tags {
+			if tag = NormalizeTag(tag); tag == "" {
+				continue
+			}
+			if err := b.repository.AddTag(ownerID, bookmark.ID, tag); err != nil {
+				return report, fmt.Errorf("cannot tag bookmark: %w", err)
+			}
+		}
+		report.Created++
+	}
+	return report, nil
+}
+
+func validImportURL(v string) error {
+	u, err := url.Parse(v)
+	if err != nil {
+		return &BadURLError{cause: err}
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return &BadURLError{cause: fmt.Errorf("unsupported scheme %q", u.Scheme)}
+	}
+	if u.Host == "" {
+		return &BadURLError{cause: errors.New("missing host")}
+	}
+	return nil
+}
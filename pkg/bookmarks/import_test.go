@@ -0,0 +1,111 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmarks
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBookmarks_Import(t *testing.T) {
+	const ownerID = 42
+	created := time.Date(2015, 1, 2, 3, 4, 5, 0, time.UTC)
+	var inserted []*Bookmark
+	repository := &RepositoryMock{
+		HasURLFunc: func(_ int64, url string) (bool, error) {
+			return url == "https://example.com/existing", nil
+		},
+		InsertFunc: func(bookmark *Bookmark) error {
+			bookmark.ID = int64(len(inserted) + 1)
+			inserted = append(inserted, bookmark)
+			return nil
+		},
+		AddTagFunc: func(int64, int64, string) error { return nil },
+	}
+	report, err := New(repository, nil).Import(ownerID, []*Bookmark{
+		{URL: "https://example.com/old", CreatedAt: created, Tags: []string{"Go Lang", " "}},
+		{URL: "https://example.com/new", Inbox: NewLink, LastStatusCode: 500},
+		{URL: "https://example.com/old"},
+		{URL: "https://example.com/existing"},
+		{URL: "javascript:alert(1)"},
+		{URL: "/relative"},
+		{URL: "://"},
+	}, false)
+	if err != nil {
+		t.Fatal("cannot import:", err)
+	}
+	if report.Created != 2 || report.Duplicates != 2 || len(report.Failures) != 3 {
+		t.Fatalf("unexpected report: %#v", report)
+	}
+	if report.Failures[0].URL != "javascript:alert(1)" || report.Failures[0].Reason == "" {
+		t.Errorf("unexpected failure: %#v", report.Failures[0])
+	}
+	if calls := repository.HasURLCalls(); len(calls) != 3 || calls[0].OwnerID != ownerID {
+		t.Error("unexpected duplicate checks:", calls)
+	}
+	old, recent := inserted[0], inserted[1]
+	if old.OwnerID != ownerID || !old.CreatedAt.Equal(created) || old.Inbox != Read {
+		t.Errorf("unexpected bookmark: %#v", old)
+	}
+	if recent.CreatedAt.IsZero() || recent.Inbox != NewLink || recent.LastStatusCode != 0 {
+		t.Errorf("unexpected bookmark: %#v", recent)
+	}
+	if calls := repository.AddTagCalls(); len(calls) != 1 || calls[0].OwnerID != ownerID || calls[0].ID != old.ID || calls[0].Tag != "go-lang" {
+		t.Error("unexpected tags:", calls)
+	}
+
+	t.Run("dryRun", func(t *testing.T) {
+		repository := &RepositoryMock{
+			HasURLFunc: func(_ int64, url string) (bool, error) {
+				return url == "https://example.com/existing", nil
+			},
+		}
+		report, err := New(repository, nil).Import(ownerID, []*Bookmark{
+			{URL: "https://example.com/new"},
+			{URL: "https://example.com/existing"},
+			{URL: "javascript:alert(1)"},
+		}, true)
+		if err != nil {
+			t.Fatal("cannot import:", err)
+		}
+		if !report.DryRun || report.Created != 1 || report.Duplicates != 1 || len(report.Failures) != 1 {
+			t.Fatalf("unexpected report: %#v", report)
+		}
+		if len(repository.InsertCalls()) != 0 {
+			t.Error("dry run stored bookmarks")
+		}
+	})
+	t.Run("badSetup", func(t *testing.T) {
+		if _, err := New(nil, nil).Import(ownerID, nil, false); !errors.Is(err, errBookmarksRepositoryNotSet) {
+			t.Error("unexpected error:", err)
+		}
+	})
+	t.Run("badDB", func(t *testing.T) {
+		errDB := errors.New("bad DB")
+		list := []*Bookmark{{URL: "https://example.com/"}}
+		for name, repository := range map[string]*RepositoryMock{
+			"HasURL": {HasURLFunc: func(int64, string) (bool, error) { return false, errDB }},
+			"Insert": {
+				HasURLFunc: func(int64, string) (bool, error) { return false, nil },
+				InsertFunc: func(*Bookmark) error { return errDB },
+			},
+		} {
+			if _, err := New(repository, nil).Import(ownerID, list, false); !errors.Is(err, errDB) {
+				t.Error(name, "unexpected error:", err)
+			}
+		}
+	})
+}
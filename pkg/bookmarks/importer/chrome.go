@@ -0,0 +1,95 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"cirello.io/alreadyread/pkg/bookmarks"
+)
+
+// chromeNode is a bookmark or a folder of Chrome's Bookmarks file.
+type chromeNode struct {
+	Type      string        `json:"type"`
+	Name      string        `json:"name"`
+	URL       string        `json:"url"`
+	DateAdded string        `json:"date_added"`
+	Children  []*chromeNode `json:"children"`
+}
+
+// parseChrome reads the Bookmarks file of a Chrome profile. The names of the
+// folders that contain a bookmark become its tags, except for the root
+// folders, such as the bookmarks bar.
+func parseChrome(r io.Reader) ([]*bookmarks.Bookmark, error) {
+	var file struct {
+		Roots map[string]json.RawMessage `json:"roots"`
+	}
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, fmt.Errorf("cannot parse Chrome bookmarks: %w", err)
+	}
+	// Roots are walked in a stable order, as the file has no particular
+	// one. Some entries under roots are not folders, such as sync metadata.
+	var list []*bookmarks.Bookmark
+	for _, name := range slices.Sorted(maps.Keys(file.Roots)) {
+		root := &chromeNode{}
+		if err := json.Unmarshal(file.Roots[name], root); err != nil || root.Type != "folder" {
+			continue
+		}
+		for _, child := range root.Children {
+			list = walkChrome(list, child, nil)
+		}
+	}
+	return list, nil
+}
+
+func walkChrome(list []*bookmarks.Bookmark, node *chromeNode, folders []string) []*bookmarks.Bookmark {
+	switch node.Type {
+	case "folder":
+		folders = append(folders, node.Name)
+		for _, child := range node.Children {
+			list = walkChrome(list, child, folders)
+		}
+	case "url":
+		list = append(list, &bookmarks.Bookmark{
+			URL:       strings.TrimSpace(node.URL),
+			Title:     strings.TrimSpace(node.Name),
+			Tags:      tagsOf(folders),
+			Inbox:     bookmarks.Read,
+			CreatedAt: parseChromeTime(node.DateAdded),
+		})
+	}
+	return list
+}
+
+// chromeEpochOffset is the number of microseconds between 1601-01-01, the
+// epoch of Chrome timestamps, and the Unix epoch.
+const chromeEpochOffset = 11644473600 * 1_000_000
+
+// parseChromeTime reads a Chrome timestamp, which counts microseconds since
+// 1601-01-01 UTC.
+func parseChromeTime(v string) time.Time {
+	n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	if err != nil || n <= chromeEpochOffset {
+		return time.Time{}
+	}
+	return time.UnixMicro(n - chromeEpochOffset)
+}
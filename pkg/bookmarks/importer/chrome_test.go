@@ -0,0 +1,63 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"cirello.io/alreadyread/pkg/bookmarks"
+)
+
+func TestParse_chrome(t *testing.T) {
+	const export = `{
+	"checksum": "0123",
+	"roots": {
+		"bookmark_bar": {
+			"children": [
+				{"date_added": "13344473600000000", "name": "The Go Programming Language", "type": "url", "url": "https://go.dev/"},
+				{"children": [
+					{"children": [
+						{"date_added": "", "name": "pkg.go.dev", "type": "url", "url": "https://pkg.go.dev/"}
+					], "name": "Go", "type": "folder"},
+					{"children": [
+						{"date_added": "", "name": "Go blog", "type": "url", "url": "https://go.dev/blog/"}
+					], "name": "Reading, later", "type": "folder"}
+				], "name": "Dev Tools", "type": "folder"}
+			],
+			"name": "Bookmarks bar",
+			"type": "folder"
+		},
+		"other": {"children": [{"name": "Other", "type": "url", "url": "https://example.com/"}], "name": "Other bookmarks", "type": "folder"},
+		"synced": {"children": [], "name": "Mobile bookmarks", "type": "folder"},
+		"sync_transaction_version": "1"
+	},
+	"version": 1
+}`
+	got, err := Parse(Chrome, strings.NewReader(export))
+	if err != nil {
+		t.Fatal("cannot parse Chrome bookmarks:", err)
+	}
+	checkBookmarks(t, got, []*bookmarks.Bookmark{
+		{URL: "https://go.dev/", Title: "The Go Programming Language", Inbox: bookmarks.Read, CreatedAt: time.Unix(1700000000, 0)},
+		{URL: "https://pkg.go.dev/", Title: "pkg.go.dev", Tags: []string{"dev-tools", "go"}, Inbox: bookmarks.Read},
+		{URL: "https://go.dev/blog/", Title: "Go blog", Tags: []string{"dev-tools", "reading-later"}, Inbox: bookmarks.Read},
+		{URL: "https://example.com/", Title: "Other", Inbox: bookmarks.Read},
+	})
+	if _, err := Parse(Chrome, strings.NewReader("not JSON")); err == nil {
+		t.Error("expected error missing")
+	}
+}
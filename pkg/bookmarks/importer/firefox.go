@@ -0,0 +1,178 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"cirello.io/alreadyread/pkg/bookmarks"
+	_ "modernc.org/sqlite" // SQLite3 driver
+)
+
+// Firefox marks its root folders with fixed GUIDs.
+const firefoxTagsRoot = "tags________"
+
+var firefoxRoots = map[string]bool{
+	"root________":  true,
+	"menu________":  true,
+	"toolbar_____":  true,
+	"unfiled_____":  true,
+	"mobile______":  true,
+	firefoxTagsRoot: true,
+}
+
+// Types of moz_bookmarks entries.
+const (
+	firefoxBookmark = 1
+	firefoxFolder   = 2
+)
+
+type firefoxItem struct {
+	id, kind, parent, placeID int64
+	title, guid               string
+	url, placeTitle           string
+	dateAdded                 int64
+}
+
+// parseFirefox reads the bookmarks of a Firefox profile from its
+// places.sqlite database, which is opened read-only. The folders that contain
+// a bookmark, other than the root folders, become its tags along with the
+// tags set in Firefox. Firefox must be closed, as it locks the database.
+func parseFirefox(fn string) ([]*bookmarks.Bookmark, error) {
+	if strings.ContainsAny(fn, "?#") {
+		return nil, fmt.Errorf("cannot open Firefox bookmarks: unsupported character in file name %q", fn)
+	}
+	if _, err := os.Stat(fn); err != nil {
+		return nil, fmt.Errorf("cannot open Firefox bookmarks: %w", err)
+	}
+	db, err := sql.Open("sqlite", "file:"+fn+"?mode=ro")
+	if err != nil {
+		return nil, fmt.Errorf("cannot open Firefox bookmarks: %w", err)
+	}
+	defer db.Close()
+	rows, err := db.Query(`
+		SELECT
+			b.id, b.type, b.parent, COALESCE(b.fk, 0), COALESCE(b.title, ''), COALESCE(b.guid, ''),
+			COALESCE(p.url, ''), COALESCE(p.title, ''), COALESCE(b.dateAdded, 0)
+		FROM
+			moz_bookmarks b
+			LEFT JOIN moz_places p ON p.id = b.fk
+		ORDER BY
+			b.id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read Firefox bookmarks: %w", err)
+	}
+	defer rows.Close()
+	var (
+		items = make(map[int64]*firefoxItem)
+		order []*firefoxItem
+	)
+	for rows.Next() {
+		item := &firefoxItem{}
+		if err := rows.Scan(&item.id, &item.kind, &item.parent, &item.placeID, &item.title, &item.guid, &item.url, &item.placeTitle, &item.dateAdded); err != nil {
+			return nil, fmt.Errorf("cannot read Firefox bookmarks: %w", err)
+		}
+		items[item.id] = item
+		order = append(order, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read Firefox bookmarks: %w", err)
+	}
+
+	// Firefox tags are folders under the tags root, holding one entry for
+	// each tagged place.
+	tags := make(map[int64][]string)
+	for _, item := range order {
+		if isFirefoxTag(items, item) {
+			tags[item.placeID] = append(tags[item.placeID], items[item.parent].title)
+		}
+	}
+	var list []*bookmarks.Bookmark
+	for _, item := range order {
+		// Smart bookmarks, such as "Most Visited", are queries rather
+		// than links.
+		if item.kind != firefoxBookmark || isFirefoxTag(items, item) || item.url == "" || strings.HasPrefix(item.url, "place:") {
+			continue
+		}
+		title := item.title
+		if title == "" {
+			title = item.placeTitle
+		}
+		list = append(list, &bookmarks.Bookmark{
+			URL:       strings.TrimSpace(item.url),
+			Title:     strings.TrimSpace(title),
+			Tags:      tagsOf(append(firefoxFolders(items, item), tags[item.placeID]...)),
+			Inbox:     bookmarks.Read,
+			CreatedAt: parseFirefoxTime(item.dateAdded),
+		})
+	}
+	return list, nil
+}
+
+// isFirefoxTag reports whether the item is the entry that tags a place,
+// rather than a bookmark.
+func isFirefoxTag(items map[int64]*firefoxItem, item *firefoxItem) bool {
+	parent := items[item.parent]
+	if item.kind != firefoxBookmark || parent == nil {
+		return false
+	}
+	grandparent := items[parent.parent]
+	return grandparent != nil && grandparent.guid == firefoxTagsRoot
+}
+
+// firefoxFolders lists the folders that contain the item, outermost first,
+// leaving out the root folders.
+func firefoxFolders(items map[int64]*firefoxItem, item *firefoxItem) []string {
+	var folders []string
+	seen := make(map[int64]bool)
+	for parent := items[item.parent]; parent != nil && !seen[parent.id]; parent = items[parent.parent] {
+		seen[parent.id] = true
+		if parent.kind == firefoxFolder && !firefoxRoots[parent.guid] {
+			folders = append([]string{parent.title}, folders...)
+		}
+	}
+	return folders
+}
+
+// parseFirefoxTime reads a Firefox timestamp, in microseconds since the Unix
+// epoch.
+func parseFirefoxTime(v int64) time.Time {
+	if v <= 0 {
+		return time.Time{}
+	}
+	return time.UnixMicro(v)
+}
+
+// parseFirefoxUpload reads a places.sqlite database from r, which is copied
+// to a temporary file first, as SQLite can only open files.
+func parseFirefoxUpload(r io.Reader) ([]*bookmarks.Bookmark, error) {
+	f, err := os.CreateTemp("", "alreadyread-places-*.sqlite")
+	if err != nil {
+		return nil, fmt.Errorf("cannot store Firefox bookmarks: %w", err)
+	}
+	defer os.Remove(f.Name())
+	_, err = io.Copy(f, r)
+	if err := errors.Join(err, f.Close()); err != nil {
+		return nil, fmt.Errorf("cannot store Firefox bookmarks: %w", err)
+	}
+	return parseFirefox(f.Name())
+}
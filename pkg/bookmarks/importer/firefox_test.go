@@ -0,0 +1,131 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"cirello.io/alreadyread/pkg/bookmarks"
+)
+
+// newPlaces creates a places.sqlite database with the tables and the root
+// folders that Firefox reads bookmarks from.
+func newPlaces(t *testing.T) string {
+	t.Helper()
+	fn := filepath.Join(t.TempDir(), "places.sqlite")
+	db, err := sql.Open("sqlite", fn)
+	if err != nil {
+		t.Fatal("cannot create database:", err)
+	}
+	defer db.Close()
+	_, err = db.Exec(`
+		CREATE TABLE moz_places (id INTEGER PRIMARY KEY, url LONGVARCHAR, title LONGVARCHAR);
+		CREATE TABLE moz_bookmarks (id INTEGER PRIMARY KEY, type INTEGER, fk INTEGER DEFAULT NULL, parent INTEGER, position INTEGER, title LONGVARCHAR, dateAdded INTEGER, lastModified INTEGER, guid TEXT);
+		INSERT INTO moz_bookmarks (id, type, parent, title, guid) VALUES
+			(1, 2, 0, '', 'root________'),
+			(2, 2, 1, 'menu', 'menu________'),
+			(3, 2, 1, 'toolbar', 'toolbar_____'),
+			(4, 2, 1, 'tags', 'tags________'),
+			(5, 2, 1, 'unfiled', 'unfiled_____'),
+			(6, 2, 1, 'mobile', 'mobile______');
+		INSERT INTO moz_places (id, url, title) VALUES
+			(1, 'https://go.dev/', 'Page title'),
+			(2, 'https://pkg.go.dev/', 'pkg.go.dev'),
+			(3, 'place:sort=8&maxResults=10', NULL),
+			(4, 'https://example.com/', 'Example'),
+			(5, 'https://go.dev/blog/', 'Go blog');
+		INSERT INTO moz_bookmarks (id, type, fk, parent, title, dateAdded, guid) VALUES
+			(10, 1, 1, 3, 'The Go Programming Language', 1700000001000000, 'a'),
+			(11, 2, NULL, 2, 'Dev Tools', 0, 'b'),
+			(12, 2, NULL, 11, 'Go', 0, 'c'),
+			(13, 1, 2, 12, NULL, 1700000002000000, 'd'),
+			(14, 1, 3, 3, 'Most Visited', 0, 'e'),
+			(15, 3, NULL, 2, NULL, 0, 'f'),
+			(16, 1, 4, 5, 'Example', NULL, 'g'),
+			(20, 2, NULL, 4, 'Reference', 0, 'h'),
+			(21, 1, 2, 20, NULL, 0, 'i'),
+			(22, 1, 1, 20, NULL, 0, 'j'),
+			(30, 2, NULL, 11, 'Reading, later', 0, 'k'),
+			(31, 1, 5, 30, NULL, 0, 'l');
+	`)
+	if err != nil {
+		t.Fatal("cannot create places:", err)
+	}
+	return fn
+}
+
+func TestParseFile_firefox(t *testing.T) {
+	fn := newPlaces(t)
+	want := []*bookmarks.Bookmark{
+		{URL: "https://go.dev/", Title: "The Go Programming Language", Tags: []string{"reference"}, Inbox: bookmarks.Read, CreatedAt: time.Unix(1700000001, 0)},
+		{URL: "https://pkg.go.dev/", Title: "pkg.go.dev", Tags: []string{"dev-tools", "go", "reference"}, Inbox: bookmarks.Read, CreatedAt: time.Unix(1700000002, 0)},
+		{URL: "https://example.com/", Title: "Example", Inbox: bookmarks.Read},
+		{URL: "https://go.dev/blog/", Title: "Go blog", Tags: []string{"dev-tools", "reading-later"}, Inbox: bookmarks.Read},
+	}
+	got, err := ParseFile(Firefox, fn)
+	if err != nil {
+		t.Fatal("cannot parse Firefox bookmarks:", err)
+	}
+	checkBookmarks(t, got, want)
+
+	t.Run("upload", func(t *testing.T) {
+		f, err := os.Open(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		got, err := Parse(Firefox, f)
+		if err != nil {
+			t.Fatal("cannot parse Firefox bookmarks:", err)
+		}
+		checkBookmarks(t, got, want)
+	})
+	t.Run("readOnly", func(t *testing.T) {
+		info, err := os.Stat(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ParseFile(Firefox, fn); err != nil {
+			t.Fatal("cannot parse Firefox bookmarks:", err)
+		}
+		if after, err := os.Stat(fn); err != nil || !after.ModTime().Equal(info.ModTime()) || after.Size() != info.Size() {
+			t.Error("database changed while reading bookmarks")
+		}
+	})
+}
+
+func TestParseFile_firefoxBad(t *testing.T) {
+	dir := t.TempDir()
+	notSQLite := filepath.Join(dir, "places.sqlite")
+	if err := os.WriteFile(notSQLite, []byte("not a database"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, fn := range []string{
+		filepath.Join(dir, "missing.sqlite"),
+		filepath.Join(dir, "odd?name.sqlite"),
+		notSQLite,
+	} {
+		if _, err := ParseFile(Firefox, fn); err == nil {
+			t.Error(fn, "expected error missing")
+		}
+	}
+	if _, err := ParseFile(Netscape, filepath.Join(dir, "missing.html")); err == nil {
+		t.Error("expected error missing")
+	}
+}
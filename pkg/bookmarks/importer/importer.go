@@ -0,0 +1,198 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package importer reads the files that browsers and other bookmarking
+// services export, so that their bookmarks can be imported.
+package importer // import "cirello.io/alreadyread/pkg/bookmarks/importer"
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"cirello.io/alreadyread/pkg/bookmarks"
+	"cirello.io/alreadyread/pkg/bookmarks/netscape"
+)
+
+// Format names a supported import file format.
+type Format string
+
+// Supported import formats.
+const (
+	// Netscape is the bookmarks.html format exported by browsers and most
+	// bookmarking services.
+	Netscape Format = "netscape"
+	// Pocket is Pocket's export, either the HTML file or the newer CSV.
+	Pocket Format = "pocket"
+	// Instapaper is Instapaper's CSV export.
+	Instapaper Format = "instapaper"
+	// Pinboard is Pinboard's JSON export.
+	Pinboard Format = "pinboard"
+	// Firefox is the places.sqlite database of a Firefox profile.
+	Firefox Format = "firefox"
+	// Chrome is the Bookmarks file of a Chrome profile.
+	Chrome Format = "chrome"
+)
+
+// Formats lists the supported formats, in the order they are offered to
+// users.
+var Formats = []Format{Netscape, Pocket, Instapaper, Pinboard, Firefox, Chrome}
+
+// ErrUnknownFormat is returned when the requested format is not supported.
+var ErrUnknownFormat = errors.New("unknown import format")
+
+// ParseFormat validates the name of an import format.
+func ParseFormat(v string) (Format, error) {
+	for _, f := range Formats {
+		if string(f) == strings.ToLower(v) {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownFormat, v)
+}
+
+// Description tells users which file the format refers to.
+func (f Format) Description() string {
+	switch f {
+	case Netscape:
+		return "Netscape bookmark file (bookmarks.html), as exported by browsers and most bookmarking services"
+	case Pocket:
+		return "Pocket export (ril_export.html or part_000000.csv)"
+	case Instapaper:
+		return "Instapaper CSV export"
+	case Pinboard:
+		return "Pinboard JSON export"
+	case Firefox:
+		return "Firefox places.sqlite, from the profile folder, with Firefox closed"
+	case Chrome:
+		return "Chrome Bookmarks file, from the profile folder"
+	}
+	return string(f)
+}
+
+// Parse reads the bookmarks in a file of the given format. Bookmarks keep
+// their creation date, tags and whether they were read, as far as the format
+// tells them.
+func Parse(f Format, r io.Reader) ([]*bookmarks.Bookmark, error) {
+	switch f {
+	case Netscape:
+		return netscape.Parse(r)
+	case Pocket:
+		return parsePocket(r)
+	case Instapaper:
+		return parseInstapaper(r)
+	case Pinboard:
+		return parsePinboard(r)
+	case Firefox:
+		return parseFirefoxUpload(r)
+	case Chrome:
+		return parseChrome(r)
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, f)
+}
+
+// ParseFile reads the bookmarks in the named file. Unlike Parse, it reads
+// Firefox databases in place.
+func ParseFile(f Format, fn string) ([]*bookmarks.Bookmark, error) {
+	if f == Firefox {
+		return parseFirefox(fn)
+	}
+	file, err := os.Open(fn)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open bookmark file: %w", err)
+	}
+	defer file.Close()
+	return Parse(f, file)
+}
+
+// csvTable reads a CSV file whose first row names the columns.
+type csvTable struct {
+	r       *csv.Reader
+	columns map[string]int
+	record  []string
+}
+
+func newCSVTable(r io.Reader, required ...string) (*csvTable, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("cannot parse CSV file: missing header")
+	} else if err != nil {
+		return nil, fmt.Errorf("cannot parse CSV file: %w", err)
+	}
+	t := &csvTable{r: cr, columns: make(map[string]int)}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		t.columns[name] = i
+	}
+	for _, name := range required {
+		if _, ok := t.columns[name]; !ok {
+			return nil, fmt.Errorf("cannot parse CSV file: missing %q column", name)
+		}
+	}
+	return t, nil
+}
+
+// next moves to the next row. It returns false at the end of the file.
+func (t *csvTable) next() (bool, error) {
+	record, err := t.r.Read()
+	if errors.Is(err, io.EOF) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("cannot parse CSV file: %w", err)
+	}
+	t.record = record
+	return true, nil
+}
+
+// get returns the named column of the current row, or an empty string if
+// the file or the row does not have it.
+func (t *csvTable) get(name string) string {
+	i, ok := t.columns[name]
+	if !ok || i >= len(t.record) {
+		return ""
+	}
+	return strings.TrimSpace(t.record[i])
+}
+
+// parseUnix reads a Unix timestamp in seconds.
+func parseUnix(v string) time.Time {
+	n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	if err != nil || n <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(n, 0)
+}
+
+// tagsOf makes one tag of each name, such as the names of the folders that
+// contain a bookmark, outermost first. Unlike ParseTags, it does not split
+// names on commas, so that a folder named "Reading, later" stays one tag.
+func tagsOf(names []string) []string {
+	var tags []string
+	for _, name := range names {
+		tag := bookmarks.NormalizeTag(name)
+		if tag == "" || slices.Contains(tags, tag) {
+			continue
+		}
+		tags = append(tags, tag)
+	}
+	return tags
+}
@@ -0,0 +1,85 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"cirello.io/alreadyread/pkg/bookmarks"
+)
+
+func TestParseFormat(t *testing.T) {
+	for _, f := range Formats {
+		if got, err := ParseFormat(strings.ToUpper(string(f))); err != nil || got != f {
+			t.Errorf("ParseFormat(%q) = %v, %v", f, got, err)
+		}
+		if f.Description() == "" {
+			t.Errorf("%q has no description", f)
+		}
+	}
+	for _, v := range []string{"", "xml"} {
+		if _, err := ParseFormat(v); !errors.Is(err, ErrUnknownFormat) {
+			t.Errorf("ParseFormat(%q): expected error missing: %v", v, err)
+		}
+	}
+	if _, err := Parse("xml", strings.NewReader("")); !errors.Is(err, ErrUnknownFormat) {
+		t.Error("expected error missing:", err)
+	}
+}
+
+func TestParse_netscape(t *testing.T) {
+	got, err := Parse(Netscape, strings.NewReader(`<DL><p><DT><A HREF="https://example.com/" TOREAD="1">example</A></DL><p>`))
+	if err != nil {
+		t.Fatal("cannot parse bookmark file:", err)
+	}
+	want := []*bookmarks.Bookmark{{URL: "https://example.com/", Title: "example", Inbox: bookmarks.NewLink}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected bookmarks:\ngot  %#v\nwant %#v", got, want)
+	}
+}
+
+func Test_parseUnix(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"", time.Time{}},
+		{"garbage", time.Time{}},
+		{"0", time.Time{}},
+		{" 1700000000 ", time.Unix(1700000000, 0)},
+	}
+	for _, tt := range tests {
+		if got := parseUnix(tt.in); !got.Equal(tt.want) {
+			t.Errorf("parseUnix(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+// checkBookmarks compares parsed bookmarks with the expected ones.
+func checkBookmarks(t *testing.T, got, want []*bookmarks.Bookmark) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("unexpected bookmark count: got %d, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("unexpected bookmark %d:\ngot  %#v\nwant %#v", i, got[i], want[i])
+		}
+	}
+}
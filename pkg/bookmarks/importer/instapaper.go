@@ -0,0 +1,66 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"cirello.io/alreadyread/pkg/bookmarks"
+)
+
+// parseInstapaper reads the URL,Title,Selection,Folder,Timestamp export.
+// Links in the Archive folder are read, all others are not. Folders other
+// than Unread and Archive become tags, as do the tags of newer exports,
+// which come as a JSON list.
+func parseInstapaper(r io.Reader) ([]*bookmarks.Bookmark, error) {
+	t, err := newCSVTable(r, "url")
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse Instapaper export: %w", err)
+	}
+	var list []*bookmarks.Bookmark
+	for {
+		ok, err := t.next()
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse Instapaper export: %w", err)
+		} else if !ok {
+			return list, nil
+		}
+		var tags []string
+		if v := t.get("tags"); v != "" {
+			if err := json.Unmarshal([]byte(v), &tags); err != nil {
+				tags = []string{v}
+			}
+		}
+		inbox := bookmarks.NewLink
+		switch folder := t.get("folder"); strings.ToLower(folder) {
+		case "archive":
+			inbox = bookmarks.Read
+		case "unread", "":
+		default:
+			tags = append(tags, folder)
+		}
+		list = append(list, &bookmarks.Bookmark{
+			URL:         t.get("url"),
+			Title:       t.get("title"),
+			Description: t.get("selection"),
+			Tags:        tagsOf(tags),
+			Inbox:       inbox,
+			CreatedAt:   parseUnix(t.get("timestamp")),
+		})
+	}
+}
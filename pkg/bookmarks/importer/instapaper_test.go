@@ -0,0 +1,53 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"cirello.io/alreadyread/pkg/bookmarks"
+)
+
+func TestParse_instapaper(t *testing.T) {
+	const export = "URL,Title,Selection,Folder,Timestamp,Tags\n" +
+		"https://go.dev/,The Go Programming Language,Build simple systems,Unread,1700000001,\"[\"\"go\"\",\"\"Reference\"\"]\"\n" +
+		"https://example.com/read,Read,,Archive,1700000002,\n" +
+		"https://example.com/starred,Starred,,Starred,1700000003,not json\n" +
+		"https://example.com/folder,In a folder,,\"Talks, to watch\",,\n"
+	got, err := Parse(Instapaper, strings.NewReader(export))
+	if err != nil {
+		t.Fatal("cannot parse Instapaper export:", err)
+	}
+	checkBookmarks(t, got, []*bookmarks.Bookmark{
+		{URL: "https://go.dev/", Title: "The Go Programming Language", Description: "Build simple systems", Tags: []string{"go", "reference"}, Inbox: bookmarks.NewLink, CreatedAt: time.Unix(1700000001, 0)},
+		{URL: "https://example.com/read", Title: "Read", Inbox: bookmarks.Read, CreatedAt: time.Unix(1700000002, 0)},
+		{URL: "https://example.com/starred", Title: "Starred", Tags: []string{"not-json", "starred"}, Inbox: bookmarks.NewLink, CreatedAt: time.Unix(1700000003, 0)},
+		{URL: "https://example.com/folder", Title: "In a folder", Tags: []string{"talks-to-watch"}, Inbox: bookmarks.NewLink},
+	})
+}
+
+func TestParse_instapaperBad(t *testing.T) {
+	for name, export := range map[string]string{
+		"empty":         "",
+		"missingColumn": "Title,Folder\nfoo,Unread\n",
+		"badQuotes":     "URL,Title\nhttps://example.com/,\"foo\n",
+	} {
+		if _, err := Parse(Instapaper, strings.NewReader(export)); err == nil {
+			t.Error(name, "expected error missing")
+		}
+	}
+}
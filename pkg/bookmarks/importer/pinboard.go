@@ -0,0 +1,72 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"cirello.io/alreadyread/pkg/bookmarks"
+)
+
+// pinboardPost is one entry of Pinboard's JSON export. Pinboard calls the
+// title "description" and the description "extended".
+type pinboardPost struct {
+	Href        string `json:"href"`
+	Description string `json:"description"`
+	Extended    string `json:"extended"`
+	Time        string `json:"time"`
+	ToRead      string `json:"toread"`
+	Tags        string `json:"tags"`
+}
+
+// parsePinboard reads Pinboard's JSON export, in which tags are separated
+// by spaces.
+func parsePinboard(r io.Reader) ([]*bookmarks.Bookmark, error) {
+	dec := json.NewDecoder(r)
+	if tok, err := dec.Token(); err != nil {
+		return nil, fmt.Errorf("cannot parse Pinboard export: %w", err)
+	} else if tok != json.Delim('[') {
+		return nil, errors.New("cannot parse Pinboard export: expected a list of bookmarks")
+	}
+	var list []*bookmarks.Bookmark
+	for dec.More() {
+		var post pinboardPost
+		if err := dec.Decode(&post); err != nil {
+			return nil, fmt.Errorf("cannot parse Pinboard export: %w", err)
+		}
+		inbox := bookmarks.Read
+		if post.ToRead == "yes" {
+			inbox = bookmarks.NewLink
+		}
+		createdAt, _ := time.Parse(time.RFC3339, post.Time)
+		list = append(list, &bookmarks.Bookmark{
+			URL:         strings.TrimSpace(post.Href),
+			Title:       strings.TrimSpace(post.Description),
+			Description: strings.TrimSpace(post.Extended),
+			Tags:        bookmarks.ParseTags(strings.Join(strings.Fields(post.Tags), ",")),
+			Inbox:       inbox,
+			CreatedAt:   createdAt,
+		})
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, fmt.Errorf("cannot parse Pinboard export: %w", err)
+	}
+	return list, nil
+}
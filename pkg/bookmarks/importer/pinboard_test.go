@@ -0,0 +1,51 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"cirello.io/alreadyread/pkg/bookmarks"
+)
+
+func TestParse_pinboard(t *testing.T) {
+	const export = `[
+{"href":"https:\/\/go.dev\/","description":"The Go Programming Language","extended":"Build simple systems","meta":"0123","hash":"4567","time":"2023-11-14T22:13:21Z","shared":"no","toread":"yes","tags":"go Reference"},
+{"href":"https:\/\/example.com\/read","description":"Read","extended":"","meta":"","hash":"","time":"not a date","shared":"yes","toread":"no","tags":""}
+]`
+	got, err := Parse(Pinboard, strings.NewReader(export))
+	if err != nil {
+		t.Fatal("cannot parse Pinboard export:", err)
+	}
+	checkBookmarks(t, got, []*bookmarks.Bookmark{
+		{URL: "https://go.dev/", Title: "The Go Programming Language", Description: "Build simple systems", Tags: []string{"go", "reference"}, Inbox: bookmarks.NewLink, CreatedAt: time.Date(2023, 11, 14, 22, 13, 21, 0, time.UTC)},
+		{URL: "https://example.com/read", Title: "Read", Inbox: bookmarks.Read},
+	})
+}
+
+func TestParse_pinboardBad(t *testing.T) {
+	for name, export := range map[string]string{
+		"empty":     "",
+		"notAList":  `{"href":"https://example.com/"}`,
+		"badEntry":  `[{"href":1}]`,
+		"truncated": `[{"href":"https://example.com/"}`,
+	} {
+		if _, err := Parse(Pinboard, strings.NewReader(export)); err == nil {
+			t.Error(name, "expected error missing")
+		}
+	}
+}
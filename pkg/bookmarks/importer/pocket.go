@@ -0,0 +1,140 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"cirello.io/alreadyread/pkg/bookmarks"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// parsePocket reads either of Pocket's exports: the HTML file, which lists
+// unread and archived links under separate headings, or the CSV file that
+// replaced it.
+func parsePocket(r io.Reader) ([]*bookmarks.Bookmark, error) {
+	br := bufio.NewReader(r)
+	for {
+		c, _, err := br.ReadRune()
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("cannot read Pocket export: %w", err)
+		}
+		if c == '\ufeff' || strings.ContainsRune(" \t\r\n", c) {
+			continue
+		}
+		_ = br.UnreadRune()
+		if c == '<' {
+			return parsePocketHTML(br)
+		}
+		return parsePocketCSV(br)
+	}
+}
+
+func parsePocketHTML(r io.Reader) ([]*bookmarks.Bookmark, error) {
+	var (
+		z = html.NewTokenizer(r)
+
+		list  []*bookmarks.Bookmark
+		inbox = bookmarks.NewLink
+
+		reading atom.Atom // element whose text is being read, if any
+		text    strings.Builder
+		attrs   map[string]string
+	)
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if err := z.Err(); !errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("cannot parse Pocket export: %w", err)
+			}
+			return list, nil
+		case html.TextToken:
+			if reading != 0 {
+				text.Write(z.Text())
+			}
+		case html.StartTagToken:
+			name, hasAttr := z.TagName()
+			switch tag := atom.Lookup(name); tag {
+			case atom.H1, atom.A:
+				attrs = make(map[string]string)
+				for hasAttr {
+					var key, val []byte
+					key, val, hasAttr = z.TagAttr()
+					attrs[string(key)] = string(val)
+				}
+				reading = tag
+				text.Reset()
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch tag := atom.Lookup(name); {
+			case tag == atom.H1 && reading == atom.H1:
+				// The archive section is titled "Read Archive".
+				inbox = bookmarks.NewLink
+				if strings.Contains(strings.ToLower(text.String()), "archive") {
+					inbox = bookmarks.Read
+				}
+				reading = 0
+			case tag == atom.A && reading == atom.A:
+				if url := strings.TrimSpace(attrs["href"]); url != "" {
+					list = append(list, &bookmarks.Bookmark{
+						URL:       url,
+						Title:     strings.TrimSpace(text.String()),
+						Tags:      bookmarks.ParseTags(attrs["tags"]),
+						Inbox:     inbox,
+						CreatedAt: parseUnix(attrs["time_added"]),
+					})
+				}
+				reading = 0
+			}
+		}
+	}
+}
+
+// parsePocketCSV reads the title,url,time_added,tags,status export, in which
+// tags are separated by pipes and status is either "unread" or "archive".
+func parsePocketCSV(r io.Reader) ([]*bookmarks.Bookmark, error) {
+	t, err := newCSVTable(r, "url")
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse Pocket export: %w", err)
+	}
+	var list []*bookmarks.Bookmark
+	for {
+		ok, err := t.next()
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse Pocket export: %w", err)
+		} else if !ok {
+			return list, nil
+		}
+		inbox := bookmarks.NewLink
+		if t.get("status") == "archive" {
+			inbox = bookmarks.Read
+		}
+		list = append(list, &bookmarks.Bookmark{
+			URL:       t.get("url"),
+			Title:     t.get("title"),
+			Tags:      bookmarks.ParseTags(strings.ReplaceAll(t.get("tags"), "|", ",")),
+			Inbox:     inbox,
+			CreatedAt: parseUnix(t.get("time_added")),
+		})
+	}
+}
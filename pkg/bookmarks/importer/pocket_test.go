@@ -0,0 +1,85 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"cirello.io/alreadyread/pkg/bookmarks"
+)
+
+func TestParse_pocketHTML(t *testing.T) {
+	const export = `<!DOCTYPE html>
+<html>
+	<head>
+		<meta http-equiv="Content-Type" content="text/html; charset=UTF-8" />
+		<title>Pocket Export</title>
+	</head>
+	<body>
+		<h1>Unread</h1>
+		<ul>
+			<li><a href="https://go.dev/" time_added="1700000001" tags="go,Reference">The Go &amp; Programming Language</a></li>
+			<li><a href="https://example.com/" time_added="" tags=""></a></li>
+		</ul>
+
+		<h1>Read Archive</h1>
+		<ul>
+			<li><a href="https://example.com/read" time_added="1700000002" tags="">Read</a></li>
+			<li><a>no link</a></li>
+		</ul>
+	</body>
+</html>`
+	got, err := Parse(Pocket, strings.NewReader(export))
+	if err != nil {
+		t.Fatal("cannot parse Pocket export:", err)
+	}
+	checkBookmarks(t, got, []*bookmarks.Bookmark{
+		{URL: "https://go.dev/", Title: "The Go & Programming Language", Tags: []string{"go", "reference"}, Inbox: bookmarks.NewLink, CreatedAt: time.Unix(1700000001, 0)},
+		{URL: "https://example.com/", Inbox: bookmarks.NewLink},
+		{URL: "https://example.com/read", Title: "Read", Inbox: bookmarks.Read, CreatedAt: time.Unix(1700000002, 0)},
+	})
+}
+
+func TestParse_pocketCSV(t *testing.T) {
+	const export = "\ufefftitle,url,time_added,tags,status\n" +
+		"\"Go, the language\",https://go.dev/,1700000001,go|Reference,unread\n" +
+		"Read,https://example.com/read,1700000002,,archive\n" +
+		"Short row,https://example.com/short\n"
+	got, err := Parse(Pocket, strings.NewReader(export))
+	if err != nil {
+		t.Fatal("cannot parse Pocket export:", err)
+	}
+	checkBookmarks(t, got, []*bookmarks.Bookmark{
+		{URL: "https://go.dev/", Title: "Go, the language", Tags: []string{"go", "reference"}, Inbox: bookmarks.NewLink, CreatedAt: time.Unix(1700000001, 0)},
+		{URL: "https://example.com/read", Title: "Read", Inbox: bookmarks.Read, CreatedAt: time.Unix(1700000002, 0)},
+		{URL: "https://example.com/short", Title: "Short row", Inbox: bookmarks.NewLink},
+	})
+}
+
+func TestParse_pocketBad(t *testing.T) {
+	for name, export := range map[string]string{
+		"missingColumn": "title,time_added\nfoo,1\n",
+		"badQuotes":     "title,url\n\"foo,https://example.com/\n",
+	} {
+		if _, err := Parse(Pocket, strings.NewReader(export)); err == nil {
+			t.Error(name, "expected error missing")
+		}
+	}
+	if got, err := Parse(Pocket, strings.NewReader(" \n")); err != nil || len(got) != 0 {
+		t.Error("unexpected result for empty file:", got, err)
+	}
+}
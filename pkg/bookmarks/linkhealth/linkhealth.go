@@ -0,0 +1,197 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package linkhealth checks the links of the bookmarks on a schedule.
+package linkhealth // import "cirello.io/alreadyread/pkg/bookmarks/linkhealth"
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/adhocore/gronx"
+)
+
+// DefaultSchedule checks the links that are due every hour. Each link is only
+// checked when its own next check is due, so frequent runs are cheap.
+const DefaultSchedule = "0 * * * *"
+
+// ErrInvalidSchedule is returned when the schedule is not a valid cron
+// expression.
+var ErrInvalidSchedule = errors.New("invalid link health schedule")
+
+//go:generate go tool moq -out refresher_mocks_test.go . Refresher
+type Refresher interface {
+	RefreshExpiredLinks(context.Context) error
+}
+
+//go:generate go tool moq -out store_mocks_test.go . Store
+type Store interface {
+	// LastLinkHealthRun loads the latest run, or a zero Run if there was
+	// none.
+	LastLinkHealthRun() (Run, error)
+
+	// SaveLinkHealthRun records the run, replacing the previous one.
+	SaveLinkHealthRun(Run) error
+}
+
+// Run records one pass over the links that are due. FinishedAt is zero while
+// the run goes on, and stays so if it is interrupted.
+type Run struct {
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Err        string
+}
+
+// Status tells what the scheduler is up to.
+type Status struct {
+	Schedule string
+	Running  bool
+	Pending  bool
+	Last     Run
+	Next     time.Time
+}
+
+// Scheduler runs the link checker on a cron schedule, and on demand.
+type Scheduler struct {
+	refresher Refresher
+	store     Store
+	schedule  string
+	trigger   chan struct{}
+	now       func() time.Time
+
+	mu     sync.Mutex
+	status Status
+}
+
+// New creates a scheduler that refreshes the links on the given cron
+// schedule, keeping track of its runs in the store.
+func New(refresher Refresher, store Store, schedule string) (*Scheduler, error) {
+	if !gronx.IsValid(schedule) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidSchedule, schedule)
+	}
+	return &Scheduler{
+		refresher: refresher,
+		store:     store,
+		schedule:  schedule,
+		trigger:   make(chan struct{}, 1),
+		now:       time.Now,
+		status:    Status{Schedule: schedule},
+	}, nil
+}
+
+// Run checks the links on schedule until the context is canceled. Runs missed
+// while the server was down, or interrupted by a shutdown, take place right
+// away. Failed link checks do not stop the scheduler, only failures to track
+// its runs do.
+func (s *Scheduler) Run(ctx context.Context) error {
+	last, err := s.store.LastLinkHealthRun()
+	if err != nil {
+		return fmt.Errorf("cannot load last link health run: %w", err)
+	}
+	for {
+		next, err := s.next(last)
+		if err != nil {
+			return err
+		}
+		s.update(func(st *Status) { st.Last, st.Next = last, next })
+		timer := time.NewTimer(max(next.Sub(s.now()), 0))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		case <-s.trigger:
+			timer.Stop()
+		}
+		if last, err = s.runOnce(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+// next is when the run after the given one is due. Without any run to go by,
+// the first run waits for the next tick of the schedule.
+func (s *Scheduler) next(last Run) (time.Time, error) {
+	ref := last.StartedAt
+	if ref.IsZero() {
+		ref = s.now()
+	}
+	next, err := gronx.NextTickAfter(s.schedule, ref, false)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot find next link health run: %w", err)
+	}
+	return next, nil
+}
+
+func (s *Scheduler) runOnce(ctx context.Context) (Run, error) {
+	run := Run{StartedAt: s.now()}
+	if err := s.store.SaveLinkHealthRun(run); err != nil {
+		return run, fmt.Errorf("cannot record link health run: %w", err)
+	}
+	s.update(func(st *Status) {
+		// This run also serves any request for one made until now.
+		select {
+		case <-s.trigger:
+		default:
+		}
+		st.Running, st.Pending = true, false
+	})
+	defer s.update(func(st *Status) { st.Running = false })
+	log.Println("linkHealth: starting")
+	err := s.refresher.RefreshExpiredLinks(ctx)
+	if ctx.Err() != nil {
+		return run, ctx.Err()
+	}
+	run.FinishedAt = s.now()
+	if err != nil {
+		log.Println("linkHealth: cannot refresh expired links:", err)
+		run.Err = err.Error()
+	}
+	if err := s.store.SaveLinkHealthRun(run); err != nil {
+		return run, fmt.Errorf("cannot record link health run: %w", err)
+	}
+	log.Println("linkHealth: done in", run.FinishedAt.Sub(run.StartedAt))
+	return run, nil
+}
+
+// Trigger asks for a run as soon as possible, or right after the current one.
+// It reports false if a run was already asked for.
+func (s *Scheduler) Trigger() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case s.trigger <- struct{}{}:
+		s.status.Pending = true
+		return true
+	default:
+		return false
+	}
+}
+
+// Status reports the last run, and whether one is going on or asked for.
+func (s *Scheduler) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+func (s *Scheduler) update(fn func(*Status)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(&s.status)
+}
@@ -0,0 +1,187 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linkhealth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	if _, err := New(nil, nil, "every hour"); !errors.Is(err, ErrInvalidSchedule) {
+		t.Error("unexpected error for invalid schedule:", err)
+	}
+	s, err := New(nil, nil, DefaultSchedule)
+	if err != nil {
+		t.Fatal("cannot create scheduler:", err)
+	}
+	if status := s.Status(); status.Schedule != DefaultSchedule || status.Running || status.Pending {
+		t.Errorf("unexpected initial status: %#v", status)
+	}
+}
+
+func TestScheduler_next(t *testing.T) {
+	now := time.Date(2023, 5, 6, 7, 30, 0, 0, time.UTC)
+	s, err := New(nil, nil, DefaultSchedule)
+	if err != nil {
+		t.Fatal("cannot create scheduler:", err)
+	}
+	s.now = func() time.Time { return now }
+	tests := []struct {
+		name string
+		last Run
+		want time.Time
+	}{
+		{"neverRan", Run{}, time.Date(2023, 5, 6, 8, 0, 0, 0, time.UTC)},
+		{"ranRecently", Run{StartedAt: now.Add(-10 * time.Minute), FinishedAt: now}, time.Date(2023, 5, 6, 8, 0, 0, 0, time.UTC)},
+		{"missed", Run{StartedAt: now.Add(-3 * time.Hour), FinishedAt: now.Add(-3 * time.Hour)}, time.Date(2023, 5, 6, 5, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.next(tt.last)
+			if err != nil || !got.Equal(tt.want) {
+				t.Errorf("Scheduler.next() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+// runStore keeps the last run in memory, and tells when a run finishes.
+func runStore(last Run) (*StoreMock, <-chan Run) {
+	finished := make(chan Run, 10)
+	store := &StoreMock{
+		LastLinkHealthRunFunc: func() (Run, error) { return last, nil },
+		SaveLinkHealthRunFunc: func(run Run) error {
+			if !run.FinishedAt.IsZero() {
+				finished <- run
+			}
+			return nil
+		},
+	}
+	return store, finished
+}
+
+func TestScheduler_Run(t *testing.T) {
+	errRefresh := errors.New("cannot refresh")
+	t.Run("trigger", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		refresher := &RefresherMock{RefreshExpiredLinksFunc: func(context.Context) error { return errRefresh }}
+		store, finished := runStore(Run{StartedAt: time.Now(), FinishedAt: time.Now()})
+		s, err := New(refresher, store, "0 0 1 1 *")
+		if err != nil {
+			t.Fatal("cannot create scheduler:", err)
+		}
+		if !s.Trigger() {
+			t.Fatal("cannot trigger run")
+		}
+		if s.Trigger() {
+			t.Error("run triggered twice")
+		}
+		if status := s.Status(); !status.Pending {
+			t.Errorf("triggered run is not pending: %#v", status)
+		}
+		errc := make(chan error, 1)
+		go func() { errc <- s.Run(ctx) }()
+		run := <-finished
+		if run.Err != errRefresh.Error() || run.FinishedAt.Before(run.StartedAt) {
+			t.Errorf("unexpected run: %#v", run)
+		}
+		cancel()
+		if err := <-errc; !errors.Is(err, context.Canceled) {
+			t.Error("unexpected error:", err)
+		}
+		if calls := store.SaveLinkHealthRunCalls(); len(calls) != 2 || !calls[0].Run.FinishedAt.IsZero() {
+			t.Errorf("run start was not recorded: %#v", calls)
+		}
+		status := s.Status()
+		if status.Running || status.Pending || status.Last.Err != errRefresh.Error() || !status.Next.After(status.Last.StartedAt) {
+			t.Errorf("unexpected status: %#v", status)
+		}
+	})
+	t.Run("missed", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		refresher := &RefresherMock{RefreshExpiredLinksFunc: func(context.Context) error { return nil }}
+		longAgo := time.Now().Add(-3 * time.Hour)
+		store, finished := runStore(Run{StartedAt: longAgo, FinishedAt: longAgo})
+		s, err := New(refresher, store, DefaultSchedule)
+		if err != nil {
+			t.Fatal("cannot create scheduler:", err)
+		}
+		errc := make(chan error, 1)
+		go func() { errc <- s.Run(ctx) }()
+		if run := <-finished; run.Err != "" {
+			t.Errorf("unexpected run: %#v", run)
+		}
+		cancel()
+		if err := <-errc; !errors.Is(err, context.Canceled) {
+			t.Error("unexpected error:", err)
+		}
+		if len(refresher.RefreshExpiredLinksCalls()) != 1 {
+			t.Error("missed run did not take place right away")
+		}
+	})
+	t.Run("interrupted", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		refresher := &RefresherMock{RefreshExpiredLinksFunc: func(ctx context.Context) error {
+			cancel()
+			<-ctx.Done()
+			return ctx.Err()
+		}}
+		store, _ := runStore(Run{})
+		s, err := New(refresher, store, DefaultSchedule)
+		if err != nil {
+			t.Fatal("cannot create scheduler:", err)
+		}
+		s.Trigger()
+		if err := s.Run(ctx); !errors.Is(err, context.Canceled) {
+			t.Error("unexpected error:", err)
+		}
+		if calls := store.SaveLinkHealthRunCalls(); len(calls) != 1 || !calls[0].Run.FinishedAt.IsZero() {
+			t.Errorf("interrupted run must not be recorded as finished: %#v", calls)
+		}
+		if s.Status().Running {
+			t.Error("interrupted run is still running")
+		}
+	})
+	t.Run("badStore", func(t *testing.T) {
+		errDB := errors.New("bad DB")
+		store := &StoreMock{LastLinkHealthRunFunc: func() (Run, error) { return Run{}, errDB }}
+		s, err := New(nil, store, DefaultSchedule)
+		if err != nil {
+			t.Fatal("cannot create scheduler:", err)
+		}
+		if err := s.Run(context.Background()); !errors.Is(err, errDB) {
+			t.Error("unexpected error:", err)
+		}
+
+		store = &StoreMock{
+			LastLinkHealthRunFunc: func() (Run, error) { return Run{}, nil },
+			SaveLinkHealthRunFunc: func(Run) error { return errDB },
+		}
+		s, err = New(nil, store, DefaultSchedule)
+		if err != nil {
+			t.Fatal("cannot create scheduler:", err)
+		}
+		s.Trigger()
+		if err := s.Run(context.Background()); !errors.Is(err, errDB) {
+			t.Error("unexpected error:", err)
+		}
+	})
+}
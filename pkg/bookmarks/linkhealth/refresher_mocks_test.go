@@ -0,0 +1,75 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package linkhealth
+
+import (
+	"context"
+	"sync"
+)
+
+// Ensure, that RefresherMock does implement Refresher.
+// If this is not the case, regenerate this file with moq.
+var _ Refresher = &RefresherMock{}
+
+// RefresherMock is a mock implementation of Refresher.
+//
+//	func TestSomethingThatUsesRefresher(t *testing.T) {
+//
+//		// make and configure a mocked Refresher
+//		mockedRefresher := &RefresherMock{
+//			RefreshExpiredLinksFunc: func(contextMoqParam context.Context) error {
+//				panic("mock out the RefreshExpiredLinks method")
+//			},
+//		}
+//
+//		// use mockedRefresher in code that requires Refresher
+//		// and then make assertions.
+//
+//	}
+type RefresherMock struct {
+	// RefreshExpiredLinksFunc mocks the RefreshExpiredLinks method.
+	RefreshExpiredLinksFunc func(contextMoqParam context.Context) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// RefreshExpiredLinks holds details about calls to the RefreshExpiredLinks method.
+		RefreshExpiredLinks []struct {
+			// ContextMoqParam is the contextMoqParam argument value.
+			ContextMoqParam context.Context
+		}
+	}
+	lockRefreshExpiredLinks sync.RWMutex
+}
+
+// RefreshExpiredLinks calls RefreshExpiredLinksFunc.
+func (mock *RefresherMock) RefreshExpiredLinks(contextMoqParam context.Context) error {
+	if mock.RefreshExpiredLinksFunc == nil {
+		panic("RefresherMock.RefreshExpiredLinksFunc: method is nil but Refresher.RefreshExpiredLinks was just called")
+	}
+	callInfo := struct {
+		ContextMoqParam context.Context
+	}{
+		ContextMoqParam: contextMoqParam,
+	}
+	mock.lockRefreshExpiredLinks.Lock()
+	mock.calls.RefreshExpiredLinks = append(mock.calls.RefreshExpiredLinks, callInfo)
+	mock.lockRefreshExpiredLinks.Unlock()
+	return mock.RefreshExpiredLinksFunc(contextMoqParam)
+}
+
+// RefreshExpiredLinksCalls gets all the calls that were made to RefreshExpiredLinks.
+// Check the length with:
+//
+//	len(mockedRefresher.RefreshExpiredLinksCalls())
+func (mock *RefresherMock) RefreshExpiredLinksCalls() []struct {
+	ContextMoqParam context.Context
+} {
+	var calls []struct {
+		ContextMoqParam context.Context
+	}
+	mock.lockRefreshExpiredLinks.RLock()
+	calls = mock.calls.RefreshExpiredLinks
+	mock.lockRefreshExpiredLinks.RUnlock()
+	return calls
+}
@@ -0,0 +1,111 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package linkhealth
+
+import (
+	"sync"
+)
+
+// Ensure, that StoreMock does implement Store.
+// If this is not the case, regenerate this file with moq.
+var _ Store = &StoreMock{}
+
+// StoreMock is a mock implementation of Store.
+//
+//	func TestSomethingThatUsesStore(t *testing.T) {
+//
+//		// make and configure a mocked Store
+//		mockedStore := &StoreMock{
+//			LastLinkHealthRunFunc: func() (Run, error) {
+//				panic("mock out the LastLinkHealthRun method")
+//			},
+//			SaveLinkHealthRunFunc: func(run Run) error {
+//				panic("mock out the SaveLinkHealthRun method")
+//			},
+//		}
+//
+//		// use mockedStore in code that requires Store
+//		// and then make assertions.
+//
+//	}
+type StoreMock struct {
+	// LastLinkHealthRunFunc mocks the LastLinkHealthRun method.
+	LastLinkHealthRunFunc func() (Run, error)
+
+	// SaveLinkHealthRunFunc mocks the SaveLinkHealthRun method.
+	SaveLinkHealthRunFunc func(run Run) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// LastLinkHealthRun holds details about calls to the LastLinkHealthRun method.
+		LastLinkHealthRun []struct {
+		}
+		// SaveLinkHealthRun holds details about calls to the SaveLinkHealthRun method.
+		SaveLinkHealthRun []struct {
+			// Run is the run argument value.
+			Run Run
+		}
+	}
+	lockLastLinkHealthRun sync.RWMutex
+	lockSaveLinkHealthRun sync.RWMutex
+}
+
+// LastLinkHealthRun calls LastLinkHealthRunFunc.
+func (mock *StoreMock) LastLinkHealthRun() (Run, error) {
+	if mock.LastLinkHealthRunFunc == nil {
+		panic("StoreMock.LastLinkHealthRunFunc: method is nil but Store.LastLinkHealthRun was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockLastLinkHealthRun.Lock()
+	mock.calls.LastLinkHealthRun = append(mock.calls.LastLinkHealthRun, callInfo)
+	mock.lockLastLinkHealthRun.Unlock()
+	return mock.LastLinkHealthRunFunc()
+}
+
+// LastLinkHealthRunCalls gets all the calls that were made to LastLinkHealthRun.
+// Check the length with:
+//
+//	len(mockedStore.LastLinkHealthRunCalls())
+func (mock *StoreMock) LastLinkHealthRunCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockLastLinkHealthRun.RLock()
+	calls = mock.calls.LastLinkHealthRun
+	mock.lockLastLinkHealthRun.RUnlock()
+	return calls
+}
+
+// SaveLinkHealthRun calls SaveLinkHealthRunFunc.
+func (mock *StoreMock) SaveLinkHealthRun(run Run) error {
+	if mock.SaveLinkHealthRunFunc == nil {
+		panic("StoreMock.SaveLinkHealthRunFunc: method is nil but Store.SaveLinkHealthRun was just called")
+	}
+	callInfo := struct {
+		Run Run
+	}{
+		Run: run,
+	}
+	mock.lockSaveLinkHealthRun.Lock()
+	mock.calls.SaveLinkHealthRun = append(mock.calls.SaveLinkHealthRun, callInfo)
+	mock.lockSaveLinkHealthRun.Unlock()
+	return mock.SaveLinkHealthRunFunc(run)
+}
+
+// SaveLinkHealthRunCalls gets all the calls that were made to SaveLinkHealthRun.
+// Check the length with:
+//
+//	len(mockedStore.SaveLinkHealthRunCalls())
+func (mock *StoreMock) SaveLinkHealthRunCalls() []struct {
+	Run Run
+} {
+	var calls []struct {
+		Run Run
+	}
+	mock.lockSaveLinkHealthRun.RLock()
+	calls = mock.calls.SaveLinkHealthRun
+	mock.lockSaveLinkHealthRun.RUnlock()
+	return calls
+}
@@ -0,0 +1,50 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmarks
+
+import (
+	"strings"
+	"time"
+)
+
+// Metadata describes the page behind a link, as the page itself tells with
+// its OpenGraph and Twitter card tags, its meta tags and its canonical link.
+// PublishedAt is a Unix timestamp, zero if the page does not tell.
+type Metadata struct {
+	Title        string `db:"meta_title" json:"title,omitempty"`
+	Description  string `db:"meta_description" json:"description,omitempty"`
+	ImageURL     string `db:"meta_image_url" json:"image_url,omitempty"`
+	CanonicalURL string `db:"canonical_url" json:"canonical_url,omitempty"`
+	Author       string `db:"meta_author" json:"author,omitempty"`
+	PublishedAt  int64  `db:"published_at" json:"published_at,omitempty"`
+	SiteName     string `db:"site_name" json:"site_name,omitempty"`
+	Language     string `db:"lang" json:"lang,omitempty"`
+}
+
+// Subtitle tells where the page comes from, who wrote it and when, with
+// whatever of these the page tells.
+func (m Metadata) Subtitle() string {
+	var parts []string
+	if m.SiteName != "" {
+		parts = append(parts, m.SiteName)
+	}
+	if m.Author != "" {
+		parts = append(parts, "by "+m.Author)
+	}
+	if m.PublishedAt != 0 {
+		parts = append(parts, time.Unix(m.PublishedAt, 0).UTC().Format("Jan _2 2006"))
+	}
+	return strings.Join(parts, " · ")
+}
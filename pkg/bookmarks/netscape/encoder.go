@@ -0,0 +1,103 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netscape
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"cirello.io/alreadyread/pkg/bookmarks"
+)
+
+const (
+	fileHeader = `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<!-- This is an automatically generated file.
+     It will be read and overwritten.
+     DO NOT EDIT! -->
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>Bookmarks</TITLE>
+<H1>Bookmarks</H1>
+<DL><p>
+`
+	fileFooter = "</DL><p>\n"
+)
+
+// Encoder writes bookmarks as a Netscape bookmark file, one at a time. The
+// file is a flat list: tags are written to the TAGS attribute rather than
+// as folders, and unread bookmarks are marked with TOREAD="1", so that Parse
+// reads them back as they were.
+type Encoder struct {
+	w       io.Writer
+	started bool
+}
+
+// NewEncoder returns an encoder that writes to w. Nothing is written until
+// the first bookmark is encoded or the encoder is closed.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+func (e *Encoder) start() error {
+	if e.started {
+		return nil
+	}
+	e.started = true
+	if _, err := io.WriteString(e.w, fileHeader); err != nil {
+		return fmt.Errorf("cannot write bookmark file header: %w", err)
+	}
+	return nil
+}
+
+// Encode writes one bookmark.
+func (e *Encoder) Encode(bookmark *bookmarks.Bookmark) error {
+	if err := e.start(); err != nil {
+		return err
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "    <DT><A HREF=\"%s\"", html.EscapeString(bookmark.URL))
+	if !bookmark.CreatedAt.IsZero() {
+		fmt.Fprintf(&sb, " ADD_DATE=\"%d\"", bookmark.CreatedAt.Unix())
+	}
+	if !bookmark.BumpDate.IsZero() {
+		fmt.Fprintf(&sb, " LAST_MODIFIED=\"%d\"", bookmark.BumpDate.Unix())
+	}
+	if len(bookmark.Tags) > 0 {
+		fmt.Fprintf(&sb, " TAGS=\"%s\"", html.EscapeString(strings.Join(bookmark.Tags, ",")))
+	}
+	if bookmark.Inbox == bookmarks.NewLink {
+		sb.WriteString(` TOREAD="1"`)
+	}
+	fmt.Fprintf(&sb, ">%s</A>\n", html.EscapeString(bookmark.Title))
+	if bookmark.Description != "" {
+		fmt.Fprintf(&sb, "    <DD>%s\n", html.EscapeString(bookmark.Description))
+	}
+	if _, err := io.WriteString(e.w, sb.String()); err != nil {
+		return fmt.Errorf("cannot write bookmark: %w", err)
+	}
+	return nil
+}
+
+// Close finishes the file. It does not close the underlying writer.
+func (e *Encoder) Close() error {
+	if err := e.start(); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(e.w, fileFooter); err != nil {
+		return fmt.Errorf("cannot write bookmark file footer: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netscape
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"cirello.io/alreadyread/pkg/bookmarks"
+)
+
+func TestEncoder(t *testing.T) {
+	list := []*bookmarks.Bookmark{
+		{
+			URL:         "https://go.dev/?a=1&b=\"2\"",
+			Title:       "The Go & <Programming> Language",
+			Description: "Build simple, secure, scalable systems",
+			Tags:        []string{"go", "reference"},
+			CreatedAt:   time.Unix(1700000001, 0),
+			BumpDate:    time.Unix(1700000002, 0),
+			Inbox:       bookmarks.NewLink,
+		},
+		{
+			URL:       "https://example.com/",
+			CreatedAt: time.Unix(1700000003, 0),
+			Inbox:     bookmarks.Read,
+		},
+	}
+	var sb strings.Builder
+	enc := NewEncoder(&sb)
+	for _, bookmark := range list {
+		if err := enc.Encode(bookmark); err != nil {
+			t.Fatal("cannot encode bookmark:", err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal("cannot close encoder:", err)
+	}
+	if !strings.HasPrefix(sb.String(), "<!DOCTYPE NETSCAPE-Bookmark-file-1>") || !strings.HasSuffix(sb.String(), "</DL><p>\n") {
+		t.Errorf("unexpected bookmark file:\n%s", sb.String())
+	}
+	got, err := Parse(strings.NewReader(sb.String()))
+	if err != nil {
+		t.Fatal("cannot parse encoded bookmark file:", err)
+	}
+	for _, bookmark := range list {
+		bookmark.BumpDate = time.Time{}
+	}
+	if !reflect.DeepEqual(got, list) {
+		t.Errorf("bookmarks changed in round trip:\ngot  %#v\nwant %#v", got, list)
+	}
+}
+
+func TestEncoder_empty(t *testing.T) {
+	var sb strings.Builder
+	if err := NewEncoder(&sb).Close(); err != nil {
+		t.Fatal("cannot close encoder:", err)
+	}
+	if got, err := Parse(strings.NewReader(sb.String())); err != nil || len(got) != 0 {
+		t.Errorf("unexpected result: %v %v", got, err)
+	}
+}
+
+type badWriter struct{}
+
+var errBadWriter = errors.New("bad writer")
+
+func (badWriter) Write([]byte) (int, error) { return 0, errBadWriter }
+
+func TestEncoder_badWriter(t *testing.T) {
+	if err := NewEncoder(badWriter{}).Encode(&bookmarks.Bookmark{}); !errors.Is(err, errBadWriter) {
+		t.Error("expected error missing:", err)
+	}
+	if err := NewEncoder(badWriter{}).Close(); !errors.Is(err, errBadWriter) {
+		t.Error("expected error missing:", err)
+	}
+}
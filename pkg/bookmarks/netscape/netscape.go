@@ -0,0 +1,168 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package netscape reads and writes the Netscape bookmark file format, the
+// bookmarks.html exported by browsers and most bookmarking services.
+package netscape // import "cirello.io/alreadyread/pkg/bookmarks/netscape"
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"cirello.io/alreadyread/pkg/bookmarks"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Parse reads the bookmarks in a Netscape bookmark file. Every folder that
+// contains a bookmark becomes one of its tags, along with the tags listed in
+// its TAGS attribute. Bookmarks are marked as read unless they carry
+// TOREAD="1", and keep their ADD_DATE as the creation date.
+func Parse(r io.Reader) ([]*bookmarks.Bookmark, error) {
+	var (
+		z = html.NewTokenizer(r)
+
+		list    []*bookmarks.Bookmark
+		folders []string // folder names, one entry per open <DL>
+
+		pendingFolder string              // folder named by the last <H3>, until its <DL> opens
+		last          *bookmarks.Bookmark // bookmark that a following <DD> describes
+
+		reading atom.Atom // element whose text is being read, if any
+		text    strings.Builder
+		attrs   map[string]string
+	)
+	read := func(tag atom.Atom) {
+		reading = tag
+		text.Reset()
+	}
+	// Descriptions are not closed, they end where the next tag starts.
+	endDescription := func() {
+		if reading == atom.Dd {
+			last.Description = strings.TrimSpace(text.String())
+			last, reading = nil, 0
+		}
+	}
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if err := z.Err(); !errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("cannot parse bookmark file: %w", err)
+			}
+			endDescription()
+			return list, nil
+		case html.TextToken:
+			if reading != 0 {
+				text.Write(z.Text())
+			}
+		case html.StartTagToken:
+			endDescription()
+			name, hasAttr := z.TagName()
+			switch tag := atom.Lookup(name); tag {
+			case atom.H3, atom.A:
+				attrs = readAttrs(z, hasAttr)
+				last = nil
+				read(tag)
+			case atom.Dd:
+				if last != nil {
+					read(tag)
+				}
+			case atom.Dl:
+				folders = append(folders, pendingFolder)
+				pendingFolder = ""
+			}
+		case html.EndTagToken:
+			endDescription()
+			name, _ := z.TagName()
+			switch tag := atom.Lookup(name); {
+			case tag == atom.H3 && reading == atom.H3:
+				pendingFolder = ""
+				if !isBrowserFolder(attrs) {
+					pendingFolder = bookmarks.NormalizeTag(text.String())
+				}
+				reading = 0
+			case tag == atom.A && reading == atom.A:
+				if bookmark := newBookmark(attrs, text.String(), folders); bookmark.URL != "" {
+					list = append(list, bookmark)
+					last = bookmark
+				}
+				reading = 0
+			case tag == atom.Dl && len(folders) > 0:
+				folders = folders[:len(folders)-1]
+			}
+		}
+	}
+}
+
+func readAttrs(z *html.Tokenizer, hasAttr bool) map[string]string {
+	attrs := make(map[string]string)
+	for hasAttr {
+		var key, val []byte
+		key, val, hasAttr = z.TagAttr()
+		attrs[string(key)] = string(val)
+	}
+	return attrs
+}
+
+// isBrowserFolder reports whether the folder is one the browser creates on
+// its own, such as the bookmarks toolbar, which makes for a meaningless tag.
+func isBrowserFolder(attrs map[string]string) bool {
+	return attrs["personal_toolbar_folder"] == "true" || attrs["unfiled_bookmarks_folder"] == "true"
+}
+
+func newBookmark(attrs map[string]string, title string, folders []string) *bookmarks.Bookmark {
+	var tags []string
+	for _, folder := range folders {
+		if folder != "" && !slices.Contains(tags, folder) {
+			tags = append(tags, folder)
+		}
+	}
+	for _, tag := range bookmarks.ParseTags(attrs["tags"]) {
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	inbox := bookmarks.Read
+	if attrs["toread"] == "1" {
+		inbox = bookmarks.NewLink
+	}
+	return &bookmarks.Bookmark{
+		URL:       strings.TrimSpace(attrs["href"]),
+		Title:     strings.TrimSpace(title),
+		Tags:      tags,
+		Inbox:     inbox,
+		CreatedAt: parseDate(attrs["add_date"]),
+	}
+}
+
+// parseDate reads a Unix timestamp. Some exporters write milliseconds or
+// microseconds instead of seconds.
+func parseDate(v string) time.Time {
+	n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	if err != nil || n <= 0 {
+		return time.Time{}
+	}
+	switch {
+	case n > 1e14:
+		return time.UnixMicro(n)
+	case n > 1e11:
+		return time.UnixMilli(n)
+	}
+	return time.Unix(n, 0)
+}
@@ -0,0 +1,127 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netscape
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"cirello.io/alreadyread/pkg/bookmarks"
+)
+
+const bookmarkFile = `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<!-- This is an automatically generated file.
+     It will be read and overwritten.
+     DO NOT EDIT! -->
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>Bookmarks</TITLE>
+<H1>Bookmarks</H1>
+<DL><p>
+    <DT><H3 ADD_DATE="1700000000" PERSONAL_TOOLBAR_FOLDER="true">Bookmarks bar</H3>
+    <DL><p>
+        <DT><A HREF="https://go.dev/" ADD_DATE="1700000001">The Go &amp; Programming Language</A>
+        <DD>Build simple, secure, scalable systems
+        <DT><H3 ADD_DATE="1700000002">Dev Tools</H3>
+        <DD>Folder descriptions are ignored
+        <DL><p>
+            <DT><A HREF="https://pkg.go.dev/" ADD_DATE="1700000003000" TAGS="Go,reference, dev tools">pkg.go.dev</A>
+            <DT><A HREF="javascript:alert(1)">bookmarklet</A>
+        </DL><p>
+        <DT><A HREF="https://example.com/later" TOREAD="1">Later</A>
+        <DD>Last description
+    </DL><p>
+    <DT><A HREF="https://example.com/" ADD_DATE="garbage"></A>
+    <DT><A>no link</A>
+</DL><p>
+`
+
+func TestParse(t *testing.T) {
+	got, err := Parse(strings.NewReader(bookmarkFile))
+	if err != nil {
+		t.Fatal("cannot parse bookmark file:", err)
+	}
+	want := []*bookmarks.Bookmark{
+		{
+			URL:         "https://go.dev/",
+			Title:       "The Go & Programming Language",
+			Description: "Build simple, secure, scalable systems",
+			CreatedAt:   time.Unix(1700000001, 0),
+			Inbox:       bookmarks.Read,
+		},
+		{
+			URL:       "https://pkg.go.dev/",
+			Title:     "pkg.go.dev",
+			Tags:      []string{"dev-tools", "go", "reference"},
+			CreatedAt: time.Unix(1700000003, 0),
+			Inbox:     bookmarks.Read,
+		},
+		{
+			URL:   "javascript:alert(1)",
+			Title: "bookmarklet",
+			Tags:  []string{"dev-tools"},
+			Inbox: bookmarks.Read,
+		},
+		{
+			URL:         "https://example.com/later",
+			Title:       "Later",
+			Description: "Last description",
+			Inbox:       bookmarks.NewLink,
+		},
+		{
+			URL:   "https://example.com/",
+			Inbox: bookmarks.Read,
+		},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("unexpected bookmark count: got %d, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("unexpected bookmark %d:\ngot  %#v\nwant %#v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestParse_empty(t *testing.T) {
+	for _, content := range []string{"", "not a bookmark file", "<DL><p></DL></DL>"} {
+		got, err := Parse(strings.NewReader(content))
+		if err != nil || len(got) != 0 {
+			t.Errorf("%q: unexpected result: %v %v", content, got, err)
+		}
+	}
+}
+
+func Test_parseDate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"", time.Time{}},
+		{"garbage", time.Time{}},
+		{"-1", time.Time{}},
+		{"1700000000", time.Unix(1700000000, 0)},
+		{"1700000000123", time.UnixMilli(1700000000123)},
+		{"1700000000123456", time.UnixMicro(1700000000123456)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := parseDate(tt.in); !got.Equal(tt.want) {
+				t.Errorf("parseDate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmarks
+
+import "time"
+
+// Option configures the bookmarks service.
+type Option func(*Bookmarks)
+
+// DefaultPageSize is how many bookmarks are listed per page unless
+// WithPageSize is used.
+const DefaultPageSize = 1000
+
+// WithPageSize sets how many bookmarks are listed per page.
+func WithPageSize(size int) Option {
+	return func(b *Bookmarks) {
+		b.pageSize = size
+	}
+}
+
+// Defaults of WithDeadLinkPolicy.
+const (
+	DefaultDeadAfterFailures = 3
+	DefaultDeadAfter         = 7 * 24 * time.Hour
+)
+
+// WithDeadLinkPolicy sets when failing links are declared dead: after the
+// given number of consecutive failed checks, spanning at least the given
+// period.
+func WithDeadLinkPolicy(failures int, period time.Duration) Option {
+	return func(b *Bookmarks) {
+		b.deadAfterFailures, b.deadAfter = failures, period
+	}
+}
+
+// Defaults of WithLinkCheckWorkers and WithHostDelay.
+const (
+	DefaultLinkCheckWorkers = 4
+	DefaultHostWorkers      = 1
+	DefaultHostDelay        = time.Second
+)
+
+// WithLinkCheckWorkers sets how many links are checked at a time, and how
+// many of them may be on the same host.
+func WithLinkCheckWorkers(workers, perHost int) Option {
+	return func(b *Bookmarks) {
+		b.checkWorkers, b.hostWorkers = workers, perHost
+	}
+}
+
+// WithHostDelay sets how long the link checker waits between two requests to
+// the same host.
+func WithHostDelay(delay time.Duration) Option {
+	return func(b *Bookmarks) {
+		b.hostDelay = delay
+	}
+}
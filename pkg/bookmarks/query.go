@@ -0,0 +1,261 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmarks
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Query is a parsed search expression. Its fields are combined with AND.
+type Query struct {
+	// Terms are words that must be present, either whole or as a prefix.
+	Terms []string
+	// Phrases are quoted sequences of words that must be present verbatim.
+	Phrases []string
+	// Excluded are words or phrases that must not be present.
+	Excluded []string
+
+	// Sites restrict results to these hosts or their subdomains.
+	Sites []string
+	// ExcludedSites drop results from these hosts or their subdomains.
+	ExcludedSites []string
+
+	// Tags restrict results to bookmarks with all these tags.
+	Tags []string
+	// ExcludedTags drop results with any of these tags.
+	ExcludedTags []string
+
+	// Inbox, when set, restricts results to the given inbox state.
+	Inbox *Inbox
+	// Dead restricts results to links that were declared dead.
+	Dead bool
+	// StatusCodes restricts results to links whose last check returned one
+	// of these codes.
+	StatusCodes []int64
+
+	// Before restricts results to bookmarks created before this instant.
+	Before time.Time
+	// After restricts results to bookmarks created at or after this instant.
+	After time.Time
+}
+
+// QuerySyntaxError reports a malformed search expression.
+type QuerySyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *QuerySyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at position %d: %s", e.Pos+1, e.Msg)
+}
+
+const queryDateLayout = "2006-01-02"
+
+// ParseQuery parses a search expression. Besides plain words, it understands:
+//
+//	"quoted phrase"   the words must appear in this exact sequence
+//	-word             exclude results matching word (also -"phrase")
+//	site:example.com  only links on example.com or its subdomains (-site: excludes)
+//	tag:name          only bookmarks tagged with name (-tag: excludes)
+//	is:inbox          only bookmarks in the inbox
+//	is:read           only bookmarks already marked as read
+//	is:dead           only links declared dead after failing repeatedly
+//	is:blocked        only links blocked by robots.txt (status:1000)
+//	is:timeout        only links that timed out (status:1001)
+//	is:private        only links to private addresses (status:1002)
+//	is:soft           only links that failed softly (status:1003)
+//	status:404        only links whose last check returned this code
+//	before:2024-01-01 only bookmarks created before this date
+//	after:2024-01-01  only bookmarks created on or after this date
+//
+// Dates start at midnight UTC, whatever the time zone of the server. Links
+// that match any of the is: link states and status: codes are kept.
+//
+// Words followed by colons that are not operators, like URLs, are handled as
+// plain words.
+func ParseQuery(s string) (*Query, error) {
+	q := &Query{}
+	runes := []rune(s)
+	for pos := 0; pos < len(runes); {
+		if unicode.IsSpace(runes[pos]) {
+			pos++
+			continue
+		}
+		start := pos
+		negated := runes[pos] == '-'
+		if negated {
+			pos++
+			if pos == len(runes) || unicode.IsSpace(runes[pos]) {
+				return nil, &QuerySyntaxError{start, "dangling '-'"}
+			}
+		}
+		if runes[pos] == '"' {
+			phrase, next, err := scanQuoted(runes, pos)
+			if err != nil {
+				return nil, err
+			}
+			pos = next
+			if phrase == "" {
+				continue
+			}
+			if negated {
+				q.Excluded = append(q.Excluded, phrase)
+			} else {
+				q.Phrases = append(q.Phrases, phrase)
+			}
+			continue
+		}
+		wordStart := pos
+		for pos < len(runes) && !unicode.IsSpace(runes[pos]) && runes[pos] != ':' {
+			pos++
+		}
+		word := string(runes[wordStart:pos])
+		if pos < len(runes) && runes[pos] == ':' && isQueryOperator(word) {
+			pos++
+			var value string
+			if pos < len(runes) && runes[pos] == '"' {
+				v, next, err := scanQuoted(runes, pos)
+				if err != nil {
+					return nil, err
+				}
+				value, pos = v, next
+			} else {
+				valueStart := pos
+				for pos < len(runes) && !unicode.IsSpace(runes[pos]) {
+					pos++
+				}
+				value = string(runes[valueStart:pos])
+			}
+			if err := q.applyOperator(start, strings.ToLower(word), value, negated); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		for pos < len(runes) && !unicode.IsSpace(runes[pos]) {
+			pos++
+		}
+		word = string(runes[wordStart:pos])
+		if negated {
+			q.Excluded = append(q.Excluded, word)
+		} else {
+			q.Terms = append(q.Terms, word)
+		}
+	}
+	return q, nil
+}
+
+func scanQuoted(runes []rune, pos int) (string, int, error) {
+	start := pos
+	pos++
+	for pos < len(runes) && runes[pos] != '"' {
+		pos++
+	}
+	if pos == len(runes) {
+		return "", 0, &QuerySyntaxError{start, "unterminated quote"}
+	}
+	return strings.TrimSpace(string(runes[start+1 : pos])), pos + 1, nil
+}
+
+func isQueryOperator(word string) bool {
+	switch strings.ToLower(word) {
+	case "site", "tag", "is", "status", "before", "after":
+		return true
+	default:
+		return false
+	}
+}
+
+func (q *Query) applyOperator(pos int, operator, value string, negated bool) error {
+	if value == "" {
+		return &QuerySyntaxError{pos, fmt.Sprintf("missing value for %s:", operator)}
+	}
+	switch operator {
+	case "site":
+		site := strings.ToLower(value)
+		if !isHostname(site) {
+			return &QuerySyntaxError{pos, fmt.Sprintf("invalid site %q", value)}
+		}
+		if negated {
+			q.ExcludedSites = append(q.ExcludedSites, site)
+		} else {
+			q.Sites = append(q.Sites, site)
+		}
+		return nil
+	case "tag":
+		tag := NormalizeTag(value)
+		if negated {
+			q.ExcludedTags = append(q.ExcludedTags, tag)
+		} else {
+			q.Tags = append(q.Tags, tag)
+		}
+		return nil
+	}
+	if negated {
+		return &QuerySyntaxError{pos, fmt.Sprintf("%s: cannot be negated", operator)}
+	}
+	switch operator {
+	case "is":
+		switch strings.ToLower(value) {
+		case "inbox", "new", "unread":
+			inbox := NewLink
+			q.Inbox = &inbox
+		case "read":
+			inbox := Read
+			q.Inbox = &inbox
+		case "dead":
+			q.Dead = true
+		case "blocked":
+			q.StatusCodes = append(q.StatusCodes, StatusBlockedByRobots)
+		case "timeout":
+			q.StatusCodes = append(q.StatusCodes, StatusTimeout)
+		case "private":
+			q.StatusCodes = append(q.StatusCodes, StatusPrivateAddress)
+		case "soft":
+			q.StatusCodes = append(q.StatusCodes, StatusSoftFailure)
+		default:
+			return &QuerySyntaxError{pos, fmt.Sprintf("unknown state is:%s (want inbox, read, dead, blocked, timeout, private or soft)", value)}
+		}
+	case "status":
+		code, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || code < 0 || (code > 999 && (code < StatusBlockedByRobots || code > StatusSoftFailure)) {
+			return &QuerySyntaxError{pos, fmt.Sprintf("invalid status code %q", value)}
+		}
+		q.StatusCodes = append(q.StatusCodes, code)
+	case "before", "after":
+		date, err := time.ParseInLocation(queryDateLayout, value, time.UTC)
+		if err != nil {
+			return &QuerySyntaxError{pos, fmt.Sprintf("invalid date %q (want YYYY-MM-DD)", value)}
+		}
+		if operator == "before" {
+			q.Before = date
+		} else {
+			q.After = date
+		}
+	}
+	return nil
+}
+
+func isHostname(s string) bool {
+	for _, r := range s {
+		if !(r == '.' || r == '-' || unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,102 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmarks
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseQuery(t *testing.T) {
+	inbox, read := NewLink, Read
+	date := func(s string) time.Time {
+		t, _ := time.ParseInLocation(queryDateLayout, s, time.UTC)
+		return t
+	}
+	tests := []struct {
+		name    string
+		query   string
+		want    *Query
+		wantErr bool
+	}{
+		{"empty", "", &Query{}, false},
+		{"terms", "  go  blog ", &Query{Terms: []string{"go", "blog"}}, false},
+		{"url", "https://example.com/a:b", &Query{Terms: []string{"https://example.com/a:b"}}, false},
+		{"phrase", `"go blog" rust`, &Query{Phrases: []string{"go blog"}, Terms: []string{"rust"}}, false},
+		{"excluded", `-java -"enterprise beans"`, &Query{Excluded: []string{"java", "enterprise beans"}}, false},
+		{"site", "site:GitHub.com -site:gist.github.com", &Query{Sites: []string{"github.com"}, ExcludedSites: []string{"gist.github.com"}}, false},
+		{"tag", `tag:"Read Later" -tag:go`, &Query{Tags: []string{"read-later"}, ExcludedTags: []string{"go"}}, false},
+		{"is:inbox", "is:inbox", &Query{Inbox: &inbox}, false},
+		{"is:read", "IS:read", &Query{Inbox: &read}, false},
+		{"is:dead", "is:dead", &Query{Dead: true}, false},
+		{"status", "status:404 status:410", &Query{StatusCodes: []int64{404, 410}}, false},
+		{"dates", "after:2024-01-01 before:2024-02-01", &Query{After: date("2024-01-01"), Before: date("2024-02-01")}, false},
+		{"bad/quote", `"go blog`, nil, true},
+		{"bad/dash", "go -", nil, true},
+		{"bad/emptyValue", "site:", nil, true},
+		{"bad/site", "site:exa%mple.com", nil, true},
+		{"bad/is", "is:banana", nil, true},
+		{"bad/status", "status:abc", nil, true},
+		{"statusSpecial", "status:1000 status:1003", &Query{StatusCodes: []int64{StatusBlockedByRobots, StatusSoftFailure}}, false},
+		{"is:linkStates", "is:blocked is:timeout is:private is:soft", &Query{StatusCodes: []int64{StatusBlockedByRobots, StatusTimeout, StatusPrivateAddress, StatusSoftFailure}}, false},
+		{"bad/statusRange", "status:1004", nil, true},
+		{"bad/statusGap", "status:999999", nil, true},
+		{"bad/date", "before:yesterday", nil, true},
+		{"bad/negated", "-is:dead", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseQuery(tt.query)
+			if tt.wantErr {
+				if syntaxErr := (*QuerySyntaxError)(nil); !errors.As(err, &syntaxErr) {
+					t.Fatalf("ParseQuery() error = %v, want *QuerySyntaxError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("ParseQuery() unexpected error:", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseQuery() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseQuery_timezone(t *testing.T) {
+	// time.Local is loaded from TZ only once, so it is swapped as well.
+	t.Setenv("TZ", "Pacific/Kiritimati")
+	defer func(local *time.Location) { time.Local = local }(time.Local)
+	time.Local = time.FixedZone("UTC+14", 14*60*60)
+	got, err := ParseQuery("after:2024-01-01 before:2024-02-01")
+	if err != nil {
+		t.Fatal("ParseQuery() unexpected error:", err)
+	}
+	if want := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC); !got.After.Equal(want) {
+		t.Errorf("ParseQuery() After = %v, want %v", got.After, want)
+	}
+	if want := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC); !got.Before.Equal(want) {
+		t.Errorf("ParseQuery() Before = %v, want %v", got.Before, want)
+	}
+}
+
+func TestQuerySyntaxError(t *testing.T) {
+	err := &QuerySyntaxError{Pos: 3, Msg: "message"}
+	if got := err.Error(); got != "syntax error at position 4: message" {
+		t.Errorf("QuerySyntaxError.Error() = %v", got)
+	}
+}
@@ -14,39 +14,92 @@
 
 package bookmarks
 
+import (
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned when the requested bookmark does not exist.
+var ErrNotFound = errors.New("bookmark not found")
+
 //go:generate go tool moq -out repository_mocks_test.go . Repository
 //go:generate go tool moq -pkg web -out ../web/repository_mocks_test.go . Repository
+
+// Repository stores bookmarks. Except for the operations that serve the link
+// checker, every operation is scoped to the bookmarks of one owner: other
+// owners' bookmarks are neither listed nor found.
 type Repository interface {
-	// All returns all bookmarks.
-	All(page int) ([]*Bookmark, error)
+	// AddLinkCheck appends the outcome of a link check to the history of the
+	// bookmark, regardless of owner.
+	AddLinkCheck(*LinkCheck) error
+
+	// AddTag attaches the tag to the bookmark, creating the tag if needed.
+	AddTag(ownerID, id int64, tag string) error
+
+	// All returns all bookmarks. Like the other listings, it returns at most
+	// limit bookmarks after the cursor, and the cursor of the next page.
+	All(ownerID int64, after Cursor, limit int) ([]*Bookmark, Cursor, error)
 
 	// Bootstrap creates table if missing.
 	Bootstrap() error
 
-	// Dead returns bookmarks that are not OK.
-	Dead(page int) ([]*Bookmark, error)
+	// ByTag returns all bookmarks labeled with the tag.
+	ByTag(ownerID int64, tag string, after Cursor, limit int) ([]*Bookmark, Cursor, error)
+
+	// Dead returns bookmarks whose links were declared dead, narrowed down
+	// by the filter.
+	Dead(ownerID int64, filter DeadFilter, after Cursor, limit int) ([]*Bookmark, Cursor, error)
 
-	// DeleteByID excludes the bookmark from the repository.
-	DeleteByID(id int64) error
+	// DeleteByID excludes the bookmark from the repository. It returns
+	// ErrNotFound if there is no such bookmark.
+	DeleteByID(ownerID, id int64) error
 
-	// Duplicated returns all bookmarks that have been added more than once.
-	Duplicated(page int) ([]*Bookmark, error)
+	// Duplicated returns all bookmarks that the owner added more than once.
+	Duplicated(ownerID int64, after Cursor, limit int) ([]*Bookmark, Cursor, error)
 
-	// Expired return all valid but expired bookmarks.
+	// Expired returns all bookmarks due for a link check, regardless of
+	// owner.
 	Expired() ([]*Bookmark, error)
 
 	// GetByID loads one bookmark.
-	GetByID(id int64) (*Bookmark, error)
+	GetByID(ownerID, id int64) (*Bookmark, error)
+
+	// HasURL reports whether the owner already bookmarked the URL.
+	HasURL(ownerID int64, url string) (bool, error)
 
 	// Inbox returns all new bookmarks that have not been marked as read.
-	Inbox(page int) ([]*Bookmark, error)
+	Inbox(ownerID int64, after Cursor, limit int) ([]*Bookmark, Cursor, error)
 
-	// Insert one bookmark.
+	// Insert one bookmark, owned by Bookmark.OwnerID. Unset creation dates
+	// default to the current time, and unset bump dates to the creation
+	// date.
 	Insert(*Bookmark) error
 
-	// Search returns all bookmarks that match the term.
-	Search(term string) ([]*Bookmark, error)
+	// LinkChecks returns the latest limit entries of the link check history
+	// of the bookmark, newest first.
+	LinkChecks(ownerID, id int64, limit int) ([]*LinkCheck, error)
 
-	// Update one bookmark.
+	// Moved returns bookmarks whose links redirect to another URL.
+	Moved(ownerID int64, after Cursor, limit int) ([]*Bookmark, Cursor, error)
+
+	// PruneLinkChecks deletes the link check history older than the given
+	// time, regardless of owner, and reports how many entries were deleted.
+	PruneLinkChecks(before time.Time) (int64, error)
+
+	// RemoveTag detaches the tag from the bookmark.
+	RemoveTag(ownerID, id int64, tag string) error
+
+	// Search returns one page of bookmarks that match the query, and the
+	// total number of matches.
+	Search(ownerID int64, query *Query, page, limit int) ([]*Bookmark, int, error)
+
+	// Tags returns all tags in use along with how many bookmarks have them.
+	Tags(ownerID int64) ([]*Tag, error)
+
+	// Update one bookmark, as long as it belongs to Bookmark.OwnerID.
 	Update(*Bookmark) error
+
+	// Walk calls fn for every bookmark of the owner, oldest first, without
+	// loading them all at once. It stops at the first error fn returns.
+	Walk(ownerID int64, fn func(*Bookmark) error) error
 }
@@ -5,6 +5,7 @@ package bookmarks
 
 import (
 	"sync"
+	"time"
 )
 
 // Ensure, that RepositoryMock does implement Repository.
@@ -17,39 +18,69 @@ var _ Repository = &RepositoryMock{}
 //
 //		// make and configure a mocked Repository
 //		mockedRepository := &RepositoryMock{
-//			AllFunc: func(page int) ([]*Bookmark, error) {
+//			AddLinkCheckFunc: func(linkCheck *LinkCheck) error {
+//				panic("mock out the AddLinkCheck method")
+//			},
+//			AddTagFunc: func(ownerID int64, id int64, tag string) error {
+//				panic("mock out the AddTag method")
+//			},
+//			AllFunc: func(ownerID int64, after Cursor, limit int) ([]*Bookmark, Cursor, error) {
 //				panic("mock out the All method")
 //			},
 //			BootstrapFunc: func() error {
 //				panic("mock out the Bootstrap method")
 //			},
-//			DeadFunc: func(page int) ([]*Bookmark, error) {
+//			ByTagFunc: func(ownerID int64, tag string, after Cursor, limit int) ([]*Bookmark, Cursor, error) {
+//				panic("mock out the ByTag method")
+//			},
+//			DeadFunc: func(ownerID int64, filter DeadFilter, after Cursor, limit int) ([]*Bookmark, Cursor, error) {
 //				panic("mock out the Dead method")
 //			},
-//			DeleteByIDFunc: func(id int64) error {
+//			DeleteByIDFunc: func(ownerID int64, id int64) error {
 //				panic("mock out the DeleteByID method")
 //			},
-//			DuplicatedFunc: func(page int) ([]*Bookmark, error) {
+//			DuplicatedFunc: func(ownerID int64, after Cursor, limit int) ([]*Bookmark, Cursor, error) {
 //				panic("mock out the Duplicated method")
 //			},
 //			ExpiredFunc: func() ([]*Bookmark, error) {
 //				panic("mock out the Expired method")
 //			},
-//			GetByIDFunc: func(id int64) (*Bookmark, error) {
+//			GetByIDFunc: func(ownerID int64, id int64) (*Bookmark, error) {
 //				panic("mock out the GetByID method")
 //			},
-//			InboxFunc: func(page int) ([]*Bookmark, error) {
+//			HasURLFunc: func(ownerID int64, url string) (bool, error) {
+//				panic("mock out the HasURL method")
+//			},
+//			InboxFunc: func(ownerID int64, after Cursor, limit int) ([]*Bookmark, Cursor, error) {
 //				panic("mock out the Inbox method")
 //			},
 //			InsertFunc: func(bookmark *Bookmark) error {
 //				panic("mock out the Insert method")
 //			},
-//			SearchFunc: func(term string) ([]*Bookmark, error) {
+//			LinkChecksFunc: func(ownerID int64, id int64, limit int) ([]*LinkCheck, error) {
+//				panic("mock out the LinkChecks method")
+//			},
+//			MovedFunc: func(ownerID int64, after Cursor, limit int) ([]*Bookmark, Cursor, error) {
+//				panic("mock out the Moved method")
+//			},
+//			PruneLinkChecksFunc: func(before time.Time) (int64, error) {
+//				panic("mock out the PruneLinkChecks method")
+//			},
+//			RemoveTagFunc: func(ownerID int64, id int64, tag string) error {
+//				panic("mock out the RemoveTag method")
+//			},
+//			SearchFunc: func(ownerID int64, query *Query, page int, limit int) ([]*Bookmark, int, error) {
 //				panic("mock out the Search method")
 //			},
+//			TagsFunc: func(ownerID int64) ([]*Tag, error) {
+//				panic("mock out the Tags method")
+//			},
 //			UpdateFunc: func(bookmark *Bookmark) error {
 //				panic("mock out the Update method")
 //			},
+//			WalkFunc: func(ownerID int64, fn func(*Bookmark) error) error {
+//				panic("mock out the Walk method")
+//			},
 //		}
 //
 //		// use mockedRepository in code that requires Repository
@@ -57,120 +88,340 @@ var _ Repository = &RepositoryMock{}
 //
 //	}
 type RepositoryMock struct {
+	// AddLinkCheckFunc mocks the AddLinkCheck method.
+	AddLinkCheckFunc func(linkCheck *LinkCheck) error
+
+	// AddTagFunc mocks the AddTag method.
+	AddTagFunc func(ownerID int64, id int64, tag string) error
+
 	// AllFunc mocks the All method.
-	AllFunc func(page int) ([]*Bookmark, error)
+	AllFunc func(ownerID int64, after Cursor, limit int) ([]*Bookmark, Cursor, error)
 
 	// BootstrapFunc mocks the Bootstrap method.
 	BootstrapFunc func() error
 
+	// ByTagFunc mocks the ByTag method.
+	ByTagFunc func(ownerID int64, tag string, after Cursor, limit int) ([]*Bookmark, Cursor, error)
+
 	// DeadFunc mocks the Dead method.
-	DeadFunc func(page int) ([]*Bookmark, error)
+	DeadFunc func(ownerID int64, filter DeadFilter, after Cursor, limit int) ([]*Bookmark, Cursor, error)
 
 	// DeleteByIDFunc mocks the DeleteByID method.
-	DeleteByIDFunc func(id int64) error
+	DeleteByIDFunc func(ownerID int64, id int64) error
 
 	// DuplicatedFunc mocks the Duplicated method.
-	DuplicatedFunc func(page int) ([]*Bookmark, error)
+	DuplicatedFunc func(ownerID int64, after Cursor, limit int) ([]*Bookmark, Cursor, error)
 
 	// ExpiredFunc mocks the Expired method.
 	ExpiredFunc func() ([]*Bookmark, error)
 
 	// GetByIDFunc mocks the GetByID method.
-	GetByIDFunc func(id int64) (*Bookmark, error)
+	GetByIDFunc func(ownerID int64, id int64) (*Bookmark, error)
+
+	// HasURLFunc mocks the HasURL method.
+	HasURLFunc func(ownerID int64, url string) (bool, error)
 
 	// InboxFunc mocks the Inbox method.
-	InboxFunc func(page int) ([]*Bookmark, error)
+	InboxFunc func(ownerID int64, after Cursor, limit int) ([]*Bookmark, Cursor, error)
 
 	// InsertFunc mocks the Insert method.
 	InsertFunc func(bookmark *Bookmark) error
 
+	// LinkChecksFunc mocks the LinkChecks method.
+	LinkChecksFunc func(ownerID int64, id int64, limit int) ([]*LinkCheck, error)
+
+	// MovedFunc mocks the Moved method.
+	MovedFunc func(ownerID int64, after Cursor, limit int) ([]*Bookmark, Cursor, error)
+
+	// PruneLinkChecksFunc mocks the PruneLinkChecks method.
+	PruneLinkChecksFunc func(before time.Time) (int64, error)
+
+	// RemoveTagFunc mocks the RemoveTag method.
+	RemoveTagFunc func(ownerID int64, id int64, tag string) error
+
 	// SearchFunc mocks the Search method.
-	SearchFunc func(term string) ([]*Bookmark, error)
+	SearchFunc func(ownerID int64, query *Query, page int, limit int) ([]*Bookmark, int, error)
+
+	// TagsFunc mocks the Tags method.
+	TagsFunc func(ownerID int64) ([]*Tag, error)
 
 	// UpdateFunc mocks the Update method.
 	UpdateFunc func(bookmark *Bookmark) error
 
+	// WalkFunc mocks the Walk method.
+	WalkFunc func(ownerID int64, fn func(*Bookmark) error) error
+
 	// calls tracks calls to the methods.
 	calls struct {
+		// AddLinkCheck holds details about calls to the AddLinkCheck method.
+		AddLinkCheck []struct {
+			// LinkCheck is the linkCheck argument value.
+			LinkCheck *LinkCheck
+		}
+		// AddTag holds details about calls to the AddTag method.
+		AddTag []struct {
+			// OwnerID is the ownerID argument value.
+			OwnerID int64
+			// ID is the id argument value.
+			ID int64
+			// Tag is the tag argument value.
+			Tag string
+		}
 		// All holds details about calls to the All method.
 		All []struct {
-			// Page is the page argument value.
-			Page int
+			// OwnerID is the ownerID argument value.
+			OwnerID int64
+			// After is the after argument value.
+			After Cursor
+			// Limit is the limit argument value.
+			Limit int
 		}
 		// Bootstrap holds details about calls to the Bootstrap method.
 		Bootstrap []struct {
 		}
+		// ByTag holds details about calls to the ByTag method.
+		ByTag []struct {
+			// OwnerID is the ownerID argument value.
+			OwnerID int64
+			// Tag is the tag argument value.
+			Tag string
+			// After is the after argument value.
+			After Cursor
+			// Limit is the limit argument value.
+			Limit int
+		}
 		// Dead holds details about calls to the Dead method.
 		Dead []struct {
-			// Page is the page argument value.
-			Page int
+			// OwnerID is the ownerID argument value.
+			OwnerID int64
+			// Filter is the filter argument value.
+			Filter DeadFilter
+			// After is the after argument value.
+			After Cursor
+			// Limit is the limit argument value.
+			Limit int
 		}
 		// DeleteByID holds details about calls to the DeleteByID method.
 		DeleteByID []struct {
+			// OwnerID is the ownerID argument value.
+			OwnerID int64
 			// ID is the id argument value.
 			ID int64
 		}
 		// Duplicated holds details about calls to the Duplicated method.
 		Duplicated []struct {
-			// Page is the page argument value.
-			Page int
+			// OwnerID is the ownerID argument value.
+			OwnerID int64
+			// After is the after argument value.
+			After Cursor
+			// Limit is the limit argument value.
+			Limit int
 		}
 		// Expired holds details about calls to the Expired method.
 		Expired []struct {
 		}
 		// GetByID holds details about calls to the GetByID method.
 		GetByID []struct {
+			// OwnerID is the ownerID argument value.
+			OwnerID int64
 			// ID is the id argument value.
 			ID int64
 		}
+		// HasURL holds details about calls to the HasURL method.
+		HasURL []struct {
+			// OwnerID is the ownerID argument value.
+			OwnerID int64
+			// URL is the url argument value.
+			URL string
+		}
 		// Inbox holds details about calls to the Inbox method.
 		Inbox []struct {
-			// Page is the page argument value.
-			Page int
+			// OwnerID is the ownerID argument value.
+			OwnerID int64
+			// After is the after argument value.
+			After Cursor
+			// Limit is the limit argument value.
+			Limit int
 		}
 		// Insert holds details about calls to the Insert method.
 		Insert []struct {
 			// Bookmark is the bookmark argument value.
 			Bookmark *Bookmark
 		}
+		// LinkChecks holds details about calls to the LinkChecks method.
+		LinkChecks []struct {
+			// OwnerID is the ownerID argument value.
+			OwnerID int64
+			// ID is the id argument value.
+			ID int64
+			// Limit is the limit argument value.
+			Limit int
+		}
+		// Moved holds details about calls to the Moved method.
+		Moved []struct {
+			// OwnerID is the ownerID argument value.
+			OwnerID int64
+			// After is the after argument value.
+			After Cursor
+			// Limit is the limit argument value.
+			Limit int
+		}
+		// PruneLinkChecks holds details about calls to the PruneLinkChecks method.
+		PruneLinkChecks []struct {
+			// Before is the before argument value.
+			Before time.Time
+		}
+		// RemoveTag holds details about calls to the RemoveTag method.
+		RemoveTag []struct {
+			// OwnerID is the ownerID argument value.
+			OwnerID int64
+			// ID is the id argument value.
+			ID int64
+			// Tag is the tag argument value.
+			Tag string
+		}
 		// Search holds details about calls to the Search method.
 		Search []struct {
-			// Term is the term argument value.
-			Term string
+			// OwnerID is the ownerID argument value.
+			OwnerID int64
+			// Query is the query argument value.
+			Query *Query
+			// Page is the page argument value.
+			Page int
+			// Limit is the limit argument value.
+			Limit int
+		}
+		// Tags holds details about calls to the Tags method.
+		Tags []struct {
+			// OwnerID is the ownerID argument value.
+			OwnerID int64
 		}
 		// Update holds details about calls to the Update method.
 		Update []struct {
 			// Bookmark is the bookmark argument value.
 			Bookmark *Bookmark
 		}
+		// Walk holds details about calls to the Walk method.
+		Walk []struct {
+			// OwnerID is the ownerID argument value.
+			OwnerID int64
+			// Fn is the fn argument value.
+			Fn func(*Bookmark) error
+		}
 	}
-	lockAll        sync.RWMutex
-	lockBootstrap  sync.RWMutex
-	lockDead       sync.RWMutex
-	lockDeleteByID sync.RWMutex
-	lockDuplicated sync.RWMutex
-	lockExpired    sync.RWMutex
-	lockGetByID    sync.RWMutex
-	lockInbox      sync.RWMutex
-	lockInsert     sync.RWMutex
-	lockSearch     sync.RWMutex
-	lockUpdate     sync.RWMutex
+	lockAddLinkCheck    sync.RWMutex
+	lockAddTag          sync.RWMutex
+	lockAll             sync.RWMutex
+	lockBootstrap       sync.RWMutex
+	lockByTag           sync.RWMutex
+	lockDead            sync.RWMutex
+	lockDeleteByID      sync.RWMutex
+	lockDuplicated      sync.RWMutex
+	lockExpired         sync.RWMutex
+	lockGetByID         sync.RWMutex
+	lockHasURL          sync.RWMutex
+	lockInbox           sync.RWMutex
+	lockInsert          sync.RWMutex
+	lockLinkChecks      sync.RWMutex
+	lockMoved           sync.RWMutex
+	lockPruneLinkChecks sync.RWMutex
+	lockRemoveTag       sync.RWMutex
+	lockSearch          sync.RWMutex
+	lockTags            sync.RWMutex
+	lockUpdate          sync.RWMutex
+	lockWalk            sync.RWMutex
+}
+
+// AddLinkCheck calls AddLinkCheckFunc.
+func (mock *RepositoryMock) AddLinkCheck(linkCheck *LinkCheck) error {
+	if mock.AddLinkCheckFunc == nil {
+		panic("RepositoryMock.AddLinkCheckFunc: method is nil but Repository.AddLinkCheck was just called")
+	}
+	callInfo := struct {
+		LinkCheck *LinkCheck
+	}{
+		LinkCheck: linkCheck,
+	}
+	mock.lockAddLinkCheck.Lock()
+	mock.calls.AddLinkCheck = append(mock.calls.AddLinkCheck, callInfo)
+	mock.lockAddLinkCheck.Unlock()
+	return mock.AddLinkCheckFunc(linkCheck)
+}
+
+// AddLinkCheckCalls gets all the calls that were made to AddLinkCheck.
+// Check the length with:
+//
+//	len(mockedRepository.AddLinkCheckCalls())
+func (mock *RepositoryMock) AddLinkCheckCalls() []struct {
+	LinkCheck *LinkCheck
+} {
+	var calls []struct {
+		LinkCheck *LinkCheck
+	}
+	mock.lockAddLinkCheck.RLock()
+	calls = mock.calls.AddLinkCheck
+	mock.lockAddLinkCheck.RUnlock()
+	return calls
+}
+
+// AddTag calls AddTagFunc.
+func (mock *RepositoryMock) AddTag(ownerID int64, id int64, tag string) error {
+	if mock.AddTagFunc == nil {
+		panic("RepositoryMock.AddTagFunc: method is nil but Repository.AddTag was just called")
+	}
+	callInfo := struct {
+		OwnerID int64
+		ID      int64
+		Tag     string
+	}{
+		OwnerID: ownerID,
+		ID:      id,
+		Tag:     tag,
+	}
+	mock.lockAddTag.Lock()
+	mock.calls.AddTag = append(mock.calls.AddTag, callInfo)
+	mock.lockAddTag.Unlock()
+	return mock.AddTagFunc(ownerID, id, tag)
+}
+
+// AddTagCalls gets all the calls that were made to AddTag.
+// Check the length with:
+//
+//	len(mockedRepository.AddTagCalls())
+func (mock *RepositoryMock) AddTagCalls() []struct {
+	OwnerID int64
+	ID      int64
+	Tag     string
+} {
+	var calls []struct {
+		OwnerID int64
+		ID      int64
+		Tag     string
+	}
+	mock.lockAddTag.RLock()
+	calls = mock.calls.AddTag
+	mock.lockAddTag.RUnlock()
+	return calls
 }
 
 // All calls AllFunc.
-func (mock *RepositoryMock) All(page int) ([]*Bookmark, error) {
+func (mock *RepositoryMock) All(ownerID int64, after Cursor, limit int) ([]*Bookmark, Cursor, error) {
 	if mock.AllFunc == nil {
 		panic("RepositoryMock.AllFunc: method is nil but Repository.All was just called")
 	}
 	callInfo := struct {
-		Page int
+		OwnerID int64
+		After   Cursor
+		Limit   int
 	}{
-		Page: page,
+		OwnerID: ownerID,
+		After:   after,
+		Limit:   limit,
 	}
 	mock.lockAll.Lock()
 	mock.calls.All = append(mock.calls.All, callInfo)
 	mock.lockAll.Unlock()
-	return mock.AllFunc(page)
+	return mock.AllFunc(ownerID, after, limit)
 }
 
 // AllCalls gets all the calls that were made to All.
@@ -178,10 +429,14 @@ func (mock *RepositoryMock) All(page int) ([]*Bookmark, error) {
 //
 //	len(mockedRepository.AllCalls())
 func (mock *RepositoryMock) AllCalls() []struct {
-	Page int
+	OwnerID int64
+	After   Cursor
+	Limit   int
 } {
 	var calls []struct {
-		Page int
+		OwnerID int64
+		After   Cursor
+		Limit   int
 	}
 	mock.lockAll.RLock()
 	calls = mock.calls.All
@@ -216,20 +471,70 @@ func (mock *RepositoryMock) BootstrapCalls() []struct {
 	return calls
 }
 
+// ByTag calls ByTagFunc.
+func (mock *RepositoryMock) ByTag(ownerID int64, tag string, after Cursor, limit int) ([]*Bookmark, Cursor, error) {
+	if mock.ByTagFunc == nil {
+		panic("RepositoryMock.ByTagFunc: method is nil but Repository.ByTag was just called")
+	}
+	callInfo := struct {
+		OwnerID int64
+		Tag     string
+		After   Cursor
+		Limit   int
+	}{
+		OwnerID: ownerID,
+		Tag:     tag,
+		After:   after,
+		Limit:   limit,
+	}
+	mock.lockByTag.Lock()
+	mock.calls.ByTag = append(mock.calls.ByTag, callInfo)
+	mock.lockByTag.Unlock()
+	return mock.ByTagFunc(ownerID, tag, after, limit)
+}
+
+// ByTagCalls gets all the calls that were made to ByTag.
+// Check the length with:
+//
+//	len(mockedRepository.ByTagCalls())
+func (mock *RepositoryMock) ByTagCalls() []struct {
+	OwnerID int64
+	Tag     string
+	After   Cursor
+	Limit   int
+} {
+	var calls []struct {
+		OwnerID int64
+		Tag     string
+		After   Cursor
+		Limit   int
+	}
+	mock.lockByTag.RLock()
+	calls = mock.calls.ByTag
+	mock.lockByTag.RUnlock()
+	return calls
+}
+
 // Dead calls DeadFunc.
-func (mock *RepositoryMock) Dead(page int) ([]*Bookmark, error) {
+func (mock *RepositoryMock) Dead(ownerID int64, filter DeadFilter, after Cursor, limit int) ([]*Bookmark, Cursor, error) {
 	if mock.DeadFunc == nil {
 		panic("RepositoryMock.DeadFunc: method is nil but Repository.Dead was just called")
 	}
 	callInfo := struct {
-		Page int
+		OwnerID int64
+		Filter  DeadFilter
+		After   Cursor
+		Limit   int
 	}{
-		Page: page,
+		OwnerID: ownerID,
+		Filter:  filter,
+		After:   after,
+		Limit:   limit,
 	}
 	mock.lockDead.Lock()
 	mock.calls.Dead = append(mock.calls.Dead, callInfo)
 	mock.lockDead.Unlock()
-	return mock.DeadFunc(page)
+	return mock.DeadFunc(ownerID, filter, after, limit)
 }
 
 // DeadCalls gets all the calls that were made to Dead.
@@ -237,10 +542,16 @@ func (mock *RepositoryMock) Dead(page int) ([]*Bookmark, error) {
 //
 //	len(mockedRepository.DeadCalls())
 func (mock *RepositoryMock) DeadCalls() []struct {
-	Page int
+	OwnerID int64
+	Filter  DeadFilter
+	After   Cursor
+	Limit   int
 } {
 	var calls []struct {
-		Page int
+		OwnerID int64
+		Filter  DeadFilter
+		After   Cursor
+		Limit   int
 	}
 	mock.lockDead.RLock()
 	calls = mock.calls.Dead
@@ -249,19 +560,21 @@ func (mock *RepositoryMock) DeadCalls() []struct {
 }
 
 // DeleteByID calls DeleteByIDFunc.
-func (mock *RepositoryMock) DeleteByID(id int64) error {
+func (mock *RepositoryMock) DeleteByID(ownerID int64, id int64) error {
 	if mock.DeleteByIDFunc == nil {
 		panic("RepositoryMock.DeleteByIDFunc: method is nil but Repository.DeleteByID was just called")
 	}
 	callInfo := struct {
-		ID int64
+		OwnerID int64
+		ID      int64
 	}{
-		ID: id,
+		OwnerID: ownerID,
+		ID:      id,
 	}
 	mock.lockDeleteByID.Lock()
 	mock.calls.DeleteByID = append(mock.calls.DeleteByID, callInfo)
 	mock.lockDeleteByID.Unlock()
-	return mock.DeleteByIDFunc(id)
+	return mock.DeleteByIDFunc(ownerID, id)
 }
 
 // DeleteByIDCalls gets all the calls that were made to DeleteByID.
@@ -269,10 +582,12 @@ func (mock *RepositoryMock) DeleteByID(id int64) error {
 //
 //	len(mockedRepository.DeleteByIDCalls())
 func (mock *RepositoryMock) DeleteByIDCalls() []struct {
-	ID int64
+	OwnerID int64
+	ID      int64
 } {
 	var calls []struct {
-		ID int64
+		OwnerID int64
+		ID      int64
 	}
 	mock.lockDeleteByID.RLock()
 	calls = mock.calls.DeleteByID
@@ -281,19 +596,23 @@ func (mock *RepositoryMock) DeleteByIDCalls() []struct {
 }
 
 // Duplicated calls DuplicatedFunc.
-func (mock *RepositoryMock) Duplicated(page int) ([]*Bookmark, error) {
+func (mock *RepositoryMock) Duplicated(ownerID int64, after Cursor, limit int) ([]*Bookmark, Cursor, error) {
 	if mock.DuplicatedFunc == nil {
 		panic("RepositoryMock.DuplicatedFunc: method is nil but Repository.Duplicated was just called")
 	}
 	callInfo := struct {
-		Page int
+		OwnerID int64
+		After   Cursor
+		Limit   int
 	}{
-		Page: page,
+		OwnerID: ownerID,
+		After:   after,
+		Limit:   limit,
 	}
 	mock.lockDuplicated.Lock()
 	mock.calls.Duplicated = append(mock.calls.Duplicated, callInfo)
 	mock.lockDuplicated.Unlock()
-	return mock.DuplicatedFunc(page)
+	return mock.DuplicatedFunc(ownerID, after, limit)
 }
 
 // DuplicatedCalls gets all the calls that were made to Duplicated.
@@ -301,10 +620,14 @@ func (mock *RepositoryMock) Duplicated(page int) ([]*Bookmark, error) {
 //
 //	len(mockedRepository.DuplicatedCalls())
 func (mock *RepositoryMock) DuplicatedCalls() []struct {
-	Page int
+	OwnerID int64
+	After   Cursor
+	Limit   int
 } {
 	var calls []struct {
-		Page int
+		OwnerID int64
+		After   Cursor
+		Limit   int
 	}
 	mock.lockDuplicated.RLock()
 	calls = mock.calls.Duplicated
@@ -340,19 +663,21 @@ func (mock *RepositoryMock) ExpiredCalls() []struct {
 }
 
 // GetByID calls GetByIDFunc.
-func (mock *RepositoryMock) GetByID(id int64) (*Bookmark, error) {
+func (mock *RepositoryMock) GetByID(ownerID int64, id int64) (*Bookmark, error) {
 	if mock.GetByIDFunc == nil {
 		panic("RepositoryMock.GetByIDFunc: method is nil but Repository.GetByID was just called")
 	}
 	callInfo := struct {
-		ID int64
+		OwnerID int64
+		ID      int64
 	}{
-		ID: id,
+		OwnerID: ownerID,
+		ID:      id,
 	}
 	mock.lockGetByID.Lock()
 	mock.calls.GetByID = append(mock.calls.GetByID, callInfo)
 	mock.lockGetByID.Unlock()
-	return mock.GetByIDFunc(id)
+	return mock.GetByIDFunc(ownerID, id)
 }
 
 // GetByIDCalls gets all the calls that were made to GetByID.
@@ -360,10 +685,12 @@ func (mock *RepositoryMock) GetByID(id int64) (*Bookmark, error) {
 //
 //	len(mockedRepository.GetByIDCalls())
 func (mock *RepositoryMock) GetByIDCalls() []struct {
-	ID int64
+	OwnerID int64
+	ID      int64
 } {
 	var calls []struct {
-		ID int64
+		OwnerID int64
+		ID      int64
 	}
 	mock.lockGetByID.RLock()
 	calls = mock.calls.GetByID
@@ -371,20 +698,60 @@ func (mock *RepositoryMock) GetByIDCalls() []struct {
 	return calls
 }
 
+// HasURL calls HasURLFunc.
+func (mock *RepositoryMock) HasURL(ownerID int64, url string) (bool, error) {
+	if mock.HasURLFunc == nil {
+		panic("RepositoryMock.HasURLFunc: method is nil but Repository.HasURL was just called")
+	}
+	callInfo := struct {
+		OwnerID int64
+		URL     string
+	}{
+		OwnerID: ownerID,
+		URL:     url,
+	}
+	mock.lockHasURL.Lock()
+	mock.calls.HasURL = append(mock.calls.HasURL, callInfo)
+	mock.lockHasURL.Unlock()
+	return mock.HasURLFunc(ownerID, url)
+}
+
+// HasURLCalls gets all the calls that were made to HasURL.
+// Check the length with:
+//
+//	len(mockedRepository.HasURLCalls())
+func (mock *RepositoryMock) HasURLCalls() []struct {
+	OwnerID int64
+	URL     string
+} {
+	var calls []struct {
+		OwnerID int64
+		URL     string
+	}
+	mock.lockHasURL.RLock()
+	calls = mock.calls.HasURL
+	mock.lockHasURL.RUnlock()
+	return calls
+}
+
 // Inbox calls InboxFunc.
-func (mock *RepositoryMock) Inbox(page int) ([]*Bookmark, error) {
+func (mock *RepositoryMock) Inbox(ownerID int64, after Cursor, limit int) ([]*Bookmark, Cursor, error) {
 	if mock.InboxFunc == nil {
 		panic("RepositoryMock.InboxFunc: method is nil but Repository.Inbox was just called")
 	}
 	callInfo := struct {
-		Page int
+		OwnerID int64
+		After   Cursor
+		Limit   int
 	}{
-		Page: page,
+		OwnerID: ownerID,
+		After:   after,
+		Limit:   limit,
 	}
 	mock.lockInbox.Lock()
 	mock.calls.Inbox = append(mock.calls.Inbox, callInfo)
 	mock.lockInbox.Unlock()
-	return mock.InboxFunc(page)
+	return mock.InboxFunc(ownerID, after, limit)
 }
 
 // InboxCalls gets all the calls that were made to Inbox.
@@ -392,10 +759,14 @@ func (mock *RepositoryMock) Inbox(page int) ([]*Bookmark, error) {
 //
 //	len(mockedRepository.InboxCalls())
 func (mock *RepositoryMock) InboxCalls() []struct {
-	Page int
+	OwnerID int64
+	After   Cursor
+	Limit   int
 } {
 	var calls []struct {
-		Page int
+		OwnerID int64
+		After   Cursor
+		Limit   int
 	}
 	mock.lockInbox.RLock()
 	calls = mock.calls.Inbox
@@ -435,20 +806,178 @@ func (mock *RepositoryMock) InsertCalls() []struct {
 	return calls
 }
 
+// LinkChecks calls LinkChecksFunc.
+func (mock *RepositoryMock) LinkChecks(ownerID int64, id int64, limit int) ([]*LinkCheck, error) {
+	if mock.LinkChecksFunc == nil {
+		panic("RepositoryMock.LinkChecksFunc: method is nil but Repository.LinkChecks was just called")
+	}
+	callInfo := struct {
+		OwnerID int64
+		ID      int64
+		Limit   int
+	}{
+		OwnerID: ownerID,
+		ID:      id,
+		Limit:   limit,
+	}
+	mock.lockLinkChecks.Lock()
+	mock.calls.LinkChecks = append(mock.calls.LinkChecks, callInfo)
+	mock.lockLinkChecks.Unlock()
+	return mock.LinkChecksFunc(ownerID, id, limit)
+}
+
+// LinkChecksCalls gets all the calls that were made to LinkChecks.
+// Check the length with:
+//
+//	len(mockedRepository.LinkChecksCalls())
+func (mock *RepositoryMock) LinkChecksCalls() []struct {
+	OwnerID int64
+	ID      int64
+	Limit   int
+} {
+	var calls []struct {
+		OwnerID int64
+		ID      int64
+		Limit   int
+	}
+	mock.lockLinkChecks.RLock()
+	calls = mock.calls.LinkChecks
+	mock.lockLinkChecks.RUnlock()
+	return calls
+}
+
+// Moved calls MovedFunc.
+func (mock *RepositoryMock) Moved(ownerID int64, after Cursor, limit int) ([]*Bookmark, Cursor, error) {
+	if mock.MovedFunc == nil {
+		panic("RepositoryMock.MovedFunc: method is nil but Repository.Moved was just called")
+	}
+	callInfo := struct {
+		OwnerID int64
+		After   Cursor
+		Limit   int
+	}{
+		OwnerID: ownerID,
+		After:   after,
+		Limit:   limit,
+	}
+	mock.lockMoved.Lock()
+	mock.calls.Moved = append(mock.calls.Moved, callInfo)
+	mock.lockMoved.Unlock()
+	return mock.MovedFunc(ownerID, after, limit)
+}
+
+// MovedCalls gets all the calls that were made to Moved.
+// Check the length with:
+//
+//	len(mockedRepository.MovedCalls())
+func (mock *RepositoryMock) MovedCalls() []struct {
+	OwnerID int64
+	After   Cursor
+	Limit   int
+} {
+	var calls []struct {
+		OwnerID int64
+		After   Cursor
+		Limit   int
+	}
+	mock.lockMoved.RLock()
+	calls = mock.calls.Moved
+	mock.lockMoved.RUnlock()
+	return calls
+}
+
+// PruneLinkChecks calls PruneLinkChecksFunc.
+func (mock *RepositoryMock) PruneLinkChecks(before time.Time) (int64, error) {
+	if mock.PruneLinkChecksFunc == nil {
+		panic("RepositoryMock.PruneLinkChecksFunc: method is nil but Repository.PruneLinkChecks was just called")
+	}
+	callInfo := struct {
+		Before time.Time
+	}{
+		Before: before,
+	}
+	mock.lockPruneLinkChecks.Lock()
+	mock.calls.PruneLinkChecks = append(mock.calls.PruneLinkChecks, callInfo)
+	mock.lockPruneLinkChecks.Unlock()
+	return mock.PruneLinkChecksFunc(before)
+}
+
+// PruneLinkChecksCalls gets all the calls that were made to PruneLinkChecks.
+// Check the length with:
+//
+//	len(mockedRepository.PruneLinkChecksCalls())
+func (mock *RepositoryMock) PruneLinkChecksCalls() []struct {
+	Before time.Time
+} {
+	var calls []struct {
+		Before time.Time
+	}
+	mock.lockPruneLinkChecks.RLock()
+	calls = mock.calls.PruneLinkChecks
+	mock.lockPruneLinkChecks.RUnlock()
+	return calls
+}
+
+// RemoveTag calls RemoveTagFunc.
+func (mock *RepositoryMock) RemoveTag(ownerID int64, id int64, tag string) error {
+	if mock.RemoveTagFunc == nil {
+		panic("RepositoryMock.RemoveTagFunc: method is nil but Repository.RemoveTag was just called")
+	}
+	callInfo := struct {
+		OwnerID int64
+		ID      int64
+		Tag     string
+	}{
+		OwnerID: ownerID,
+		ID:      id,
+		Tag:     tag,
+	}
+	mock.lockRemoveTag.Lock()
+	mock.calls.RemoveTag = append(mock.calls.RemoveTag, callInfo)
+	mock.lockRemoveTag.Unlock()
+	return mock.RemoveTagFunc(ownerID, id, tag)
+}
+
+// RemoveTagCalls gets all the calls that were made to RemoveTag.
+// Check the length with:
+//
+//	len(mockedRepository.RemoveTagCalls())
+func (mock *RepositoryMock) RemoveTagCalls() []struct {
+	OwnerID int64
+	ID      int64
+	Tag     string
+} {
+	var calls []struct {
+		OwnerID int64
+		ID      int64
+		Tag     string
+	}
+	mock.lockRemoveTag.RLock()
+	calls = mock.calls.RemoveTag
+	mock.lockRemoveTag.RUnlock()
+	return calls
+}
+
 // Search calls SearchFunc.
-func (mock *RepositoryMock) Search(term string) ([]*Bookmark, error) {
+func (mock *RepositoryMock) Search(ownerID int64, query *Query, page int, limit int) ([]*Bookmark, int, error) {
 	if mock.SearchFunc == nil {
 		panic("RepositoryMock.SearchFunc: method is nil but Repository.Search was just called")
 	}
 	callInfo := struct {
-		Term string
+		OwnerID int64
+		Query   *Query
+		Page    int
+		Limit   int
 	}{
-		Term: term,
+		OwnerID: ownerID,
+		Query:   query,
+		Page:    page,
+		Limit:   limit,
 	}
 	mock.lockSearch.Lock()
 	mock.calls.Search = append(mock.calls.Search, callInfo)
 	mock.lockSearch.Unlock()
-	return mock.SearchFunc(term)
+	return mock.SearchFunc(ownerID, query, page, limit)
 }
 
 // SearchCalls gets all the calls that were made to Search.
@@ -456,10 +985,16 @@ func (mock *RepositoryMock) Search(term string) ([]*Bookmark, error) {
 //
 //	len(mockedRepository.SearchCalls())
 func (mock *RepositoryMock) SearchCalls() []struct {
-	Term string
+	OwnerID int64
+	Query   *Query
+	Page    int
+	Limit   int
 } {
 	var calls []struct {
-		Term string
+		OwnerID int64
+		Query   *Query
+		Page    int
+		Limit   int
 	}
 	mock.lockSearch.RLock()
 	calls = mock.calls.Search
@@ -467,6 +1002,38 @@ func (mock *RepositoryMock) SearchCalls() []struct {
 	return calls
 }
 
+// Tags calls TagsFunc.
+func (mock *RepositoryMock) Tags(ownerID int64) ([]*Tag, error) {
+	if mock.TagsFunc == nil {
+		panic("RepositoryMock.TagsFunc: method is nil but Repository.Tags was just called")
+	}
+	callInfo := struct {
+		OwnerID int64
+	}{
+		OwnerID: ownerID,
+	}
+	mock.lockTags.Lock()
+	mock.calls.Tags = append(mock.calls.Tags, callInfo)
+	mock.lockTags.Unlock()
+	return mock.TagsFunc(ownerID)
+}
+
+// TagsCalls gets all the calls that were made to Tags.
+// Check the length with:
+//
+//	len(mockedRepository.TagsCalls())
+func (mock *RepositoryMock) TagsCalls() []struct {
+	OwnerID int64
+} {
+	var calls []struct {
+		OwnerID int64
+	}
+	mock.lockTags.RLock()
+	calls = mock.calls.Tags
+	mock.lockTags.RUnlock()
+	return calls
+}
+
 // Update calls UpdateFunc.
 func (mock *RepositoryMock) Update(bookmark *Bookmark) error {
 	if mock.UpdateFunc == nil {
@@ -498,3 +1065,39 @@ func (mock *RepositoryMock) UpdateCalls() []struct {
 	mock.lockUpdate.RUnlock()
 	return calls
 }
+
+// Walk calls WalkFunc.
+func (mock *RepositoryMock) Walk(ownerID int64, fn func(*Bookmark) error) error {
+	if mock.WalkFunc == nil {
+		panic("RepositoryMock.WalkFunc: method is nil but Repository.Walk was just called")
+	}
+	callInfo := struct {
+		OwnerID int64
+		Fn      func(*Bookmark) error
+	}{
+		OwnerID: ownerID,
+		Fn:      fn,
+	}
+	mock.lockWalk.Lock()
+	mock.calls.Walk = append(mock.calls.Walk, callInfo)
+	mock.lockWalk.Unlock()
+	return mock.WalkFunc(ownerID, fn)
+}
+
+// WalkCalls gets all the calls that were made to Walk.
+// Check the length with:
+//
+//	len(mockedRepository.WalkCalls())
+func (mock *RepositoryMock) WalkCalls() []struct {
+	OwnerID int64
+	Fn      func(*Bookmark) error
+} {
+	var calls []struct {
+		OwnerID int64
+		Fn      func(*Bookmark) error
+	}
+	mock.lockWalk.RLock()
+	calls = mock.calls.Walk
+	mock.lockWalk.RUnlock()
+	return calls
+}
@@ -0,0 +1,199 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqliterepo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"cirello.io/alreadyread/pkg/auth"
+)
+
+var _ auth.Repository = (*Repository)(nil)
+
+func (b *Repository) InsertToken(token *auth.Token) error {
+	result, err := b.db.Exec(`INSERT INTO tokens (user_id, name, hash, created_at) VALUES ($1, $2, $3, $4)`, token.UserID, token.Name, token.Hash, token.CreatedAt.Round(0))
+	if err != nil {
+		return fmt.Errorf("cannot insert token: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("cannot load inserted ID: %w", err)
+	}
+	token.ID = id
+	return nil
+}
+
+func (b *Repository) TokenByHash(hash string) (*auth.Token, error) {
+	return scanToken(b.db.QueryRow(`SELECT id, user_id, name, hash, created_at FROM tokens WHERE hash = $1`, hash))
+}
+
+func (b *Repository) TokenByID(id int64) (*auth.Token, error) {
+	return scanToken(b.db.QueryRow(`SELECT id, user_id, name, hash, created_at FROM tokens WHERE id = $1`, id))
+}
+
+func scanToken(row *sql.Row) (*auth.Token, error) {
+	token := &auth.Token{}
+	err := row.Scan(&token.ID, &token.UserID, &token.Name, &token.Hash, &token.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, auth.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (b *Repository) Tokens() ([]*auth.Token, error) {
+	rows, err := b.db.Query(`SELECT id, user_id, name, hash, created_at FROM tokens ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tokens []*auth.Token
+	for rows.Next() {
+		token := &auth.Token{}
+		if err := rows.Scan(&token.ID, &token.UserID, &token.Name, &token.Hash, &token.CreatedAt); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+func (b *Repository) DeleteToken(id int64) error {
+	result, err := b.db.Exec(`DELETE FROM tokens WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("cannot delete token: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("cannot count affected rows: %w", err)
+	} else if n == 0 {
+		return auth.ErrNotFound
+	}
+	return nil
+}
+
+func (b *Repository) InsertSession(session *auth.Session) error {
+	_, err := b.db.Exec(`INSERT INTO sessions (hash, user_id, created_at, expires_at) VALUES ($1, $2, $3, $4)`, session.Hash, session.UserID, session.CreatedAt.Round(0), session.ExpiresAt.Unix())
+	return err
+}
+
+func (b *Repository) SessionByHash(hash string) (*auth.Session, error) {
+	session := &auth.Session{}
+	var expiresAt int64
+	err := b.db.QueryRow(`SELECT hash, user_id, created_at, expires_at FROM sessions WHERE hash = $1`, hash).Scan(&session.Hash, &session.UserID, &session.CreatedAt, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, auth.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	session.ExpiresAt = time.Unix(expiresAt, 0)
+	return session, nil
+}
+
+func (b *Repository) DeleteSession(hash string) error {
+	_, err := b.db.Exec(`DELETE FROM sessions WHERE hash = $1`, hash)
+	return err
+}
+
+func (b *Repository) DeleteExpiredSessions(before time.Time) error {
+	_, err := b.db.Exec(`DELETE FROM sessions WHERE expires_at <= $1`, before.Unix())
+	return err
+}
+
+func (b *Repository) InsertUser(user *auth.User) error {
+	result, err := b.db.Exec(`INSERT INTO users (username, password_hash, created_at) VALUES ($1, $2, $3)`, user.Username, user.PasswordHash, user.CreatedAt.Round(0))
+	if err != nil {
+		return fmt.Errorf("cannot insert user: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("cannot load inserted ID: %w", err)
+	}
+	user.ID = id
+	return nil
+}
+
+func (b *Repository) UserByID(id int64) (*auth.User, error) {
+	return scanUser(b.db.QueryRow(`SELECT id, username, password_hash, created_at FROM users WHERE id = $1`, id))
+}
+
+func (b *Repository) UserByUsername(username string) (*auth.User, error) {
+	return scanUser(b.db.QueryRow(`SELECT id, username, password_hash, created_at FROM users WHERE username = $1`, username))
+}
+
+func scanUser(row *sql.Row) (*auth.User, error) {
+	user := &auth.User{}
+	err := row.Scan(&user.ID, &user.Username, &user.PasswordHash, &user.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, auth.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (b *Repository) Users() ([]*auth.User, error) {
+	rows, err := b.db.Query(`SELECT id, username, password_hash, created_at FROM users ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var users []*auth.User
+	for rows.Next() {
+		user := &auth.User{}
+		if err := rows.Scan(&user.ID, &user.Username, &user.PasswordHash, &user.CreatedAt); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+func (b *Repository) InsertFirstUser(user *auth.User) error {
+	tx, err := b.db.Begin()
+	if err != nil {
+		return fmt.Errorf("cannot start transaction: %w", err)
+	}
+	defer tx.Rollback()
+	// The check and the insert are a single statement, so that concurrent
+	// signups cannot both find the table empty.
+	result, err := tx.Exec(`INSERT INTO users (username, password_hash, created_at) SELECT $1, $2, $3 WHERE NOT EXISTS (SELECT 1 FROM users)`, user.Username, user.PasswordHash, user.CreatedAt.Round(0))
+	if err != nil {
+		return fmt.Errorf("cannot insert user: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("cannot check inserted user: %w", err)
+	} else if n == 0 {
+		return auth.ErrSignupClosed
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("cannot load inserted ID: %w", err)
+	}
+	if _, err := tx.Exec(`UPDATE bookmarks SET owner_id = $1 WHERE owner_id = 0`, id); err != nil {
+		return fmt.Errorf("cannot adopt bookmarks: %w", err)
+	}
+	if _, err := tx.Exec(`UPDATE tokens SET user_id = $1 WHERE user_id = 0`, id); err != nil {
+		return fmt.Errorf("cannot adopt tokens: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("cannot commit user: %w", err)
+	}
+	user.ID = id
+	return nil
+}
@@ -0,0 +1,173 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqliterepo
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"cirello.io/alreadyread/pkg/auth"
+	"cirello.io/alreadyread/pkg/bookmarks"
+)
+
+func TestRepository_tokens(t *testing.T) {
+	repository := setup(t)
+	token := &auth.Token{UserID: 1, Name: "laptop", Hash: "hash", CreatedAt: time.Now()}
+	if err := repository.InsertToken(token); err != nil {
+		t.Fatal("cannot insert token:", err)
+	}
+	if token.ID == 0 {
+		t.Fatal("did not update token ID")
+	}
+	if err := repository.InsertToken(&auth.Token{Name: "copy", Hash: "hash", CreatedAt: time.Now()}); err == nil {
+		t.Fatal("token hashes must be unique")
+	}
+	for name, load := range map[string]func() (*auth.Token, error){
+		"byHash": func() (*auth.Token, error) { return repository.TokenByHash("hash") },
+		"byID":   func() (*auth.Token, error) { return repository.TokenByID(token.ID) },
+	} {
+		found, err := load()
+		if err != nil {
+			t.Fatal(name, "cannot load token:", err)
+		}
+		if found.ID != token.ID || found.UserID != token.UserID || found.Name != token.Name || !found.CreatedAt.Equal(token.CreatedAt) {
+			t.Fatalf("%s: unexpected token: %#v", name, found)
+		}
+	}
+	if _, err := repository.TokenByHash("other"); !errors.Is(err, auth.ErrNotFound) {
+		t.Fatal("unexpected error for unknown token:", err)
+	}
+	tokens, err := repository.Tokens()
+	if err != nil || len(tokens) != 1 || tokens[0].ID != token.ID {
+		t.Fatal("unexpected tokens:", tokens, err)
+	}
+
+	now := time.Now()
+	sessions := []*auth.Session{
+		{Hash: "active", UserID: 1, CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+		{Hash: "expired", UserID: 1, CreatedAt: now, ExpiresAt: now.Add(-time.Hour)},
+	}
+	for _, session := range sessions {
+		if err := repository.InsertSession(session); err != nil {
+			t.Fatal("cannot insert session:", err)
+		}
+	}
+	if err := repository.DeleteExpiredSessions(now); err != nil {
+		t.Fatal("cannot delete expired sessions:", err)
+	}
+	if _, err := repository.SessionByHash("expired"); !errors.Is(err, auth.ErrNotFound) {
+		t.Fatal("expired session not deleted:", err)
+	}
+	found, err := repository.SessionByHash("active")
+	if err != nil {
+		t.Fatal("cannot load session:", err)
+	}
+	if found.UserID != 1 || found.ExpiresAt.Unix() != sessions[0].ExpiresAt.Unix() {
+		t.Fatalf("unexpected session: %#v", found)
+	}
+	if err := repository.DeleteSession("active"); err != nil {
+		t.Fatal("cannot delete session:", err)
+	}
+	if _, err := repository.SessionByHash("active"); !errors.Is(err, auth.ErrNotFound) {
+		t.Fatal("session not deleted:", err)
+	}
+
+	if err := repository.DeleteToken(token.ID); err != nil {
+		t.Fatal("cannot delete token:", err)
+	}
+	if err := repository.DeleteToken(token.ID); !errors.Is(err, auth.ErrNotFound) {
+		t.Fatal("unexpected error for unknown token:", err)
+	}
+}
+
+func TestRepository_users(t *testing.T) {
+	repository := setup(t)
+	user := &auth.User{Username: "Alice", PasswordHash: "hash", CreatedAt: time.Now()}
+	if err := repository.InsertUser(user); err != nil {
+		t.Fatal("cannot insert user:", err)
+	}
+	if user.ID == 0 {
+		t.Fatal("did not update user ID")
+	}
+	if err := repository.InsertUser(&auth.User{Username: "alice", PasswordHash: "hash", CreatedAt: time.Now()}); err == nil {
+		t.Fatal("usernames must be unique regardless of case")
+	}
+	for name, load := range map[string]func() (*auth.User, error){
+		"byUsername": func() (*auth.User, error) { return repository.UserByUsername("ALICE") },
+		"byID":       func() (*auth.User, error) { return repository.UserByID(user.ID) },
+	} {
+		found, err := load()
+		if err != nil {
+			t.Fatal(name, "cannot load user:", err)
+		}
+		if found.ID != user.ID || found.Username != user.Username || found.PasswordHash != user.PasswordHash || !found.CreatedAt.Equal(user.CreatedAt) {
+			t.Fatalf("%s: unexpected user: %#v", name, found)
+		}
+	}
+	if _, err := repository.UserByUsername("bob"); !errors.Is(err, auth.ErrNotFound) {
+		t.Fatal("unexpected error for unknown user:", err)
+	}
+	users, err := repository.Users()
+	if err != nil || len(users) != 1 || users[0].ID != user.ID {
+		t.Fatal("unexpected users:", users, err)
+	}
+}
+
+func TestRepository_InsertFirstUser(t *testing.T) {
+	repository := setup(t)
+	orphan := &bookmarks.Bookmark{URL: "http://example.com/orphan", CreatedAt: time.Now()}
+	owned := &bookmarks.Bookmark{URL: "http://example.com/owned", CreatedAt: time.Now(), OwnerID: 2}
+	for _, bookmark := range []*bookmarks.Bookmark{orphan, owned} {
+		if err := repository.Insert(bookmark); err != nil {
+			t.Fatal("cannot insert bookmark:", err)
+		}
+	}
+	token := &auth.Token{Name: "laptop", Hash: "hash", CreatedAt: time.Now()}
+	if err := repository.InsertToken(token); err != nil {
+		t.Fatal("cannot insert token:", err)
+	}
+	first := &auth.User{Username: "alice", PasswordHash: "hash", CreatedAt: time.Now()}
+	if err := repository.InsertFirstUser(first); err != nil {
+		t.Fatal("cannot insert first user:", err)
+	}
+	if _, err := repository.GetByID(first.ID, orphan.ID); err != nil {
+		t.Error("orphan bookmark not adopted:", err)
+	}
+	if _, err := repository.GetByID(first.ID, owned.ID); !errors.Is(err, bookmarks.ErrNotFound) {
+		t.Error("owned bookmark changed hands:", err)
+	}
+	if found, err := repository.TokenByID(token.ID); err != nil || found.UserID != first.ID {
+		t.Error("orphan token not adopted:", found, err)
+	}
+
+	late := &bookmarks.Bookmark{URL: "http://example.com/late", CreatedAt: time.Now()}
+	if err := repository.Insert(late); err != nil {
+		t.Fatal("cannot insert bookmark:", err)
+	}
+	second := &auth.User{Username: "bob", PasswordHash: "hash", CreatedAt: time.Now()}
+	if err := repository.InsertFirstUser(second); !errors.Is(err, auth.ErrSignupClosed) {
+		t.Fatal("unexpected error for second user:", err)
+	}
+	if second.ID != 0 {
+		t.Error("second user got an ID:", second.ID)
+	}
+	if _, err := repository.UserByUsername("bob"); !errors.Is(err, auth.ErrNotFound) {
+		t.Error("second user stored:", err)
+	}
+	if _, err := repository.GetByID(0, late.ID); err != nil {
+		t.Error("orphan bookmark adopted by second user:", err)
+	}
+}
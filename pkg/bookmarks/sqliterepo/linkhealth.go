@@ -0,0 +1,67 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqliterepo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"cirello.io/alreadyread/pkg/bookmarks/linkhealth"
+)
+
+var _ linkhealth.Store = (*Repository)(nil)
+
+func (b *Repository) LastLinkHealthRun() (linkhealth.Run, error) {
+	var (
+		run                   linkhealth.Run
+		startedAt, finishedAt int64
+	)
+	err := b.db.QueryRow(`SELECT started_at, finished_at, error FROM link_health_runs WHERE id = 1`).Scan(&startedAt, &finishedAt, &run.Err)
+	if errors.Is(err, sql.ErrNoRows) {
+		return run, nil
+	} else if err != nil {
+		return run, fmt.Errorf("cannot load link health run: %w", err)
+	}
+	run.StartedAt, run.FinishedAt = fromUnix(startedAt), fromUnix(finishedAt)
+	return run, nil
+}
+
+func (b *Repository) SaveLinkHealthRun(run linkhealth.Run) error {
+	_, err := b.db.Exec(`
+		INSERT INTO link_health_runs (id, started_at, finished_at, error) VALUES (1, $1, $2, $3)
+		ON CONFLICT (id) DO UPDATE SET started_at = excluded.started_at, finished_at = excluded.finished_at, error = excluded.error
+	`, toUnix(run.StartedAt), toUnix(run.FinishedAt), run.Err)
+	if err != nil {
+		return fmt.Errorf("cannot store link health run: %w", err)
+	}
+	return nil
+}
+
+// toUnix and fromUnix store zero times as 0.
+func toUnix(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+func fromUnix(sec int64) time.Time {
+	if sec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0)
+}
@@ -17,8 +17,12 @@ package sqliterepo
 import (
 	"context"
 	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/url"
+	"slices"
 	"strings"
 	"time"
 
@@ -34,29 +38,140 @@ func New(db *sql.DB) *Repository {
 	return &Repository{db: db}
 }
 
+// migrations are applied in order by Bootstrap, which records the index of
+// the last one applied in the user_version of the database.
+var migrations = []string{
+	`create table if not exists bookmarks (
+		id integer primary key autoincrement,
+		url text,
+		last_status_code int,
+		last_status_check int,
+		last_status_reason text,
+		title bigtext not null,
+		created_at datetime not null,
+		inbox int not null default 0
+	);
+	`,
+	`create index if not exists bookmarks_last_status_code  on bookmarks (last_status_code)`,
+	`create index if not exists bookmarks_last_status_check on bookmarks (last_status_check)`,
+	`create index if not exists bookmarks_created_at on bookmarks (created_at)`,
+	`create index if not exists bookmarks_inbox on bookmarks (inbox)`,
+	`alter table bookmarks add column description bigtext not null default ''`,
+	`alter table bookmarks add column bump_date datetime not null default '0000-00-00 00:00:00'`,
+	`create index if not exists bookmarks_bump_date on bookmarks (bump_date)`,
+	`update bookmarks set bump_date = created_at`,
+	`update bookmarks set inbox = 1 where inbox > 1`,
+	`create table if not exists tags (
+		id integer primary key autoincrement,
+		name text not null unique
+	);
+	`,
+	`create table if not exists bookmark_tags (
+		bookmark_id int not null,
+		tag_id int not null,
+		primary key (bookmark_id, tag_id)
+	);
+	`,
+	`create index if not exists bookmark_tags_tag_id on bookmark_tags (tag_id)`,
+	`create virtual table if not exists bookmarks_fts using fts5 (
+		title,
+		url,
+		description,
+		content='bookmarks',
+		content_rowid='id'
+	);
+	`,
+	`create trigger if not exists bookmarks_fts_insert after insert on bookmarks begin
+		insert into bookmarks_fts (rowid, title, url, description) values (new.id, new.title, new.url, new.description);
+	end;
+	`,
+	`create trigger if not exists bookmarks_fts_delete after delete on bookmarks begin
+		insert into bookmarks_fts (bookmarks_fts, rowid, title, url, description) values ('delete', old.id, old.title, old.url, old.description);
+	end;
+	`,
+	`create trigger if not exists bookmarks_fts_update after update of title, url, description on bookmarks begin
+		insert into bookmarks_fts (bookmarks_fts, rowid, title, url, description) values ('delete', old.id, old.title, old.url, old.description);
+		insert into bookmarks_fts (rowid, title, url, description) values (new.id, new.title, new.url, new.description);
+	end;
+	`,
+	`insert into bookmarks_fts (bookmarks_fts) values ('rebuild')`,
+	`create table if not exists tokens (
+		id integer primary key autoincrement,
+		name text not null,
+		hash text not null unique,
+		created_at datetime not null
+	);
+	`,
+	`create table if not exists sessions (
+		hash text primary key,
+		token_id int not null,
+		created_at datetime not null,
+		expires_at int not null
+	);
+	`,
+	`create index if not exists sessions_token_id on sessions (token_id)`,
+	`create index if not exists sessions_expires_at on sessions (expires_at)`,
+	`alter table bookmarks add column owner_id int not null default 0`,
+	`create index if not exists bookmarks_owner_id on bookmarks (owner_id)`,
+	`create table if not exists users (
+		id integer primary key autoincrement,
+		username text not null unique collate nocase,
+		password_hash text not null,
+		created_at datetime not null
+	);
+	`,
+	`alter table tokens add column user_id int not null default 0`,
+	`drop table if exists sessions`,
+	`create table if not exists sessions (
+		hash text primary key,
+		user_id int not null,
+		created_at datetime not null,
+		expires_at int not null
+	);
+	`,
+	`create index if not exists sessions_user_id on sessions (user_id)`,
+	`create index if not exists sessions_expires_at on sessions (expires_at)`,
+	`alter table bookmarks add column final_url text not null default ''`,
+	`alter table bookmarks add column redirect_count int not null default 0`,
+	`alter table bookmarks add column failure_count int not null default 0`,
+	`alter table bookmarks add column failing_since int not null default 0`,
+	`alter table bookmarks add column dead int not null default 0`,
+	`alter table bookmarks add column next_check int not null default 0`,
+	`update bookmarks set next_check = last_status_check + 604800 where last_status_code in (200, 0)`,
+	`update bookmarks set failure_count = 1, failing_since = last_status_check where last_status_code not in (200, 0)`,
+	`create index if not exists bookmarks_next_check on bookmarks (next_check)`,
+	`create table if not exists link_checks (
+		id integer primary key autoincrement,
+		bookmark_id int not null,
+		checked_at int not null,
+		code int not null,
+		reason text not null default ''
+	);
+	`,
+	`create index if not exists link_checks_bookmark_id on link_checks (bookmark_id, checked_at)`,
+	`create index if not exists link_checks_checked_at on link_checks (checked_at)`,
+	`insert into link_checks (bookmark_id, checked_at, code, reason) select id, last_status_check, last_status_code, coalesce(last_status_reason, '') from bookmarks where last_status_check > 0`,
+	`create table if not exists link_health_runs (
+		id integer primary key check (id = 1),
+		started_at int not null,
+		finished_at int not null,
+		error text not null default ''
+	);
+	`,
+	`alter table bookmarks add column meta_title text not null default ''`,
+	`alter table bookmarks add column meta_description text not null default ''`,
+	`alter table bookmarks add column meta_image_url text not null default ''`,
+	`alter table bookmarks add column canonical_url text not null default ''`,
+	`alter table bookmarks add column meta_author text not null default ''`,
+	`alter table bookmarks add column published_at int not null default 0`,
+	`alter table bookmarks add column site_name text not null default ''`,
+	`alter table bookmarks add column lang text not null default ''`,
+	`create index if not exists bookmarks_duplicate_key on bookmarks (owner_id, coalesce(nullif(canonical_url, ''), url))`,
+	`alter table bookmarks add column permanent_redirect int not null default 0`,
+	`alter table bookmarks add column page_title text not null default ''`,
+}
+
 func (b *Repository) Bootstrap() error {
-	cmds := []string{
-		`create table if not exists bookmarks (
-			id integer primary key autoincrement,
-			url text,
-			last_status_code int,
-			last_status_check int,
-			last_status_reason text,
-			title bigtext not null,
-			created_at datetime not null,
-			inbox int not null default 0
-		);
-		`,
-		`create index if not exists bookmarks_last_status_code  on bookmarks (last_status_code)`,
-		`create index if not exists bookmarks_last_status_check on bookmarks (last_status_check)`,
-		`create index if not exists bookmarks_created_at on bookmarks (created_at)`,
-		`create index if not exists bookmarks_inbox on bookmarks (inbox)`,
-		`alter table bookmarks add column description bigtext not null default ''`,
-		`alter table bookmarks add column bump_date datetime not null default '0000-00-00 00:00:00'`,
-		`create index if not exists bookmarks_bump_date on bookmarks (bump_date)`,
-		`update bookmarks set bump_date = created_at`,
-		`update bookmarks set inbox = 1 where inbox > 1`,
-	}
 	var version int
 	row := b.db.QueryRow("PRAGMA user_version;")
 	if err := row.Scan(&version); err != nil {
@@ -65,7 +180,7 @@ func (b *Repository) Bootstrap() error {
 	if version == 0 {
 		version = -1
 	}
-	for stmt, cmd := range cmds {
+	for stmt, cmd := range migrations {
 		if version >= stmt {
 			continue
 		}
@@ -81,6 +196,12 @@ func (b *Repository) Bootstrap() error {
 	return nil
 }
 
+// bookmarkColumns lists the fields read by scanRow, in order.
+const bookmarkColumns = `bookmarks.id, bookmarks.url, bookmarks.last_status_code, bookmarks.last_status_check, bookmarks.last_status_reason, bookmarks.title, bookmarks.created_at, bookmarks.inbox, bookmarks.description, bookmarks.bump_date, bookmarks.owner_id, bookmarks.final_url, bookmarks.redirect_count, bookmarks.failure_count, bookmarks.failing_since, bookmarks.dead, bookmarks.next_check,
+	bookmarks.meta_title, bookmarks.meta_description, bookmarks.meta_image_url, bookmarks.canonical_url, bookmarks.meta_author, bookmarks.published_at, bookmarks.site_name, bookmarks.lang,
+	bookmarks.permanent_redirect, bookmarks.page_title,
+	(SELECT group_concat(tags.name) FROM bookmark_tags JOIN tags ON tags.id = bookmark_tags.tag_id WHERE bookmark_tags.bookmark_id = bookmarks.id) AS tags`
+
 func (b *Repository) scanRows(rows *sql.Rows) ([]*bookmarks.Bookmark, error) {
 	var list []*bookmarks.Bookmark
 	for rows.Next() {
@@ -96,11 +217,22 @@ func (b *Repository) scanRows(rows *sql.Rows) ([]*bookmarks.Bookmark, error) {
 	return list, nil
 }
 
-func (b *Repository) scanRow(row interface{ Scan(dest ...any) error }) (*bookmarks.Bookmark, error) {
+// scanRow reads one bookmark, and any extra column selected after
+// bookmarkColumns into extra.
+func (b *Repository) scanRow(row interface{ Scan(dest ...any) error }, extra ...any) (*bookmarks.Bookmark, error) {
 	bookmark := &bookmarks.Bookmark{}
-	if err := row.Scan(&bookmark.ID, &bookmark.URL, &bookmark.LastStatusCode, &bookmark.LastStatusCheck, &bookmark.LastStatusReason, &bookmark.Title, &bookmark.CreatedAt, &bookmark.Inbox, &bookmark.Description, &bookmark.BumpDate); err != nil {
+	var tags sql.NullString
+	dest := []any{&bookmark.ID, &bookmark.URL, &bookmark.LastStatusCode, &bookmark.LastStatusCheck, &bookmark.LastStatusReason, &bookmark.Title, &bookmark.CreatedAt, &bookmark.Inbox, &bookmark.Description, &bookmark.BumpDate, &bookmark.OwnerID, &bookmark.FinalURL, &bookmark.RedirectCount, &bookmark.FailureCount, &bookmark.FailingSince, &bookmark.Dead, &bookmark.NextCheck,
+		&bookmark.Metadata.Title, &bookmark.Metadata.Description, &bookmark.Metadata.ImageURL, &bookmark.Metadata.CanonicalURL, &bookmark.Metadata.Author, &bookmark.Metadata.PublishedAt, &bookmark.Metadata.SiteName, &bookmark.Metadata.Language,
+		&bookmark.PermanentRedirect, &bookmark.PageTitle,
+		&tags}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
 		return nil, err
 	}
+	if tags.String != "" {
+		bookmark.Tags = strings.Split(tags.String, ",")
+		slices.Sort(bookmark.Tags)
+	}
 	u, err := url.Parse(bookmark.URL)
 	if err == nil {
 		bookmark.Host = u.Host
@@ -108,61 +240,177 @@ func (b *Repository) scanRow(row interface{ Scan(dest ...any) error }) (*bookmar
 	return bookmark, nil
 }
 
-const pageSize = 1000
+// position is the decoded form of a cursor: the sort keys of the last
+// bookmark of a page.
+type position struct {
+	Date string `json:"d"`
+	ID   int64  `json:"i"`
+	URL  string `json:"u,omitempty"`
+}
+
+// dateKey formats t the same way the database driver stores it, so that the
+// keyset comparisons are made against identical representations.
+func dateKey(t time.Time) string {
+	return t.Round(0).String()
+}
+
+func encodeCursor(p position) bookmarks.Cursor {
+	b, _ := json.Marshal(p)
+	return bookmarks.Cursor(base64.RawURLEncoding.EncodeToString(b))
+}
 
-func (b *Repository) Inbox(page int) ([]*bookmarks.Bookmark, error) {
-	rows, err := b.db.Query(`SELECT id, url, last_status_code, last_status_check, last_status_reason, title, created_at, inbox, description, bump_date FROM bookmarks WHERE inbox = 1 ORDER BY bump_date DESC, id DESC LIMIT $1 OFFSET $2`, pageSize, page*pageSize)
+func decodeCursor(c bookmarks.Cursor) (*position, error) {
+	if c == "" {
+		return nil, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(string(c))
 	if err != nil {
-		return nil, err
+		return nil, bookmarks.ErrInvalidCursor
 	}
-	return b.scanRows(rows)
+	p := &position{}
+	if err := json.Unmarshal(b, p); err != nil || p.Date == "" {
+		return nil, bookmarks.ErrInvalidCursor
+	}
+	return p, nil
 }
 
-func (b *Repository) Duplicated(page int) ([]*bookmarks.Bookmark, error) {
-	rows, err := b.db.Query(`SELECT id, url, last_status_code, last_status_check, last_status_reason, title, created_at, inbox, description, bump_date FROM bookmarks WHERE url IN (SELECT url FROM bookmarks GROUP BY url HAVING count(url) > 1) ORDER BY url, created_at DESC LIMIT $1 OFFSET $2`, pageSize, page*pageSize)
+// listPage runs a keyset paginated listing. The query must end with a WHERE
+// clause predicate, into which keyset is appended if there is a cursor, and
+// is followed by order. The next cursor is built from the last row with key.
+func (b *Repository) listPage(query, keyset, order string, after bookmarks.Cursor, limit int, key func(*bookmarks.Bookmark) position, args ...any) ([]*bookmarks.Bookmark, bookmarks.Cursor, error) {
+	p, err := decodeCursor(after)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	return b.scanRows(rows)
+	if p != nil {
+		query += " AND " + keyset
+		args = append(args, sql.Named("date", p.Date), sql.Named("id", p.ID), sql.Named("url", p.URL))
+	}
+	args = append(args, sql.Named("limit", limit))
+	rows, err := b.db.Query(query+" ORDER BY "+order+" LIMIT :limit", args...)
+	if err != nil {
+		return nil, "", err
+	}
+	list, err := b.scanRows(rows)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(list) < limit {
+		return list, "", nil
+	}
+	return list, encodeCursor(key(list[len(list)-1])), nil
 }
 
-func (b *Repository) Dead(page int) ([]*bookmarks.Bookmark, error) {
-	rows, err := b.db.Query(`SELECT id, url, last_status_code, last_status_check, last_status_reason, title, created_at, inbox, description, bump_date FROM bookmarks WHERE NOT (last_status_code == 200 OR last_status_code == 0) ORDER BY created_at DESC, last_status_code DESC, id DESC LIMIT $1 OFFSET $2`, pageSize, page*pageSize)
-	if err != nil {
-		return nil, err
+func byBumpDate(bookmark *bookmarks.Bookmark) position {
+	return position{Date: dateKey(bookmark.BumpDate), ID: bookmark.ID}
+}
+
+func byCreatedAt(bookmark *bookmarks.Bookmark) position {
+	return position{Date: dateKey(bookmark.CreatedAt), ID: bookmark.ID}
+}
+
+// byDuplicateKey sorts bookmarks by what makes them duplicates: their
+// canonical URL, or their URL if the page does not have one.
+func byDuplicateKey(bookmark *bookmarks.Bookmark) position {
+	key := bookmark.Metadata.CanonicalURL
+	if key == "" {
+		key = bookmark.URL
 	}
-	return b.scanRows(rows)
+	return position{Date: dateKey(bookmark.CreatedAt), ID: bookmark.ID, URL: key}
 }
 
-func (b *Repository) All(page int) ([]*bookmarks.Bookmark, error) {
-	rows, err := b.db.Query(`SELECT id, url, last_status_code, last_status_check, last_status_reason, title, created_at, inbox, description, bump_date FROM bookmarks ORDER BY bump_date DESC LIMIT $1 OFFSET $2`, pageSize, page*pageSize)
-	if err != nil {
-		return nil, err
+const (
+	duplicateKey    = `coalesce(nullif(canonical_url, ''), url)`
+	bumpDateKeyset  = `(bump_date, id) < (:date, :id)`
+	bumpDateOrder   = `bump_date DESC, id DESC`
+	createdAtKeyset = `(created_at, id) < (:date, :id)`
+	createdAtOrder  = `created_at DESC, id DESC`
+)
+
+func (b *Repository) Inbox(ownerID int64, after bookmarks.Cursor, limit int) ([]*bookmarks.Bookmark, bookmarks.Cursor, error) {
+	return b.listPage(`SELECT `+bookmarkColumns+` FROM bookmarks WHERE owner_id = :owner AND inbox = 1`, bumpDateKeyset, bumpDateOrder, after, limit, byBumpDate, sql.Named("owner", ownerID))
+}
+
+func (b *Repository) Duplicated(ownerID int64, after bookmarks.Cursor, limit int) ([]*bookmarks.Bookmark, bookmarks.Cursor, error) {
+	return b.listPage(
+		`SELECT `+bookmarkColumns+` FROM bookmarks WHERE owner_id = :owner AND `+duplicateKey+` IN (SELECT `+duplicateKey+` FROM bookmarks WHERE owner_id = :owner GROUP BY 1 HAVING count(*) > 1)`,
+		`(`+duplicateKey+` > :url OR (`+duplicateKey+` = :url AND (created_at, id) < (:date, :id)))`,
+		duplicateKey+`, created_at DESC, id DESC`,
+		after, limit, byDuplicateKey, sql.Named("owner", ownerID),
+	)
+}
+
+func (b *Repository) Dead(ownerID int64, filter bookmarks.DeadFilter, after bookmarks.Cursor, limit int) ([]*bookmarks.Bookmark, bookmarks.Cursor, error) {
+	query := `SELECT ` + bookmarkColumns + ` FROM bookmarks WHERE owner_id = :owner AND dead = 1`
+	switch filter {
+	case bookmarks.SoftDead:
+		query += ` AND last_status_code = :soft`
+	case bookmarks.HardDead:
+		query += ` AND last_status_code != :soft`
+	}
+	return b.listPage(query, createdAtKeyset, createdAtOrder, after, limit, byCreatedAt, sql.Named("owner", ownerID), sql.Named("soft", bookmarks.StatusSoftFailure))
+}
+
+func (b *Repository) Moved(ownerID int64, after bookmarks.Cursor, limit int) ([]*bookmarks.Bookmark, bookmarks.Cursor, error) {
+	return b.listPage(`SELECT `+bookmarkColumns+` FROM bookmarks WHERE owner_id = :owner AND final_url != '' AND final_url != url`, createdAtKeyset, createdAtOrder, after, limit, byCreatedAt, sql.Named("owner", ownerID))
+}
+
+func (b *Repository) All(ownerID int64, after bookmarks.Cursor, limit int) ([]*bookmarks.Bookmark, bookmarks.Cursor, error) {
+	return b.listPage(`SELECT `+bookmarkColumns+` FROM bookmarks WHERE owner_id = :owner`, bumpDateKeyset, bumpDateOrder, after, limit, byBumpDate, sql.Named("owner", ownerID))
+}
+
+// walkBatchSize is how many bookmarks Walk reads at a time. Reading in
+// batches keeps slow consumers from holding the database connection.
+const walkBatchSize = 500
+
+func (b *Repository) Walk(ownerID int64, fn func(*bookmarks.Bookmark) error) error {
+	var after bookmarks.Cursor
+	for {
+		list, next, err := b.listPage(`SELECT `+bookmarkColumns+` FROM bookmarks WHERE owner_id = :owner`, `(created_at, id) > (:date, :id)`, `created_at, id`, after, walkBatchSize, byCreatedAt, sql.Named("owner", ownerID))
+		if err != nil {
+			return err
+		}
+		for _, bookmark := range list {
+			if err := fn(bookmark); err != nil {
+				return err
+			}
+		}
+		if next == "" {
+			return nil
+		}
+		after = next
 	}
-	return b.scanRows(rows)
 }
 
 func (b *Repository) Expired() ([]*bookmarks.Bookmark, error) {
-	const week = 7 * 24 * time.Hour
-	deadline := time.Now().Add(-week).Unix()
-	rows, err := b.db.Query(`SELECT id, url, last_status_code, last_status_check, last_status_reason, title, created_at, inbox, description, bump_date FROM bookmarks WHERE last_status_code IN (200,0) AND last_status_check <= $1`, deadline)
+	rows, err := b.db.Query(`SELECT `+bookmarkColumns+` FROM bookmarks WHERE next_check <= $1`, time.Now().Unix())
 	if err != nil {
 		return nil, err
 	}
 	return b.scanRows(rows)
 }
 
+// Insert stores a new bookmark. Dates are stored in UTC, so that they can be
+// compared with each other, and with search filters, as plain strings.
 func (b *Repository) Insert(bookmark *bookmarks.Bookmark) error {
-	now := time.Now()
-	bookmark.CreatedAt = now
-	bookmark.BumpDate = now
-	bookmark.Inbox = 1
+	if bookmark.CreatedAt.IsZero() {
+		bookmark.CreatedAt = time.Now()
+	}
+	if bookmark.BumpDate.IsZero() {
+		bookmark.BumpDate = bookmark.CreatedAt
+	}
 	result, err := b.db.Exec(`
 		INSERT INTO bookmarks
-		(url, last_status_code, last_status_check, last_status_reason, title, created_at, bump_date, inbox, description)
+		(url, last_status_code, last_status_check, last_status_reason, title, created_at, bump_date, inbox, description, owner_id, final_url, redirect_count, failure_count, failing_since, dead, next_check,
+		meta_title, meta_description, meta_image_url, canonical_url, meta_author, published_at, site_name, lang,
+		permanent_redirect, page_title)
 		VALUES
-		($1, $2, $3, $4, $5, $6, $7, $8, $9)
-	`, bookmark.URL, bookmark.LastStatusCode, bookmark.LastStatusCheck, bookmark.LastStatusReason, bookmark.Title, bookmark.CreatedAt, bookmark.BumpDate, bookmark.Inbox, bookmark.Description)
+		($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16,
+		$17, $18, $19, $20, $21, $22, $23, $24,
+		$25, $26)
+	`, bookmark.URL, bookmark.LastStatusCode, bookmark.LastStatusCheck, bookmark.LastStatusReason, bookmark.Title, bookmark.CreatedAt.UTC().Round(0), bookmark.BumpDate.UTC().Round(0), bookmark.Inbox, bookmark.Description, bookmark.OwnerID, bookmark.FinalURL, bookmark.RedirectCount, bookmark.FailureCount, bookmark.FailingSince, bookmark.Dead, bookmark.NextCheck,
+		bookmark.Metadata.Title, bookmark.Metadata.Description, bookmark.Metadata.ImageURL, bookmark.Metadata.CanonicalURL, bookmark.Metadata.Author, bookmark.Metadata.PublishedAt, bookmark.Metadata.SiteName, bookmark.Metadata.Language,
+		bookmark.PermanentRedirect, bookmark.PageTitle)
 	if err != nil {
 		return fmt.Errorf("cannot insert row: %w", err)
 	}
@@ -174,20 +422,31 @@ func (b *Repository) Insert(bookmark *bookmarks.Bookmark) error {
 	return nil
 }
 
-func (b *Repository) GetByID(id int64) (*bookmarks.Bookmark, error) {
+func (b *Repository) HasURL(ownerID int64, url string) (bool, error) {
+	var found bool
+	err := b.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM bookmarks WHERE owner_id = $1 AND url = $2)`, ownerID, url).Scan(&found)
+	return found, err
+}
+
+func (b *Repository) GetByID(ownerID, id int64) (*bookmarks.Bookmark, error) {
 	row := b.db.QueryRow(`
 	SELECT
-		id, url, last_status_code, last_status_check, last_status_reason, title, created_at, inbox, description, bump_date
+		`+bookmarkColumns+`
 	FROM
 		bookmarks
 	WHERE
 		id = $1
-	`, id)
-	return b.scanRow(row)
+		AND owner_id = $2
+	`, id, ownerID)
+	bookmark, err := b.scanRow(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, bookmarks.ErrNotFound
+	}
+	return bookmark, err
 }
 
 func (b *Repository) Update(bookmark *bookmarks.Bookmark) error {
-	_, err := b.db.Exec(`
+	result, err := b.db.Exec(`
 		UPDATE bookmarks
 		SET
 			url = $1,
@@ -197,46 +456,296 @@ func (b *Repository) Update(bookmark *bookmarks.Bookmark) error {
 			title = $5,
 			inbox = $6,
 			description = $7,
-			bump_date = $8
+			bump_date = $8,
+			final_url = $9,
+			redirect_count = $10,
+			failure_count = $11,
+			failing_since = $12,
+			dead = $13,
+			next_check = $14,
+			meta_title = $15,
+			meta_description = $16,
+			meta_image_url = $17,
+			canonical_url = $18,
+			meta_author = $19,
+			published_at = $20,
+			site_name = $21,
+			lang = $22,
+			permanent_redirect = $23,
+			page_title = $24
 		WHERE
-			id = $9
-	`, bookmark.URL, bookmark.LastStatusCode, bookmark.LastStatusCheck, bookmark.LastStatusReason, bookmark.Title, bookmark.Inbox, bookmark.Description, bookmark.BumpDate, bookmark.ID)
-	return err
+			id = $25
+			AND owner_id = $26
+	`, bookmark.URL, bookmark.LastStatusCode, bookmark.LastStatusCheck, bookmark.LastStatusReason, bookmark.Title, bookmark.Inbox, bookmark.Description, bookmark.BumpDate.UTC().Round(0), bookmark.FinalURL, bookmark.RedirectCount, bookmark.FailureCount, bookmark.FailingSince, bookmark.Dead, bookmark.NextCheck,
+		bookmark.Metadata.Title, bookmark.Metadata.Description, bookmark.Metadata.ImageURL, bookmark.Metadata.CanonicalURL, bookmark.Metadata.Author, bookmark.Metadata.PublishedAt, bookmark.Metadata.SiteName, bookmark.Metadata.Language,
+		bookmark.PermanentRedirect, bookmark.PageTitle,
+		bookmark.ID, bookmark.OwnerID)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(result)
+}
+
+func (b *Repository) DeleteByID(ownerID, id int64) error {
+	if _, err := b.db.Exec(`DELETE FROM bookmark_tags WHERE bookmark_id IN (SELECT id FROM bookmarks WHERE id = $1 AND owner_id = $2)`, id, ownerID); err != nil {
+		return err
+	}
+	if _, err := b.db.Exec(`DELETE FROM link_checks WHERE bookmark_id IN (SELECT id FROM bookmarks WHERE id = $1 AND owner_id = $2)`, id, ownerID); err != nil {
+		return err
+	}
+	result, err := b.db.Exec(`DELETE FROM bookmarks WHERE id = $1 AND owner_id = $2`, id, ownerID)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(result)
+}
+
+// expectOneRow reports whether the statement touched a bookmark.
+func expectOneRow(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("cannot count affected rows: %w", err)
+	}
+	if n == 0 {
+		return bookmarks.ErrNotFound
+	}
+	return nil
+}
+
+func (b *Repository) AddLinkCheck(check *bookmarks.LinkCheck) error {
+	_, err := b.db.Exec(`INSERT INTO link_checks (bookmark_id, checked_at, code, reason) VALUES ($1, $2, $3, $4)`, check.BookmarkID, check.CheckedAt, check.Code, check.Reason)
+	if err != nil {
+		return fmt.Errorf("cannot insert link check: %w", err)
+	}
+	return nil
+}
+
+func (b *Repository) LinkChecks(ownerID, id int64, limit int) ([]*bookmarks.LinkCheck, error) {
+	rows, err := b.db.Query(`
+		SELECT link_checks.bookmark_id, link_checks.checked_at, link_checks.code, link_checks.reason
+		FROM link_checks JOIN bookmarks ON bookmarks.id = link_checks.bookmark_id
+		WHERE link_checks.bookmark_id = $1 AND bookmarks.owner_id = $2
+		ORDER BY link_checks.checked_at DESC, link_checks.id DESC
+		LIMIT $3
+	`, id, ownerID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []*bookmarks.LinkCheck
+	for rows.Next() {
+		check := &bookmarks.LinkCheck{}
+		if err := rows.Scan(&check.BookmarkID, &check.CheckedAt, &check.Code, &check.Reason); err != nil {
+			return nil, err
+		}
+		list = append(list, check)
+	}
+	return list, rows.Err()
+}
+
+func (b *Repository) PruneLinkChecks(before time.Time) (int64, error) {
+	result, err := b.db.Exec(`DELETE FROM link_checks WHERE checked_at < $1`, before.Unix())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
-func (b *Repository) DeleteByID(id int64) error {
-	_, err := b.db.Exec(`DELETE FROM bookmarks WHERE id = $1`, id)
+func (b *Repository) AddTag(ownerID, id int64, tag string) error {
+	tx, err := b.db.Begin()
+	if err != nil {
+		return fmt.Errorf("cannot start transaction: %w", err)
+	}
+	defer tx.Rollback()
+	var found int64
+	err = tx.QueryRow(`SELECT id FROM bookmarks WHERE id = $1 AND owner_id = $2`, id, ownerID).Scan(&found)
+	if errors.Is(err, sql.ErrNoRows) {
+		return bookmarks.ErrNotFound
+	} else if err != nil {
+		return fmt.Errorf("cannot find bookmark: %w", err)
+	}
+	if _, err := tx.Exec(`INSERT INTO tags (name) VALUES ($1) ON CONFLICT (name) DO NOTHING`, tag); err != nil {
+		return fmt.Errorf("cannot create tag: %w", err)
+	}
+	if _, err := tx.Exec(`INSERT OR IGNORE INTO bookmark_tags (bookmark_id, tag_id) SELECT $1, id FROM tags WHERE name = $2`, id, tag); err != nil {
+		return fmt.Errorf("cannot tag bookmark: %w", err)
+	}
+	return tx.Commit()
+}
+
+func (b *Repository) RemoveTag(ownerID, id int64, tag string) error {
+	_, err := b.db.Exec(`DELETE FROM bookmark_tags WHERE bookmark_id IN (SELECT id FROM bookmarks WHERE id = $1 AND owner_id = $2) AND tag_id IN (SELECT id FROM tags WHERE name = $3)`, id, ownerID, tag)
 	return err
 }
 
-func (b *Repository) Search(term string) ([]*bookmarks.Bookmark, error) {
-	explodedTerm := "%" + strings.Join(strings.Split(term, ""), "%") + "%"
+func (b *Repository) Tags(ownerID int64) ([]*bookmarks.Tag, error) {
 	rows, err := b.db.Query(`
 		SELECT
-			id, url, last_status_code, last_status_check, last_status_reason, title, created_at, inbox, description, bump_date
+			tags.name, count(bookmark_tags.bookmark_id)
 		FROM
-			bookmarks
+			tags
+			JOIN bookmark_tags ON bookmark_tags.tag_id = tags.id
+			JOIN bookmarks ON bookmarks.id = bookmark_tags.bookmark_id
 		WHERE
-			title LIKE $1 COLLATE NOCASE
-			OR
-			url LIKE $1 COLLATE NOCASE
-			OR
-			description LIKE $1 COLLATE NOCASE
+			bookmarks.owner_id = $1
+		GROUP BY
+			tags.name
 		ORDER BY
-			CASE
-				WHEN title = $2 THEN 3
-				WHEN title LIKE $3 THEN 2
-				WHEN title LIKE $4 THEN 1
-				WHEN title LIKE $5 THEN 1
-				ELSE 0
-			END DESC,
-			created_at DESC,
-			id DESC
-	`, explodedTerm, term, term+"%", "%"+term+"%", "%"+term)
+			tags.name
+	`, ownerID)
 	if err != nil {
 		return nil, err
 	}
-	return b.scanRows(rows)
+	defer rows.Close()
+	var list []*bookmarks.Tag
+	for rows.Next() {
+		tag := &bookmarks.Tag{}
+		if err := rows.Scan(&tag.Name, &tag.Count); err != nil {
+			return nil, err
+		}
+		list = append(list, tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (b *Repository) ByTag(ownerID int64, tag string, after bookmarks.Cursor, limit int) ([]*bookmarks.Bookmark, bookmarks.Cursor, error) {
+	return b.listPage(
+		`SELECT `+bookmarkColumns+` FROM bookmarks WHERE owner_id = :owner AND id IN (SELECT bookmark_id FROM bookmark_tags JOIN tags ON tags.id = bookmark_tags.tag_id WHERE tags.name = :tag)`,
+		bumpDateKeyset, bumpDateOrder, after, limit, byBumpDate, sql.Named("owner", ownerID), sql.Named("tag", tag),
+	)
+}
+
+// hostExpr extracts the lowercased host (and port, if any) from the bookmark
+// URL.
+const hostExpr = `lower(CASE
+	WHEN instr(substr(bookmarks.url, instr(bookmarks.url, '://') + 3), '/') > 0
+	THEN substr(substr(bookmarks.url, instr(bookmarks.url, '://') + 3), 1, instr(substr(bookmarks.url, instr(bookmarks.url, '://') + 3), '/') - 1)
+	ELSE substr(bookmarks.url, instr(bookmarks.url, '://') + 3)
+END)`
+
+// Search runs the query as a full-text search over titles, URLs and
+// descriptions, combined with the query filters. Results are ranked with
+// bm25 when the query has terms or phrases, or by creation date otherwise.
+// Along with the requested page, it returns the total number of matches.
+func (b *Repository) Search(ownerID int64, query *bookmarks.Query, page, limit int) ([]*bookmarks.Bookmark, int, error) {
+	var (
+		args       []any
+		conditions []string
+	)
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	conditions = append(conditions, "bookmarks.owner_id = "+arg(ownerID))
+	for _, site := range query.Sites {
+		p := arg(site)
+		conditions = append(conditions, "("+hostExpr+" = "+p+" OR "+hostExpr+" LIKE '%.' || "+p+")")
+	}
+	for _, site := range query.ExcludedSites {
+		p := arg(site)
+		conditions = append(conditions, "NOT ("+hostExpr+" = "+p+" OR "+hostExpr+" LIKE '%.' || "+p+")")
+	}
+	const taggedWith = `bookmarks.id IN (SELECT bookmark_id FROM bookmark_tags JOIN tags ON tags.id = bookmark_tags.tag_id WHERE tags.name = `
+	for _, tag := range query.Tags {
+		conditions = append(conditions, taggedWith+arg(tag)+")")
+	}
+	for _, tag := range query.ExcludedTags {
+		conditions = append(conditions, "NOT "+taggedWith+arg(tag)+")")
+	}
+	if query.Inbox != nil {
+		conditions = append(conditions, "bookmarks.inbox = "+arg(*query.Inbox))
+	}
+	if query.Dead {
+		conditions = append(conditions, "bookmarks.dead = 1")
+	}
+	if len(query.StatusCodes) > 0 {
+		var codes []string
+		for _, code := range query.StatusCodes {
+			codes = append(codes, arg(code))
+		}
+		conditions = append(conditions, "bookmarks.last_status_code IN ("+strings.Join(codes, ", ")+")")
+	}
+	if !query.Before.IsZero() {
+		conditions = append(conditions, "bookmarks.created_at < "+arg(query.Before.UTC()))
+	}
+	if !query.After.IsZero() {
+		conditions = append(conditions, "bookmarks.created_at >= "+arg(query.After.UTC()))
+	}
+	if excluded := ftsAny(query.Excluded); excluded != "" {
+		conditions = append(conditions, "bookmarks.id NOT IN (SELECT rowid FROM bookmarks_fts WHERE bookmarks_fts MATCH "+arg(excluded)+")")
+	}
+
+	from, order := "bookmarks", "bookmarks.created_at DESC, bookmarks.id DESC"
+	snippet := "''"
+	if match := ftsMatch(query.Terms, query.Phrases); match != "" {
+		from = "bookmarks_fts JOIN bookmarks ON bookmarks.id = bookmarks_fts.rowid"
+		order = "bm25(bookmarks_fts, 10.0, 5.0, 1.0), " + order
+		conditions = append([]string{"bookmarks_fts MATCH " + arg(match)}, conditions...)
+		snippet = "snippet(bookmarks_fts, -1, " + arg(bookmarks.SnippetMatchStart) + ", " + arg(bookmarks.SnippetMatchEnd) + ", '…', 16)"
+	}
+	where := "WHERE " + strings.Join(conditions, " AND ")
+
+	var total int
+	if err := b.db.QueryRow(`SELECT count(*) FROM `+from+` `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := b.db.Query(`
+		SELECT
+			`+bookmarkColumns+`,
+			`+snippet+`
+		FROM
+			`+from+`
+		`+where+`
+		ORDER BY
+			`+order+`
+		LIMIT `+arg(limit)+` OFFSET `+arg(page*limit), args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	var list []*bookmarks.Bookmark
+	for rows.Next() {
+		var snippet string
+		bookmark, err := b.scanRow(rows, &snippet)
+		if err != nil {
+			return nil, 0, err
+		}
+		bookmark.Snippet = snippet
+		list = append(list, bookmark)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+// ftsMatch builds a FTS5 query in which every term must be present, either
+// whole or as a prefix, and every phrase must be present verbatim.
+func ftsMatch(terms, phrases []string) string {
+	var parts []string
+	for _, term := range terms {
+		parts = append(parts, ftsQuote(term)+"*")
+	}
+	for _, phrase := range phrases {
+		parts = append(parts, ftsQuote(phrase))
+	}
+	return strings.Join(parts, " ")
+}
+
+// ftsAny builds a FTS5 query that matches any of the words or phrases.
+func ftsAny(words []string) string {
+	var parts []string
+	for _, word := range words {
+		parts = append(parts, ftsQuote(word))
+	}
+	return strings.Join(parts, " OR ")
+}
+
+func ftsQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
 }
 
 func (b *Repository) Vacuum(ctx context.Context) error {
@@ -18,11 +18,15 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"net/http"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
 	"cirello.io/alreadyread/pkg/bookmarks"
+	"cirello.io/alreadyread/pkg/bookmarks/linkhealth"
 	"github.com/DATA-DOG/go-sqlmock"
 	_ "modernc.org/sqlite" // SQLite3 driver
 )
@@ -68,6 +72,36 @@ func TestRepository_Bootstrap(t *testing.T) {
 			t.Error("unexpected error found (bootstrap should be idempotent):", err)
 		}
 	})
+	t.Run("failingLinks", func(t *testing.T) {
+		// Applies the migrations before the failure_count one, as an
+		// older version would have, with a link that failed its check.
+		db := newConn(t)
+		n := slices.Index(migrations, `alter table bookmarks add column final_url text not null default ''`)
+		if n < 0 {
+			t.Fatal("migration not found")
+		}
+		for _, cmd := range append(slices.Clone(migrations[:n]), `insert into bookmarks (url, last_status_code, last_status_check, title, created_at) values ('http://example.com/', 404, 1700000000, '', '2023-11-14 00:00:00')`) {
+			if _, err := db.Exec(cmd); err != nil {
+				t.Fatal("cannot apply migration:", err)
+			}
+		}
+		if _, err := db.Exec(fmt.Sprintf("PRAGMA user_version = %d", n-1)); err != nil {
+			t.Fatal("cannot set migration index:", err)
+		}
+		b := New(db)
+		if err := b.Bootstrap(); err != nil {
+			t.Fatal("cannot run bootstrap:", err)
+		}
+		var failureCount, failingSince int64
+		var dead bool
+		row := b.db.QueryRow("SELECT failure_count, failing_since, dead FROM bookmarks WHERE id = 1")
+		if err := row.Scan(&failureCount, &failingSince, &dead); err != nil {
+			t.Fatal("cannot load bookmark:", err)
+		}
+		if failureCount != 1 || failingSince != 1700000000 || dead {
+			t.Errorf("failing link not migrated as failing once: failureCount=%v failingSince=%v dead=%v", failureCount, failingSince, dead)
+		}
+	})
 }
 
 func TestRepository_basicCycle(t *testing.T) {
@@ -84,7 +118,7 @@ func TestRepository_basicCycle(t *testing.T) {
 		t.Fatal("cannot insert bookmark:", err)
 	}
 	t.Log("bookmark.ID:", inserted.ID)
-	loaded, err := b.GetByID(inserted.ID)
+	loaded, err := b.GetByID(0, inserted.ID)
 	if err != nil {
 		t.Fatal("cannot load bookmark:", err)
 	}
@@ -95,15 +129,30 @@ func TestRepository_basicCycle(t *testing.T) {
 		t.Fatalf("inserted and loaded rows do not match\n%#v\n%#v", inserted, loaded)
 	}
 	updated := &bookmarks.Bookmark{
-		ID:    loaded.ID,
-		Title: "new-title",
-		URL:   "https://newurl.com",
-		Inbox: bookmarks.NewLink,
+		ID:           loaded.ID,
+		Title:        "new-title",
+		URL:          "https://newurl.com",
+		Inbox:        bookmarks.NewLink,
+		FailureCount: 3,
+		FailingSince: 1700000000,
+		Dead:         true,
+		NextCheck:    1700086400,
+		PageTitle:    "page-title",
+		Metadata: bookmarks.Metadata{
+			Title:        "og-title",
+			Description:  "og-description",
+			ImageURL:     "https://newurl.com/image.png",
+			CanonicalURL: "https://newurl.com/",
+			Author:       "author",
+			PublishedAt:  1690000000,
+			SiteName:     "New URL",
+			Language:     "en",
+		},
 	}
 	if err := b.Update(updated); err != nil {
 		t.Fatal("cannot update bookmark:", err)
 	}
-	inbox, err := b.Inbox(0)
+	inbox, _, err := b.Inbox(0, "", bookmarks.DefaultPageSize)
 	if err != nil {
 		t.Fatal("cannot load inbox bookmarks:", err)
 	}
@@ -112,14 +161,20 @@ func TestRepository_basicCycle(t *testing.T) {
 	}
 	isUpdated := inbox[0].ID == updated.ID &&
 		inbox[0].Title == updated.Title &&
-		inbox[0].URL == updated.URL
+		inbox[0].URL == updated.URL &&
+		inbox[0].FailureCount == updated.FailureCount &&
+		inbox[0].FailingSince == updated.FailingSince &&
+		inbox[0].Dead == updated.Dead &&
+		inbox[0].NextCheck == updated.NextCheck &&
+		inbox[0].PageTitle == updated.PageTitle &&
+		inbox[0].Metadata == updated.Metadata
 	if !isUpdated {
 		t.Fatal("failed to update the bookmark")
 	}
-	if err := b.DeleteByID(inbox[0].ID); err != nil {
+	if err := b.DeleteByID(0, inbox[0].ID); err != nil {
 		t.Fatal("cannot delete bookmark:", err)
 	}
-	all, err := b.All(0)
+	all, _, err := b.All(0, "", bookmarks.DefaultPageSize)
 	if err != nil {
 		t.Fatal("cannot load all bookmarks:", err)
 	}
@@ -197,7 +252,7 @@ func TestRepository_Insert(t *testing.T) {
 			t.Fatal("cannot create mock:", err)
 		}
 		errDB := errors.New("bad DB")
-		mock.ExpectExec("INSERT INTO bookmarks").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnError(errDB)
+		mock.ExpectExec("INSERT INTO bookmarks").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnError(errDB)
 		if err := New(db).Insert(&bookmarks.Bookmark{}); !errors.Is(err, errDB) {
 			t.Error("expected error missing: ", err)
 		}
@@ -208,7 +263,7 @@ func TestRepository_Insert(t *testing.T) {
 			t.Fatal("cannot create mock:", err)
 		}
 		errResult := errors.New("bad result")
-		mock.ExpectExec("INSERT INTO bookmarks").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewErrorResult(errResult))
+		mock.ExpectExec("INSERT INTO bookmarks").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewErrorResult(errResult))
 		if err := New(db).Insert(&bookmarks.Bookmark{}); !errors.Is(err, errResult) {
 			t.Error("expected error missing: ", err)
 		}
@@ -221,9 +276,168 @@ func TestRepository_Insert(t *testing.T) {
 		if bookmark.ID == 0 {
 			t.Error("did not update bookmark ID")
 		}
+		if bookmark.CreatedAt.IsZero() || !bookmark.BumpDate.Equal(bookmark.CreatedAt) {
+			t.Error("did not set default dates:", bookmark.CreatedAt, bookmark.BumpDate)
+		}
+	})
+	t.Run("keepDates", func(t *testing.T) {
+		repository := setup(t)
+		created := time.Date(2015, 1, 2, 3, 4, 5, 0, time.UTC)
+		bookmark := &bookmarks.Bookmark{URL: "http://example.com", CreatedAt: created, Inbox: bookmarks.Read}
+		if err := repository.Insert(bookmark); err != nil {
+			t.Fatal("could not insert bookmark:", err)
+		}
+		loaded, err := repository.GetByID(0, bookmark.ID)
+		if err != nil {
+			t.Fatal("cannot load bookmark:", err)
+		}
+		if !loaded.CreatedAt.Equal(created) || !loaded.BumpDate.Equal(created) || loaded.Inbox != bookmarks.Read {
+			t.Errorf("unexpected bookmark: %#v", loaded)
+		}
+	})
+}
+
+func TestRepository_HasURL(t *testing.T) {
+	repository := setup(t)
+	if err := repository.Insert(&bookmarks.Bookmark{URL: "http://example.com", OwnerID: 1}); err != nil {
+		t.Fatal("could not insert bookmark:", err)
+	}
+	tests := []struct {
+		ownerID int64
+		url     string
+		want    bool
+	}{
+		{1, "http://example.com", true},
+		{1, "http://example.org", false},
+		{2, "http://example.com", false},
+	}
+	for _, tt := range tests {
+		got, err := repository.HasURL(tt.ownerID, tt.url)
+		if err != nil {
+			t.Fatal("cannot check URL:", err)
+		}
+		if got != tt.want {
+			t.Errorf("HasURL(%d, %q) = %v, want %v", tt.ownerID, tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestRepository_notFound(t *testing.T) {
+	repository := setup(t)
+	if _, err := repository.GetByID(0, 1); !errors.Is(err, bookmarks.ErrNotFound) {
+		t.Error("GetByID: expected error missing:", err)
+	}
+	if err := repository.Update(&bookmarks.Bookmark{ID: 1}); !errors.Is(err, bookmarks.ErrNotFound) {
+		t.Error("Update: expected error missing:", err)
+	}
+	if err := repository.DeleteByID(0, 1); !errors.Is(err, bookmarks.ErrNotFound) {
+		t.Error("DeleteByID: expected error missing:", err)
+	}
+	t.Run("badResult", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatal("cannot create mock:", err)
+		}
+		errResult := errors.New("bad result")
+		mock.ExpectExec("DELETE FROM bookmark_tags").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("DELETE FROM link_checks").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("DELETE FROM bookmarks").WillReturnResult(sqlmock.NewErrorResult(errResult))
+		if err := New(db).DeleteByID(0, 1); !errors.Is(err, errResult) {
+			t.Error("expected error missing: ", err)
+		}
 	})
 }
 
+func TestRepository_owners(t *testing.T) {
+	repository := setup(t)
+	const alice, bob = 1, 2
+	now := time.Now()
+	insert := func(ownerID int64, url, tag string) *bookmarks.Bookmark {
+		t.Helper()
+		bookmark := &bookmarks.Bookmark{
+			OwnerID:        ownerID,
+			URL:            url,
+			Title:          "banana",
+			Inbox:          bookmarks.NewLink,
+			LastStatusCode: 500,
+			Dead:           true,
+			CreatedAt:      now,
+			BumpDate:       now,
+		}
+		if err := repository.Insert(bookmark); err != nil {
+			t.Fatal("cannot insert bookmark:", err)
+		}
+		if err := repository.AddTag(ownerID, bookmark.ID, tag); err != nil {
+			t.Fatal("cannot tag bookmark:", err)
+		}
+		return bookmark
+	}
+	alicesFirst := insert(alice, "http://example.com/shared", "alice")
+	insert(alice, "http://example.com/shared", "alice")
+	bobs := insert(bob, "http://example.com/shared", "bob")
+
+	dead := func(ownerID int64, after bookmarks.Cursor, limit int) ([]*bookmarks.Bookmark, bookmarks.Cursor, error) {
+		return repository.Dead(ownerID, bookmarks.AllDead, after, limit)
+	}
+	for name, list := range map[string]func(int64, bookmarks.Cursor, int) ([]*bookmarks.Bookmark, bookmarks.Cursor, error){
+		"inbox":      repository.Inbox,
+		"all":        repository.All,
+		"dead":       dead,
+		"duplicated": repository.Duplicated,
+	} {
+		found, _, err := list(bob, "", bookmarks.DefaultPageSize)
+		if err != nil {
+			t.Fatal(name, "cannot list bookmarks:", err)
+		}
+		if name == "duplicated" {
+			if len(found) != 0 {
+				t.Errorf("%s: duplicates must not span owners: %v", name, found)
+			}
+			continue
+		}
+		if len(found) != 1 || found[0].ID != bobs.ID || found[0].OwnerID != bob {
+			t.Errorf("%s: unexpected bookmarks: %v", name, found)
+		}
+	}
+	if found, _, err := repository.Duplicated(alice, "", bookmarks.DefaultPageSize); err != nil || len(found) != 2 {
+		t.Error("unexpected duplicates:", found, err)
+	}
+	if found, total, err := repository.Search(bob, &bookmarks.Query{Terms: []string{"banana"}}, 0, bookmarks.DefaultPageSize); err != nil || total != 1 || len(found) != 1 || found[0].ID != bobs.ID {
+		t.Error("unexpected search results:", found, total, err)
+	}
+	if found, _, err := repository.ByTag(bob, "alice", "", bookmarks.DefaultPageSize); err != nil || len(found) != 0 {
+		t.Error("unexpected tagged bookmarks:", found, err)
+	}
+	if tags, err := repository.Tags(bob); err != nil || len(tags) != 1 || tags[0].Name != "bob" {
+		t.Error("unexpected tags:", tags, err)
+	}
+
+	if _, err := repository.GetByID(bob, alicesFirst.ID); !errors.Is(err, bookmarks.ErrNotFound) {
+		t.Error("GetByID: expected error missing:", err)
+	}
+	stolen := *alicesFirst
+	stolen.OwnerID = bob
+	if err := repository.Update(&stolen); !errors.Is(err, bookmarks.ErrNotFound) {
+		t.Error("Update: expected error missing:", err)
+	}
+	if err := repository.AddTag(bob, alicesFirst.ID, "bob"); !errors.Is(err, bookmarks.ErrNotFound) {
+		t.Error("AddTag: expected error missing:", err)
+	}
+	if err := repository.RemoveTag(bob, alicesFirst.ID, "alice"); err != nil {
+		t.Error("RemoveTag: unexpected error:", err)
+	}
+	if err := repository.DeleteByID(bob, alicesFirst.ID); !errors.Is(err, bookmarks.ErrNotFound) {
+		t.Error("DeleteByID: expected error missing:", err)
+	}
+	loaded, err := repository.GetByID(alice, alicesFirst.ID)
+	if err != nil {
+		t.Fatal("cannot load bookmark:", err)
+	}
+	if len(loaded.Tags) != 1 || loaded.Tags[0] != "alice" {
+		t.Error("tags changed by another owner:", loaded.Tags)
+	}
+}
+
 func TestRepository_Inbox(t *testing.T) {
 	t.Run("badDB", func(t *testing.T) {
 		db, mock, err := sqlmock.New()
@@ -232,7 +446,7 @@ func TestRepository_Inbox(t *testing.T) {
 		}
 		errDB := errors.New("bad DB")
 		mock.ExpectQuery("SELECT").WillReturnError(errDB)
-		if _, err := New(db).Inbox(0); !errors.Is(err, errDB) {
+		if _, _, err := New(db).Inbox(0, "", bookmarks.DefaultPageSize); !errors.Is(err, errDB) {
 			t.Error("expected error missing: ", err)
 		}
 	})
@@ -242,7 +456,7 @@ func TestRepository_Inbox(t *testing.T) {
 		if err := repository.Insert(bookmark); err != nil {
 			t.Fatal("could not insert bookmark:", err)
 		}
-		found, err := repository.Inbox(0)
+		found, _, err := repository.Inbox(0, "", bookmarks.DefaultPageSize)
 		if err != nil {
 			t.Fatal("cannot list bookmarks:", err)
 		}
@@ -263,7 +477,7 @@ func TestRepository_Duplicated(t *testing.T) {
 		}
 		errDB := errors.New("bad DB")
 		mock.ExpectQuery("SELECT").WillReturnError(errDB)
-		if _, err := New(db).Duplicated(0); !errors.Is(err, errDB) {
+		if _, _, err := New(db).Duplicated(0, "", bookmarks.DefaultPageSize); !errors.Is(err, errDB) {
 			t.Error("expected error missing: ", err)
 		}
 	})
@@ -276,7 +490,7 @@ func TestRepository_Duplicated(t *testing.T) {
 		if err := repository.Insert(bookmark); err != nil {
 			t.Fatal("could not insert bookmark:", err)
 		}
-		found, err := repository.Duplicated(0)
+		found, _, err := repository.Duplicated(0, "", bookmarks.DefaultPageSize)
 		if err != nil {
 			t.Fatal("cannot list bookmarks:", err)
 		}
@@ -287,6 +501,36 @@ func TestRepository_Duplicated(t *testing.T) {
 			t.Fatal("did not find expected bookmark")
 		}
 	})
+	t.Run("canonical", func(t *testing.T) {
+		repository := setup(t)
+		for _, bookmark := range []*bookmarks.Bookmark{
+			{URL: "https://example.com/a?utm_source=feed", Metadata: bookmarks.Metadata{CanonicalURL: "https://example.com/a"}},
+			{URL: "https://example.com/b", Metadata: bookmarks.Metadata{CanonicalURL: "https://example.com/c"}},
+			{URL: "https://example.com/a"},
+			{URL: "https://example.com/b"},
+		} {
+			if err := repository.Insert(bookmark); err != nil {
+				t.Fatal("could not insert bookmark:", err)
+			}
+		}
+		var found []string
+		for cursor := bookmarks.Cursor(""); ; {
+			page, next, err := repository.Duplicated(0, cursor, 1)
+			if err != nil {
+				t.Fatal("cannot list bookmarks:", err)
+			}
+			for _, bookmark := range page {
+				found = append(found, bookmark.URL)
+			}
+			if next == "" {
+				break
+			}
+			cursor = next
+		}
+		if want := []string{"https://example.com/a", "https://example.com/a?utm_source=feed"}; !slices.Equal(found, want) {
+			t.Errorf("unexpected duplicates: %v, want %v", found, want)
+		}
+	})
 }
 
 func TestRepository_Dead(t *testing.T) {
@@ -297,26 +541,28 @@ func TestRepository_Dead(t *testing.T) {
 		}
 		errDB := errors.New("bad DB")
 		mock.ExpectQuery("SELECT").WillReturnError(errDB)
-		if _, err := New(db).Dead(0); !errors.Is(err, errDB) {
+		if _, _, err := New(db).Dead(0, bookmarks.AllDead, "", bookmarks.DefaultPageSize); !errors.Is(err, errDB) {
 			t.Error("expected error missing: ", err)
 		}
 	})
 	t.Run("good", func(t *testing.T) {
 		repository := setup(t)
+		filter := bookmarks.AllDead
 		bookmarks := []*bookmarks.Bookmark{
-			{URL: "http://example.com", LastStatusCode: 400},
-			{URL: "http://example.com", LastStatusCode: 500},
+			{URL: "http://example.com", LastStatusCode: 400, Dead: true},
+			{URL: "http://example.com", LastStatusCode: 500, Dead: true},
+			{URL: "http://example.com", LastStatusCode: 500, FailureCount: 1},
 		}
 		for _, bookmark := range bookmarks {
 			if err := repository.Insert(bookmark); err != nil {
 				t.Fatal("could not insert bookmark:", err)
 			}
 		}
-		found, err := repository.Dead(0)
+		found, _, err := repository.Dead(0, filter, "", len(bookmarks))
 		if err != nil {
 			t.Fatal("cannot list bookmarks:", err)
 		}
-		if len(found) != len(bookmarks) {
+		if len(found) != 2 {
 			t.Fatal("unexpected bookmark count")
 		}
 		if found[0].ID != bookmarks[1].ID {
@@ -326,6 +572,77 @@ func TestRepository_Dead(t *testing.T) {
 			t.Fatal("did not find expected bookmark")
 		}
 	})
+	t.Run("filter", func(t *testing.T) {
+		repository := setup(t)
+		hard := &bookmarks.Bookmark{URL: "http://example.com/gone", LastStatusCode: 404, Dead: true}
+		soft := &bookmarks.Bookmark{URL: "http://example.com/parked", LastStatusCode: bookmarks.StatusSoftFailure, Dead: true}
+		for _, bookmark := range []*bookmarks.Bookmark{hard, soft} {
+			if err := repository.Insert(bookmark); err != nil {
+				t.Fatal("could not insert bookmark:", err)
+			}
+		}
+		for filter, want := range map[bookmarks.DeadFilter][]int64{
+			bookmarks.AllDead:  {soft.ID, hard.ID},
+			bookmarks.SoftDead: {soft.ID},
+			bookmarks.HardDead: {hard.ID},
+		} {
+			found, _, err := repository.Dead(0, filter, "", bookmarks.DefaultPageSize)
+			if err != nil {
+				t.Fatal("cannot list bookmarks:", err)
+			}
+			var got []int64
+			for _, bookmark := range found {
+				got = append(got, bookmark.ID)
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("%q: unexpected bookmarks: %v, want %v", filter, got, want)
+			}
+		}
+	})
+}
+
+func TestRepository_Moved(t *testing.T) {
+	t.Run("badDB", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatal("cannot create mock:", err)
+		}
+		errDB := errors.New("bad DB")
+		mock.ExpectQuery("SELECT").WillReturnError(errDB)
+		if _, _, err := New(db).Moved(0, "", bookmarks.DefaultPageSize); !errors.Is(err, errDB) {
+			t.Error("expected error missing: ", err)
+		}
+	})
+	t.Run("good", func(t *testing.T) {
+		repository := setup(t)
+		moved := &bookmarks.Bookmark{URL: "http://example.com", FinalURL: "https://example.com/", RedirectCount: 2, PermanentRedirect: true}
+		for _, bookmark := range []*bookmarks.Bookmark{
+			moved,
+			{URL: "http://example.org"},
+			{URL: "http://example.net", FinalURL: "http://example.net", RedirectCount: 1},
+		} {
+			if err := repository.Insert(bookmark); err != nil {
+				t.Fatal("could not insert bookmark:", err)
+			}
+		}
+		found, _, err := repository.Moved(0, "", bookmarks.DefaultPageSize)
+		if err != nil {
+			t.Fatal("cannot list bookmarks:", err)
+		}
+		if len(found) != 1 || found[0].ID != moved.ID || found[0].FinalURL != moved.FinalURL || found[0].RedirectCount != 2 || !found[0].PermanentRedirect {
+			t.Fatalf("did not find expected bookmark: %#v", found)
+		}
+		moved.URL, moved.FinalURL, moved.RedirectCount, moved.PermanentRedirect = moved.FinalURL, "", 0, false
+		if err := repository.Update(moved); err != nil {
+			t.Fatal("cannot update bookmark:", err)
+		}
+		if found, _, err := repository.Moved(0, "", bookmarks.DefaultPageSize); err != nil || len(found) != 0 {
+			t.Fatal("unexpected moved bookmarks:", found, err)
+		}
+		if found, err := repository.GetByID(0, moved.ID); err != nil || found.PermanentRedirect {
+			t.Fatal("permanent redirect not cleared:", found, err)
+		}
+	})
 }
 
 func TestRepository_All(t *testing.T) {
@@ -336,7 +653,7 @@ func TestRepository_All(t *testing.T) {
 		}
 		errDB := errors.New("bad DB")
 		mock.ExpectQuery("SELECT").WillReturnError(errDB)
-		if _, err := New(db).All(0); !errors.Is(err, errDB) {
+		if _, _, err := New(db).All(0, "", bookmarks.DefaultPageSize); !errors.Is(err, errDB) {
 			t.Error("expected error missing: ", err)
 		}
 	})
@@ -349,7 +666,7 @@ func TestRepository_All(t *testing.T) {
 		if err := repository.Insert(bookmark); err != nil {
 			t.Fatal("could not insert bookmark:", err)
 		}
-		found, err := repository.All(0)
+		found, _, err := repository.All(0, "", bookmarks.DefaultPageSize)
 		if err != nil {
 			t.Fatal("cannot list bookmarks:", err)
 		}
@@ -360,6 +677,92 @@ func TestRepository_All(t *testing.T) {
 			t.Fatal("did not find expected bookmark")
 		}
 	})
+	t.Run("canonical", func(t *testing.T) {
+		repository := setup(t)
+		for _, bookmark := range []*bookmarks.Bookmark{
+			{URL: "https://example.com/a?utm_source=feed", Metadata: bookmarks.Metadata{CanonicalURL: "https://example.com/a"}},
+			{URL: "https://example.com/b", Metadata: bookmarks.Metadata{CanonicalURL: "https://example.com/c"}},
+			{URL: "https://example.com/a"},
+			{URL: "https://example.com/b"},
+		} {
+			if err := repository.Insert(bookmark); err != nil {
+				t.Fatal("could not insert bookmark:", err)
+			}
+		}
+		var found []string
+		for cursor := bookmarks.Cursor(""); ; {
+			page, next, err := repository.Duplicated(0, cursor, 1)
+			if err != nil {
+				t.Fatal("cannot list bookmarks:", err)
+			}
+			for _, bookmark := range page {
+				found = append(found, bookmark.URL)
+			}
+			if next == "" {
+				break
+			}
+			cursor = next
+		}
+		if want := []string{"https://example.com/a", "https://example.com/a?utm_source=feed"}; !slices.Equal(found, want) {
+			t.Errorf("unexpected duplicates: %v, want %v", found, want)
+		}
+	})
+}
+
+func TestRepository_Walk(t *testing.T) {
+	t.Run("badDB", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatal("cannot create mock:", err)
+		}
+		errDB := errors.New("bad DB")
+		mock.ExpectQuery("SELECT").WillReturnError(errDB)
+		if err := New(db).Walk(0, func(*bookmarks.Bookmark) error { return nil }); !errors.Is(err, errDB) {
+			t.Error("expected error missing: ", err)
+		}
+	})
+	repository := setup(t)
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	// Enough bookmarks to need more than one batch, inserted newest first.
+	total := walkBatchSize + 1
+	for i := total - 1; i >= 0; i-- {
+		bookmark := &bookmarks.Bookmark{URL: fmt.Sprint("http://example.com/", i), OwnerID: 1, CreatedAt: start.Add(time.Duration(i) * time.Hour)}
+		if err := repository.Insert(bookmark); err != nil {
+			t.Fatal("could not insert bookmark:", err)
+		}
+	}
+	if err := repository.Insert(&bookmarks.Bookmark{URL: "http://example.org", OwnerID: 2}); err != nil {
+		t.Fatal("could not insert bookmark:", err)
+	}
+	t.Run("good", func(t *testing.T) {
+		var found []*bookmarks.Bookmark
+		err := repository.Walk(1, func(bookmark *bookmarks.Bookmark) error {
+			found = append(found, bookmark)
+			return nil
+		})
+		if err != nil {
+			t.Fatal("cannot walk bookmarks:", err)
+		}
+		if len(found) != total {
+			t.Fatal("unexpected bookmark count:", len(found))
+		}
+		for i, bookmark := range found {
+			if want := fmt.Sprint("http://example.com/", i); bookmark.URL != want || bookmark.OwnerID != 1 {
+				t.Fatalf("unexpected bookmark %d: %s (owner %d), want %s", i, bookmark.URL, bookmark.OwnerID, want)
+			}
+		}
+	})
+	t.Run("stop", func(t *testing.T) {
+		errStop := errors.New("stop")
+		calls := 0
+		err := repository.Walk(1, func(*bookmarks.Bookmark) error {
+			calls++
+			return errStop
+		})
+		if !errors.Is(err, errStop) || calls != 1 {
+			t.Error("walk did not stop:", calls, err)
+		}
+	})
 }
 
 func TestRepository_Expired(t *testing.T) {
@@ -376,9 +779,13 @@ func TestRepository_Expired(t *testing.T) {
 	})
 	t.Run("good", func(t *testing.T) {
 		repository := setup(t)
-		bookmark := &bookmarks.Bookmark{URL: "http://example.com", LastStatusCode: http.StatusOK, LastStatusCheck: time.Now().Add(-30 * 24 * time.Hour).Unix()}
-		if err := repository.Insert(bookmark); err != nil {
-			t.Fatal("could not insert bookmark:", err)
+		now := time.Now()
+		bookmark := &bookmarks.Bookmark{URL: "http://example.com", LastStatusCode: http.StatusOK, LastStatusCheck: now.Add(-30 * 24 * time.Hour).Unix(), NextCheck: now.Add(-time.Hour).Unix()}
+		notDue := &bookmarks.Bookmark{URL: "http://example.com/dead", LastStatusCode: http.StatusNotFound, Dead: true, NextCheck: now.Add(time.Hour).Unix()}
+		for _, b := range []*bookmarks.Bookmark{bookmark, notDue} {
+			if err := repository.Insert(b); err != nil {
+				t.Fatal("could not insert bookmark:", err)
+			}
 		}
 		found, err := repository.Expired()
 		if err != nil {
@@ -391,6 +798,150 @@ func TestRepository_Expired(t *testing.T) {
 			t.Fatal("did not find expected bookmark")
 		}
 	})
+	t.Run("canonical", func(t *testing.T) {
+		repository := setup(t)
+		for _, bookmark := range []*bookmarks.Bookmark{
+			{URL: "https://example.com/a?utm_source=feed", Metadata: bookmarks.Metadata{CanonicalURL: "https://example.com/a"}},
+			{URL: "https://example.com/b", Metadata: bookmarks.Metadata{CanonicalURL: "https://example.com/c"}},
+			{URL: "https://example.com/a"},
+			{URL: "https://example.com/b"},
+		} {
+			if err := repository.Insert(bookmark); err != nil {
+				t.Fatal("could not insert bookmark:", err)
+			}
+		}
+		var found []string
+		for cursor := bookmarks.Cursor(""); ; {
+			page, next, err := repository.Duplicated(0, cursor, 1)
+			if err != nil {
+				t.Fatal("cannot list bookmarks:", err)
+			}
+			for _, bookmark := range page {
+				found = append(found, bookmark.URL)
+			}
+			if next == "" {
+				break
+			}
+			cursor = next
+		}
+		if want := []string{"https://example.com/a", "https://example.com/a?utm_source=feed"}; !slices.Equal(found, want) {
+			t.Errorf("unexpected duplicates: %v, want %v", found, want)
+		}
+	})
+}
+
+func TestRepository_linkChecks(t *testing.T) {
+	t.Run("badDB", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatal("cannot create mock:", err)
+		}
+		errDB := errors.New("bad DB")
+		mock.ExpectExec("INSERT INTO link_checks").WillReturnError(errDB)
+		mock.ExpectQuery("SELECT").WillReturnError(errDB)
+		mock.ExpectExec("DELETE FROM link_checks").WillReturnError(errDB)
+		repository := New(db)
+		if err := repository.AddLinkCheck(&bookmarks.LinkCheck{BookmarkID: 1}); !errors.Is(err, errDB) {
+			t.Error("AddLinkCheck: expected error missing:", err)
+		}
+		if _, err := repository.LinkChecks(0, 1, 10); !errors.Is(err, errDB) {
+			t.Error("LinkChecks: expected error missing:", err)
+		}
+		if _, err := repository.PruneLinkChecks(time.Now()); !errors.Is(err, errDB) {
+			t.Error("PruneLinkChecks: expected error missing:", err)
+		}
+	})
+	t.Run("good", func(t *testing.T) {
+		const alice, bob = 1, 2
+		repository := setup(t)
+		bookmark := &bookmarks.Bookmark{URL: "http://example.com", OwnerID: alice}
+		other := &bookmarks.Bookmark{URL: "http://example.com/other", OwnerID: alice}
+		for _, b := range []*bookmarks.Bookmark{bookmark, other} {
+			if err := repository.Insert(b); err != nil {
+				t.Fatal("could not insert bookmark:", err)
+			}
+		}
+		for i, code := range []int64{http.StatusOK, http.StatusServiceUnavailable, http.StatusOK} {
+			check := &bookmarks.LinkCheck{BookmarkID: bookmark.ID, CheckedAt: int64(1000 * (i + 1)), Code: code, Reason: http.StatusText(int(code))}
+			if err := repository.AddLinkCheck(check); err != nil {
+				t.Fatal("cannot add link check:", err)
+			}
+		}
+		if err := repository.AddLinkCheck(&bookmarks.LinkCheck{BookmarkID: other.ID, CheckedAt: 500, Code: http.StatusOK}); err != nil {
+			t.Fatal("cannot add link check:", err)
+		}
+
+		found, err := repository.LinkChecks(alice, bookmark.ID, 2)
+		if err != nil {
+			t.Fatal("cannot load link checks:", err)
+		}
+		if len(found) != 2 || found[0].CheckedAt != 3000 || found[1].CheckedAt != 2000 || found[1].Code != http.StatusServiceUnavailable || found[1].Reason != "Service Unavailable" {
+			t.Fatalf("unexpected link checks: %#v", found)
+		}
+		if found, err := repository.LinkChecks(bob, bookmark.ID, 10); err != nil || len(found) != 0 {
+			t.Fatal("link checks must not span owners:", found, err)
+		}
+
+		pruned, err := repository.PruneLinkChecks(time.Unix(2000, 0))
+		if err != nil || pruned != 2 {
+			t.Fatal("unexpected pruning:", pruned, err)
+		}
+		if found, err := repository.LinkChecks(alice, bookmark.ID, 10); err != nil || len(found) != 2 {
+			t.Fatal("unexpected link checks after pruning:", found, err)
+		}
+
+		if err := repository.DeleteByID(alice, bookmark.ID); err != nil {
+			t.Fatal("cannot delete bookmark:", err)
+		}
+		var left int
+		if err := repository.db.QueryRow("SELECT count(*) FROM link_checks").Scan(&left); err != nil || left != 0 {
+			t.Fatal("link checks left behind:", left, err)
+		}
+	})
+}
+
+func TestRepository_linkHealthRuns(t *testing.T) {
+	t.Run("badDB", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatal("cannot create mock:", err)
+		}
+		errDB := errors.New("bad DB")
+		mock.ExpectQuery("SELECT").WillReturnError(errDB)
+		mock.ExpectExec("INSERT INTO link_health_runs").WillReturnError(errDB)
+		repository := New(db)
+		if _, err := repository.LastLinkHealthRun(); !errors.Is(err, errDB) {
+			t.Error("LastLinkHealthRun: expected error missing:", err)
+		}
+		if err := repository.SaveLinkHealthRun(linkhealth.Run{}); !errors.Is(err, errDB) {
+			t.Error("SaveLinkHealthRun: expected error missing:", err)
+		}
+	})
+	t.Run("good", func(t *testing.T) {
+		repository := setup(t)
+		if run, err := repository.LastLinkHealthRun(); err != nil || run != (linkhealth.Run{}) {
+			t.Fatal("unexpected run before any was saved:", run, err)
+		}
+		started := linkhealth.Run{StartedAt: time.Unix(1000, 0)}
+		if err := repository.SaveLinkHealthRun(started); err != nil {
+			t.Fatal("cannot save run:", err)
+		}
+		if run, err := repository.LastLinkHealthRun(); err != nil || !run.StartedAt.Equal(started.StartedAt) || !run.FinishedAt.IsZero() {
+			t.Fatal("unexpected started run:", run, err)
+		}
+		finished := linkhealth.Run{StartedAt: time.Unix(1000, 0), FinishedAt: time.Unix(2000, 0), Err: "bad DB"}
+		if err := repository.SaveLinkHealthRun(finished); err != nil {
+			t.Fatal("cannot save run:", err)
+		}
+		run, err := repository.LastLinkHealthRun()
+		if err != nil || !run.StartedAt.Equal(finished.StartedAt) || !run.FinishedAt.Equal(finished.FinishedAt) || run.Err != finished.Err {
+			t.Fatal("unexpected finished run:", run, err)
+		}
+		var count int
+		if err := repository.db.QueryRow("SELECT count(*) FROM link_health_runs").Scan(&count); err != nil || count != 1 {
+			t.Fatal("only the last run must be kept:", count, err)
+		}
+	})
 }
 
 func TestRepository_Search(t *testing.T) {
@@ -400,18 +951,88 @@ func TestRepository_Search(t *testing.T) {
 			t.Fatal("cannot create mock:", err)
 		}
 		errDB := errors.New("bad DB")
-		mock.ExpectQuery("SELECT").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnError(errDB)
-		if _, err := New(db).Search(""); !errors.Is(err, errDB) {
+		mock.ExpectQuery("SELECT count").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnError(errDB)
+		if _, _, err := New(db).Search(0, &bookmarks.Query{Terms: []string{"banana"}}, 0, bookmarks.DefaultPageSize); !errors.Is(err, errDB) {
 			t.Error("expected error missing: ", err)
 		}
 	})
+	t.Run("badDB/page", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatal("cannot create mock:", err)
+		}
+		errDB := errors.New("bad DB")
+		mock.ExpectQuery("SELECT count").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectQuery("SELECT").WillReturnError(errDB)
+		if _, _, err := New(db).Search(0, &bookmarks.Query{Terms: []string{"banana"}}, 0, bookmarks.DefaultPageSize); !errors.Is(err, errDB) {
+			t.Error("expected error missing: ", err)
+		}
+	})
+	t.Run("badDB/emptyTerm", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatal("cannot create mock:", err)
+		}
+		errDB := errors.New("bad DB")
+		mock.ExpectQuery("SELECT").WillReturnError(errDB)
+		if _, _, err := New(db).Search(0, &bookmarks.Query{}, 0, bookmarks.DefaultPageSize); !errors.Is(err, errDB) {
+			t.Error("expected error missing: ", err)
+		}
+	})
+	t.Run("ranking", func(t *testing.T) {
+		repository := setup(t)
+		inDescription := &bookmarks.Bookmark{URL: "http://example.com/1", Title: "unrelated", Description: "all about golang"}
+		inTitle := &bookmarks.Bookmark{URL: "http://example.com/2", Title: "The Golang Blog"}
+		notFound := &bookmarks.Bookmark{URL: "http://example.com/3", Title: "gopher", Description: "go lang"}
+		for _, bookmark := range []*bookmarks.Bookmark{inDescription, inTitle, notFound} {
+			if err := repository.Insert(bookmark); err != nil {
+				t.Fatal("could not insert bookmark:", err)
+			}
+		}
+		found, _, err := repository.Search(0, &bookmarks.Query{Terms: []string{"gola"}}, 0, bookmarks.DefaultPageSize)
+		if err != nil {
+			t.Fatal("cannot search bookmarks:", err)
+		}
+		if len(found) != 2 {
+			t.Fatal("unexpected bookmark count:", len(found))
+		}
+		if found[0].ID != inTitle.ID || found[1].ID != inDescription.ID {
+			t.Fatal("unexpected ranking")
+		}
+		if want := bookmarks.SnippetMatchStart + "golang" + bookmarks.SnippetMatchEnd; !strings.Contains(found[1].Snippet, want) {
+			t.Error("unexpected snippet:", found[1].Snippet)
+		}
+	})
+	t.Run("keptInSync", func(t *testing.T) {
+		repository := setup(t)
+		bookmark := &bookmarks.Bookmark{URL: "http://example.com", Title: "before"}
+		if err := repository.Insert(bookmark); err != nil {
+			t.Fatal("could not insert bookmark:", err)
+		}
+		bookmark.Title = "after"
+		if err := repository.Update(bookmark); err != nil {
+			t.Fatal("could not update bookmark:", err)
+		}
+		if found, _, err := repository.Search(0, &bookmarks.Query{Terms: []string{"before"}}, 0, bookmarks.DefaultPageSize); err != nil || len(found) != 0 {
+			t.Fatal("stale index entry found:", err, len(found))
+		}
+		if found, _, err := repository.Search(0, &bookmarks.Query{Terms: []string{"after"}}, 0, bookmarks.DefaultPageSize); err != nil || len(found) != 1 {
+			t.Fatal("updated entry not found:", err, len(found))
+		}
+		if err := repository.DeleteByID(0, bookmark.ID); err != nil {
+			t.Fatal("could not delete bookmark:", err)
+		}
+		if found, _, err := repository.Search(0, &bookmarks.Query{Terms: []string{"after"}}, 0, bookmarks.DefaultPageSize); err != nil || len(found) != 0 {
+			t.Fatal("deleted entry found:", err, len(found))
+		}
+	})
 	t.Run("good", func(t *testing.T) {
 		repository := setup(t)
 		bookmark := &bookmarks.Bookmark{URL: "http://example.com", LastStatusCode: http.StatusInternalServerError, LastStatusCheck: time.Now().Add(-30 * 24 * time.Hour).Unix()}
 		if err := repository.Insert(bookmark); err != nil {
 			t.Fatal("could not insert bookmark:", err)
 		}
-		found, err := repository.Search("example.com")
+		found, _, err := repository.Search(0, &bookmarks.Query{Terms: []string{"example.com"}}, 0, bookmarks.DefaultPageSize)
 		if err != nil {
 			t.Fatal("cannot list bookmarks:", err)
 		}
@@ -422,6 +1043,342 @@ func TestRepository_Search(t *testing.T) {
 			t.Fatal("did not find expected bookmark")
 		}
 	})
+	t.Run("canonical", func(t *testing.T) {
+		repository := setup(t)
+		for _, bookmark := range []*bookmarks.Bookmark{
+			{URL: "https://example.com/a?utm_source=feed", Metadata: bookmarks.Metadata{CanonicalURL: "https://example.com/a"}},
+			{URL: "https://example.com/b", Metadata: bookmarks.Metadata{CanonicalURL: "https://example.com/c"}},
+			{URL: "https://example.com/a"},
+			{URL: "https://example.com/b"},
+		} {
+			if err := repository.Insert(bookmark); err != nil {
+				t.Fatal("could not insert bookmark:", err)
+			}
+		}
+		var found []string
+		for cursor := bookmarks.Cursor(""); ; {
+			page, next, err := repository.Duplicated(0, cursor, 1)
+			if err != nil {
+				t.Fatal("cannot list bookmarks:", err)
+			}
+			for _, bookmark := range page {
+				found = append(found, bookmark.URL)
+			}
+			if next == "" {
+				break
+			}
+			cursor = next
+		}
+		if want := []string{"https://example.com/a", "https://example.com/a?utm_source=feed"}; !slices.Equal(found, want) {
+			t.Errorf("unexpected duplicates: %v, want %v", found, want)
+		}
+	})
+}
+
+func TestRepository_tags(t *testing.T) {
+	repository := setup(t)
+	tagged := &bookmarks.Bookmark{URL: "http://example.com/tagged"}
+	untagged := &bookmarks.Bookmark{URL: "http://example.com/untagged"}
+	for _, bookmark := range []*bookmarks.Bookmark{tagged, untagged} {
+		if err := repository.Insert(bookmark); err != nil {
+			t.Fatal("could not insert bookmark:", err)
+		}
+	}
+	for _, tag := range []string{"b", "a", "a"} {
+		if err := repository.AddTag(0, tagged.ID, tag); err != nil {
+			t.Fatal("cannot tag bookmark:", err)
+		}
+	}
+	loaded, err := repository.GetByID(0, tagged.ID)
+	if err != nil {
+		t.Fatal("cannot load bookmark:", err)
+	}
+	if !slices.Equal(loaded.Tags, []string{"a", "b"}) {
+		t.Fatal("unexpected tags:", loaded.Tags)
+	}
+	found, _, err := repository.ByTag(0, "a", "", bookmarks.DefaultPageSize)
+	if err != nil {
+		t.Fatal("cannot list bookmarks by tag:", err)
+	}
+	if len(found) != 1 || found[0].ID != tagged.ID {
+		t.Fatal("did not find expected bookmark")
+	}
+	tags, err := repository.Tags(0)
+	if err != nil {
+		t.Fatal("cannot list tags:", err)
+	}
+	if len(tags) != 2 || tags[0].Name != "a" || tags[0].Count != 1 {
+		t.Fatal("unexpected tag list")
+	}
+	if err := repository.RemoveTag(0, tagged.ID, "a"); err != nil {
+		t.Fatal("cannot untag bookmark:", err)
+	}
+	found, _, err = repository.ByTag(0, "a", "", bookmarks.DefaultPageSize)
+	if err != nil {
+		t.Fatal("cannot list bookmarks by tag:", err)
+	}
+	if len(found) != 0 {
+		t.Fatal("tag not removed")
+	}
+	if err := repository.DeleteByID(0, tagged.ID); err != nil {
+		t.Fatal("cannot delete bookmark:", err)
+	}
+	tags, err = repository.Tags(0)
+	if err != nil {
+		t.Fatal("cannot list tags:", err)
+	}
+	if len(tags) != 0 {
+		t.Fatal("deleted bookmark still tagged")
+	}
+}
+
+func TestRepository_Search_filters(t *testing.T) {
+	repository := setup(t)
+	dead := &bookmarks.Bookmark{URL: "https://gist.github.com/dead", Title: "dead gist", LastStatusCode: http.StatusNotFound, Dead: true, Inbox: bookmarks.NewLink}
+	read := &bookmarks.Bookmark{URL: "https://github.com/read", Title: "read repo", LastStatusCode: http.StatusOK, Inbox: bookmarks.NewLink}
+	other := &bookmarks.Bookmark{URL: "https://notgithub.com/other", Title: "other repo", Description: "golang tips", Inbox: bookmarks.NewLink}
+	for _, bookmark := range []*bookmarks.Bookmark{dead, read, other} {
+		if err := repository.Insert(bookmark); err != nil {
+			t.Fatal("could not insert bookmark:", err)
+		}
+	}
+	read.Inbox = bookmarks.Read
+	if err := repository.Update(read); err != nil {
+		t.Fatal("could not update bookmark:", err)
+	}
+	if err := repository.AddTag(0, other.ID, "go"); err != nil {
+		t.Fatal("could not tag bookmark:", err)
+	}
+	inbox, readInbox := bookmarks.NewLink, bookmarks.Read
+	tomorrow := time.Now().Add(24 * time.Hour)
+	tests := []struct {
+		name  string
+		query *bookmarks.Query
+		want  []int64
+	}{
+		{"site", &bookmarks.Query{Sites: []string{"github.com"}}, []int64{dead.ID, read.ID}},
+		{"excludedSite", &bookmarks.Query{ExcludedSites: []string{"github.com"}}, []int64{other.ID}},
+		{"tag", &bookmarks.Query{Tags: []string{"go"}}, []int64{other.ID}},
+		{"excludedTag", &bookmarks.Query{ExcludedTags: []string{"go"}}, []int64{dead.ID, read.ID}},
+		{"inbox", &bookmarks.Query{Inbox: &inbox}, []int64{dead.ID, other.ID}},
+		{"read", &bookmarks.Query{Inbox: &readInbox}, []int64{read.ID}},
+		{"dead", &bookmarks.Query{Dead: true}, []int64{dead.ID}},
+		{"status", &bookmarks.Query{StatusCodes: []int64{http.StatusOK, http.StatusNotFound}}, []int64{dead.ID, read.ID}},
+		{"before", &bookmarks.Query{Before: tomorrow}, []int64{dead.ID, read.ID, other.ID}},
+		{"after", &bookmarks.Query{After: tomorrow}, nil},
+		{"phrase", &bookmarks.Query{Phrases: []string{"golang tips"}}, []int64{other.ID}},
+		{"phrase/wrongOrder", &bookmarks.Query{Phrases: []string{"tips golang"}}, nil},
+		{"excluded", &bookmarks.Query{Terms: []string{"repo"}, Excluded: []string{"other"}}, []int64{read.ID}},
+		{"excludedOnly", &bookmarks.Query{Excluded: []string{"repo"}}, []int64{dead.ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, total, err := repository.Search(0, tt.query, 0, bookmarks.DefaultPageSize)
+			if err != nil {
+				t.Fatal("cannot search bookmarks:", err)
+			}
+			if total != len(tt.want) {
+				t.Errorf("Search() total = %v, want %v", total, len(tt.want))
+			}
+			var got []int64
+			for _, bookmark := range found {
+				got = append(got, bookmark.ID)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Search() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepository_Search_timezone(t *testing.T) {
+	repository := setup(t)
+	// Just after midnight in UTC+14 is still the previous day in UTC.
+	kiritimati := time.FixedZone("UTC+14", 14*60*60)
+	bookmark := &bookmarks.Bookmark{URL: "https://example.com", CreatedAt: time.Date(2024, 1, 1, 5, 0, 0, 0, kiritimati)}
+	if err := repository.Insert(bookmark); err != nil {
+		t.Fatal("could not insert bookmark:", err)
+	}
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		name  string
+		query *bookmarks.Query
+		want  int
+	}{
+		{"before", &bookmarks.Query{Before: day}, 1},
+		{"after", &bookmarks.Query{After: day}, 0},
+		{"afterLocal", &bookmarks.Query{After: day.In(kiritimati)}, 0},
+	} {
+		_, total, err := repository.Search(0, tt.query, 0, bookmarks.DefaultPageSize)
+		if err != nil {
+			t.Fatal("cannot search bookmarks:", err)
+		}
+		if total != tt.want {
+			t.Errorf("%s: Search() total = %v, want %v", tt.name, total, tt.want)
+		}
+	}
+}
+
+func TestRepository_Search_pagination(t *testing.T) {
+	const limit = 3
+	repository := setup(t)
+	for i := 0; i < limit+1; i++ {
+		if err := repository.Insert(&bookmarks.Bookmark{URL: "http://example.com", Title: "banana"}); err != nil {
+			t.Fatal("could not insert bookmark:", err)
+		}
+	}
+	for page, want := range []int{limit, 1, 0} {
+		found, total, err := repository.Search(0, &bookmarks.Query{Terms: []string{"banana"}}, page, limit)
+		if err != nil {
+			t.Fatal("cannot search bookmarks:", err)
+		}
+		if len(found) != want {
+			t.Errorf("page %d: unexpected bookmark count: %d", page, len(found))
+		}
+		if total != limit+1 {
+			t.Errorf("page %d: unexpected total: %d", page, total)
+		}
+	}
+}
+
+func TestRepository_cursor(t *testing.T) {
+	const limit = 2
+	repository := setup(t)
+	now := time.Now()
+	var inserted []int64
+	for i := range 5 {
+		bookmark := &bookmarks.Bookmark{
+			URL:            fmt.Sprintf("http://example.com/%d", i%2),
+			Inbox:          bookmarks.NewLink,
+			LastStatusCode: 500,
+			Dead:           true,
+			CreatedAt:      now.Add(time.Duration(i) * time.Minute),
+			BumpDate:       now.Add(time.Duration(i%3) * time.Minute),
+		}
+		if err := repository.Insert(bookmark); err != nil {
+			t.Fatal("could not insert bookmark:", err)
+		}
+		if err := repository.AddTag(0, bookmark.ID, "tag"); err != nil {
+			t.Fatal("could not tag bookmark:", err)
+		}
+		inserted = append(inserted, bookmark.ID)
+	}
+	type listFunc func(int64, bookmarks.Cursor, int) ([]*bookmarks.Bookmark, bookmarks.Cursor, error)
+	byTag := func(ownerID int64, after bookmarks.Cursor, limit int) ([]*bookmarks.Bookmark, bookmarks.Cursor, error) {
+		return repository.ByTag(ownerID, "tag", after, limit)
+	}
+	dead := func(ownerID int64, after bookmarks.Cursor, limit int) ([]*bookmarks.Bookmark, bookmarks.Cursor, error) {
+		return repository.Dead(ownerID, bookmarks.AllDead, after, limit)
+	}
+	tests := []struct {
+		name string
+		list listFunc
+	}{
+		{"inbox", repository.Inbox},
+		{"all", repository.All},
+		{"dead", dead},
+		{"duplicated", repository.Duplicated},
+		{"byTag", byTag},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			complete, _, err := tt.list(0, "", len(inserted))
+			if err != nil {
+				t.Fatal("cannot load complete list:", err)
+			}
+			var (
+				paged  []*bookmarks.Bookmark
+				cursor bookmarks.Cursor
+			)
+			for pages := 0; ; pages++ {
+				if pages > len(inserted) {
+					t.Fatal("pagination did not stop")
+				}
+				found, next, err := tt.list(0, cursor, limit)
+				if err != nil {
+					t.Fatal("cannot load page:", err)
+				}
+				if len(found) > limit {
+					t.Fatal("page is larger than the limit:", len(found))
+				}
+				paged = append(paged, found...)
+				if next == "" {
+					break
+				}
+				cursor = next
+			}
+			if len(paged) != len(complete) || len(complete) == 0 {
+				t.Fatalf("unexpected bookmark count: got %d, want %d", len(paged), len(complete))
+			}
+			for i := range complete {
+				if paged[i].ID != complete[i].ID {
+					t.Fatalf("pages do not match the complete list at %d: got %d, want %d", i, paged[i].ID, complete[i].ID)
+				}
+			}
+		})
+	}
+	t.Run("bumpedWhilePaging", func(t *testing.T) {
+		first, next, err := repository.Inbox(0, "", limit)
+		if err != nil {
+			t.Fatal("cannot load page:", err)
+		}
+		bumped := first[0]
+		bumped.BumpDate = now.Add(time.Hour)
+		if err := repository.Update(bumped); err != nil {
+			t.Fatal("cannot bump bookmark:", err)
+		}
+		seen := make(map[int64]bool)
+		for _, bookmark := range first {
+			seen[bookmark.ID] = true
+		}
+		for next != "" {
+			var found []*bookmarks.Bookmark
+			found, next, err = repository.Inbox(0, next, limit)
+			if err != nil {
+				t.Fatal("cannot load page:", err)
+			}
+			for _, bookmark := range found {
+				if seen[bookmark.ID] {
+					t.Fatal("bookmark listed twice:", bookmark.ID)
+				}
+				seen[bookmark.ID] = true
+			}
+		}
+		if len(seen) != len(inserted) {
+			t.Error("unexpected bookmark count:", len(seen))
+		}
+	})
+	t.Run("invalid", func(t *testing.T) {
+		for _, cursor := range []bookmarks.Cursor{"garbage", "e30"} {
+			if _, _, err := repository.All(0, cursor, limit); !errors.Is(err, bookmarks.ErrInvalidCursor) {
+				t.Errorf("%q: expected error missing: %v", cursor, err)
+			}
+		}
+	})
+}
+
+func Test_ftsMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		terms   []string
+		phrases []string
+		want    string
+	}{
+		{"empty", nil, nil, ""},
+		{"term", []string{"go"}, nil, `"go"*`},
+		{"terms", []string{"go", "blog"}, nil, `"go"* "blog"*`},
+		{"quotes", []string{`"hi"`}, nil, `"""hi"""*`},
+		{"url", []string{"example.com"}, nil, `"example.com"*`},
+		{"phrases", []string{"go"}, []string{"the blog"}, `"go"* "the blog"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ftsMatch(tt.terms, tt.phrases); got != tt.want {
+				t.Errorf("ftsMatch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
 }
 
 func TestRepository_Vacuum(t *testing.T) {
@@ -0,0 +1,49 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmarks
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Tag is a label attached to one or more bookmarks.
+type Tag struct {
+	Name  string `db:"name" json:"name"`
+	Count int64  `db:"count" json:"count"`
+}
+
+// NormalizeTag lowercases the tag and replaces inner whitespace with dashes.
+// Commas are removed as they are used to separate tags.
+func NormalizeTag(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	tag = strings.ReplaceAll(tag, ",", "")
+	return strings.Join(strings.FieldsFunc(tag, unicode.IsSpace), "-")
+}
+
+// ParseTags splits a comma separated list of tags, normalizing and
+// deduplicating them.
+func ParseTags(v string) []string {
+	var tags []string
+	for _, tag := range strings.Split(v, ",") {
+		tag = NormalizeTag(tag)
+		if tag == "" || slices.Contains(tags, tag) {
+			continue
+		}
+		tags = append(tags, tag)
+	}
+	return tags
+}
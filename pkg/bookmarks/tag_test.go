@@ -0,0 +1,62 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bookmarks
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{"", ""},
+		{"  ", ""},
+		{"Go", "go"},
+		{" Read Later ", "read-later"},
+		{"a,b", "ab"},
+		{"tab\tseparated", "tab-separated"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			if got := NormalizeTag(tt.tag); got != tt.want {
+				t.Errorf("NormalizeTag() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	tests := []struct {
+		name string
+		v    string
+		want []string
+	}{
+		{"empty", "", nil},
+		{"single", "go", []string{"go"}},
+		{"multiple", "go, Rust ,read later", []string{"go", "rust", "read-later"}},
+		{"duplicated", "go,GO, go", []string{"go"}},
+		{"blanks", ",, ,go,", []string{"go"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseTags(tt.v); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseTags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
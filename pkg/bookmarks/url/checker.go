@@ -15,55 +15,315 @@
 package url
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"net/netip"
+	neturl "net/url"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/PuerkitoBio/goquery"
+	"cirello.io/alreadyread/pkg/bookmarks"
 )
 
 //go:generate go tool moq -out httpGetter_mocks_test.go . httpGetter
 type httpGetter interface {
-	Get(url string) (resp *http.Response, err error)
+	Do(req *http.Request) (*http.Response, error)
 }
 
+// DefaultUserAgent is how the link checker introduces itself unless
+// WithUserAgent is used.
+const DefaultUserAgent = "alreadyread"
+
+// Defaults of WithTimeouts and WithMaxTitleBytes.
+const (
+	DefaultConnectTimeout = 10 * time.Second
+	DefaultReadTimeout    = 30 * time.Second
+	DefaultTotalTimeout   = time.Minute
+	DefaultMaxTitleBytes  = 1 << 20
+)
+
 type Checker struct {
 	timeNow    func() time.Time
 	httpClient httpGetter
+	userAgent  string
+
+	connectTimeout time.Duration
+	readTimeout    time.Duration
+	totalTimeout   time.Duration
+	maxTitleBytes  int64
+
+	allowedHosts    map[string]bool
+	allowedNetworks []netip.Prefix
+
+	extractors map[string]Extractor
+
+	robotsMu    sync.Mutex
+	robotsCache map[string]*robotsEntry
+
+	probeMu    sync.Mutex
+	probeCache map[string]*probeEntry
 }
 
-func NewChecker() *Checker {
-	return &Checker{
-		timeNow:    time.Now,
-		httpClient: http.DefaultClient,
+// Option configures the checker.
+type Option func(*Checker)
+
+// WithUserAgent sets the User-Agent header of the requests. Its product
+// token, up to the first slash, picks the robots.txt rules that apply.
+func WithUserAgent(userAgent string) Option {
+	return func(u *Checker) {
+		u.userAgent = userAgent
 	}
 }
 
-// Check dials bookmark URL and updates its state with the errors if any.
-func (u *Checker) Check(url, originalTitle string) (title string, when int64, code int64, reason string) {
-	title = originalTitle
-	res, err := u.httpClient.Get(url)
-	if err != nil {
-		return originalTitle, u.timeNow().Unix(), http.StatusServiceUnavailable, err.Error()
+// WithTimeouts sets how long a check may take to connect to the host, to wait
+// for the host to send anything or the whole of a response, and in total,
+// redirects included. Values that are not positive keep the defaults.
+func WithTimeouts(connect, read, total time.Duration) Option {
+	return func(u *Checker) {
+		u.connectTimeout, u.readTimeout, u.totalTimeout = connect, read, total
+	}
+}
+
+// WithMaxTitleBytes sets how much of a page is read when looking for its
+// title. Values that are not positive keep the default.
+func WithMaxTitleBytes(n int64) Option {
+	return func(u *Checker) {
+		u.maxTitleBytes = n
+	}
+}
+
+// WithAllowedHosts lets the checker reach private, loopback and link-local
+// addresses on the given hosts, such as intranet servers. Each host is either
+// a hostname, an IP address or a network in CIDR notation.
+func WithAllowedHosts(hosts ...string) Option {
+	return func(u *Checker) {
+		for _, host := range hosts {
+			if network, err := netip.ParsePrefix(host); err == nil {
+				u.allowedNetworks = append(u.allowedNetworks, network.Masked())
+			} else if addr, err := netip.ParseAddr(host); err == nil {
+				u.allowedNetworks = append(u.allowedNetworks, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			} else if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+				u.allowedHosts[host] = true
+			}
+		}
+	}
+}
+
+// WithExtractor reads the title and metadata of the content of the given
+// media type with the extractor, instead of the built-in one if any. A media
+// type such as "image/*" covers the whole type, unless a more specific
+// extractor is set.
+func WithExtractor(mediaType string, extractor Extractor) Option {
+	return func(u *Checker) {
+		u.extractors[strings.ToLower(mediaType)] = extractor
+	}
+}
+
+func NewChecker(opts ...Option) *Checker {
+	u := &Checker{
+		timeNow:      time.Now,
+		userAgent:    DefaultUserAgent,
+		robotsCache:  make(map[string]*robotsEntry),
+		probeCache:   make(map[string]*probeEntry),
+		allowedHosts: make(map[string]bool),
+	}
+	u.extractors = u.defaultExtractors()
+	for _, opt := range opts {
+		opt(u)
+	}
+	u.connectTimeout = orDefault(u.connectTimeout, DefaultConnectTimeout)
+	u.readTimeout = orDefault(u.readTimeout, DefaultReadTimeout)
+	u.totalTimeout = orDefault(u.totalTimeout, DefaultTotalTimeout)
+	u.maxTitleBytes = orDefault(u.maxTitleBytes, DefaultMaxTitleBytes)
+	u.httpClient = &http.Client{Transport: u.newTransport()}
+	return u
+}
+
+func orDefault[T time.Duration | int64](v, def T) T {
+	if v <= 0 {
+		return def
+	}
+	return v
+}
+
+// Check dials bookmark URL and reports its state with the errors if any.
+// Redirects are followed, and recorded in the result. Links that the
+// robots.txt of their host disallows are not fetched, and neither are links
+// whose host cannot be reached for its robots.txt. Checks that take too long
+// are reported with StatusTimeout, and links that lead to private addresses
+// with StatusPrivateAddress. The title and metadata are read with
+// the extractor for the media type of the content. Pages that load, but do
+// not seem to be the page that was bookmarked, are reported with
+// StatusSoftFailure. Every request of the check waits for the pacer of the
+// request, if any.
+func (u *Checker) Check(ctx context.Context, req bookmarks.CheckRequest) bookmarks.CheckResult {
+	url, originalTitle := req.URL, req.Title
+	result := bookmarks.CheckResult{Title: originalTitle, When: u.timeNow().Unix()}
+	ctx, cancel := context.WithTimeout(ctx, u.totalTimeout)
+	defer cancel()
+	var robots *robotsRules
+	link, err := neturl.Parse(url)
+	if err == nil && (link.Scheme == "http" || link.Scheme == "https") {
+		robots, err = u.robots(ctx, req.Pacer, link)
+		result.CrawlDelay = robots.delay()
+		if err == nil && !robots.allowed(link.RequestURI()) {
+			result.Code, result.Reason = bookmarks.StatusBlockedByRobots, bookmarks.StatusText(bookmarks.StatusBlockedByRobots)
+			return result
+		}
+	}
+	var res *http.Response
+	if err == nil {
+		res, err = u.get(ctx, req.Pacer, url)
+	}
+	if errors.As(err, new(*privateAddressError)) {
+		result.Code, result.Reason = bookmarks.StatusPrivateAddress, err.Error()
+		return result
+	} else if isTimeout(err) {
+		result.Code, result.Reason = bookmarks.StatusTimeout, err.Error()
+		return result
+	} else if err != nil {
+		result.Code, result.Reason = http.StatusServiceUnavailable, err.Error()
+		return result
 	}
 	defer res.Body.Close()
+	result.Code = int64(res.StatusCode)
+	result.Redirects, result.PermanentRedirect = redirects(res)
+	if res.StatusCode == http.StatusTooManyRequests || res.StatusCode == http.StatusServiceUnavailable {
+		result.RetryAfter = retryAfter(res.Header.Get("Retry-After"), u.timeNow())
+	}
 	if res.StatusCode != http.StatusOK {
-		return originalTitle, u.timeNow().Unix(), int64(res.StatusCode), http.StatusText(res.StatusCode)
+		result.Reason = http.StatusText(res.StatusCode)
+		return result
 	}
-	isHTML := strings.Contains(res.Header.Get("Content-Type"), "text/html")
-	if originalTitle != "" || !isHTML {
-		return originalTitle, u.timeNow().Unix(), int64(res.StatusCode), http.StatusText(res.StatusCode)
+	if originalTitle != "" {
+		result.Reason = http.StatusText(res.StatusCode)
 	}
-	doc, err := goquery.NewDocumentFromReader(res.Body)
-	if err == nil {
-		doc.Find("HEAD>TITLE").Each(func(_ int, s *goquery.Selection) {
-			title = strings.TrimSpace(s.Text())
-		})
+	page := link
+	if res.Request != nil {
+		page = res.Request.URL
 	}
-	return title, u.timeNow().Unix(), int64(res.StatusCode), ""
+	// The page is kept to look for soft failures once it is read.
+	body, err := io.ReadAll(io.LimitReader(res.Body, u.maxTitleBytes))
+	if isTimeout(err) {
+		result.Code, result.Reason = bookmarks.StatusTimeout, err.Error()
+		return result
+	}
+	content := newContent(page, res.Header, bytes.NewReader(body))
+	if int64(len(body)) == u.maxTitleBytes && res.Header.Get("Accept-Ranges") == "bytes" {
+		content.tail = func(n int64) ([]byte, error) {
+			return u.tail(ctx, req.Pacer, page.String(), n)
+		}
+	}
+	if content.MediaType != "text/html" {
+		result.Reason = http.StatusText(res.StatusCode)
+	}
+	title, metadata, err := u.extract(content)
+	if err != nil {
+		result.Code, result.Reason = bookmarks.StatusTimeout, err.Error()
+		return result
+	}
+	if result.Title == "" {
+		result.Title = title
+	}
+	result.PageTitle, result.Metadata = title, metadata
+	if reason := u.softFailure(ctx, req.Pacer, link, robots, content, body, title, originalTitle, req.PageTitle); reason != "" {
+		result.Code, result.Reason = bookmarks.StatusSoftFailure, reason
+	}
+	return result
+}
+
+// get fetches the URL once the pacer, if any, lets it.
+func (u *Checker) get(ctx context.Context, pacer bookmarks.HostPacer, url string) (*http.Response, error) {
+	if pacer != nil {
+		if err := pacer.Wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return u.httpClient.Do(req)
+}
+
+// tail fetches the last n bytes of the URL, once the pacer, if any, lets it.
+// Hosts that answer with the whole content get no further than its headers.
+func (u *Checker) tail(ctx context.Context, pacer bookmarks.HostPacer, url string, n int64) ([]byte, error) {
+	if pacer != nil {
+		if err := pacer.Wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=-%d", n))
+	res, err := u.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusPartialContent {
+		return nil, nil
+	}
+	return io.ReadAll(io.LimitReader(res.Body, n))
+}
+
+// isTimeout reports whether the error comes from one of the timeouts of the
+// check.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())
+}
+
+// redirects lists the URLs that the client was redirected to before getting
+// the response, oldest first, and reports whether all of the redirects were
+// permanent. The request of each response links to the redirect response that
+// caused it, if any.
+func redirects(res *http.Response) (list []string, permanent bool) {
+	permanent = true
+	for req := res.Request; req != nil && req.Response != nil; req = req.Response.Request {
+		list = append(list, req.URL.String())
+		code := req.Response.StatusCode
+		permanent = permanent && (code == http.StatusMovedPermanently || code == http.StatusPermanentRedirect)
+	}
+	slices.Reverse(list)
+	return list, permanent && len(list) > 0
+}
+
+// retryAfter reads a Retry-After header, which is either a number of seconds
+// or an HTTP date. Invalid values and dates in the past mean no wait.
+func retryAfter(v string, now time.Time) time.Duration {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseInt(v, 10, 32); err == nil {
+		return time.Duration(max(seconds, 0)) * time.Second
+	}
+	when, err := http.ParseTime(v)
+	if err != nil {
+		return 0
+	}
+	return max(when.Sub(now), 0)
+}
+
+func (u *Checker) Title(ctx context.Context, url string) string {
+	return u.Check(ctx, bookmarks.CheckRequest{URL: url}).Title
 }
 
-func (u *Checker) Title(url string) string {
-	title, _, _, _ := u.Check(url, "")
-	return title
+// Preview reads the title and metadata of the page, to fill in a new
+// bookmark.
+func (u *Checker) Preview(ctx context.Context, url string) (string, bookmarks.Metadata) {
+	result := u.Check(ctx, bookmarks.CheckRequest{URL: url})
+	return result.Title, result.Metadata
 }
@@ -15,11 +15,19 @@
 package url
 
 import (
+	"bytes"
+	"context"
 	"io"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 	"time"
+
+	"cirello.io/alreadyread/pkg/bookmarks"
 )
 
 func TestCheckLink(t *testing.T) {
@@ -39,11 +47,14 @@ func TestCheckLink(t *testing.T) {
 		wantCode   int64
 		wantWhen   int64
 		wantReason string
+
+		wantRedirects  []string
+		wantRetryAfter time.Duration
 	}{
 		{
 			name: "404",
 			url:  "http://example.com/404",
-			httpGetter: &httpGetterMock{GetFunc: func(string) (*http.Response, error) {
+			httpGetter: &httpGetterMock{DoFunc: func(*http.Request) (*http.Response, error) {
 				return &http.Response{
 					StatusCode: http.StatusNotFound,
 					Body:       io.NopCloser(strings.NewReader("")),
@@ -58,7 +69,7 @@ func TestCheckLink(t *testing.T) {
 		{
 			name: "200",
 			url:  "http://example.com/",
-			httpGetter: &httpGetterMock{GetFunc: func(string) (*http.Response, error) {
+			httpGetter: &httpGetterMock{DoFunc: func(*http.Request) (*http.Response, error) {
 				return &http.Response{
 					StatusCode: http.StatusOK,
 					Status:     http.StatusText(http.StatusOK),
@@ -78,7 +89,7 @@ func TestCheckLink(t *testing.T) {
 			name:  "Custom Title",
 			url:   "http://example.com/",
 			title: "Custom Title",
-			httpGetter: &httpGetterMock{GetFunc: func(string) (*http.Response, error) {
+			httpGetter: &httpGetterMock{DoFunc: func(*http.Request) (*http.Response, error) {
 				return &http.Response{
 					StatusCode: http.StatusOK,
 					Body:       io.NopCloser(strings.NewReader("<html><head><title>Example Domain</title></head></html>"))}, nil
@@ -93,7 +104,7 @@ func TestCheckLink(t *testing.T) {
 			name:  "Custom Title Bad Link",
 			url:   "http://example.com/",
 			title: "Custom Title",
-			httpGetter: &httpGetterMock{GetFunc: func(string) (*http.Response, error) {
+			httpGetter: &httpGetterMock{DoFunc: func(*http.Request) (*http.Response, error) {
 				return &http.Response{
 					StatusCode: http.StatusInternalServerError,
 					Body:       io.NopCloser(strings.NewReader(""))}, nil
@@ -104,6 +115,22 @@ func TestCheckLink(t *testing.T) {
 			wantWhen:   now().Unix(),
 			wantReason: "Internal Server Error",
 		},
+		{
+			name: "429",
+			url:  "http://example.com/busy",
+			httpGetter: &httpGetterMock{DoFunc: func(*http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusTooManyRequests,
+					Header:     http.Header{"Retry-After": {"120"}},
+					Body:       io.NopCloser(strings.NewReader("")),
+				}, nil
+			}},
+			wantURL:        "http://example.com/busy",
+			wantCode:       http.StatusTooManyRequests,
+			wantWhen:       now().Unix(),
+			wantReason:     "Too Many Requests",
+			wantRetryAfter: 2 * time.Minute,
+		},
 		{
 			name:       "invalid URL",
 			url:        "invalid-url",
@@ -118,7 +145,8 @@ func TestCheckLink(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			checker.httpClient = tt.httpGetter
-			gotTitle, gotWhen, gotCode, gotReason := checker.Check(tt.url, tt.title)
+			got := checker.Check(context.TODO(), bookmarks.CheckRequest{URL: tt.url, Title: tt.title})
+			gotTitle, gotWhen, gotCode, gotReason := got.Title, got.When, got.Code, got.Reason
 			if gotTitle != tt.wantTitle {
 				t.Errorf("%s CheckLink().Title = %v, want %v", tt.name, gotTitle, tt.wantTitle)
 			}
@@ -131,6 +159,102 @@ func TestCheckLink(t *testing.T) {
 			if gotReason != tt.wantReason {
 				t.Errorf("%s CheckLink().Reason = %v, want %v", tt.name, gotReason, tt.wantReason)
 			}
+			if !slices.Equal(got.Redirects, tt.wantRedirects) {
+				t.Errorf("%s CheckLink().Redirects = %v, want %v", tt.name, got.Redirects, tt.wantRedirects)
+			}
+			if got.RetryAfter != tt.wantRetryAfter {
+				t.Errorf("%s CheckLink().RetryAfter = %v, want %v", tt.name, got.RetryAfter, tt.wantRetryAfter)
+			}
+		})
+	}
+}
+
+func Test_retryAfter(t *testing.T) {
+	now := time.Date(2023, 5, 6, 7, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"", 0},
+		{"garbage", 0},
+		{"-5", 0},
+		{" 30 ", 30 * time.Second},
+		{"99999999999", 0},
+		{now.Add(time.Hour).Format(http.TimeFormat), time.Hour},
+		{now.Add(-time.Hour).Format(http.TimeFormat), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := retryAfter(tt.in, now); got != tt.want {
+				t.Errorf("retryAfter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckLink_redirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/old", http.RedirectHandler("/older", http.StatusMovedPermanently))
+	mux.Handle("/older", http.RedirectHandler("/new", http.StatusFound))
+	mux.Handle("/moved", http.RedirectHandler("/moved-again", http.StatusMovedPermanently))
+	mux.Handle("/moved-again", http.RedirectHandler("/new", http.StatusPermanentRedirect))
+	mux.HandleFunc("/new", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, "<html><head><title>New</title></head></html>")
+	})
+	mux.HandleFunc("/stay", func(w http.ResponseWriter, r *http.Request) {})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	checker := NewChecker(allowTestServer)
+
+	got := checker.Check(context.TODO(), bookmarks.CheckRequest{URL: ts.URL + "/old"})
+	if want := []string{ts.URL + "/older", ts.URL + "/new"}; !slices.Equal(got.Redirects, want) {
+		t.Errorf("unexpected redirects: %v, want %v", got.Redirects, want)
+	}
+	if got.FinalURL() != ts.URL+"/new" || got.Code != http.StatusOK || got.Title != "New" {
+		t.Errorf("unexpected result: %#v", got)
+	}
+	if got.PermanentRedirect {
+		t.Error("redirects through a temporary one must not be permanent")
+	}
+	if got := checker.Check(context.TODO(), bookmarks.CheckRequest{URL: ts.URL + "/moved"}); got.FinalURL() != ts.URL+"/new" || !got.PermanentRedirect {
+		t.Errorf("unexpected permanent redirects: %#v", got)
+	}
+	if got := checker.Check(context.TODO(), bookmarks.CheckRequest{URL: ts.URL + "/stay"}); got.Redirects != nil || got.FinalURL() != "" || got.PermanentRedirect {
+		t.Errorf("unexpected redirects: %#v", got)
+	}
+}
+
+func TestCheckLink_charset(t *testing.T) {
+	tests := []struct {
+		fixture     string
+		contentType string
+		wantTitle   string
+	}{
+		{"shift_jis.html", "text/html", "日本語のページ & テスト"},
+		{"windows-1251.html", "text/html; charset=windows-1251", "Привет, мир…"},
+		{"iso-8859-1.html", "text/html", "Café & crème brûlée"},
+		{"utf-8-bom.html", "text/html; charset=shift_jis", "Ünïcödé ✓"},
+		{"utf-16le.html", "text/html", "Grüße aus Köln"},
+		{"undeclared.html", "text/html", "naïve résumé"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			page, err := os.ReadFile(filepath.Join("testdata", "charset", tt.fixture))
+			if err != nil {
+				t.Fatal("cannot read fixture:", err)
+			}
+			checker := NewChecker()
+			checker.httpClient = &httpGetterMock{DoFunc: func(*http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{"Content-Type": {tt.contentType}},
+					Body:       io.NopCloser(bytes.NewReader(page)),
+				}, nil
+			}}
+			if got := checker.Check(context.TODO(), bookmarks.CheckRequest{URL: "http://example.com/"}); got.Title != tt.wantTitle {
+				t.Errorf("Check().Title = %q, want %q", got.Title, tt.wantTitle)
+			}
 		})
 	}
 }
@@ -151,7 +275,7 @@ func TestTitle(t *testing.T) {
 		{
 			name: "404",
 			url:  "http://example.com/404",
-			httpGetter: &httpGetterMock{GetFunc: func(string) (*http.Response, error) {
+			httpGetter: &httpGetterMock{DoFunc: func(*http.Request) (*http.Response, error) {
 				return &http.Response{
 					StatusCode: http.StatusNotFound,
 					Body:       io.NopCloser(strings.NewReader("")),
@@ -162,7 +286,7 @@ func TestTitle(t *testing.T) {
 		{
 			name: "200",
 			url:  "http://example.com/",
-			httpGetter: &httpGetterMock{GetFunc: func(string) (*http.Response, error) {
+			httpGetter: &httpGetterMock{DoFunc: func(*http.Request) (*http.Response, error) {
 				return &http.Response{
 					StatusCode: http.StatusOK,
 					Status:     http.StatusText(http.StatusOK),
@@ -184,7 +308,7 @@ func TestTitle(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			checker.httpClient = tt.httpGetter
-			gotTitle := checker.Title(tt.url)
+			gotTitle := checker.Title(context.TODO(), tt.url)
 			if gotTitle != tt.wantTitle {
 				t.Errorf("%s Title() = %v, want %v", tt.name, gotTitle, tt.wantTitle)
 			}
@@ -197,8 +321,7 @@ func TestContentExtraction(t *testing.T) {
 	checker.timeNow = func() time.Time {
 		return time.Unix(0, 0)
 	}
-	title, _, _, _ := checker.Check("https://www.example.org", "")
-	if title != "Example Domain" {
+	if title := checker.Check(context.TODO(), bookmarks.CheckRequest{URL: "https://www.example.org"}).Title; title != "Example Domain" {
 		t.Fatal("cannot extract HTML title")
 	}
 }
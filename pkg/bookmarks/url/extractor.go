@@ -0,0 +1,175 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package url
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	_ "image/gif"  // GIF dimensions
+	_ "image/jpeg" // JPEG dimensions
+	_ "image/png"  // PNG dimensions
+	"io"
+	"mime"
+	"net/http"
+	neturl "net/url"
+	"path"
+	"strings"
+
+	"cirello.io/alreadyread/pkg/bookmarks"
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html/charset"
+)
+
+// Content is a response whose title and metadata are being extracted.
+type Content struct {
+	// URL is where the content was served from, after redirects.
+	URL *neturl.URL
+	// MediaType is the media type of the content as the Content-Type header
+	// tells, or as its first bytes suggest if the header is missing.
+	MediaType string
+	Header    http.Header
+	// Body reads the content, up to the limit set with WithMaxTitleBytes.
+	Body io.Reader
+
+	// tail fetches the end of the content, if Body may be cut short and the
+	// host serves byte ranges.
+	tail func(n int64) ([]byte, error)
+}
+
+func newContent(url *neturl.URL, header http.Header, body io.Reader) *Content {
+	c := &Content{URL: url, Header: header, Body: body}
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil || mediaType == "" {
+		sniffer := bufio.NewReader(body)
+		head, _ := sniffer.Peek(512)
+		mediaType, _, _ = mime.ParseMediaType(http.DetectContentType(head))
+		c.Body = sniffer
+	}
+	c.MediaType = mediaType
+	return c
+}
+
+// Tail fetches the last n bytes of the content, for formats that describe
+// themselves at the end, such as PDF. It returns nil if Body holds the whole
+// content, or if the host does not serve byte ranges.
+func (c *Content) Tail(n int64) ([]byte, error) {
+	if c.tail == nil {
+		return nil, nil
+	}
+	return c.tail(n)
+}
+
+// Filename is the name of the file that the content came from, as the
+// Content-Disposition header tells or as the URL path ends.
+func (c *Content) Filename() string {
+	if _, params, err := mime.ParseMediaType(c.Header.Get("Content-Disposition")); err == nil {
+		if name := path.Base(strings.ReplaceAll(params["filename"], `\`, "/")); name != "." && name != "/" {
+			return name
+		}
+	}
+	if c.URL == nil {
+		return ""
+	}
+	if name := path.Base(c.URL.Path); name != "." && name != "/" {
+		return name
+	}
+	return ""
+}
+
+// An Extractor reads the title and metadata of a kind of content.
+type Extractor func(c *Content) (title string, metadata bookmarks.Metadata, err error)
+
+// defaultExtractors knows HTML pages, PDF documents and images.
+func (u *Checker) defaultExtractors() map[string]Extractor {
+	return map[string]Extractor{
+		"text/html":             u.extractHTML,
+		"application/xhtml+xml": u.extractHTML,
+		"application/pdf":       extractPDF,
+		"image/*":               extractImage,
+	}
+}
+
+// extract reads the title and metadata of the content, with the extractor
+// for its media type, if any. Content other than HTML pages falls back on
+// its filename for a title. Only timeouts are errors: content that cannot be
+// read just goes without a title.
+func (u *Checker) extract(c *Content) (string, bookmarks.Metadata, error) {
+	var (
+		title    string
+		metadata bookmarks.Metadata
+	)
+	extractor := u.extractors[c.MediaType]
+	if extractor == nil {
+		mainType, _, _ := strings.Cut(c.MediaType, "/")
+		extractor = u.extractors[mainType+"/*"]
+	}
+	if extractor != nil {
+		var err error
+		title, metadata, err = extractor(c)
+		if isTimeout(err) {
+			return "", bookmarks.Metadata{}, err
+		}
+	}
+	if title == "" && c.MediaType != "text/html" {
+		title = c.Filename()
+	}
+	return title, metadata, nil
+}
+
+// extractHTML reads the title and metadata of an HTML page.
+func (u *Checker) extractHTML(c *Content) (string, bookmarks.Metadata, error) {
+	doc, err := readPage(c)
+	if err != nil {
+		return "", bookmarks.Metadata{}, err
+	}
+	var title string
+	doc.Find("HEAD>TITLE").Each(func(_ int, s *goquery.Selection) {
+		title = strings.TrimSpace(s.Text())
+	})
+	return title, extractMetadata(doc, c.URL), nil
+}
+
+// readPage parses an HTML page. The page is transcoded to UTF-8 first, from
+// the encoding that its byte order mark, the Content-Type header or its
+// <meta> tags tell, in this order of precedence. Pages that tell nothing are
+// read as windows-1252, unless they are valid UTF-8.
+func readPage(c *Content) (*goquery.Document, error) {
+	decoded, err := charset.NewReader(c.Body, c.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+	// The decoders keep the byte order mark, which would push the whole
+	// page into its body.
+	body := bufio.NewReader(decoded)
+	if r, _, err := body.ReadRune(); err == nil && r != '\uFEFF' {
+		_ = body.UnreadRune()
+	}
+	return goquery.NewDocumentFromReader(body)
+}
+
+// extractImage describes an image with its format and dimensions, the way
+// browsers title the images they show on their own.
+func extractImage(c *Content) (string, bookmarks.Metadata, error) {
+	config, format, err := image.DecodeConfig(c.Body)
+	if err != nil {
+		return "", bookmarks.Metadata{}, err
+	}
+	description := fmt.Sprintf("%s image, %d × %d pixels", strings.ToUpper(format), config.Width, config.Height)
+	if name := c.Filename(); name != "" {
+		return name + " (" + description + ")", bookmarks.Metadata{}, nil
+	}
+	return description, bookmarks.Metadata{}, nil
+}
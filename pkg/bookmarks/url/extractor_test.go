@@ -0,0 +1,280 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package url
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	neturl "net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cirello.io/alreadyread/pkg/bookmarks"
+)
+
+const (
+	pdfInfoLiteral = "%PDF-1.4\n" +
+		"1 0 obj\n<< /Type /Outlines /First 2 0 R >>\nendobj\n" +
+		"2 0 obj\n<< /Title (Chapter 1) /Parent 1 0 R >>\nendobj\n" +
+		"11 0 obj\n<< /Producer (test) >>\nendobj\n" +
+		"1 0 obj\n<< /Title (Caf\\351 \\(2024\\)\\\n edition) /Author (Jane Doe) >>\nendobj\n" +
+		"trailer\n<< /Root 1 0 R /Info 1 0 R >>\n%%EOF\n"
+	pdfInfoHex = "%PDF-1.7\n" +
+		"7 0 obj\n<</Title <FEFF 0047 0072 00FC 00DF 0065>>>\nendobj\n" +
+		"trailer\n<</Info 7 0 R>>\n%%EOF\n"
+	pdfXMP = "%PDF-1.6\n" +
+		"3 0 obj\n<< /Type /Metadata /Subtype /XML >>\nstream\n" +
+		`<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF><rdf:Description>` +
+		`<dc:title><rdf:Alt><rdf:li xml:lang="x-default">XMP &amp; Title</rdf:li></rdf:Alt></dc:title>` +
+		`<dc:creator><rdf:Seq><rdf:li>John Roe</rdf:li></rdf:Seq></dc:creator>` +
+		"</rdf:Description></rdf:RDF></x:xmpmeta>\nendstream\nendobj\n" +
+		"4 0 obj\n<< /Title (Info Title) /Author (Someone Else) >>\nendobj\n" +
+		"trailer\n<< /Info 4 0 R >>\n%%EOF\n"
+	pdfOutlineOnly = "%PDF-1.4\n" +
+		"2 0 obj\n<< /Title (Chapter 1) >>\nendobj\n" +
+		"trailer\n<< /Root 1 0 R >>\n%%EOF\n"
+)
+
+func TestCheckLink_extractors(t *testing.T) {
+	var pngImage bytes.Buffer
+	if err := png.Encode(&pngImage, image.NewGray(image.Rect(0, 0, 3, 2))); err != nil {
+		t.Fatal("cannot encode image:", err)
+	}
+	tests := []struct {
+		name       string
+		url        string
+		header     http.Header
+		body       string
+		wantTitle  string
+		wantAuthor string
+	}{
+		{"pdfInfo", "https://example.com/files/cafe.pdf", http.Header{"Content-Type": {"application/pdf"}}, pdfInfoLiteral, "Café (2024) edition", "Jane Doe"},
+		{"pdfHex", "https://example.com/files/grusse.pdf", http.Header{"Content-Type": {"application/pdf"}}, pdfInfoHex, "Grüße", ""},
+		{"pdfXMP", "https://example.com/files/xmp.pdf", http.Header{"Content-Type": {"application/pdf"}}, pdfXMP, "XMP & Title", "John Roe"},
+		{"pdfSniffed", "https://example.com/download?id=1", nil, pdfInfoHex, "Grüße", ""},
+		{"pdfUntitled", "https://example.com/files/report%202024.pdf", http.Header{"Content-Type": {"application/pdf"}}, pdfOutlineOnly, "report 2024.pdf", ""},
+		{"contentDisposition", "https://example.com/download?id=1", http.Header{
+			"Content-Type":        {"application/pdf"},
+			"Content-Disposition": {`attachment; filename="Q3 report.pdf"`},
+		}, pdfOutlineOnly, "Q3 report.pdf", ""},
+		{"contentDispositionUTF8", "https://example.com/download?id=1", http.Header{
+			"Content-Type":        {"application/octet-stream"},
+			"Content-Disposition": {`attachment; filename*=UTF-8''na%C3%AFve%20r%C3%A9sum%C3%A9.docx`},
+		}, "PK", "naïve résumé.docx", ""},
+		{"image", "https://example.com/images/dot.png", http.Header{"Content-Type": {"image/png"}}, pngImage.String(), "dot.png (PNG image, 3 × 2 pixels)", ""},
+		{"imageUnnamed", "https://example.com/", http.Header{"Content-Type": {"image/png"}}, pngImage.String(), "PNG image, 3 × 2 pixels", ""},
+		{"imageUnknown", "https://example.com/images/cat.webp", http.Header{"Content-Type": {"image/webp"}}, "RIFF", "cat.webp", ""},
+		{"unnamed", "https://example.com/", http.Header{"Content-Type": {"application/octet-stream"}}, "\x00\x01", "", ""},
+		{"htmlUntitled", "https://example.com/page.html", http.Header{"Content-Type": {"text/html"}}, "<html></html>", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := NewChecker()
+			checker.httpClient = &httpGetterMock{DoFunc: func(req *http.Request) (*http.Response, error) {
+				header := tt.header
+				if header == nil {
+					header = http.Header{}
+				}
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     header,
+					Body:       io.NopCloser(strings.NewReader(tt.body)),
+					Request:    req,
+				}, nil
+			}}
+			got := checker.Check(context.TODO(), bookmarks.CheckRequest{URL: tt.url})
+			if got.Code != http.StatusOK || got.Title != tt.wantTitle || got.Metadata.Author != tt.wantAuthor {
+				t.Errorf("unexpected result: %q %q %#v", got.Title, got.Metadata.Author, got)
+			}
+		})
+	}
+}
+
+// pdfDocument lays out a PDF document as TeX and word processors write them:
+// the page streams come first, then the document information dictionary,
+// the cross-reference table and the trailer.
+func pdfDocument(pageBytes int, info string) []byte {
+	var doc bytes.Buffer
+	doc.WriteString("%PDF-1.5\n%\xd0\xd4\xc5\xd8\n")
+	doc.WriteString("3 0 obj\n<< /Length " + fmt.Sprint(pageBytes) + " /Filter /FlateDecode >>\nstream\n")
+	doc.Write(bytes.Repeat([]byte{0x78, 0x9c, 0xed, 0x5d, 0x2f, 0xa3}, pageBytes/6))
+	doc.WriteString("\nendstream\nendobj\n")
+	doc.WriteString("11 0 obj\n<< /Type /Catalog /Pages 1 0 R /Outlines 4 0 R >>\nendobj\n")
+	doc.WriteString(info)
+	doc.WriteString("xref\n0 13\n0000000000 65535 f \n0000000015 00000 n \n")
+	doc.WriteString("trailer\n<< /Size 13 /Root 11 0 R /Info 12 0 R /ID [<5f2a> <5f2a>] >>\nstartxref\n4242\n%%EOF\n")
+	return doc.Bytes()
+}
+
+func TestCheckLink_pdfTail(t *testing.T) {
+	const info = "12 0 obj\n<< /Producer (pdfTeX-1.40.25) /Creator (TeX) /CreationDate (D:20240101000000Z) /Title (A Long Paper) /Author (Ada Lovelace) /Trapped /False >>\nendobj\n"
+	// Object streams keep the dictionary compressed, and the trailer is
+	// the dictionary of a cross-reference stream.
+	objectStream := bytes.Replace(pdfDocument(8<<10, "5 0 obj\n<< /Type /ObjStm /N 2 /First 9 /Filter /FlateDecode >>\nstream\n\x78\x9c\x4b\x4c\nendstream\nendobj\n"),
+		[]byte("trailer\n<<"), []byte("13 0 obj\n<< /Type /XRef"), 1)
+	var ranges atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", http.NotFound)
+	mux.HandleFunc("/paper.pdf", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "paper.pdf", time.Time{}, bytes.NewReader(pdfDocument(8<<10, info)))
+	})
+	mux.HandleFunc("/short.pdf", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "short.pdf", time.Time{}, bytes.NewReader(pdfDocument(100, info)))
+	})
+	mux.HandleFunc("/whole.pdf", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write(pdfDocument(8<<10, info))
+	})
+	mux.HandleFunc("/compressed.pdf", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "compressed.pdf", time.Time{}, bytes.NewReader(objectStream))
+	})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") != "" {
+			ranges.Add(1)
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		name       string
+		path       string
+		wantTitle  string
+		wantAuthor string
+		wantRanges int32
+	}{
+		{"tail", "/paper.pdf", "A Long Paper", "Ada Lovelace", 1},
+		{"withinLimit", "/short.pdf", "A Long Paper", "Ada Lovelace", 0},
+		{"noRanges", "/whole.pdf", "whole.pdf", "", 0},
+		{"objectStream", "/compressed.pdf", "compressed.pdf", "", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ranges.Store(0)
+			var turns int
+			pacer := pacerFunc(func(context.Context) error {
+				turns++
+				return nil
+			})
+			checker := NewChecker(WithMaxTitleBytes(1<<10), allowTestServer)
+			got := checker.Check(context.TODO(), bookmarks.CheckRequest{URL: ts.URL + tt.path, Pacer: pacer})
+			if got.Code != http.StatusOK || got.Title != tt.wantTitle || got.Metadata.Author != tt.wantAuthor {
+				t.Errorf("unexpected result: %q %q %#v", got.Title, got.Metadata.Author, got)
+			}
+			if n := ranges.Load(); n != tt.wantRanges {
+				t.Error("unexpected range requests:", n)
+			}
+			// robots.txt and the document, and the tail if fetched.
+			if want := 2 + int(tt.wantRanges); turns != want {
+				t.Error("range request did not wait for the pacer:", turns)
+			}
+		})
+	}
+}
+
+func TestWithExtractor(t *testing.T) {
+	firstLine := func(c *Content) (string, bookmarks.Metadata, error) {
+		b, err := io.ReadAll(c.Body)
+		line, _, _ := strings.Cut(string(b), "\n")
+		return line, bookmarks.Metadata{}, err
+	}
+	named := func(c *Content) (string, bookmarks.Metadata, error) {
+		return "custom " + c.Filename(), bookmarks.Metadata{}, nil
+	}
+	checker := NewChecker(WithExtractor("text/plain", firstLine), WithExtractor("Image/PNG", named))
+	tests := []struct {
+		url         string
+		contentType string
+		body        string
+		want        string
+	}{
+		{"https://example.com/notes.txt", "text/plain; charset=utf-8", "Notes\nsecond line", "Notes"},
+		{"https://example.com/dot.png", "image/png", "not an image", "custom dot.png"},
+		{"https://example.com/dot.gif", "image/gif", "not an image", "dot.gif"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			checker.httpClient = &httpGetterMock{DoFunc: func(req *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{"Content-Type": {tt.contentType}},
+					Body:       io.NopCloser(strings.NewReader(tt.body)),
+					Request:    req,
+				}, nil
+			}}
+			if got := checker.Check(context.TODO(), bookmarks.CheckRequest{URL: tt.url}); got.Title != tt.want {
+				t.Errorf("Check().Title = %q, want %q", got.Title, tt.want)
+			}
+		})
+	}
+}
+
+func TestContent_Filename(t *testing.T) {
+	tests := []struct {
+		url         string
+		disposition string
+		want        string
+	}{
+		{"https://example.com/a/b.pdf", "", "b.pdf"},
+		{"https://example.com/a/", "", "a"},
+		{"https://example.com/", "", ""},
+		{"https://example.com", "", ""},
+		{"https://example.com/a/b.pdf", `inline; filename="c.pdf"`, "c.pdf"},
+		{"https://example.com/a/b.pdf", `attachment; filename="..\\..\\evil.exe"`, "evil.exe"},
+		{"https://example.com/a/b.pdf", `attachment`, "b.pdf"},
+		{"https://example.com/a/b.pdf", `garbage;;`, "b.pdf"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url+" "+tt.disposition, func(t *testing.T) {
+			u, _ := neturl.Parse(tt.url)
+			c := &Content{URL: u, Header: http.Header{"Content-Disposition": {tt.disposition}}}
+			if got := c.Filename(); got != tt.want {
+				t.Errorf("Filename() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_pdfLiteralString(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"plain) trailing", "plain"},
+		{"nested (parens) kept)", "nested (parens) kept"},
+		{`escaped \) paren)`, "escaped ) paren"},
+		{`tab\tand\nnewline)`, "tab\tand\nnewline"},
+		{`octal \101\60\0061)`, "octal A0\x061"},
+		{"continued \\\r\nline)", "continued line"},
+		{`unknown \q escape)`, "unknown q escape"},
+		{"unterminated", "unterminated"},
+		{`trailing backslash \`, "trailing backslash "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := string(pdfLiteralString([]byte(tt.in))); got != tt.want {
+				t.Errorf("pdfLiteralString() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -18,8 +18,8 @@ var _ httpGetter = &httpGetterMock{}
 //
 //		// make and configure a mocked httpGetter
 //		mockedhttpGetter := &httpGetterMock{
-//			GetFunc: func(url string) (*http.Response, error) {
-//				panic("mock out the Get method")
+//			DoFunc: func(req *http.Request) (*http.Response, error) {
+//				panic("mock out the Do method")
 //			},
 //		}
 //
@@ -28,48 +28,48 @@ var _ httpGetter = &httpGetterMock{}
 //
 //	}
 type httpGetterMock struct {
-	// GetFunc mocks the Get method.
-	GetFunc func(url string) (*http.Response, error)
+	// DoFunc mocks the Do method.
+	DoFunc func(req *http.Request) (*http.Response, error)
 
 	// calls tracks calls to the methods.
 	calls struct {
-		// Get holds details about calls to the Get method.
-		Get []struct {
-			// URL is the url argument value.
-			URL string
+		// Do holds details about calls to the Do method.
+		Do []struct {
+			// Req is the req argument value.
+			Req *http.Request
 		}
 	}
-	lockGet sync.RWMutex
+	lockDo sync.RWMutex
 }
 
-// Get calls GetFunc.
-func (mock *httpGetterMock) Get(url string) (*http.Response, error) {
-	if mock.GetFunc == nil {
-		panic("httpGetterMock.GetFunc: method is nil but httpGetter.Get was just called")
+// Do calls DoFunc.
+func (mock *httpGetterMock) Do(req *http.Request) (*http.Response, error) {
+	if mock.DoFunc == nil {
+		panic("httpGetterMock.DoFunc: method is nil but httpGetter.Do was just called")
 	}
 	callInfo := struct {
-		URL string
+		Req *http.Request
 	}{
-		URL: url,
+		Req: req,
 	}
-	mock.lockGet.Lock()
-	mock.calls.Get = append(mock.calls.Get, callInfo)
-	mock.lockGet.Unlock()
-	return mock.GetFunc(url)
+	mock.lockDo.Lock()
+	mock.calls.Do = append(mock.calls.Do, callInfo)
+	mock.lockDo.Unlock()
+	return mock.DoFunc(req)
 }
 
-// GetCalls gets all the calls that were made to Get.
+// DoCalls gets all the calls that were made to Do.
 // Check the length with:
 //
-//	len(mockedhttpGetter.GetCalls())
-func (mock *httpGetterMock) GetCalls() []struct {
-	URL string
+//	len(mockedhttpGetter.DoCalls())
+func (mock *httpGetterMock) DoCalls() []struct {
+	Req *http.Request
 } {
 	var calls []struct {
-		URL string
+		Req *http.Request
 	}
-	mock.lockGet.RLock()
-	calls = mock.calls.Get
-	mock.lockGet.RUnlock()
+	mock.lockDo.RLock()
+	calls = mock.calls.Do
+	mock.lockDo.RUnlock()
 	return calls
 }
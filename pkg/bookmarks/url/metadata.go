@@ -0,0 +1,132 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package url
+
+import (
+	neturl "net/url"
+	"strings"
+	"time"
+
+	"cirello.io/alreadyread/pkg/bookmarks"
+	"github.com/PuerkitoBio/goquery"
+)
+
+// extractMetadata reads what the page tells about itself. The first of the
+// listed tags that the page has wins. Links are resolved against base, the
+// URL the page was served from.
+func extractMetadata(doc *goquery.Document, base *neturl.URL) bookmarks.Metadata {
+	meta := make(map[string]string)
+	doc.Find("meta").Each(func(_ int, s *goquery.Selection) {
+		content := strings.Join(strings.Fields(s.AttrOr("content", "")), " ")
+		if content == "" {
+			return
+		}
+		for _, attr := range []string{"property", "name", "itemprop", "http-equiv"} {
+			if key := strings.ToLower(strings.TrimSpace(s.AttrOr(attr, ""))); key != "" {
+				if _, ok := meta[key]; !ok {
+					meta[key] = content
+				}
+			}
+		}
+	})
+	first := func(keys ...string) string {
+		for _, key := range keys {
+			if v := meta[key]; v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+	canonical := ""
+	doc.Find("link[href]").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		for _, rel := range strings.Fields(s.AttrOr("rel", "")) {
+			if strings.EqualFold(rel, "canonical") {
+				canonical = s.AttrOr("href", "")
+				return false
+			}
+		}
+		return true
+	})
+	if canonical == "" {
+		canonical = meta["og:url"]
+	}
+	author := first("author", "article:author", "twitter:creator")
+	if isLink(author) {
+		author = ""
+	}
+	lang := strings.TrimSpace(doc.Find("html").AttrOr("lang", ""))
+	if lang == "" {
+		lang = first("content-language", "og:locale")
+	}
+	return bookmarks.Metadata{
+		Title:        first("og:title", "twitter:title"),
+		Description:  first("og:description", "twitter:description", "description"),
+		ImageURL:     resolveLink(base, first("og:image", "og:image:url", "og:image:secure_url", "twitter:image", "twitter:image:src")),
+		CanonicalURL: resolveLink(base, canonical),
+		Author:       author,
+		PublishedAt:  parsePublished(first("article:published_time", "og:published_time", "datepublished", "date", "dc.date.issued", "dcterms.created")),
+		SiteName:     first("og:site_name", "application-name"),
+		Language:     lang,
+	}
+}
+
+// resolveLink makes an absolute web link out of ref, or an empty string if it
+// is not one.
+func resolveLink(base *neturl.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return ""
+	}
+	u, err := neturl.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return ""
+	}
+	u.Fragment = ""
+	return u.String()
+}
+
+func isLink(v string) bool {
+	u, err := neturl.Parse(v)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https")
+}
+
+// publishedLayouts are the date formats that pages use for their publication
+// date, most of them ISO 8601.
+var publishedLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+// parsePublished reads a publication date as a Unix timestamp, zero if it
+// cannot be read.
+func parsePublished(v string) int64 {
+	for _, layout := range publishedLayouts {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t.Unix()
+		}
+	}
+	return 0
+}
@@ -0,0 +1,125 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package url
+
+import (
+	"context"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"cirello.io/alreadyread/pkg/bookmarks"
+	"github.com/PuerkitoBio/goquery"
+)
+
+const metadataPage = `<!DOCTYPE html>
+<html lang="en-US">
+<head>
+	<title>Page Title</title>
+	<meta name="description" content="Plain description">
+	<meta property="og:title" content="  OpenGraph
+		Title ">
+	<meta property="og:description" content="OpenGraph description">
+	<meta property="og:image" content="/images/cover.png">
+	<meta property="og:site_name" content="Example Site">
+	<meta property="og:url" content="https://example.com/og-url">
+	<meta property="article:published_time" content="2024-03-05T12:30:00+01:00">
+	<meta property="article:author" content="https://example.com/authors/jane">
+	<meta name="author" content="Jane Doe">
+	<meta name="twitter:title" content="Twitter Title">
+	<link rel="stylesheet" href="/style.css">
+	<link rel="Canonical" href="/articles/1#top">
+</head>
+<body></body>
+</html>`
+
+func Test_extractMetadata(t *testing.T) {
+	base, _ := neturl.Parse("https://www.example.com/articles/1?utm_source=feed")
+	tests := []struct {
+		name string
+		page string
+		want bookmarks.Metadata
+	}{
+		{"full", metadataPage, bookmarks.Metadata{
+			Title:        "OpenGraph Title",
+			Description:  "OpenGraph description",
+			ImageURL:     "https://www.example.com/images/cover.png",
+			CanonicalURL: "https://www.example.com/articles/1",
+			Author:       "Jane Doe",
+			PublishedAt:  time.Date(2024, 3, 5, 11, 30, 0, 0, time.UTC).Unix(),
+			SiteName:     "Example Site",
+			Language:     "en-US",
+		}},
+		{"fallbacks", `<html><head>
+			<meta name="twitter:title" content="Twitter Title">
+			<meta name="description" content="Plain description">
+			<meta name="twitter:image" content="https://cdn.example.com/card.jpg">
+			<meta property="og:url" content="https://example.com/og-url">
+			<meta property="article:author" content="https://example.com/authors/jane">
+			<meta itemprop="datePublished" content="2024-03-05">
+			<meta name="application-name" content="Example App">
+			<meta http-equiv="Content-Language" content="pt-BR">
+		</head></html>`, bookmarks.Metadata{
+			Title:        "Twitter Title",
+			Description:  "Plain description",
+			ImageURL:     "https://cdn.example.com/card.jpg",
+			CanonicalURL: "https://example.com/og-url",
+			PublishedAt:  time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC).Unix(),
+			SiteName:     "Example App",
+			Language:     "pt-BR",
+		}},
+		{"bad", `<html><head>
+			<meta property="og:image" content="javascript:alert(1)">
+			<link rel="canonical" href="mailto:someone@example.com">
+			<meta property="article:published_time" content="last tuesday">
+			<meta property="og:title" content="">
+		</head></html>`, bookmarks.Metadata{}},
+		{"empty", "", bookmarks.Metadata{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.page))
+			if err != nil {
+				t.Fatal("cannot parse page:", err)
+			}
+			if got := extractMetadata(doc, base); got != tt.want {
+				t.Errorf("extractMetadata() =\n%#v\nwant\n%#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckLink_metadata(t *testing.T) {
+	checker := NewChecker()
+	checker.httpClient = &httpGetterMock{DoFunc: func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"text/html; charset=utf-8"}},
+			Body:       io.NopCloser(strings.NewReader(metadataPage)),
+			Request:    req,
+		}, nil
+	}}
+	got := checker.Check(context.TODO(), bookmarks.CheckRequest{URL: "https://example.com/articles/1", Title: "Custom Title"})
+	if got.Title != "Custom Title" || got.Metadata.SiteName != "Example Site" || got.Metadata.CanonicalURL != "https://example.com/articles/1" {
+		t.Errorf("unexpected result: %#v", got)
+	}
+	title, metadata := checker.Preview(context.TODO(), "https://example.com/articles/1")
+	if title != "Page Title" || metadata.Title != "OpenGraph Title" {
+		t.Errorf("unexpected preview: %q %#v", title, metadata)
+	}
+}
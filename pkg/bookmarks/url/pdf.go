@@ -0,0 +1,212 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package url
+
+import (
+	"bytes"
+	"encoding/hex"
+	"html"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"cirello.io/alreadyread/pkg/bookmarks"
+)
+
+// pdfTailBytes is how much of the end of a PDF document is fetched when the
+// document is larger than the read limit, as the trailer that points to the
+// document information dictionary is at the end, and the dictionary itself
+// usually is too.
+const pdfTailBytes = 64 << 10
+
+// extractPDF reads the title and author of a PDF document from its XMP
+// metadata or, failing that, from its document information dictionary.
+// Documents larger than the read limit have their end fetched for the
+// dictionary, if the host serves byte ranges. Documents that keep these in
+// compressed streams, as in the object streams of PDF 1.5, go without.
+func extractPDF(c *Content) (string, bookmarks.Metadata, error) {
+	data, err := io.ReadAll(c.Body)
+	if err != nil {
+		return "", bookmarks.Metadata{}, err
+	}
+	title, author := xmpProperty(data, "title"), xmpProperty(data, "creator")
+	info := pdfInfo(data)
+	if info == nil && title == "" {
+		tail, err := c.Tail(pdfTailBytes)
+		if isTimeout(err) {
+			return "", bookmarks.Metadata{}, err
+		}
+		// The tail may overlap with what was read, but the last trailer
+		// and the last copy of an object win anyway.
+		info = pdfInfo(append(data, tail...))
+	}
+	if info != nil {
+		if title == "" {
+			title = pdfInfoString(info, "Title")
+		}
+		if author == "" {
+			author = pdfInfoString(info, "Author")
+		}
+	}
+	return title, bookmarks.Metadata{Author: author}, nil
+}
+
+var xmpProperties = map[string]*regexp.Regexp{
+	"title":   regexp.MustCompile(`(?s)<dc:title>.*?<rdf:li[^>]*>(.*?)</rdf:li>`),
+	"creator": regexp.MustCompile(`(?s)<dc:creator>.*?<rdf:li[^>]*>(.*?)</rdf:li>`),
+}
+
+// xmpProperty reads the first value of a Dublin Core property of the XMP
+// metadata packet.
+func xmpProperty(data []byte, name string) string {
+	m := xmpProperties[name].FindSubmatch(data)
+	if m == nil {
+		return ""
+	}
+	return strings.TrimSpace(html.UnescapeString(string(m[1])))
+}
+
+var pdfInfoRef = regexp.MustCompile(`/Info\s+(\d+)\s+(\d+)\s+R`)
+
+// pdfInfo finds the document information dictionary that the trailer points
+// to. Outlines and annotations have titles too, so looking for any /Title
+// would not do. The last trailer wins, as it comes with the latest update.
+func pdfInfo(data []byte) []byte {
+	refs := pdfInfoRef.FindAllSubmatch(data, -1)
+	if len(refs) == 0 {
+		return nil
+	}
+	ref := refs[len(refs)-1]
+	obj := regexp.MustCompile(`(?:^|[^0-9])` + string(ref[1]) + `\s+` + string(ref[2]) + `\s+obj\b`)
+	locs := obj.FindAllIndex(data, -1)
+	if len(locs) == 0 {
+		return nil
+	}
+	info := data[locs[len(locs)-1][1]:]
+	if end := bytes.Index(info, []byte("endobj")); end >= 0 {
+		info = info[:end]
+	}
+	return info
+}
+
+// pdfInfoString reads a text string entry of the dictionary.
+func pdfInfoString(dict []byte, key string) string {
+	m := regexp.MustCompile(`/` + key + `\s*([(<])`).FindSubmatchIndex(dict)
+	if m == nil {
+		return ""
+	}
+	var raw []byte
+	switch rest := dict[m[3]:]; dict[m[2]] {
+	case '(':
+		raw = pdfLiteralString(rest)
+	case '<':
+		raw = pdfHexString(rest)
+	}
+	return strings.TrimSpace(pdfText(raw))
+}
+
+// pdfLiteralString decodes a string written between balanced parentheses,
+// given what follows the opening one.
+func pdfLiteralString(b []byte) []byte {
+	var out []byte
+	depth := 1
+	for i := 0; i < len(b); i++ {
+		switch c := b[i]; c {
+		case '\\':
+			if i++; i == len(b) {
+				return out
+			}
+			switch c := b[i]; c {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b':
+				out = append(out, '\b')
+			case 'f':
+				out = append(out, '\f')
+			case '\r':
+				// A backslash at the end of a line continues the string.
+				if i+1 < len(b) && b[i+1] == '\n' {
+					i++
+				}
+			case '\n':
+			case '0', '1', '2', '3', '4', '5', '6', '7':
+				v := c - '0'
+				for n := 1; n < 3 && i+1 < len(b) && b[i+1] >= '0' && b[i+1] <= '7'; n++ {
+					i++
+					v = v*8 + b[i] - '0'
+				}
+				out = append(out, v)
+			default:
+				out = append(out, c)
+			}
+		case '(':
+			depth++
+			out = append(out, c)
+		case ')':
+			if depth--; depth == 0 {
+				return out
+			}
+			out = append(out, c)
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// pdfHexString decodes a string written in hexadecimal, given what follows
+// the opening angle bracket.
+func pdfHexString(b []byte) []byte {
+	if end := bytes.IndexByte(b, '>'); end >= 0 {
+		b = b[:end]
+	}
+	digits := []byte(strings.Join(strings.Fields(string(b)), ""))
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out, err := hex.DecodeString(string(digits))
+	if err != nil {
+		return nil
+	}
+	return out
+}
+
+// pdfText decodes a PDF text string, which is either UTF-16BE or UTF-8 with
+// a byte order mark, or PDFDocEncoding, read here as Latin-1, which it
+// matches for the most part.
+func pdfText(b []byte) string {
+	switch {
+	case bytes.HasPrefix(b, []byte{0xfe, 0xff}):
+		b = b[2:]
+		units := make([]uint16, 0, len(b)/2)
+		for i := 0; i+1 < len(b); i += 2 {
+			units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+		}
+		return string(utf16.Decode(units))
+	case bytes.HasPrefix(b, []byte{0xef, 0xbb, 0xbf}) && utf8.Valid(b[3:]):
+		return string(b[3:])
+	}
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
@@ -0,0 +1,223 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package url
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"cirello.io/alreadyread/pkg/bookmarks"
+)
+
+// robotsTTL is how long the robots.txt of a host is trusted before it is
+// fetched again.
+const robotsTTL = 24 * time.Hour
+
+// maxRobotsSize is how much of a robots.txt is read. Crawlers must read at
+// least 500 KiB of it, and may ignore the rest.
+const maxRobotsSize = 512 << 10
+
+// robotsRules are the rules of a robots.txt that apply to the link checker.
+// A nil *robotsRules allows everything.
+type robotsRules struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+type robotsEntry struct {
+	rules   *robotsRules
+	expires time.Time
+}
+
+// disallowAll are the rules of a host whose robots.txt is unreachable.
+var disallowAll = &robotsRules{rules: []robotsRule{{allow: false, pattern: "/"}}}
+
+// robots loads the rules that the host of the link sets for the link checker,
+// from the cache if possible. Hosts without a robots.txt allow everything,
+// and hosts whose robots.txt fails with a server error disallow everything.
+// Hosts that cannot be reached at all are reported with the error, and asked
+// again next time.
+func (u *Checker) robots(ctx context.Context, pacer bookmarks.HostPacer, link *neturl.URL) (*robotsRules, error) {
+	key := link.Scheme + "://" + link.Host
+	now := u.timeNow()
+	u.robotsMu.Lock()
+	entry, ok := u.robotsCache[key]
+	u.robotsMu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.rules, nil
+	}
+	rules, err := u.fetchRobots(ctx, pacer, key+"/robots.txt")
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch robots.txt: %w", err)
+	}
+	u.robotsMu.Lock()
+	for key, entry := range u.robotsCache {
+		if !now.Before(entry.expires) {
+			delete(u.robotsCache, key)
+		}
+	}
+	u.robotsCache[key] = &robotsEntry{rules: rules, expires: now.Add(robotsTTL)}
+	u.robotsMu.Unlock()
+	return rules, nil
+}
+
+// fetchRobots reads the robots.txt at the given URL. Like server errors, a
+// 429 means that the host cannot tell what may be fetched yet, while any
+// other error of the client means that there is no robots.txt.
+func (u *Checker) fetchRobots(ctx context.Context, pacer bookmarks.HostPacer, robotsURL string) (*robotsRules, error) {
+	res, err := u.get(ctx, pacer, robotsURL)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	switch {
+	case res.StatusCode >= http.StatusInternalServerError, res.StatusCode == http.StatusTooManyRequests:
+		return disallowAll, nil
+	case res.StatusCode != http.StatusOK:
+		return nil, nil
+	}
+	return parseRobots(io.LimitReader(res.Body, maxRobotsSize), robotsAgent(u.userAgent)), nil
+}
+
+// robotsAgent is the product token of the user agent, which is what
+// robots.txt groups name.
+func robotsAgent(userAgent string) string {
+	token, _, _ := strings.Cut(userAgent, "/")
+	token, _, _ = strings.Cut(token, " ")
+	return token
+}
+
+// parseRobots reads the rules of the groups that name the agent or, if there
+// are none, the rules of the groups for every agent.
+func parseRobots(r io.Reader, agent string) *robotsRules {
+	var (
+		specific, everyone   robotsRules
+		foundSpecific        bool
+		inAgents             bool // whether the previous line named an agent
+		isSpecific, isAnyone bool // whom the current group applies to
+	)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		if key == "user-agent" {
+			if !inAgents {
+				isSpecific, isAnyone = false, false
+			}
+			inAgents = true
+			switch {
+			case value == "*":
+				isAnyone = true
+			case strings.EqualFold(value, agent):
+				isSpecific, foundSpecific = true, true
+			}
+			continue
+		}
+		inAgents = false
+		var apply func(*robotsRules)
+		switch key {
+		case "allow", "disallow":
+			if value == "" {
+				continue
+			}
+			rule := robotsRule{allow: key == "allow", pattern: value}
+			apply = func(rr *robotsRules) { rr.rules = append(rr.rules, rule) }
+		case "crawl-delay":
+			seconds, err := strconv.ParseFloat(value, 64)
+			if err != nil || seconds <= 0 {
+				continue
+			}
+			delay := time.Duration(min(seconds, robotsTTL.Seconds()) * float64(time.Second))
+			apply = func(rr *robotsRules) { rr.crawlDelay = delay }
+		default:
+			continue
+		}
+		if isSpecific {
+			apply(&specific)
+		}
+		if isAnyone {
+			apply(&everyone)
+		}
+	}
+	if foundSpecific {
+		return &specific
+	}
+	return &everyone
+}
+
+// allowed reports whether the link checker may fetch the path, which
+// includes the query string. The rule with the longest pattern that matches
+// wins, and allowing wins ties.
+func (rr *robotsRules) allowed(path string) bool {
+	if rr == nil || path == "/robots.txt" {
+		return true
+	}
+	allow, longest := true, -1
+	for _, rule := range rr.rules {
+		if !robotsMatch(rule.pattern, path) {
+			continue
+		}
+		if n := len(rule.pattern); n > longest || (n == longest && rule.allow) {
+			allow, longest = rule.allow, n
+		}
+	}
+	return allow
+}
+
+// robotsMatch reports whether the path matches the pattern of a rule, in
+// which * matches any sequence of characters and a trailing $ anchors the
+// pattern to the end of the path.
+func robotsMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	parts := strings.Split(strings.TrimSuffix(pattern, "$"), "*")
+	rest, ok := strings.CutPrefix(path, parts[0])
+	if !ok {
+		return false
+	}
+	for i, part := range parts[1:] {
+		if anchored && i == len(parts)-2 {
+			return strings.HasSuffix(rest, part)
+		}
+		idx := strings.Index(rest, part)
+		if idx < 0 {
+			return false
+		}
+		rest = rest[idx+len(part):]
+	}
+	return !anchored || rest == ""
+}
+
+func (rr *robotsRules) delay() time.Duration {
+	if rr == nil {
+		return 0
+	}
+	return rr.crawlDelay
+}
@@ -0,0 +1,225 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package url
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cirello.io/alreadyread/pkg/bookmarks"
+)
+
+const robotsFile = `# comments are ignored
+User-agent: *
+Disallow: /private
+Crawl-delay: 2
+
+User-agent: otherbot
+User-agent: AlreadyRead
+Disallow: /secret
+Allow: /secret/ok
+Disallow: /*.pdf$
+Crawl-delay: 1.5
+
+Sitemap: https://example.com/sitemap.xml
+`
+
+func Test_parseRobots(t *testing.T) {
+	tests := []struct {
+		agent     string
+		path      string
+		want      bool
+		wantDelay time.Duration
+	}{
+		{"alreadyread", "/", true, 1500 * time.Millisecond},
+		{"alreadyread", "/private", true, 1500 * time.Millisecond},
+		{"alreadyread", "/secret", false, 1500 * time.Millisecond},
+		{"alreadyread", "/secret/page", false, 1500 * time.Millisecond},
+		{"alreadyread", "/secret/ok", true, 1500 * time.Millisecond},
+		{"alreadyread", "/files/report.pdf", false, 1500 * time.Millisecond},
+		{"alreadyread", "/files/report.pdf?download=1", true, 1500 * time.Millisecond},
+		{"alreadyread", "/robots.txt", true, 1500 * time.Millisecond},
+		{"somebot", "/private/page", false, 2 * time.Second},
+		{"somebot", "/secret", true, 2 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.agent+tt.path, func(t *testing.T) {
+			rules := parseRobots(strings.NewReader(robotsFile), tt.agent)
+			if got := rules.allowed(tt.path); got != tt.want {
+				t.Errorf("robotsRules.allowed() = %v, want %v", got, tt.want)
+			}
+			if got := rules.delay(); got != tt.wantDelay {
+				t.Errorf("robotsRules.delay() = %v, want %v", got, tt.wantDelay)
+			}
+		})
+	}
+	t.Run("empty", func(t *testing.T) {
+		var rules *robotsRules
+		if !rules.allowed("/anything") || rules.delay() != 0 {
+			t.Error("missing robots.txt must allow everything")
+		}
+		rules = parseRobots(strings.NewReader("User-agent: *\nDisallow:\n"), "alreadyread")
+		if !rules.allowed("/anything") {
+			t.Error("empty Disallow must allow everything")
+		}
+	})
+}
+
+func Test_robotsMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"/", "/anything", true},
+		{"/fish", "/fish.html", true},
+		{"/fish", "/Fish", false},
+		{"/fish$", "/fish", true},
+		{"/fish$", "/fish/", false},
+		{"/*.php", "/index.php?x=1", true},
+		{"/*.php$", "/index.php?x=1", false},
+		{"/*.php$", "/a.php.php", true},
+		{"/a*b*c", "/axxbyyc", true},
+		{"/a*b*c", "/axxcyyb", false},
+	}
+	for _, tt := range tests {
+		if got := robotsMatch(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("robotsMatch(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func Test_robotsAgent(t *testing.T) {
+	for ua, want := range map[string]string{
+		"alreadyread":                    "alreadyread",
+		"alreadyread/1.0":                "alreadyread",
+		"alreadyread (+https://example)": "alreadyread",
+		"alreadyread/1.0 (+https://a/b)": "alreadyread",
+	} {
+		if got := robotsAgent(ua); got != want {
+			t.Errorf("robotsAgent(%q) = %q, want %q", ua, got, want)
+		}
+	}
+}
+
+func TestCheckLink_robots(t *testing.T) {
+	var robotsFetches atomic.Int32
+	var userAgent atomic.Value
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		robotsFetches.Add(1)
+		io.WriteString(w, robotsFile)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		userAgent.Store(r.UserAgent())
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	now := time.Unix(0, 0)
+	checker := NewChecker(WithUserAgent("AlreadyRead/1.0"), allowTestServer)
+	checker.timeNow = func() time.Time { return now }
+	got := checker.Check(context.TODO(), bookmarks.CheckRequest{URL: ts.URL + "/secret/page", Title: "title"})
+	if got.Code != bookmarks.StatusBlockedByRobots || !got.Blocked() || got.Failed() || got.CrawlDelay != 1500*time.Millisecond {
+		t.Errorf("unexpected result for blocked link: %#v", got)
+	}
+	if userAgent.Load() != nil {
+		t.Error("blocked link was fetched")
+	}
+	got = checker.Check(context.TODO(), bookmarks.CheckRequest{URL: ts.URL + "/private", Title: "title"})
+	if got.Code != http.StatusOK || got.CrawlDelay != 1500*time.Millisecond {
+		t.Errorf("unexpected result for allowed link: %#v", got)
+	}
+	if ua := userAgent.Load(); ua != "AlreadyRead/1.0" {
+		t.Error("unexpected user agent:", ua)
+	}
+	if n := robotsFetches.Load(); n != 1 {
+		t.Error("robots.txt not cached:", n)
+	}
+	now = now.Add(robotsTTL)
+	checker.Check(context.TODO(), bookmarks.CheckRequest{URL: ts.URL + "/", Title: "title"})
+	if n := robotsFetches.Load(); n != 2 {
+		t.Error("robots.txt not fetched again once expired:", n)
+	}
+
+	t.Run("missing", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/robots.txt" {
+				http.NotFound(w, r)
+			}
+		}))
+		defer ts.Close()
+		if got := NewChecker(allowTestServer).Check(context.TODO(), bookmarks.CheckRequest{URL: ts.URL + "/secret", Title: "title"}); got.Code != http.StatusOK || got.CrawlDelay != 0 {
+			t.Errorf("unexpected result without robots.txt: %#v", got)
+		}
+	})
+	t.Run("unreachable", func(t *testing.T) {
+		for _, code := range []int{http.StatusInternalServerError, http.StatusServiceUnavailable, http.StatusTooManyRequests} {
+			var fetched atomic.Bool
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/robots.txt" {
+					w.WriteHeader(code)
+					return
+				}
+				fetched.Store(true)
+			}))
+			got := NewChecker(allowTestServer).Check(context.TODO(), bookmarks.CheckRequest{URL: ts.URL + "/page", Title: "title"})
+			ts.Close()
+			if !got.Blocked() || fetched.Load() {
+				t.Errorf("%d: link fetched despite robots.txt failing: %#v", code, got)
+			}
+		}
+	})
+	t.Run("networkError", func(t *testing.T) {
+		var fetched atomic.Bool
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/robots.txt" {
+				conn, _, _ := w.(http.Hijacker).Hijack()
+				conn.Close()
+				return
+			}
+			fetched.Store(true)
+		}))
+		defer ts.Close()
+		checker := NewChecker(allowTestServer)
+		got := checker.Check(context.TODO(), bookmarks.CheckRequest{URL: ts.URL + "/page", Title: "title"})
+		if got.Code != http.StatusServiceUnavailable || !got.Failed() || !strings.Contains(got.Reason, "robots.txt") || fetched.Load() {
+			t.Errorf("unexpected result when robots.txt cannot be fetched: %#v", got)
+		}
+		if len(checker.robotsCache) != 0 {
+			t.Error("unreachable robots.txt cached")
+		}
+	})
+	t.Run("pruned", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer ts.Close()
+		now := time.Unix(0, 0)
+		checker := NewChecker(allowTestServer)
+		checker.timeNow = func() time.Time { return now }
+		checker.robotsCache["http://gone.example.com"] = &robotsEntry{expires: now.Add(time.Second)}
+		checker.robotsCache["http://recent.example.com"] = &robotsEntry{expires: now.Add(robotsTTL)}
+		now = now.Add(time.Minute)
+		checker.Check(context.TODO(), bookmarks.CheckRequest{URL: ts.URL + "/", Title: "title"})
+		if _, ok := checker.robotsCache["http://gone.example.com"]; ok || len(checker.robotsCache) != 2 {
+			t.Errorf("expired robots.txt not pruned: %v", checker.robotsCache)
+		}
+	})
+}
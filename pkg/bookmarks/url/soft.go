@@ -0,0 +1,251 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package url
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"cirello.io/alreadyread/pkg/bookmarks"
+)
+
+// probeTTL is how long the probe of a host is trusted before it is fetched
+// again.
+const probeTTL = 24 * time.Hour
+
+// probePage is what a host serves, with a 200 OK, for a page that does not
+// exist. A nil *probePage means that the host reports missing pages with an
+// error, or that it could not be probed.
+type probePage struct {
+	finalURL string // where the redirects led, if anywhere
+	title    string
+	size     int
+}
+
+type probeEntry struct {
+	page    *probePage
+	expires time.Time
+}
+
+// parkingHosts are domain marketplaces and parking services that expired
+// domains redirect to.
+var parkingHosts = []string{
+	"above.com",
+	"afternic.com",
+	"bodis.com",
+	"dan.com",
+	"domainmarket.com",
+	"hugedomains.com",
+	"parkingcrew.net",
+	"sedo.com",
+	"sedoparking.com",
+	"undeveloped.com",
+}
+
+// parkingFingerprints are found in the title and text of the pages of parked
+// domains. They are lower case.
+var parkingFingerprints = []string{
+	"this domain is for sale",
+	"this domain may be for sale",
+	"this domain name is for sale",
+	"buy this domain",
+	"inquire about this domain",
+	"make an offer",
+	"this domain has expired",
+	"this domain is parked",
+	"parked free",
+	"related searches",
+}
+
+// Pages of parked domains say so early, and more than once.
+const (
+	parkedTextBytes = 4 << 10
+	parkedSignals   = 2
+)
+
+var (
+	notFoundTitle = regexp.MustCompile(`(?i)\b404\b|\bnot found\b|\b(page|file) (does not|doesn't|no longer) exists?\b|\b(could not|cannot|can't) be found\b|\bno longer available\b`)
+	loginTitle    = regexp.MustCompile(`(?i)^\s*(sign[ -]?in|log[ -]?in|sign[ -]?on)\b`)
+	loginPath     = regexp.MustCompile(`(?i)(^|/)(login|log-in|signin|sign-in|sign_in|logon|sso|auth|authenticate|oauth2?|session/new|users/sign_in)(/|\.|$)`)
+	loginHosts    = []string{"login.", "signin.", "sso.", "auth.", "accounts."}
+)
+
+// softFailure tells why the page that the link loaded does not seem to be the
+// page that was bookmarked, or returns an empty string if it does. Pages
+// that redirect to a login page or to a domain marketplace, pages of parked
+// domains, and pages whose title changed to an error or login title are soft
+// failures. So are pages that look like what the host serves for a random
+// path, which means that the host answers missing pages with a 200 OK. Only
+// pages whose title changed since the last time the link loaded, going by
+// lastTitle, are compared with the latter.
+func (u *Checker) softFailure(ctx context.Context, pacer bookmarks.HostPacer, link *neturl.URL, robots *robotsRules, page *Content, body []byte, title, originalTitle, lastTitle string) string {
+	redirected := page.URL.String() != link.String()
+	switch {
+	case redirected && isLoginPage(page.URL) && !isLoginPage(link):
+		return "redirects to a login page"
+	case redirected && isParkingHost(page.URL.Hostname()) && !isParkingHost(link.Hostname()):
+		return "redirects to a domain marketplace"
+	case page.MediaType != "text/html":
+		return ""
+	case isParked(page, body, title):
+		return "parked domain"
+	}
+	renamed := originalTitle != "" && title != originalTitle
+	if renamed && notFoundTitle.MatchString(title) && !notFoundTitle.MatchString(originalTitle) {
+		return fmt.Sprintf("the title changed to %q", title)
+	}
+	if renamed && loginTitle.MatchString(title) && !loginTitle.MatchString(originalTitle) {
+		return fmt.Sprintf("the title changed to %q", title)
+	}
+	// Single page applications serve the same page for every path, and
+	// their pages need not have the title they were bookmarked with. So
+	// pages are only compared with the probe when their title changed
+	// since the last time the link loaded.
+	titleChanged := lastTitle != "" && title != lastTitle
+	if link.Path == "" || link.Path == "/" || (!redirected && !titleChanged) {
+		return ""
+	}
+	probe := u.probe(ctx, pacer, link, robots)
+	switch {
+	case probe == nil:
+		return ""
+	case redirected && probe.finalURL == page.URL.String():
+		return "redirects where missing pages do"
+	case titleChanged && title != "" && title == probe.title && similarSize(len(body), probe.size):
+		return "looks like the page served for missing pages"
+	}
+	return ""
+}
+
+func isLoginPage(u *neturl.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	for _, prefix := range loginHosts {
+		if strings.HasPrefix(host, prefix) {
+			return true
+		}
+	}
+	return loginPath.MatchString(u.Path)
+}
+
+func isParkingHost(host string) bool {
+	host = strings.ToLower(host)
+	for _, parker := range parkingHosts {
+		if host == parker || strings.HasSuffix(host, "."+parker) {
+			return true
+		}
+	}
+	return false
+}
+
+// isParked reports whether the page is that of a parked domain, going by
+// the fingerprints found in its title and in the beginning of its visible
+// text. A single fingerprint could as well be in an article about domains.
+func isParked(page *Content, body []byte, title string) bool {
+	if countFingerprints(string(body)) < parkedSignals {
+		// Skips parsing the page again when it cannot be parked.
+		return false
+	}
+	doc, err := readPage(newContent(page.URL, page.Header, bytes.NewReader(body)))
+	if err != nil {
+		return false
+	}
+	doc.Find("script, style, noscript, template").Remove()
+	text := strings.Join(strings.Fields(doc.Find("body").Text()), " ")
+	if len(text) > parkedTextBytes {
+		text = text[:parkedTextBytes]
+	}
+	return countFingerprints(title+"\n"+text) >= parkedSignals
+}
+
+func countFingerprints(text string) int {
+	text = strings.ToLower(text)
+	var n int
+	for _, fingerprint := range parkingFingerprints {
+		if strings.Contains(text, fingerprint) {
+			n++
+		}
+	}
+	return n
+}
+
+// similarSize reports whether two page sizes are within 10% of each other,
+// as pages for missing paths often repeat the path.
+func similarSize(a, b int) bool {
+	return max(a-b, b-a) <= max(a, b)/10
+}
+
+// probe loads what the host of the link serves for a random path, from the
+// cache if possible. Paths that robots.txt disallows are not probed.
+func (u *Checker) probe(ctx context.Context, pacer bookmarks.HostPacer, link *neturl.URL, robots *robotsRules) *probePage {
+	key := link.Scheme + "://" + link.Host
+	now := u.timeNow()
+	u.probeMu.Lock()
+	entry, ok := u.probeCache[key]
+	u.probeMu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.page
+	}
+	path := "/" + strings.ToLower(rand.Text())
+	if !robots.allowed(path) {
+		return nil
+	}
+	page, err := u.fetchProbe(ctx, pacer, key+path)
+	if err != nil {
+		// The host may answer next time.
+		return nil
+	}
+	u.probeMu.Lock()
+	for key, entry := range u.probeCache {
+		if !now.Before(entry.expires) {
+			delete(u.probeCache, key)
+		}
+	}
+	u.probeCache[key] = &probeEntry{page: page, expires: now.Add(probeTTL)}
+	u.probeMu.Unlock()
+	return page
+}
+
+func (u *Checker) fetchProbe(ctx context.Context, pacer bookmarks.HostPacer, probeURL string) (*probePage, error) {
+	res, err := u.get(ctx, pacer, probeURL)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(res.Body, u.maxTitleBytes))
+	if err != nil {
+		return nil, err
+	}
+	probe := &probePage{size: len(body)}
+	page, _ := neturl.Parse(probeURL)
+	if res.Request != nil && res.Request.URL.String() != probeURL {
+		page = res.Request.URL
+		probe.finalURL = page.String()
+	}
+	if c := newContent(page, res.Header, bytes.NewReader(body)); c.MediaType == "text/html" {
+		probe.title, _, _ = u.extractHTML(c)
+	}
+	return probe, nil
+}
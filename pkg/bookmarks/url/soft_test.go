@@ -0,0 +1,199 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package url
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	neturl "net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"cirello.io/alreadyread/pkg/bookmarks"
+)
+
+func TestCheckLink_softFailures(t *testing.T) {
+	var probes atomic.Int32
+	// The page for missing paths repeats the path, amid the usual layout.
+	notFound := func(path string) string {
+		return "<nav>Home · Blog · About</nav>" + strings.Repeat("<p>layout</p>", 20) + "<p>Sorry, there is nothing at " + path + "</p>"
+	}
+	page := func(title, body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "<html><head><title>%s</title></head><body>%s</body></html>", title, body)
+		}
+	}
+	// soft answers missing pages with a 200 OK, unlike strict.
+	soft := http.NewServeMux()
+	soft.HandleFunc("/robots.txt", http.NotFound)
+	soft.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		probes.Add(1)
+		page("Example", notFound(r.URL.Path))(w, r)
+	})
+	soft.HandleFunc("/article", page("Example", notFound("/article")))
+	soft.HandleFunc("/renamed", page("New Name", "A long article that is still here."))
+	soft.HandleFunc("/parked", page("example.com", "This domain is for sale! Make an offer to the owner."))
+	soft.HandleFunc("/domains", page("On domains", "How to buy a domain: look for a page that says 'this domain is for sale'."))
+	soft.HandleFunc("/scripted", page("Scripted", "<script>// buy this domain, make an offer</script>An article."))
+	soft.HandleFunc("/gone", page("Page Not Found", ""))
+	soft.HandleFunc("/members", http.RedirectHandler("/login?next=/members", http.StatusFound).ServeHTTP)
+	soft.HandleFunc("/login", page("Sign in", "<form></form>"))
+	soft.HandleFunc("/walled", page("Log in to continue", "<form></form>"))
+	softServer := httptest.NewServer(soft)
+	defer softServer.Close()
+
+	strict := http.NewServeMux()
+	strict.HandleFunc("/robots.txt", http.NotFound)
+	strict.HandleFunc("/", http.NotFound)
+	strict.HandleFunc("/article", page("Example", notFound("/article")))
+	strictServer := httptest.NewServer(strict)
+	defer strictServer.Close()
+
+	// home answers missing pages with a redirect to the home page.
+	home := http.NewServeMux()
+	home.HandleFunc("/robots.txt", http.NotFound)
+	home.HandleFunc("/{$}", page("Home", "Welcome"))
+	home.HandleFunc("/", http.RedirectHandler("/", http.StatusMovedPermanently).ServeHTTP)
+	home.HandleFunc("/moved", http.RedirectHandler("/new-place", http.StatusMovedPermanently).ServeHTTP)
+	home.HandleFunc("/new-place", page("New Place", ""))
+	homeServer := httptest.NewServer(home)
+	defer homeServer.Close()
+
+	tests := []struct {
+		name       string
+		url        string
+		title      string
+		lastTitle  string
+		wantCode   int64
+		wantReason string
+	}{
+		{"probeMatches", softServer.URL + "/article", "Old Article", "Old Article", bookmarks.StatusSoftFailure, "looks like the page served for missing pages"},
+		{"titleUnchanged", softServer.URL + "/article", "Example", "Example", http.StatusOK, ""},
+		{"bookmarkRetitled", softServer.URL + "/article", "My Notes", "Example", http.StatusOK, ""},
+		{"noLastTitle", softServer.URL + "/article", "Old Article", "", http.StatusOK, ""},
+		{"newBookmark", softServer.URL + "/article", "", "", http.StatusOK, ""},
+		{"renamed", softServer.URL + "/renamed", "Old Name", "Old Name", http.StatusOK, ""},
+		{"parked", softServer.URL + "/parked", "Old Article", "Old Article", bookmarks.StatusSoftFailure, "parked domain"},
+		{"parkingMentioned", softServer.URL + "/domains", "On domains", "On domains", http.StatusOK, ""},
+		{"parkingScript", softServer.URL + "/scripted", "Scripted", "Scripted", http.StatusOK, ""},
+		{"notFoundTitle", softServer.URL + "/gone", "Old Article", "", bookmarks.StatusSoftFailure, `the title changed to "Page Not Found"`},
+		{"loginRedirect", softServer.URL + "/members", "Members", "", bookmarks.StatusSoftFailure, "redirects to a login page"},
+		{"loginBookmarked", softServer.URL + "/login", "Sign in", "Sign in", http.StatusOK, ""},
+		{"loginTitle", softServer.URL + "/walled", "Old Article", "", bookmarks.StatusSoftFailure, `the title changed to "Log in to continue"`},
+		{"strictHost", strictServer.URL + "/article", "Old Article", "Old Article", http.StatusOK, ""},
+		{"redirectsHome", homeServer.URL + "/deleted", "Old Article", "", bookmarks.StatusSoftFailure, "redirects where missing pages do"},
+		{"redirectsElsewhere", homeServer.URL + "/moved", "Old Place", "Old Place", http.StatusOK, ""},
+		{"homePage", homeServer.URL + "/", "Old Home", "Old Home", http.StatusOK, ""},
+	}
+	checker := NewChecker(allowTestServer)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := checker.Check(context.TODO(), bookmarks.CheckRequest{URL: tt.url, Title: tt.title, PageTitle: tt.lastTitle})
+			if got.Code != tt.wantCode || (tt.wantReason != "" && got.Reason != tt.wantReason) {
+				t.Errorf("Check() = %v %q, want %v %q", got.Code, got.Reason, tt.wantCode, tt.wantReason)
+			}
+			if got.Failed() != (tt.wantCode != http.StatusOK) {
+				t.Errorf("Check().Failed() = %v", got.Failed())
+			}
+		})
+	}
+	if n := probes.Load(); n != 1 {
+		t.Error("probe not cached:", n)
+	}
+	t.Run("pacer", func(t *testing.T) {
+		var turns int
+		pacer := pacerFunc(func(context.Context) error {
+			turns++
+			return nil
+		})
+		got := NewChecker(allowTestServer).Check(context.TODO(), bookmarks.CheckRequest{URL: softServer.URL + "/article", Title: "Old Article", PageTitle: "Old Article", Pacer: pacer})
+		if got.Code != bookmarks.StatusSoftFailure {
+			t.Errorf("unexpected result: %#v", got)
+		}
+		// robots.txt, the page and the probe.
+		if turns != 3 {
+			t.Error("requests did not wait for the pacer:", turns)
+		}
+		errTurn := errors.New("no turn")
+		pacer = func(context.Context) error { return errTurn }
+		if got := NewChecker(allowTestServer).Check(context.TODO(), bookmarks.CheckRequest{URL: softServer.URL + "/article", Title: "Old Article", PageTitle: "Old Article", Pacer: pacer}); !got.Failed() || !strings.Contains(got.Reason, errTurn.Error()) {
+			t.Errorf("unexpected result without a turn: %#v", got)
+		}
+	})
+}
+
+type pacerFunc func(context.Context) error
+
+func (f pacerFunc) Wait(ctx context.Context) error { return f(ctx) }
+
+func Test_isLoginPage(t *testing.T) {
+	for link, want := range map[string]bool{
+		"https://example.com/login":                    true,
+		"https://example.com/users/sign_in":            true,
+		"https://example.com/auth/realms/x":            true,
+		"https://accounts.example.com/ServiceLogin":    true,
+		"https://example.com/session/new":              true,
+		"https://example.com/blog/login-best-practice": false,
+		"https://example.com/authors":                  false,
+		"https://example.com/":                         false,
+	} {
+		u, _ := neturl.Parse(link)
+		if got := isLoginPage(u); got != want {
+			t.Errorf("isLoginPage(%q) = %v, want %v", link, got, want)
+		}
+	}
+}
+
+func Test_isParkingHost(t *testing.T) {
+	for host, want := range map[string]bool{
+		"sedo.com":      true,
+		"www.dan.com":   true,
+		"SEDO.COM":      true,
+		"jordan.com":    false,
+		"example.com":   false,
+		"sedo.com.evil": false,
+	} {
+		if got := isParkingHost(host); got != want {
+			t.Errorf("isParkingHost(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
+
+func Test_notFoundTitle(t *testing.T) {
+	for title, want := range map[string]bool{
+		"404":                         true,
+		"Error 404 - Example":         true,
+		"Page Not Found":              true,
+		"This page does not exist":    true,
+		"The file could not be found": true,
+		"Example Domain":              false,
+		"Found in translation":        false,
+		"Route 4040":                  false,
+	} {
+		if got := notFoundTitle.MatchString(title); got != want {
+			t.Errorf("notFoundTitle.MatchString(%q) = %v, want %v", title, got, want)
+		}
+	}
+}
+
+func Test_similarSize(t *testing.T) {
+	if !similarSize(1000, 1090) || !similarSize(1090, 1000) || similarSize(1000, 1200) || !similarSize(0, 0) {
+		t.Error("unexpected size comparison")
+	}
+}
@@ -0,0 +1,236 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package url
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// newTransport makes the HTTP transport of the checker, which gives up on
+// hosts that take too long to accept the connection, to send anything or to
+// send the whole response, and refuses to connect to private addresses. The
+// addresses are vetted as they are dialed, after name resolution, so neither
+// redirects nor DNS rebinding get around the guard. Proxies would connect on
+// behalf of the checker, out of its sight, so none are used.
+func (u *Checker) newTransport() http.RoundTripper {
+	dialer := &net.Dialer{Timeout: u.connectTimeout, KeepAlive: 30 * time.Second}
+	guarded := &net.Dialer{Timeout: u.connectTimeout, KeepAlive: 30 * time.Second, Control: u.guard}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		d := guarded
+		if host, _, err := net.SplitHostPort(addr); err == nil && u.allowedHosts[strings.ToLower(host)] {
+			d = dialer
+		}
+		conn, err := d.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &readTimeoutConn{Conn: conn, timeout: u.readTimeout}, nil
+	}
+	transport.TLSHandshakeTimeout = u.readTimeout
+	return &userAgentTransport{userAgent: u.userAgent, next: &bodyTimeoutTransport{timeout: u.readTimeout, next: transport}}
+}
+
+// privateAddressError tells that the checker refused to connect to an
+// address.
+type privateAddressError struct {
+	addr netip.Addr
+}
+
+func (e *privateAddressError) Error() string {
+	return "refusing to connect to private address " + e.addr.String()
+}
+
+// guard refuses connections to private addresses, unless they are allowed.
+// It runs right before the dialer connects to a resolved address.
+func (u *Checker) guard(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("cannot parse dialed address: %w", err)
+	}
+	addr := addrPort.Addr().Unmap()
+	if !isPrivateAddress(addr) {
+		return nil
+	}
+	for _, network := range u.allowedNetworks {
+		if network.Contains(addr) {
+			return nil
+		}
+	}
+	return &privateAddressError{addr: addr}
+}
+
+// specialPurposeNetworks are the networks that the IANA special-purpose
+// address registries do not deem globally reachable, along with multicast and
+// deprecated networks.
+var specialPurposeNetworks = mustParsePrefixes(
+	"0.0.0.0/8",          // this network
+	"10.0.0.0/8",         // private use
+	"100.64.0.0/10",      // shared address space, for carrier-grade NATs and cloud metadata services
+	"127.0.0.0/8",        // loopback
+	"169.254.0.0/16",     // link local, where cloud metadata services live
+	"172.16.0.0/12",      // private use
+	"192.0.0.0/24",       // IETF protocol assignments
+	"192.0.2.0/24",       // documentation (TEST-NET-1)
+	"192.88.99.0/24",     // deprecated 6to4 relay anycast
+	"192.168.0.0/16",     // private use
+	"198.18.0.0/15",      // benchmarking
+	"198.51.100.0/24",    // documentation (TEST-NET-2)
+	"203.0.113.0/24",     // documentation (TEST-NET-3)
+	"224.0.0.0/4",        // multicast
+	"240.0.0.0/4",        // reserved
+	"255.255.255.255/32", // limited broadcast
+	"::/96",              // unspecified, loopback and deprecated IPv4-compatible addresses
+	"64:ff9b:1::/48",     // local-use IPv4/IPv6 translation
+	"100::/64",           // discard-only
+	"2001::/23",          // IETF protocol assignments, Teredo included
+	"2001:db8::/32",      // documentation
+	"3fff::/20",          // documentation
+	"5f00::/16",          // segment routing (SRv6) SIDs
+	"fc00::/7",           // unique local
+	"fe80::/10",          // link local
+	"fec0::/10",          // deprecated site local
+	"ff00::/8",           // multicast
+)
+
+// Networks of IPv6 addresses that carry an IPv4 address, which is where the
+// connection ends up.
+var (
+	nat64Network     = netip.MustParsePrefix("64:ff9b::/96")
+	sixToFourNetwork = netip.MustParsePrefix("2002::/16")
+)
+
+func mustParsePrefixes(networks ...string) []netip.Prefix {
+	prefixes := make([]netip.Prefix, len(networks))
+	for i, network := range networks {
+		prefixes[i] = netip.MustParsePrefix(network)
+	}
+	return prefixes
+}
+
+// isPrivateAddress reports whether the address is not on the public
+// internet: loopback, private networks, link-local (where cloud metadata
+// services live), multicast, and every other special-purpose address. IPv6
+// addresses that carry an IPv4 address are judged by the IPv4 address.
+func isPrivateAddress(addr netip.Addr) bool {
+	addr = embeddedIPv4(addr.WithZone(""))
+	if !addr.IsValid() {
+		return true
+	}
+	for _, network := range specialPurposeNetworks {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// embeddedIPv4 returns the IPv4 address carried by IPv4-mapped, NAT64 and
+// 6to4 addresses, and any other address as is.
+func embeddedIPv4(addr netip.Addr) netip.Addr {
+	b := addr.As16()
+	switch {
+	case addr.Is4In6():
+		return addr.Unmap()
+	case nat64Network.Contains(addr):
+		return netip.AddrFrom4([4]byte(b[12:16]))
+	case sixToFourNetwork.Contains(addr):
+		return netip.AddrFrom4([4]byte(b[2:6]))
+	}
+	return addr
+}
+
+// readTimeoutConn fails reads that wait longer than the timeout for data.
+type readTimeoutConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *readTimeoutConn) Read(b []byte) (int, error) {
+	if err := c.Conn.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
+		return 0, err
+	}
+	return c.Conn.Read(b)
+}
+
+// bodyTimeoutTransport gives up on response bodies that take longer than the
+// timeout to arrive in full, counting from the response headers. Hosts that
+// trickle the body in keep every read of readTimeoutConn short, but not the
+// whole of them.
+type bodyTimeoutTransport struct {
+	timeout time.Duration
+	next    http.RoundTripper
+}
+
+func (t *bodyTimeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancelCause(req.Context())
+	res, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel(nil)
+		return nil, err
+	}
+	timeout := fmt.Errorf("response body took longer than %v: %w", t.timeout, os.ErrDeadlineExceeded)
+	timer := time.AfterFunc(t.timeout, func() { cancel(timeout) })
+	res.Body = &timeoutBody{ReadCloser: res.Body, ctx: ctx, stop: func() {
+		timer.Stop()
+		cancel(nil)
+	}}
+	return res, nil
+}
+
+// timeoutBody reports the reads cut short by bodyTimeoutTransport as
+// timeouts.
+type timeoutBody struct {
+	io.ReadCloser
+	ctx  context.Context
+	stop func()
+}
+
+func (b *timeoutBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if cause := context.Cause(b.ctx); err != nil && errors.Is(cause, os.ErrDeadlineExceeded) {
+		return n, cause
+	}
+	return n, err
+}
+
+func (b *timeoutBody) Close() error {
+	b.stop()
+	return b.ReadCloser.Close()
+}
+
+// userAgentTransport sets the User-Agent header of every request, redirects
+// included.
+type userAgentTransport struct {
+	userAgent string
+	next      http.RoundTripper
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.userAgent)
+	return t.next.RoundTrip(req)
+}
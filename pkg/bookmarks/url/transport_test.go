@@ -0,0 +1,230 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package url
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cirello.io/alreadyread/pkg/bookmarks"
+)
+
+// allowTestServer lets the checker reach the test servers, which listen on
+// the loopback address.
+var allowTestServer = WithAllowedHosts("127.0.0.1")
+
+func TestCheckLink_timeouts(t *testing.T) {
+	release := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+	mux.HandleFunc("/trickle", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		for {
+			io.WriteString(w, "<!-- padding -->")
+			w.(http.Flusher).Flush()
+			select {
+			case <-release:
+				return
+			case <-r.Context().Done():
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	})
+	mux.HandleFunc("/padded", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, "<html><head>"+strings.Repeat("<!-- padding -->", 1024)+"<title>Padded</title></head></html>")
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	defer close(release)
+
+	t.Run("read", func(t *testing.T) {
+		checker := NewChecker(WithTimeouts(time.Second, 50*time.Millisecond, 5*time.Second), allowTestServer)
+		if got := checker.Check(context.TODO(), bookmarks.CheckRequest{URL: ts.URL + "/slow"}); got.Code != bookmarks.StatusTimeout || got.Reason == "" {
+			t.Errorf("unexpected result: %#v", got)
+		}
+	})
+	t.Run("total", func(t *testing.T) {
+		checker := NewChecker(WithTimeouts(time.Second, time.Second, 200*time.Millisecond), allowTestServer)
+		if got := checker.Check(context.TODO(), bookmarks.CheckRequest{URL: ts.URL + "/trickle"}); got.Code != bookmarks.StatusTimeout {
+			t.Errorf("unexpected result: %#v", got)
+		}
+	})
+	t.Run("slowTrickle", func(t *testing.T) {
+		checker := NewChecker(WithTimeouts(time.Second, 200*time.Millisecond, 10*time.Second), allowTestServer)
+		start := time.Now()
+		got := checker.Check(context.TODO(), bookmarks.CheckRequest{URL: ts.URL + "/trickle"})
+		if got.Code != bookmarks.StatusTimeout || !strings.Contains(got.Reason, "response body") {
+			t.Errorf("unexpected result: %#v", got)
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Error("trickling body read for too long:", elapsed)
+		}
+	})
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.TODO())
+		cancel()
+		if got := NewChecker(allowTestServer).Check(ctx, bookmarks.CheckRequest{URL: ts.URL + "/slow"}); got.Code == http.StatusOK || got.Code == bookmarks.StatusTimeout {
+			t.Errorf("unexpected result: %#v", got)
+		}
+	})
+	t.Run("maxTitleBytes", func(t *testing.T) {
+		if got := NewChecker(allowTestServer).Check(context.TODO(), bookmarks.CheckRequest{URL: ts.URL + "/padded"}); got.Title != "Padded" {
+			t.Errorf("title not found: %#v", got)
+		}
+		checker := NewChecker(WithMaxTitleBytes(1024), allowTestServer)
+		if got := checker.Check(context.TODO(), bookmarks.CheckRequest{URL: ts.URL + "/padded"}); got.Code != http.StatusOK || got.Title != "" {
+			t.Errorf("title read past the limit: %#v", got)
+		}
+	})
+}
+
+func TestNewChecker_defaults(t *testing.T) {
+	checker := NewChecker(WithTimeouts(0, -1, 0), WithMaxTitleBytes(-1))
+	if checker.connectTimeout != DefaultConnectTimeout || checker.readTimeout != DefaultReadTimeout ||
+		checker.totalTimeout != DefaultTotalTimeout || checker.maxTitleBytes != DefaultMaxTitleBytes {
+		t.Errorf("defaults not applied: %#v", checker)
+	}
+}
+
+func TestCheckLink_privateAddress(t *testing.T) {
+	var fetched atomic.Bool
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", http.NotFound)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fetched.Store(true)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	_, port, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	mux.Handle("/redirect", http.RedirectHandler(ts.URL+"/", http.StatusFound))
+
+	t.Run("blocked", func(t *testing.T) {
+		fetched.Store(false)
+		got := NewChecker().Check(context.TODO(), bookmarks.CheckRequest{URL: ts.URL + "/", Title: "title"})
+		if got.Code != bookmarks.StatusPrivateAddress || !got.PrivateAddress() || got.Failed() || fetched.Load() {
+			t.Errorf("unexpected result: %#v", got)
+		}
+		if title := NewChecker().Title(context.TODO(), ts.URL+"/"); title != "" || fetched.Load() {
+			t.Error("private address fetched for its title")
+		}
+	})
+	t.Run("redirect", func(t *testing.T) {
+		fetched.Store(false)
+		checker := NewChecker(WithAllowedHosts("localhost"))
+		got := checker.Check(context.TODO(), bookmarks.CheckRequest{URL: "http://localhost:" + port + "/redirect", Title: "title"})
+		if got.Code != bookmarks.StatusPrivateAddress || fetched.Load() {
+			t.Errorf("redirect to a private address followed: %#v", got)
+		}
+	})
+	t.Run("allowed", func(t *testing.T) {
+		for _, hosts := range [][]string{{"127.0.0.1"}, {"127.0.0.0/8"}, {"example.com", "127.0.0.1/32"}} {
+			fetched.Store(false)
+			got := NewChecker(WithAllowedHosts(hosts...)).Check(context.TODO(), bookmarks.CheckRequest{URL: ts.URL + "/", Title: "title"})
+			if got.Code != http.StatusOK || !fetched.Load() {
+				t.Errorf("%v: unexpected result: %#v", hosts, got)
+			}
+		}
+	})
+}
+
+func TestChecker_guard(t *testing.T) {
+	checker := NewChecker(WithAllowedHosts("10.1.0.0/16", "fd00::1", "intranet"))
+	tests := []struct {
+		address string
+		allowed bool
+	}{
+		{"93.184.215.14:80", true},
+		{"[2606:2800:21f:cb07:6820:80da:af6b:8b2c]:443", true},
+		{"127.0.0.1:80", false},
+		{"[::1]:80", false},
+		{"10.0.0.1:80", false},
+		{"10.1.2.3:80", true},
+		{"172.16.0.1:80", false},
+		{"192.168.1.1:80", false},
+		{"169.254.169.254:80", false},
+		{"100.100.100.200:80", false},
+		{"0.0.0.0:80", false},
+		{"[::ffff:127.0.0.1]:80", false},
+		{"[::ffff:10.1.0.1]:80", true},
+		{"[fe80::1]:80", false},
+		{"[fd00::1]:80", true},
+		{"[fd00::2]:80", false},
+		{"224.0.0.1:80", false},
+		{"0.1.2.3:80", false},
+		{"100.64.0.1:80", false},
+		{"100.127.255.255:80", false},
+		{"100.128.0.1:80", true},
+		{"192.0.0.8:80", false},
+		{"192.0.2.1:80", false},
+		{"192.88.99.1:80", false},
+		{"198.18.0.1:80", false},
+		{"198.19.255.255:80", false},
+		{"198.20.0.1:80", true},
+		{"198.51.100.1:80", false},
+		{"203.0.113.1:80", false},
+		{"240.0.0.1:80", false},
+		{"255.255.255.255:80", false},
+		{"[::]:80", false},
+		{"[::127.0.0.1]:80", false},
+		{"[64:ff9b::7f00:1]:80", false},
+		{"[64:ff9b::a9fe:a9fe]:80", false},
+		{"[64:ff9b::5db8:d70e]:80", true},
+		{"[64:ff9b:1::1]:80", false},
+		{"[100::1]:80", false},
+		{"[2001::1]:80", false},
+		{"[2001:db8::1]:80", false},
+		{"[2002:7f00:1::1]:80", false},
+		{"[2002:c0a8:101::1]:80", false},
+		{"[2002:5db8:d70e::1]:80", true},
+		{"[3fff::1]:80", false},
+		{"[5f00::1]:80", false},
+		{"[fc00::1]:80", false},
+		{"[fe80::1%eth0]:80", false},
+		{"[fec0::1]:80", false},
+		{"[ff02::1]:80", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			err := checker.guard("tcp", tt.address, nil)
+			if got := err == nil; got != tt.allowed {
+				t.Errorf("guard() = %v, want allowed %v", err, tt.allowed)
+			}
+			if err != nil && !errors.As(err, new(*privateAddressError)) {
+				t.Error("unexpected error:", err)
+			}
+		})
+	}
+	if err := checker.guard("tcp", "garbage", nil); err == nil {
+		t.Error("malformed address allowed")
+	}
+	if !checker.allowedHosts["intranet"] {
+		t.Error("hostname missing from the allowlist")
+	}
+}
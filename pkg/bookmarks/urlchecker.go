@@ -14,9 +14,137 @@
 
 package bookmarks
 
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
 //go:generate go tool moq -out urlchecker_mocks_test.go . URLChecker
 //go:generate go tool moq -pkg web -out ../web/urlchecker_mocks_test.go . URLChecker
 type URLChecker interface {
-	Check(url, originalTitle string) (title string, when int64, code int64, reason string)
-	Title(url string) (title string)
+	Check(ctx context.Context, link CheckRequest) CheckResult
+	Preview(ctx context.Context, url string) (title string, metadata Metadata)
+}
+
+// CheckRequest is a link to check.
+type CheckRequest struct {
+	URL string
+	// Title is the title of the bookmark, which the result keeps. Links
+	// checked without one get the title of their page.
+	Title string
+	// PageTitle is the title that the page had the last time the link
+	// loaded, if known. Soft failures are told in part by how the title of
+	// the page changed since.
+	PageTitle string
+	// Pacer, if set, is waited on before every request of the check, so
+	// that the requests made on top of fetching the link, such as for
+	// robots.txt, are spaced out like the checks of a run of the link
+	// checker.
+	Pacer HostPacer
+}
+
+// HostPacer spaces out the requests sent to the host of a link.
+type HostPacer interface {
+	// Wait returns once the host may be sent another request.
+	Wait(ctx context.Context) error
+}
+
+// CheckResult is the outcome of checking a link.
+type CheckResult struct {
+	// Title is the title of the page, if the link was checked without one.
+	// Otherwise it is the original title.
+	Title string
+	// PageTitle is the title of the page, if it could be read.
+	PageTitle string
+	// When is the Unix time of the check.
+	When int64
+	// Code is the HTTP status code of the last response.
+	Code int64
+	// Reason explains why the check failed, if it did.
+	Reason string
+	// Redirects lists the URLs the link redirected to, in the order they
+	// were followed.
+	Redirects []string
+	// PermanentRedirect tells that every redirect was permanent (301 or
+	// 308), meaning that the link itself has moved.
+	PermanentRedirect bool
+	// RetryAfter is how long the host asked to be left alone, with the
+	// Retry-After header of a 429 or 503 response.
+	RetryAfter time.Duration
+	// CrawlDelay is how long the host asks bots to wait between requests,
+	// with its robots.txt.
+	CrawlDelay time.Duration
+	// Metadata is what the page tells about itself, if it could be read.
+	Metadata Metadata
+}
+
+// Link check outcomes that are not HTTP status codes. They are numbered past
+// the HTTP status codes, so that they are stored and searched alike.
+const (
+	// StatusBlockedByRobots means that the robots.txt of the host does not
+	// let the link checker fetch the link.
+	StatusBlockedByRobots = 1000
+
+	// StatusTimeout means that the host took too long to answer.
+	StatusTimeout = 1001
+
+	// StatusPrivateAddress means that the link leads to a private,
+	// loopback or link-local address, which the link checker does not
+	// connect to.
+	StatusPrivateAddress = 1002
+
+	// StatusSoftFailure means that the link loads, but not the page that
+	// was bookmarked: an error page served as if all was well, a login
+	// wall, or a parking page of an expired domain.
+	StatusSoftFailure = 1003
+)
+
+// StatusText describes the outcome of a link check, given its code.
+func StatusText(code int64) string {
+	switch code {
+	case StatusBlockedByRobots:
+		return "Blocked by robots.txt"
+	case StatusTimeout:
+		return "Timed out"
+	case StatusPrivateAddress:
+		return "Private address"
+	case StatusSoftFailure:
+		return "Soft failure"
+	}
+	return http.StatusText(int(code))
+}
+
+// Failed reports whether the link did not work. A zero Code means the link
+// was not checked at all, and links blocked by robots.txt or leading to
+// private addresses are not checked either.
+func (r CheckResult) Failed() bool {
+	return r.Code != 0 && r.Code != http.StatusOK && !r.Blocked() && !r.PrivateAddress()
+}
+
+// RateLimited reports whether the host asked to be left alone for a while,
+// with a 429, or with a 503 that says when to come back. The link may well
+// work once the wait is over.
+func (r CheckResult) RateLimited() bool {
+	return r.Code == http.StatusTooManyRequests || (r.Code == http.StatusServiceUnavailable && r.RetryAfter > 0)
+}
+
+// Blocked reports whether robots.txt kept the link from being checked.
+func (r CheckResult) Blocked() bool {
+	return r.Code == StatusBlockedByRobots
+}
+
+// PrivateAddress reports whether the link was not checked because it leads
+// to a private address.
+func (r CheckResult) PrivateAddress() bool {
+	return r.Code == StatusPrivateAddress
+}
+
+// FinalURL is where the redirects led, or an empty string if there were
+// none.
+func (r CheckResult) FinalURL() string {
+	if len(r.Redirects) == 0 {
+		return ""
+	}
+	return r.Redirects[len(r.Redirects)-1]
 }
@@ -4,6 +4,7 @@
 package bookmarks
 
 import (
+	"context"
 	"sync"
 )
 
@@ -17,11 +18,11 @@ var _ URLChecker = &URLCheckerMock{}
 //
 //		// make and configure a mocked URLChecker
 //		mockedURLChecker := &URLCheckerMock{
-//			CheckFunc: func(url string, originalTitle string) (string, int64, int64, string) {
+//			CheckFunc: func(ctx context.Context, link CheckRequest) CheckResult {
 //				panic("mock out the Check method")
 //			},
-//			TitleFunc: func(url string) string {
-//				panic("mock out the Title method")
+//			PreviewFunc: func(ctx context.Context, url string) (string, Metadata) {
+//				panic("mock out the Preview method")
 //			},
 //		}
 //
@@ -31,46 +32,48 @@ var _ URLChecker = &URLCheckerMock{}
 //	}
 type URLCheckerMock struct {
 	// CheckFunc mocks the Check method.
-	CheckFunc func(url string, originalTitle string) (string, int64, int64, string)
+	CheckFunc func(ctx context.Context, link CheckRequest) CheckResult
 
-	// TitleFunc mocks the Title method.
-	TitleFunc func(url string) string
+	// PreviewFunc mocks the Preview method.
+	PreviewFunc func(ctx context.Context, url string) (string, Metadata)
 
 	// calls tracks calls to the methods.
 	calls struct {
 		// Check holds details about calls to the Check method.
 		Check []struct {
-			// URL is the url argument value.
-			URL string
-			// OriginalTitle is the originalTitle argument value.
-			OriginalTitle string
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Link is the link argument value.
+			Link CheckRequest
 		}
-		// Title holds details about calls to the Title method.
-		Title []struct {
+		// Preview holds details about calls to the Preview method.
+		Preview []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
 			// URL is the url argument value.
 			URL string
 		}
 	}
-	lockCheck sync.RWMutex
-	lockTitle sync.RWMutex
+	lockCheck   sync.RWMutex
+	lockPreview sync.RWMutex
 }
 
 // Check calls CheckFunc.
-func (mock *URLCheckerMock) Check(url string, originalTitle string) (string, int64, int64, string) {
+func (mock *URLCheckerMock) Check(ctx context.Context, link CheckRequest) CheckResult {
 	if mock.CheckFunc == nil {
 		panic("URLCheckerMock.CheckFunc: method is nil but URLChecker.Check was just called")
 	}
 	callInfo := struct {
-		URL           string
-		OriginalTitle string
+		Ctx  context.Context
+		Link CheckRequest
 	}{
-		URL:           url,
-		OriginalTitle: originalTitle,
+		Ctx:  ctx,
+		Link: link,
 	}
 	mock.lockCheck.Lock()
 	mock.calls.Check = append(mock.calls.Check, callInfo)
 	mock.lockCheck.Unlock()
-	return mock.CheckFunc(url, originalTitle)
+	return mock.CheckFunc(ctx, link)
 }
 
 // CheckCalls gets all the calls that were made to Check.
@@ -78,12 +81,12 @@ func (mock *URLCheckerMock) Check(url string, originalTitle string) (string, int
 //
 //	len(mockedURLChecker.CheckCalls())
 func (mock *URLCheckerMock) CheckCalls() []struct {
-	URL           string
-	OriginalTitle string
+	Ctx  context.Context
+	Link CheckRequest
 } {
 	var calls []struct {
-		URL           string
-		OriginalTitle string
+		Ctx  context.Context
+		Link CheckRequest
 	}
 	mock.lockCheck.RLock()
 	calls = mock.calls.Check
@@ -91,34 +94,38 @@ func (mock *URLCheckerMock) CheckCalls() []struct {
 	return calls
 }
 
-// Title calls TitleFunc.
-func (mock *URLCheckerMock) Title(url string) string {
-	if mock.TitleFunc == nil {
-		panic("URLCheckerMock.TitleFunc: method is nil but URLChecker.Title was just called")
+// Preview calls PreviewFunc.
+func (mock *URLCheckerMock) Preview(ctx context.Context, url string) (string, Metadata) {
+	if mock.PreviewFunc == nil {
+		panic("URLCheckerMock.PreviewFunc: method is nil but URLChecker.Preview was just called")
 	}
 	callInfo := struct {
+		Ctx context.Context
 		URL string
 	}{
+		Ctx: ctx,
 		URL: url,
 	}
-	mock.lockTitle.Lock()
-	mock.calls.Title = append(mock.calls.Title, callInfo)
-	mock.lockTitle.Unlock()
-	return mock.TitleFunc(url)
+	mock.lockPreview.Lock()
+	mock.calls.Preview = append(mock.calls.Preview, callInfo)
+	mock.lockPreview.Unlock()
+	return mock.PreviewFunc(ctx, url)
 }
 
-// TitleCalls gets all the calls that were made to Title.
+// PreviewCalls gets all the calls that were made to Preview.
 // Check the length with:
 //
-//	len(mockedURLChecker.TitleCalls())
-func (mock *URLCheckerMock) TitleCalls() []struct {
+//	len(mockedURLChecker.PreviewCalls())
+func (mock *URLCheckerMock) PreviewCalls() []struct {
+	Ctx context.Context
 	URL string
 } {
 	var calls []struct {
+		Ctx context.Context
 		URL string
 	}
-	mock.lockTitle.RLock()
-	calls = mock.calls.Title
-	mock.lockTitle.RUnlock()
+	mock.lockPreview.RLock()
+	calls = mock.calls.Preview
+	mock.lockPreview.RUnlock()
 	return calls
 }
@@ -0,0 +1,157 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"time"
+
+	"cirello.io/alreadyread/frontend"
+	"cirello.io/alreadyread/pkg/auth"
+	"cirello.io/alreadyread/pkg/bookmarks/linkhealth"
+)
+
+//go:generate go tool moq -out linkhealth_mocks_test.go . LinkHealth
+type LinkHealth interface {
+	Trigger() bool
+	Status() linkhealth.Status
+}
+
+// apiLinkHealthStatus tells how the link checker is doing. Times are omitted
+// when they are not known yet.
+type apiLinkHealthStatus struct {
+	Schedule     string     `json:"schedule"`
+	Running      bool       `json:"running"`
+	Pending      bool       `json:"pending"`
+	LastStarted  *time.Time `json:"last_started,omitempty"`
+	LastFinished *time.Time `json:"last_finished,omitempty"`
+	LastError    string     `json:"last_error,omitempty"`
+	Next         *time.Time `json:"next,omitempty"`
+}
+
+func newAPILinkHealthStatus(status linkhealth.Status) *apiLinkHealthStatus {
+	optionalTime := func(t time.Time) *time.Time {
+		if t.IsZero() {
+			return nil
+		}
+		return &t
+	}
+	return &apiLinkHealthStatus{
+		Schedule:     status.Schedule,
+		Running:      status.Running,
+		Pending:      status.Pending,
+		LastStarted:  optionalTime(status.Last.StartedAt),
+		LastFinished: optionalTime(status.Last.FinishedAt),
+		LastError:    status.Last.Err,
+		Next:         optionalTime(status.Next),
+	}
+}
+
+func (s *Server) registerAdminRoutes(router *http.ServeMux) {
+	if s.linkHealth == nil {
+		return
+	}
+	router.HandleFunc("GET /admin", s.admin)
+	router.HandleFunc("POST /admin/linkHealth", s.triggerLinkHealth)
+	router.HandleFunc("GET /api/v1/admin/linkhealth", s.apiLinkHealth)
+	router.HandleFunc("POST /api/v1/admin/linkhealth", s.apiTriggerLinkHealth)
+}
+
+// isAdmin reports whether the request comes from the administrator. Without
+// authentication there are no users to tell apart, and anyone who can reach
+// the server administers it, just like they can edit every bookmark.
+func (s *Server) isAdmin(r *http.Request) (bool, error) {
+	if s.auth == nil {
+		return true, nil
+	}
+	user, ok := auth.FromContext(r.Context())
+	if !ok {
+		return false, nil
+	}
+	return s.auth.IsAdmin(user)
+}
+
+// requireAdmin rejects requests that do not come from the administrator. It
+// reports whether the request may go on.
+func (s *Server) requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	ok, err := s.isAdmin(r)
+	if err != nil {
+		log.Println("cannot check for administrator:", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return false
+	} else if !ok {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// requireHTMX rejects the form posts that do not come from htmx. Other sites
+// cannot make a browser send the HX-Request header without passing a CORS
+// preflight first, so this keeps them from posting on behalf of the user. It
+// reports whether the request may go on.
+func requireHTMX(w http.ResponseWriter, r *http.Request) bool {
+	if r.Header.Get("HX-Request") != "true" {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func (s *Server) admin(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+	buf := &bytes.Buffer{}
+	frontend.RenderAdmin(buf, s.linkHealth.Status())
+	s.renderPage(w, r, "Admin", buf)
+}
+
+func (s *Server) triggerLinkHealth(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) || !requireHTMX(w, r) {
+		return
+	}
+	s.linkHealth.Trigger()
+	frontend.RenderAdmin(w, s.linkHealth.Status())
+}
+
+func (s *Server) apiLinkHealth(w http.ResponseWriter, r *http.Request) {
+	if !s.apiRequireAdmin(w, r) {
+		return
+	}
+	writeAPIResponse(w, http.StatusOK, newAPILinkHealthStatus(s.linkHealth.Status()))
+}
+
+func (s *Server) apiTriggerLinkHealth(w http.ResponseWriter, r *http.Request) {
+	if !s.apiRequireAdmin(w, r) {
+		return
+	}
+	s.linkHealth.Trigger()
+	writeAPIResponse(w, http.StatusAccepted, newAPILinkHealthStatus(s.linkHealth.Status()))
+}
+
+func (s *Server) apiRequireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	ok, err := s.isAdmin(r)
+	if err != nil {
+		apiFail(w, "cannot check for administrator", err)
+		return false
+	} else if !ok {
+		writeAPIError(w, http.StatusForbidden, http.StatusText(http.StatusForbidden))
+		return false
+	}
+	return true
+}
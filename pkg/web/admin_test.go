@@ -0,0 +1,158 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cirello.io/alreadyread/pkg/auth"
+	"cirello.io/alreadyread/pkg/bookmarks"
+	"cirello.io/alreadyread/pkg/bookmarks/linkhealth"
+)
+
+// newLinkHealth pretends to be a scheduler that never ran.
+func newLinkHealth() *LinkHealthMock {
+	m := &LinkHealthMock{}
+	m.StatusFunc = func() linkhealth.Status {
+		return linkhealth.Status{
+			Schedule: "%FIND-SCHEDULE%",
+			Pending:  len(m.TriggerCalls()) > 0,
+			Next:     time.Date(2023, 5, 6, 8, 0, 0, 0, time.UTC),
+		}
+	}
+	m.TriggerFunc = func() bool { return len(m.TriggerCalls()) == 1 }
+	return m
+}
+
+func TestServer_admin(t *testing.T) {
+	get := func(t *testing.T, ts *httptest.Server, method, path string, header http.Header) (int, string) {
+		t.Helper()
+		req, err := http.NewRequest(method, ts.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		resp, err := ts.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+	t.Run("notConfigured", func(t *testing.T) {
+		ts := httptest.NewServer(New(bookmarks.New(&RepositoryMock{}, nil), nil, []string{"localhost"}))
+		defer ts.Close()
+		if code, _ := get(t, ts, http.MethodGet, "/admin", nil); code != http.StatusNotFound {
+			t.Error("unexpected status code:", code)
+		}
+		if code, _ := get(t, ts, http.MethodPost, "/api/v1/admin/linkhealth", nil); code != http.StatusNotFound {
+			t.Error("unexpected status code:", code)
+		}
+	})
+	t.Run("open", func(t *testing.T) {
+		linkHealth := newLinkHealth()
+		ts := httptest.NewServer(New(bookmarks.New(&RepositoryMock{}, nil), nil, []string{"localhost"}, WithLinkHealth(linkHealth)))
+		defer ts.Close()
+		code, body := get(t, ts, http.MethodGet, "/admin", nil)
+		if code != http.StatusOK || !strings.Contains(body, "%FIND-SCHEDULE%") || !strings.Contains(body, "not been checked yet") {
+			t.Fatal("unexpected admin page:", code, body)
+		}
+		if code, _ := get(t, ts, http.MethodPost, "/admin/linkHealth", nil); code != http.StatusForbidden {
+			t.Error("form post not made by htmx accepted:", code)
+		}
+		if len(linkHealth.TriggerCalls()) != 0 {
+			t.Fatal("link checker triggered by a form post not made by htmx")
+		}
+		code, body = get(t, ts, http.MethodPost, "/admin/linkHealth", http.Header{"Hx-Request": {"true"}})
+		if code != http.StatusOK || !strings.Contains(body, "starts as soon as possible") || !strings.Contains(body, "disabled") {
+			t.Fatal("unexpected link health panel:", code, body)
+		}
+		if len(linkHealth.TriggerCalls()) != 1 {
+			t.Error("link checker not triggered")
+		}
+
+		var status apiLinkHealthStatus
+		if code := apiRequest(t, ts, http.MethodPost, "/api/v1/admin/linkhealth", "", &status); code != http.StatusAccepted {
+			t.Error("unexpected status code:", code)
+		}
+		if code := apiRequest(t, ts, http.MethodGet, "/api/v1/admin/linkhealth", "", &status); code != http.StatusOK {
+			t.Error("unexpected status code:", code)
+		}
+		if status.Schedule != "%FIND-SCHEDULE%" || !status.Pending || status.LastStarted != nil || status.Next == nil {
+			t.Errorf("unexpected status: %#v", status)
+		}
+	})
+	t.Run("auth", func(t *testing.T) {
+		a, adminSecret := newTestAuth(t, auth.WithSignup(true))
+		user, err := a.SignUp("bob", testPassword)
+		if err != nil {
+			t.Fatal("cannot sign up:", err)
+		}
+		_, userSecret, err := a.Mint(user.ID, "phone")
+		if err != nil {
+			t.Fatal("cannot mint token:", err)
+		}
+		_, userSession, err := a.Login("bob", testPassword)
+		if err != nil {
+			t.Fatal("cannot log in:", err)
+		}
+		_, adminSession, err := a.Login(testUsername, testPassword)
+		if err != nil {
+			t.Fatal("cannot log in:", err)
+		}
+		linkHealth := newLinkHealth()
+		ts := httptest.NewServer(New(bookmarks.New(&RepositoryMock{}, nil), nil, []string{"localhost"}, WithAuth(a), WithLinkHealth(linkHealth)))
+		defer ts.Close()
+
+		for _, path := range []string{"/admin", "/admin/linkHealth"} {
+			method := http.MethodGet
+			if path == "/admin/linkHealth" {
+				method = http.MethodPost
+			}
+			if code, _ := get(t, ts, method, path, http.Header{"Cookie": {sessionCookie + "=" + userSession}}); code != http.StatusForbidden {
+				t.Error(path, "unexpected status code:", code)
+			}
+		}
+		for _, method := range []string{http.MethodGet, http.MethodPost} {
+			if code, _ := get(t, ts, method, "/api/v1/admin/linkhealth", http.Header{"Authorization": {"Bearer " + userSecret}}); code != http.StatusForbidden {
+				t.Error(method, "unexpected status code:", code)
+			}
+		}
+		if len(linkHealth.TriggerCalls()) != 0 {
+			t.Fatal("link checker triggered by a user who is not the administrator")
+		}
+
+		if code, _ := get(t, ts, http.MethodGet, "/admin", http.Header{"Cookie": {sessionCookie + "=" + adminSession}}); code != http.StatusOK {
+			t.Error("unexpected status code:", code)
+		}
+		if code, _ := get(t, ts, http.MethodPost, "/admin/linkHealth", http.Header{"Cookie": {sessionCookie + "=" + adminSession}}); code != http.StatusForbidden {
+			t.Error("form post not made by htmx accepted:", code)
+		}
+		if code, _ := get(t, ts, http.MethodPost, "/admin/linkHealth", http.Header{"Cookie": {sessionCookie + "=" + adminSession}, "Hx-Request": {"true"}}); code != http.StatusOK {
+			t.Error("unexpected status code:", code)
+		}
+		if code, _ := get(t, ts, http.MethodPost, "/api/v1/admin/linkhealth", http.Header{"Authorization": {"Bearer " + adminSecret}}); code != http.StatusAccepted {
+			t.Error("unexpected status code:", code)
+		}
+	})
+}
@@ -0,0 +1,438 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"cirello.io/alreadyread/pkg/bookmarks"
+	"cirello.io/alreadyread/pkg/bookmarks/export"
+)
+
+// maxAPIRequestSize limits the size of JSON request bodies.
+const maxAPIRequestSize = 1 << 20
+
+// apiBookmark is the JSON representation of a bookmark.
+type apiBookmark struct {
+	ID          int64         `json:"id"`
+	URL         string        `json:"url"`
+	Title       string        `json:"title"`
+	Description string        `json:"description"`
+	Tags        []string      `json:"tags"`
+	Inbox       bool          `json:"inbox"`
+	CreatedAt   time.Time     `json:"created_at"`
+	BumpedAt    time.Time     `json:"bumped_at"`
+	Status      apiLinkStatus `json:"status"`
+}
+
+// apiLinkStatus is the outcome of the last link check. FinalURL is where the
+// link redirects to, if it has moved, and PermanentRedirect whether it moved
+// for good, so that the bookmark can follow it. Failures counts the
+// consecutive failed checks since FailingSince. BlockedByRobots tells that the
+// link was not checked because robots.txt does not allow it, and
+// PrivateAddress because it leads to a private address. SoftFailure tells
+// that the link loads, but not the page that was bookmarked.
+type apiLinkStatus struct {
+	Code              int64     `json:"code"`
+	Reason            string    `json:"reason,omitempty"`
+	CheckedAt         time.Time `json:"checked_at,omitzero"`
+	FinalURL          string    `json:"final_url,omitempty"`
+	Redirects         int64     `json:"redirects,omitempty"`
+	PermanentRedirect bool      `json:"permanent_redirect,omitempty"`
+	Dead              bool      `json:"dead"`
+	Failures          int64     `json:"failures,omitempty"`
+	FailingSince      time.Time `json:"failing_since,omitzero"`
+	BlockedByRobots   bool      `json:"blocked_by_robots,omitempty"`
+	PrivateAddress    bool      `json:"private_address,omitempty"`
+	SoftFailure       bool      `json:"soft_failure,omitempty"`
+}
+
+func newAPIBookmark(b *bookmarks.Bookmark) *apiBookmark {
+	ab := &apiBookmark{
+		ID:          b.ID,
+		URL:         b.URL,
+		Title:       b.Title,
+		Description: b.Description,
+		Tags:        b.Tags,
+		Inbox:       b.Inbox == bookmarks.NewLink,
+		CreatedAt:   b.CreatedAt,
+		BumpedAt:    b.BumpDate,
+		Status: apiLinkStatus{
+			Code:      b.LastStatusCode,
+			Reason:    b.LastStatusReason,
+			FinalURL:  b.FinalURL,
+			Redirects: b.RedirectCount,
+			Dead:      b.Dead,
+			Failures:  b.FailureCount,
+
+			PermanentRedirect: b.PermanentRedirect,
+
+			BlockedByRobots: b.Blocked(),
+			PrivateAddress:  b.PrivateAddress(),
+			SoftFailure:     b.SoftFailure(),
+		},
+	}
+	if ab.Tags == nil {
+		ab.Tags = []string{}
+	}
+	if b.LastStatusCheck > 0 {
+		ab.Status.CheckedAt = time.Unix(b.LastStatusCheck, 0)
+	}
+	if b.FailingSince > 0 {
+		ab.Status.FailingSince = time.Unix(b.FailingSince, 0)
+	}
+	return ab
+}
+
+func newAPIBookmarks(list []*bookmarks.Bookmark) []*apiBookmark {
+	ret := make([]*apiBookmark, 0, len(list))
+	for _, b := range list {
+		ret = append(ret, newAPIBookmark(b))
+	}
+	return ret
+}
+
+// apiBookmarkRequest is the body used to create or replace a bookmark.
+type apiBookmarkRequest struct {
+	URL         string   `json:"url"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+}
+
+// apiListResponse is a page of bookmarks. NextCursor is empty on the last
+// page.
+type apiListResponse struct {
+	Bookmarks  []*apiBookmark `json:"bookmarks"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+// apiSearchResponse is a page of search results, along with how many
+// bookmarks match the query in total.
+type apiSearchResponse struct {
+	Bookmarks []*apiBookmark `json:"bookmarks"`
+	Page      int            `json:"page"`
+	Total     int            `json:"total"`
+}
+
+// apiHistoryResponse is the latest link checks of a bookmark, newest first,
+// along with the percentage of them that succeeded.
+type apiHistoryResponse struct {
+	Uptime float64         `json:"uptime"`
+	Checks []*apiLinkCheck `json:"checks"`
+}
+
+// apiLinkCheck is one entry of the link check history.
+type apiLinkCheck struct {
+	CheckedAt time.Time `json:"checked_at"`
+	Code      int64     `json:"code"`
+	Reason    string    `json:"reason,omitempty"`
+}
+
+// apiErrorResponse is the body of every API error.
+type apiErrorResponse struct {
+	Error string `json:"error"`
+}
+
+func (s *Server) registerAPIRoutes(router *http.ServeMux) {
+	router.HandleFunc("GET /api/v1/inbox", s.apiList(s.bookmarks.Inbox))
+	router.HandleFunc("GET /api/v1/all", s.apiList(s.bookmarks.All))
+	router.HandleFunc("GET /api/v1/dead", s.apiDead)
+	router.HandleFunc("GET /api/v1/moved", s.apiList(s.bookmarks.Moved))
+	router.HandleFunc("GET /api/v1/duplicated", s.apiList(s.bookmarks.Duplicated))
+	router.HandleFunc("GET /api/v1/search", s.apiSearch)
+	router.HandleFunc("POST /api/v1/bookmarks", s.apiCreate)
+	router.HandleFunc("GET /api/v1/bookmarks/{id}", s.apiGet)
+	router.HandleFunc("PUT /api/v1/bookmarks/{id}", s.apiUpdate)
+	router.HandleFunc("DELETE /api/v1/bookmarks/{id}", s.apiDelete)
+	router.HandleFunc("POST /api/v1/bookmarks/{id}/read", s.apiMarkRead)
+	router.HandleFunc("POST /api/v1/bookmarks/{id}/bump", s.apiBump)
+	router.HandleFunc("POST /api/v1/bookmarks/{id}/move", s.apiMove)
+	router.HandleFunc("GET /api/v1/bookmarks/{id}/history", s.apiHistory)
+	router.HandleFunc("GET /api/v1/export/{format}", s.apiExport)
+	// The catch-all gets the requests for the routes above made with
+	// another method too, which are told apart by the methods that would
+	// have matched.
+	router.HandleFunc("/api/v1/", func(w http.ResponseWriter, r *http.Request) {
+		var allowed []string
+		for _, method := range apiMethods {
+			probe := r.Clone(r.Context())
+			probe.Method = method
+			if _, pattern := router.Handler(probe); pattern != "/api/v1/" {
+				allowed = append(allowed, method)
+			}
+		}
+		if len(allowed) > 0 {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			writeAPIError(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+			return
+		}
+		writeAPIError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+	})
+}
+
+// apiMethods are the methods that the API routes may be registered with, in
+// the order that the Allow header lists them.
+var apiMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodDelete}
+
+func (s *Server) apiList(list func(int64, bookmarks.Cursor) ([]*bookmarks.Bookmark, bookmarks.Cursor, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		found, next, err := list(s.owner(r), bookmarks.Cursor(r.URL.Query().Get("cursor")))
+		if err != nil {
+			apiFail(w, "cannot list bookmarks", err)
+			return
+		}
+		writeAPIResponse(w, http.StatusOK, &apiListResponse{
+			Bookmarks:  newAPIBookmarks(found),
+			NextCursor: string(next),
+		})
+	}
+}
+
+// apiDead lists the dead bookmarks, narrowed down by the filter query
+// parameter.
+func (s *Server) apiDead(w http.ResponseWriter, r *http.Request) {
+	filter, err := bookmarks.ParseDeadFilter(r.URL.Query().Get("filter"))
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.apiList(func(ownerID int64, cursor bookmarks.Cursor) ([]*bookmarks.Bookmark, bookmarks.Cursor, error) {
+		return s.bookmarks.Dead(ownerID, filter, cursor)
+	})(w, r)
+}
+
+func (s *Server) apiSearch(w http.ResponseWriter, r *http.Request) {
+	page := 0
+	if v := r.URL.Query().Get("page"); v != "" {
+		var err error
+		page, err = strconv.Atoi(v)
+		if err != nil || page < 0 {
+			writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("invalid page %q", v))
+			return
+		}
+	}
+	found, total, err := s.bookmarks.Search(s.owner(r), r.URL.Query().Get("q"), page)
+	if err != nil {
+		apiFail(w, "cannot search bookmarks", err)
+		return
+	}
+	writeAPIResponse(w, http.StatusOK, &apiSearchResponse{
+		Bookmarks: newAPIBookmarks(found),
+		Page:      page,
+		Total:     total,
+	})
+}
+
+func (s *Server) apiCreate(w http.ResponseWriter, r *http.Request) {
+	req, ok := readAPIBookmarkRequest(w, r)
+	if !ok {
+		return
+	}
+	bookmark := &bookmarks.Bookmark{
+		URL:         req.URL,
+		Title:       req.Title,
+		Description: req.Description,
+		Tags:        bookmarks.ParseTags(strings.Join(req.Tags, ",")),
+	}
+	if err := s.bookmarks.Insert(r.Context(), s.owner(r), bookmark); err != nil {
+		apiFail(w, "cannot store new bookmark", err)
+		return
+	}
+	s.apiRespondBookmark(w, r, http.StatusCreated, bookmark.ID)
+}
+
+func (s *Server) apiGet(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiBookmarkID(w, r)
+	if !ok {
+		return
+	}
+	s.apiRespondBookmark(w, r, http.StatusOK, id)
+}
+
+func (s *Server) apiUpdate(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiBookmarkID(w, r)
+	if !ok {
+		return
+	}
+	req, ok := readAPIBookmarkRequest(w, r)
+	if !ok {
+		return
+	}
+	bookmark, err := s.bookmarks.Edit(r.Context(), s.owner(r), &bookmarks.Bookmark{
+		ID:          id,
+		URL:         req.URL,
+		Title:       req.Title,
+		Description: req.Description,
+		Tags:        req.Tags,
+	})
+	if err != nil {
+		apiFail(w, "cannot edit bookmark", err)
+		return
+	}
+	writeAPIResponse(w, http.StatusOK, newAPIBookmark(bookmark))
+}
+
+func (s *Server) apiDelete(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiBookmarkID(w, r)
+	if !ok {
+		return
+	}
+	if err := s.bookmarks.DeleteByID(s.owner(r), id); err != nil {
+		apiFail(w, "cannot delete bookmark", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) apiMarkRead(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiBookmarkID(w, r)
+	if !ok {
+		return
+	}
+	if err := s.bookmarks.UpdateInbox(s.owner(r), id, "read"); err != nil {
+		apiFail(w, "cannot update bookmark", err)
+		return
+	}
+	s.apiRespondBookmark(w, r, http.StatusOK, id)
+}
+
+func (s *Server) apiBump(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiBookmarkID(w, r)
+	if !ok {
+		return
+	}
+	if err := s.bookmarks.Bump(s.owner(r), id); err != nil {
+		apiFail(w, "cannot update bookmark", err)
+		return
+	}
+	s.apiRespondBookmark(w, r, http.StatusOK, id)
+}
+
+func (s *Server) apiMove(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiBookmarkID(w, r)
+	if !ok {
+		return
+	}
+	bookmark, err := s.bookmarks.FollowRedirect(s.owner(r), id)
+	if err != nil {
+		apiFail(w, "cannot update bookmark", err)
+		return
+	}
+	writeAPIResponse(w, http.StatusOK, newAPIBookmark(bookmark))
+}
+
+func (s *Server) apiHistory(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiBookmarkID(w, r)
+	if !ok {
+		return
+	}
+	history, err := s.bookmarks.History(s.owner(r), id)
+	if err != nil {
+		apiFail(w, "cannot load link history", err)
+		return
+	}
+	resp := &apiHistoryResponse{Uptime: history.Uptime, Checks: make([]*apiLinkCheck, 0, len(history.Checks))}
+	for _, check := range history.Checks {
+		resp.Checks = append(resp.Checks, &apiLinkCheck{
+			CheckedAt: time.Unix(check.CheckedAt, 0),
+			Code:      check.Code,
+			Reason:    check.Reason,
+		})
+	}
+	writeAPIResponse(w, http.StatusOK, resp)
+}
+
+func (s *Server) apiExport(w http.ResponseWriter, r *http.Request) {
+	format, err := export.ParseFormat(r.PathValue("format"))
+	if err != nil {
+		writeAPIError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err := s.streamExport(w, r, format); err != nil {
+		apiFail(w, "cannot export bookmarks", err)
+	}
+}
+
+func (s *Server) apiRespondBookmark(w http.ResponseWriter, r *http.Request, code int, id int64) {
+	bookmark, err := s.bookmarks.GetByID(s.owner(r), id)
+	if err != nil {
+		apiFail(w, "cannot load bookmark", err)
+		return
+	}
+	writeAPIResponse(w, code, newAPIBookmark(bookmark))
+}
+
+func apiBookmarkID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid bookmark ID")
+		return 0, false
+	}
+	return id, true
+}
+
+func readAPIBookmarkRequest(w http.ResponseWriter, r *http.Request) (*apiBookmarkRequest, bool) {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAPIRequestSize))
+	dec.DisallowUnknownFields()
+	req := &apiBookmarkRequest{}
+	if err := dec.Decode(req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return nil, false
+	}
+	if strings.TrimSpace(req.URL) == "" {
+		writeAPIError(w, http.StatusBadRequest, "missing url")
+		return nil, false
+	}
+	return req, true
+}
+
+// apiFail maps errors from the bookmarks service to status codes. Unexpected
+// errors are logged and not exposed to the client.
+func apiFail(w http.ResponseWriter, msg string, err error) {
+	var syntaxErr *bookmarks.QuerySyntaxError
+	switch {
+	case errors.Is(err, bookmarks.ErrNotFound):
+		writeAPIError(w, http.StatusNotFound, bookmarks.ErrNotFound.Error())
+	case errors.Is(err, bookmarks.ErrNotMoved), errors.Is(err, bookmarks.ErrDuplicateURL):
+		writeAPIError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, &bookmarks.BadURLError{}),
+		errors.Is(err, bookmarks.ErrInvalidCursor),
+		errors.As(err, &syntaxErr):
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+	default:
+		log.Println(msg+":", err)
+		writeAPIError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+}
+
+func writeAPIError(w http.ResponseWriter, code int, msg string) {
+	writeAPIResponse(w, code, &apiErrorResponse{Error: msg})
+}
+
+func writeAPIResponse(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("cannot write API response:", err)
+	}
+}
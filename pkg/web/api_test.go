@@ -0,0 +1,493 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"cirello.io/alreadyread/pkg/bookmarks"
+)
+
+func apiRequest(t *testing.T, ts *httptest.Server, method, path, body string, v any) int {
+	t.Helper()
+	req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNoContent {
+		return resp.StatusCode
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Fatal("unexpected content type:", ct)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatal("cannot decode response:", err)
+	}
+	return resp.StatusCode
+}
+
+func TestAPI(t *testing.T) {
+	errDB := errors.New("bad DB")
+	storedBookmark := func() *bookmarks.Bookmark {
+		return &bookmarks.Bookmark{ID: 1, Title: "title", URL: "https://example.com", Inbox: bookmarks.NewLink, Tags: []string{"tag"}}
+	}
+	getByID := func(_, id int64) (*bookmarks.Bookmark, error) {
+		if id != 1 {
+			return nil, bookmarks.ErrNotFound
+		}
+		return storedBookmark(), nil
+	}
+	urlChecker := &URLCheckerMock{
+		CheckFunc: func(_ context.Context, link bookmarks.CheckRequest) bookmarks.CheckResult {
+			return bookmarks.CheckResult{Title: link.Title, Code: http.StatusOK}
+		},
+	}
+	t.Run("list", func(t *testing.T) {
+		repository := &RepositoryMock{
+			InboxFunc: func(_ int64, after bookmarks.Cursor, _ int) ([]*bookmarks.Bookmark, bookmarks.Cursor, error) {
+				switch after {
+				case "":
+					return []*bookmarks.Bookmark{storedBookmark()}, "next", nil
+				case "next":
+					return nil, "", nil
+				}
+				return nil, "", bookmarks.ErrInvalidCursor
+			},
+			AllFunc: func(int64, bookmarks.Cursor, int) ([]*bookmarks.Bookmark, bookmarks.Cursor, error) {
+				return nil, "", errDB
+			},
+		}
+		ts := httptest.NewServer(New(bookmarks.New(repository, urlChecker), nil, []string{"localhost"}))
+		defer ts.Close()
+
+		var page apiListResponse
+		if code := apiRequest(t, ts, http.MethodGet, "/api/v1/inbox", "", &page); code != http.StatusOK {
+			t.Fatal("not OK:", code)
+		}
+		if len(page.Bookmarks) != 1 || page.Bookmarks[0].URL != "https://example.com" || !page.Bookmarks[0].Inbox || page.NextCursor != "next" {
+			t.Fatalf("unexpected first page: %#v", page)
+		}
+		page = apiListResponse{}
+		if code := apiRequest(t, ts, http.MethodGet, "/api/v1/inbox?cursor=next", "", &page); code != http.StatusOK {
+			t.Fatal("not OK:", code)
+		}
+		if page.Bookmarks == nil || len(page.Bookmarks) != 0 || page.NextCursor != "" {
+			t.Fatalf("unexpected last page: %#v", page)
+		}
+
+		var apiErr apiErrorResponse
+		if code := apiRequest(t, ts, http.MethodGet, "/api/v1/inbox?cursor=garbage", "", &apiErr); code != http.StatusBadRequest || apiErr.Error == "" {
+			t.Fatal("unexpected response for bad cursor:", code, apiErr)
+		}
+		apiErr = apiErrorResponse{}
+		if code := apiRequest(t, ts, http.MethodGet, "/api/v1/all", "", &apiErr); code != http.StatusInternalServerError || apiErr.Error != http.StatusText(http.StatusInternalServerError) {
+			t.Fatal("unexpected response for bad DB:", code, apiErr)
+		}
+	})
+	t.Run("dead", func(t *testing.T) {
+		repository := &RepositoryMock{
+			DeadFunc: func(_ int64, filter bookmarks.DeadFilter, _ bookmarks.Cursor, _ int) ([]*bookmarks.Bookmark, bookmarks.Cursor, error) {
+				if filter != bookmarks.HardDead {
+					return nil, "", nil
+				}
+				dead := storedBookmark()
+				dead.LastStatusCode, dead.FailureCount, dead.FailingSince, dead.Dead = http.StatusNotFound, 4, 1700000000, true
+				return []*bookmarks.Bookmark{dead}, "", nil
+			},
+		}
+		ts := httptest.NewServer(New(bookmarks.New(repository, urlChecker), nil, []string{"localhost"}))
+		defer ts.Close()
+		var page apiListResponse
+		if code := apiRequest(t, ts, http.MethodGet, "/api/v1/dead?filter=hard", "", &page); code != http.StatusOK {
+			t.Fatal("not OK:", code)
+		}
+		if len(page.Bookmarks) != 1 {
+			t.Fatalf("unexpected dead bookmarks: %#v", page)
+		}
+		if status := page.Bookmarks[0].Status; !status.Dead || status.Failures != 4 || !status.FailingSince.Equal(time.Unix(1700000000, 0)) {
+			t.Errorf("unexpected link status: %#v", status)
+		}
+		var apiErr apiErrorResponse
+		if code := apiRequest(t, ts, http.MethodGet, "/api/v1/dead?filter=garbage", "", &apiErr); code != http.StatusBadRequest || apiErr.Error == "" {
+			t.Error("unexpected response for bad filter:", code, apiErr)
+		}
+	})
+	t.Run("blocked", func(t *testing.T) {
+		repository := &RepositoryMock{GetByIDFunc: func(int64, int64) (*bookmarks.Bookmark, error) {
+			blocked := storedBookmark()
+			blocked.LastStatusCode, blocked.LastStatusReason = bookmarks.StatusBlockedByRobots, bookmarks.StatusText(bookmarks.StatusBlockedByRobots)
+			return blocked, nil
+		}}
+		ts := httptest.NewServer(New(bookmarks.New(repository, urlChecker), nil, []string{"localhost"}))
+		defer ts.Close()
+		var got apiBookmark
+		if code := apiRequest(t, ts, http.MethodGet, "/api/v1/bookmarks/1", "", &got); code != http.StatusOK {
+			t.Fatal("not OK:", code)
+		}
+		if status := got.Status; !status.BlockedByRobots || status.Dead || status.Code != bookmarks.StatusBlockedByRobots {
+			t.Errorf("unexpected link status: %#v", status)
+		}
+	})
+	t.Run("privateAddress", func(t *testing.T) {
+		repository := &RepositoryMock{GetByIDFunc: func(int64, int64) (*bookmarks.Bookmark, error) {
+			private := storedBookmark()
+			private.LastStatusCode, private.LastStatusReason = bookmarks.StatusPrivateAddress, "refusing to connect to private address 10.0.0.1"
+			return private, nil
+		}}
+		ts := httptest.NewServer(New(bookmarks.New(repository, urlChecker), nil, []string{"localhost"}))
+		defer ts.Close()
+		var got apiBookmark
+		if code := apiRequest(t, ts, http.MethodGet, "/api/v1/bookmarks/1", "", &got); code != http.StatusOK {
+			t.Fatal("not OK:", code)
+		}
+		if status := got.Status; !status.PrivateAddress || status.BlockedByRobots || status.Code != bookmarks.StatusPrivateAddress {
+			t.Errorf("unexpected link status: %#v", status)
+		}
+	})
+	t.Run("get", func(t *testing.T) {
+		repository := &RepositoryMock{GetByIDFunc: getByID}
+		ts := httptest.NewServer(New(bookmarks.New(repository, urlChecker), nil, []string{"localhost"}))
+		defer ts.Close()
+		tests := []struct {
+			path     string
+			wantCode int
+		}{
+			{"/api/v1/bookmarks/1", http.StatusOK},
+			{"/api/v1/bookmarks/2", http.StatusNotFound},
+			{"/api/v1/bookmarks/banana", http.StatusBadRequest},
+			{"/api/v1/banana", http.StatusNotFound},
+		}
+		for _, tt := range tests {
+			t.Run(tt.path, func(t *testing.T) {
+				var got map[string]any
+				if code := apiRequest(t, ts, http.MethodGet, tt.path, "", &got); code != tt.wantCode {
+					t.Fatal("unexpected status code:", code, got)
+				}
+				if _, isError := got["error"]; isError != (tt.wantCode != http.StatusOK) {
+					t.Fatal("unexpected body:", got)
+				}
+			})
+		}
+	})
+	t.Run("methodNotAllowed", func(t *testing.T) {
+		ts := httptest.NewServer(New(bookmarks.New(&RepositoryMock{}, urlChecker), nil, []string{"localhost"}))
+		defer ts.Close()
+		tests := []struct {
+			method    string
+			path      string
+			wantCode  int
+			wantAllow string
+		}{
+			{http.MethodPatch, "/api/v1/bookmarks/1", http.StatusMethodNotAllowed, "GET, HEAD, PUT, DELETE"},
+			{http.MethodDelete, "/api/v1/inbox", http.StatusMethodNotAllowed, "GET, HEAD"},
+			{http.MethodGet, "/api/v1/bookmarks", http.StatusMethodNotAllowed, "POST"},
+			{http.MethodPost, "/api/v1/banana", http.StatusNotFound, ""},
+		}
+		for _, tt := range tests {
+			t.Run(tt.method+tt.path, func(t *testing.T) {
+				req, err := http.NewRequest(tt.method, ts.URL+tt.path, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				resp, err := ts.Client().Do(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer resp.Body.Close()
+				var got apiErrorResponse
+				if err := json.NewDecoder(resp.Body).Decode(&got); err != nil || got.Error == "" {
+					t.Fatal("unexpected body:", err, got)
+				}
+				if resp.StatusCode != tt.wantCode || resp.Header.Get("Allow") != tt.wantAllow {
+					t.Error("unexpected response:", resp.StatusCode, resp.Header.Get("Allow"))
+				}
+			})
+		}
+	})
+	t.Run("create", func(t *testing.T) {
+		var inserted *bookmarks.Bookmark
+		repository := &RepositoryMock{
+			AddLinkCheckFunc: func(*bookmarks.LinkCheck) error { return nil },
+			InsertFunc: func(b *bookmarks.Bookmark) error {
+				if b.URL == "https://bad-db.example.com" {
+					return errDB
+				}
+				b.ID = 1
+				inserted = b
+				return nil
+			},
+			AddTagFunc: func(int64, int64, string) error { return nil },
+			GetByIDFunc: func(int64, int64) (*bookmarks.Bookmark, error) {
+				return inserted, nil
+			},
+		}
+		ts := httptest.NewServer(New(bookmarks.New(repository, urlChecker), nil, []string{"localhost"}))
+		defer ts.Close()
+		tests := []struct {
+			name     string
+			body     string
+			wantCode int
+		}{
+			{"good", `{"url": "https://example.com", "title": "title", "tags": ["Go Lang", "go-lang"]}`, http.StatusCreated},
+			{"badJSON", `{"url": `, http.StatusBadRequest},
+			{"unknownField", `{"url": "https://example.com", "inbox": true}`, http.StatusBadRequest},
+			{"missingURL", `{"title": "title"}`, http.StatusBadRequest},
+			{"badURL", `{"url": "://"}`, http.StatusBadRequest},
+			{"badDB", `{"url": "https://bad-db.example.com"}`, http.StatusInternalServerError},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				var got apiBookmark
+				if code := apiRequest(t, ts, http.MethodPost, "/api/v1/bookmarks", tt.body, &got); code != tt.wantCode {
+					t.Fatal("unexpected status code:", code)
+				}
+				if tt.wantCode != http.StatusCreated {
+					return
+				}
+				if got.ID != 1 || got.Title != "title" || !slices.Equal(got.Tags, []string{"go-lang"}) {
+					t.Errorf("unexpected bookmark: %#v", got)
+				}
+			})
+		}
+	})
+	t.Run("update", func(t *testing.T) {
+		var updated *bookmarks.Bookmark
+		repository := &RepositoryMock{
+			GetByIDFunc: getByID,
+			UpdateFunc: func(b *bookmarks.Bookmark) error {
+				updated = b
+				return nil
+			},
+			AddTagFunc:    func(int64, int64, string) error { return nil },
+			RemoveTagFunc: func(int64, int64, string) error { return nil },
+		}
+		ts := httptest.NewServer(New(bookmarks.New(repository, urlChecker), nil, []string{"localhost"}))
+		defer ts.Close()
+		var got apiBookmark
+		body := `{"url": "https://example.com", "title": "new title", "description": "new description", "tags": ["other"]}`
+		if code := apiRequest(t, ts, http.MethodPut, "/api/v1/bookmarks/1", body, &got); code != http.StatusOK {
+			t.Fatal("not OK:", code)
+		}
+		if got.Title != "new title" || got.Description != "new description" || !slices.Equal(got.Tags, []string{"other"}) {
+			t.Errorf("unexpected bookmark: %#v", got)
+		}
+		if updated == nil || updated.Title != "new title" {
+			t.Error("bookmark not stored")
+		}
+		var apiErr apiErrorResponse
+		if code := apiRequest(t, ts, http.MethodPut, "/api/v1/bookmarks/2", body, &apiErr); code != http.StatusNotFound {
+			t.Error("unexpected status code for missing bookmark:", code)
+		}
+	})
+	t.Run("delete", func(t *testing.T) {
+		repository := &RepositoryMock{
+			DeleteByIDFunc: func(_, id int64) error {
+				if id != 1 {
+					return bookmarks.ErrNotFound
+				}
+				return nil
+			},
+		}
+		ts := httptest.NewServer(New(bookmarks.New(repository, urlChecker), nil, []string{"localhost"}))
+		defer ts.Close()
+		if code := apiRequest(t, ts, http.MethodDelete, "/api/v1/bookmarks/1", "", nil); code != http.StatusNoContent {
+			t.Fatal("unexpected status code:", code)
+		}
+		var apiErr apiErrorResponse
+		if code := apiRequest(t, ts, http.MethodDelete, "/api/v1/bookmarks/2", "", &apiErr); code != http.StatusNotFound {
+			t.Fatal("unexpected status code for missing bookmark:", code)
+		}
+	})
+	t.Run("actions", func(t *testing.T) {
+		var updated *bookmarks.Bookmark
+		repository := &RepositoryMock{
+			GetByIDFunc: func(_, id int64) (*bookmarks.Bookmark, error) {
+				if updated != nil {
+					return updated, nil
+				}
+				return getByID(0, id)
+			},
+			UpdateFunc: func(b *bookmarks.Bookmark) error {
+				updated = b
+				return nil
+			},
+		}
+		ts := httptest.NewServer(New(bookmarks.New(repository, urlChecker), nil, []string{"localhost"}))
+		defer ts.Close()
+		var got apiBookmark
+		if code := apiRequest(t, ts, http.MethodPost, "/api/v1/bookmarks/1/read", "", &got); code != http.StatusOK {
+			t.Fatal("not OK:", code)
+		}
+		if got.Inbox {
+			t.Error("bookmark not marked as read")
+		}
+		updated = nil
+		got = apiBookmark{}
+		if code := apiRequest(t, ts, http.MethodPost, "/api/v1/bookmarks/1/bump", "", &got); code != http.StatusOK {
+			t.Fatal("not OK:", code)
+		}
+		if got.BumpedAt.IsZero() {
+			t.Error("bookmark not bumped")
+		}
+		var apiErr apiErrorResponse
+		updated = nil
+		if code := apiRequest(t, ts, http.MethodPost, "/api/v1/bookmarks/2/bump", "", &apiErr); code != http.StatusNotFound {
+			t.Error("unexpected status code for missing bookmark:", code)
+		}
+	})
+	t.Run("move", func(t *testing.T) {
+		moved := &bookmarks.Bookmark{ID: 1, URL: "https://example.com/old", FinalURL: "https://example.com/new", RedirectCount: 1, PermanentRedirect: true}
+		repository := &RepositoryMock{
+			MovedFunc: func(int64, bookmarks.Cursor, int) ([]*bookmarks.Bookmark, bookmarks.Cursor, error) {
+				return []*bookmarks.Bookmark{moved}, "", nil
+			},
+			GetByIDFunc: func(_, id int64) (*bookmarks.Bookmark, error) {
+				switch id {
+				case 1:
+					return moved, nil
+				case 2:
+					return storedBookmark(), nil
+				}
+				return nil, bookmarks.ErrNotFound
+			},
+			HasURLFunc: func(int64, string) (bool, error) { return false, nil },
+			UpdateFunc: func(*bookmarks.Bookmark) error { return nil },
+		}
+		ts := httptest.NewServer(New(bookmarks.New(repository, urlChecker), nil, []string{"localhost"}))
+		defer ts.Close()
+		var list apiListResponse
+		if code := apiRequest(t, ts, http.MethodGet, "/api/v1/moved", "", &list); code != http.StatusOK {
+			t.Fatal("not OK:", code)
+		}
+		if len(list.Bookmarks) != 1 || list.Bookmarks[0].Status.FinalURL != "https://example.com/new" || list.Bookmarks[0].Status.Redirects != 1 || !list.Bookmarks[0].Status.PermanentRedirect {
+			t.Fatalf("unexpected moved bookmarks: %#v", list.Bookmarks)
+		}
+		var got apiBookmark
+		if code := apiRequest(t, ts, http.MethodPost, "/api/v1/bookmarks/1/move", "", &got); code != http.StatusOK {
+			t.Fatal("not OK:", code)
+		}
+		if got.URL != "https://example.com/new" || got.Status.FinalURL != "" {
+			t.Errorf("redirect not followed: %#v", got)
+		}
+		var apiErr apiErrorResponse
+		if code := apiRequest(t, ts, http.MethodPost, "/api/v1/bookmarks/2/move", "", &apiErr); code != http.StatusConflict {
+			t.Error("unexpected status code for bookmark that did not move:", code)
+		}
+		if code := apiRequest(t, ts, http.MethodPost, "/api/v1/bookmarks/3/move", "", &apiErr); code != http.StatusNotFound {
+			t.Error("unexpected status code for missing bookmark:", code)
+		}
+	})
+	t.Run("history", func(t *testing.T) {
+		repository := &RepositoryMock{
+			GetByIDFunc: getByID,
+			LinkChecksFunc: func(int64, int64, int) ([]*bookmarks.LinkCheck, error) {
+				return []*bookmarks.LinkCheck{
+					{BookmarkID: 1, CheckedAt: 1700000000, Code: http.StatusServiceUnavailable, Reason: "timeout"},
+					{BookmarkID: 1, CheckedAt: 1690000000, Code: http.StatusOK},
+				}, nil
+			},
+		}
+		ts := httptest.NewServer(New(bookmarks.New(repository, urlChecker), nil, []string{"localhost"}))
+		defer ts.Close()
+		var got apiHistoryResponse
+		if code := apiRequest(t, ts, http.MethodGet, "/api/v1/bookmarks/1/history", "", &got); code != http.StatusOK {
+			t.Fatal("not OK:", code)
+		}
+		if got.Uptime != 50 || len(got.Checks) != 2 || got.Checks[0].Reason != "timeout" || !got.Checks[0].CheckedAt.Equal(time.Unix(1700000000, 0)) {
+			t.Errorf("unexpected history: %#v", got)
+		}
+		var apiErr apiErrorResponse
+		if code := apiRequest(t, ts, http.MethodGet, "/api/v1/bookmarks/2/history", "", &apiErr); code != http.StatusNotFound {
+			t.Error("unexpected status code for missing bookmark:", code)
+		}
+	})
+	t.Run("search", func(t *testing.T) {
+		repository := &RepositoryMock{
+			SearchFunc: func(_ int64, query *bookmarks.Query, page, _ int) ([]*bookmarks.Bookmark, int, error) {
+				if len(query.Terms) != 1 || query.Terms[0] != "banana" {
+					t.Error("unexpected terms found:", query.Terms)
+				}
+				if page != 2 {
+					t.Error("unexpected page:", page)
+				}
+				return []*bookmarks.Bookmark{storedBookmark()}, 1001, nil
+			},
+		}
+		ts := httptest.NewServer(New(bookmarks.New(repository, urlChecker), nil, []string{"localhost"}))
+		defer ts.Close()
+		var got apiSearchResponse
+		if code := apiRequest(t, ts, http.MethodGet, "/api/v1/search?q=banana&page=2", "", &got); code != http.StatusOK {
+			t.Fatal("not OK:", code)
+		}
+		if len(got.Bookmarks) != 1 || got.Total != 1001 || got.Page != 2 {
+			t.Errorf("unexpected results: %#v", got)
+		}
+		for _, path := range []string{"/api/v1/search?q=%22unterminated", "/api/v1/search?q=banana&page=-1"} {
+			var apiErr apiErrorResponse
+			if code := apiRequest(t, ts, http.MethodGet, path, "", &apiErr); code != http.StatusBadRequest {
+				t.Errorf("unexpected status code for %s: %d", path, code)
+			}
+		}
+	})
+	t.Run("export", func(t *testing.T) {
+		repository := &RepositoryMock{
+			WalkFunc: func(ownerID int64, fn func(*bookmarks.Bookmark) error) error {
+				if ownerID != 7 {
+					return errDB
+				}
+				return fn(storedBookmark())
+			},
+		}
+		ts := httptest.NewServer(New(bookmarks.New(repository, urlChecker), nil, []string{"localhost"}, WithDefaultOwner(7)))
+		defer ts.Close()
+		resp, err := ts.Client().Get(ts.URL + "/api/v1/export/jsonl")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal("not OK:", resp.StatusCode)
+		}
+		if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "application/jsonl") {
+			t.Error("unexpected content type:", ct)
+		}
+		got := &bookmarks.Bookmark{}
+		if err := json.NewDecoder(resp.Body).Decode(got); err != nil || got.URL != storedBookmark().URL {
+			t.Error("unexpected export:", got, err)
+		}
+		var apiErr apiErrorResponse
+		if code := apiRequest(t, ts, http.MethodGet, "/api/v1/export/xml", "", &apiErr); code != http.StatusNotFound {
+			t.Error("unexpected status code:", code)
+		}
+		ts2 := httptest.NewServer(New(bookmarks.New(repository, urlChecker), nil, []string{"localhost"}))
+		defer ts2.Close()
+		if code := apiRequest(t, ts2, http.MethodGet, "/api/v1/export/csv", "", &apiErr); code != http.StatusInternalServerError {
+			t.Error("unexpected status code:", code)
+		}
+	})
+}
@@ -0,0 +1,203 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"cirello.io/alreadyread/frontend"
+	"cirello.io/alreadyread/pkg/auth"
+)
+
+const sessionCookie = "alreadyread_session"
+
+// authenticate rejects requests without valid credentials: API requests must
+// carry a bearer token and everything else a session cookie. The user they
+// identify is carried in the request context. It is a no-op if
+// authentication is disabled.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	if s.auth == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" || r.URL.Path == "/signup" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if strings.HasPrefix(r.URL.Path, "/api/") {
+			secret, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok {
+				secret = ""
+			}
+			user, err := s.auth.Authenticate(strings.TrimSpace(secret))
+			if errors.Is(err, auth.ErrUnauthorized) {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="alreadyread"`)
+				writeAPIError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+				return
+			} else if err != nil {
+				apiFail(w, "cannot authenticate request", err)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), user)))
+			return
+		}
+		var secret string
+		if c, err := r.Cookie(sessionCookie); err == nil {
+			secret = c.Value
+		}
+		user, err := s.auth.Session(secret)
+		if errors.Is(err, auth.ErrUnauthorized) {
+			loginURL := "/login?" + url.Values{"next": {r.URL.RequestURI()}}.Encode()
+			if r.Header.Get("HX-Request") == "true" {
+				w.Header().Set("HX-Redirect", loginURL)
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+			http.Redirect(w, r, loginURL, http.StatusSeeOther)
+			return
+		} else if err != nil {
+			log.Println("cannot authenticate request:", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), user)))
+	})
+}
+
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	next := localRedirect(r.FormValue("next"))
+	switch r.Method {
+	case http.MethodGet:
+		signupOpen, err := s.auth.SignupOpen()
+		if err != nil {
+			log.Println("cannot check signup:", err)
+		}
+		frontend.RenderLogin(w, next, "", "", signupOpen)
+	case http.MethodPost:
+		username := r.FormValue("username")
+		session, secret, err := s.auth.Login(username, r.FormValue("password"))
+		if errors.Is(err, auth.ErrUnauthorized) {
+			w.WriteHeader(http.StatusUnauthorized)
+			frontend.RenderLogin(w, next, username, "invalid username or password", false)
+			return
+		} else if err != nil {
+			log.Println("cannot log in:", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		setSessionCookie(w, r, secret, session)
+		http.Redirect(w, r, next, http.StatusSeeOther)
+	default:
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) signup(w http.ResponseWriter, r *http.Request) {
+	next := localRedirect(r.FormValue("next"))
+	switch r.Method {
+	case http.MethodGet:
+		frontend.RenderSignup(w, next, "", "", auth.MinPasswordLength)
+	case http.MethodPost:
+		username, password := r.FormValue("username"), r.FormValue("password")
+		_, err := s.auth.SignUp(username, password)
+		switch {
+		case errors.Is(err, auth.ErrSignupClosed):
+			w.WriteHeader(http.StatusForbidden)
+			frontend.RenderSignup(w, next, username, err.Error(), auth.MinPasswordLength)
+			return
+		case errors.Is(err, auth.ErrUsernameTaken):
+			w.WriteHeader(http.StatusConflict)
+			frontend.RenderSignup(w, next, username, err.Error(), auth.MinPasswordLength)
+			return
+		case errors.Is(err, auth.ErrInvalidUsername), errors.Is(err, auth.ErrWeakPassword):
+			w.WriteHeader(http.StatusBadRequest)
+			frontend.RenderSignup(w, next, username, err.Error(), auth.MinPasswordLength)
+			return
+		case err != nil:
+			log.Println("cannot sign up:", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		session, secret, err := s.auth.Login(username, password)
+		if err != nil {
+			log.Println("cannot log in after signup:", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		setSessionCookie(w, r, secret, session)
+		http.Redirect(w, r, next, http.StatusSeeOther)
+	default:
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+func setSessionCookie(w http.ResponseWriter, r *http.Request, secret string, session *auth.Session) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    secret,
+		Path:     "/",
+		Expires:  session.ExpiresAt,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (s *Server) logout(w http.ResponseWriter, r *http.Request) {
+	if c, err := r.Cookie(sessionCookie); err == nil {
+		if err := s.auth.Logout(c.Value); err != nil {
+			log.Println("cannot log out:", err)
+		}
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// localRedirect only allows redirections within this server, defaulting to
+// the index page.
+func localRedirect(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+	return next
+}
+
+// account names the user who made the request, if any.
+func account(r *http.Request) string {
+	if user, ok := auth.FromContext(r.Context()); ok {
+		return user.Username
+	}
+	return ""
+}
+
+// owner identifies whose bookmarks the request operates on: the
+// authenticated user, or the default owner if authentication is disabled.
+func (s *Server) owner(r *http.Request) int64 {
+	if user, ok := auth.FromContext(r.Context()); ok {
+		return user.ID
+	}
+	return s.defaultOwner
+}
@@ -0,0 +1,372 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"cirello.io/alreadyread/pkg/auth"
+	"cirello.io/alreadyread/pkg/bookmarks"
+)
+
+const (
+	testUsername = "alice"
+	testPassword = "correct horse"
+)
+
+// newTestAuth keeps users, tokens and sessions in memory. It signs up the
+// test user and mints a token for them.
+func newTestAuth(t *testing.T, opts ...auth.Option) (*auth.Auth, string) {
+	t.Helper()
+	var (
+		users    []*auth.User
+		tokens   []*auth.Token
+		sessions = make(map[string]*auth.Session)
+	)
+	insertUser := func(user *auth.User) error {
+		user.ID = int64(len(users) + 1)
+		users = append(users, user)
+		return nil
+	}
+	repository := &AuthRepositoryMock{
+		UsersFunc:      func() ([]*auth.User, error) { return users, nil },
+		InsertUserFunc: insertUser,
+		InsertFirstUserFunc: func(user *auth.User) error {
+			if len(users) > 0 {
+				return auth.ErrSignupClosed
+			}
+			return insertUser(user)
+		},
+		UserByIDFunc: func(id int64) (*auth.User, error) {
+			for _, user := range users {
+				if user.ID == id {
+					return user, nil
+				}
+			}
+			return nil, auth.ErrNotFound
+		},
+		UserByUsernameFunc: func(username string) (*auth.User, error) {
+			for _, user := range users {
+				if user.Username == username {
+					return user, nil
+				}
+			}
+			return nil, auth.ErrNotFound
+		},
+		InsertTokenFunc: func(token *auth.Token) error {
+			token.ID = int64(len(tokens) + 1)
+			tokens = append(tokens, token)
+			return nil
+		},
+		TokenByHashFunc: func(hash string) (*auth.Token, error) {
+			for _, token := range tokens {
+				if token.Hash == hash {
+					return token, nil
+				}
+			}
+			return nil, auth.ErrNotFound
+		},
+		DeleteExpiredSessionsFunc: func(time.Time) error { return nil },
+		InsertSessionFunc: func(session *auth.Session) error {
+			sessions[session.Hash] = session
+			return nil
+		},
+		SessionByHashFunc: func(hash string) (*auth.Session, error) {
+			if session, ok := sessions[hash]; ok {
+				return session, nil
+			}
+			return nil, auth.ErrNotFound
+		},
+		DeleteSessionFunc: func(hash string) error {
+			delete(sessions, hash)
+			return nil
+		},
+	}
+	a := auth.New(repository, opts...)
+	user, err := a.SignUp(testUsername, testPassword)
+	if err != nil {
+		t.Fatal("cannot sign up:", err)
+	}
+	_, secret, err := a.Mint(user.ID, "laptop")
+	if err != nil {
+		t.Fatal("cannot mint token:", err)
+	}
+	return a, secret
+}
+
+func TestServer_auth(t *testing.T) {
+	a, secret := newTestAuth(t)
+	repository := &RepositoryMock{
+		InboxFunc: func(ownerID int64, _ bookmarks.Cursor, _ int) ([]*bookmarks.Bookmark, bookmarks.Cursor, error) {
+			if ownerID != 1 {
+				return nil, "", nil
+			}
+			return []*bookmarks.Bookmark{{ID: 1, Title: "%FIND-TITLE%", URL: "https://example.com"}}, "", nil
+		},
+	}
+	ts := httptest.NewServer(New(bookmarks.New(repository, nil), nil, []string{"localhost"}, WithAuth(a)))
+	defer ts.Close()
+	client := ts.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	t.Run("api", func(t *testing.T) {
+		for _, authorization := range []string{"", "Bearer ", "Bearer ar_bad", secret} {
+			req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/v1/inbox", nil)
+			req.Header.Set("Authorization", authorization)
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusUnauthorized || resp.Header.Get("WWW-Authenticate") == "" {
+				t.Errorf("%q: unexpected response: %v %v", authorization, resp.StatusCode, resp.Header)
+			}
+		}
+		var page apiListResponse
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/v1/inbox", nil)
+		req.Header.Set("Authorization", "Bearer "+secret)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal("not OK:", resp.StatusCode)
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&page); err != nil || len(page.Bookmarks) != 1 {
+			t.Fatal("token owner's bookmarks not listed:", page, err)
+		}
+	})
+	t.Run("html", func(t *testing.T) {
+		resp, err := client.Get(ts.URL + "/inbox?cursor=abc")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusSeeOther || resp.Header.Get("Location") != "/login?next=%2Finbox%3Fcursor%3Dabc" {
+			t.Error("unexpected response:", resp.StatusCode, resp.Header.Get("Location"))
+		}
+
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/inbox", nil)
+		req.Header.Set("HX-Request", "true")
+		req.AddCookie(&http.Cookie{Name: sessionCookie, Value: "bad session"})
+		resp, err = client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized || !strings.HasPrefix(resp.Header.Get("HX-Redirect"), "/login") {
+			t.Error("unexpected htmx response:", resp.StatusCode, resp.Header)
+		}
+	})
+	t.Run("login", func(t *testing.T) {
+		resp, err := client.Get(ts.URL + "/login?next=/inbox")
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf := &bytes.Buffer{}
+		_, _ = io.Copy(buf, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal("cannot load login page:", resp.StatusCode)
+		}
+		if strings.Contains(buf.String(), `href="/signup`) {
+			t.Error("signup link shown although signup is closed")
+		}
+
+		for _, credentials := range []url.Values{
+			{"username": {testUsername}, "password": {"wrong password"}},
+			{"username": {"bob"}, "password": {testPassword}},
+			{"username": {testUsername}, "password": {secret}},
+		} {
+			resp, err = client.PostForm(ts.URL+"/login", credentials)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusUnauthorized {
+				t.Fatal("unexpected status code for bad credentials:", credentials, resp.StatusCode)
+			}
+		}
+
+		jar, err := cookiejar.New(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client := *client
+		client.Jar = jar
+		resp, err = client.PostForm(ts.URL+"/login", url.Values{"username": {testUsername}, "password": {testPassword}, "next": {"/inbox"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusSeeOther || resp.Header.Get("Location") != "/inbox" {
+			t.Fatal("unexpected login response:", resp.StatusCode, resp.Header.Get("Location"))
+		}
+
+		resp, err = client.Get(ts.URL + "/inbox")
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf.Reset()
+		_, _ = io.Copy(buf, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || !strings.Contains(buf.String(), "%FIND-TITLE%") {
+			t.Fatal("cannot load page after login:", resp.StatusCode)
+		}
+		if !strings.Contains(buf.String(), `action="/logout"`) || !strings.Contains(buf.String(), testUsername) {
+			t.Error("cannot find logout button")
+		}
+
+		resp, err = client.Post(ts.URL+"/logout", "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusSeeOther {
+			t.Fatal("unexpected logout response:", resp.StatusCode)
+		}
+		resp, err = client.Get(ts.URL + "/inbox")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusSeeOther {
+			t.Error("session still valid after logout:", resp.StatusCode)
+		}
+	})
+	t.Run("signupClosed", func(t *testing.T) {
+		resp, err := client.PostForm(ts.URL+"/signup", url.Values{"username": {"bob"}, "password": {testPassword}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Error("unexpected status code:", resp.StatusCode)
+		}
+	})
+}
+
+func TestServer_signup(t *testing.T) {
+	a, _ := newTestAuth(t, auth.WithSignup(true))
+	repository := &RepositoryMock{
+		InboxFunc: func(ownerID int64, _ bookmarks.Cursor, _ int) ([]*bookmarks.Bookmark, bookmarks.Cursor, error) {
+			if ownerID != 2 {
+				t.Error("unexpected owner:", ownerID)
+			}
+			return nil, "", nil
+		},
+	}
+	ts := httptest.NewServer(New(bookmarks.New(repository, nil), nil, []string{"localhost"}, WithAuth(a)))
+	defer ts.Close()
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := ts.Client()
+	client.Jar = jar
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	resp, err := client.Get(ts.URL + "/signup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatal("cannot load signup page:", resp.StatusCode)
+	}
+	tests := []struct {
+		name     string
+		username string
+		password string
+		wantCode int
+	}{
+		{"taken", testUsername, testPassword, http.StatusConflict},
+		{"invalidUsername", "b o b", testPassword, http.StatusBadRequest},
+		{"weakPassword", "bob", "short", http.StatusBadRequest},
+		{"good", "bob", testPassword, http.StatusSeeOther},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := client.PostForm(ts.URL+"/signup", url.Values{"username": {tt.username}, "password": {tt.password}, "next": {"/inbox"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantCode {
+				t.Fatal("unexpected status code:", resp.StatusCode)
+			}
+		})
+	}
+	resp, err = client.Get(ts.URL + "/inbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || len(repository.InboxCalls()) != 1 {
+		t.Error("new user not logged in after signup:", resp.StatusCode)
+	}
+}
+
+func TestServer_defaultOwner(t *testing.T) {
+	repository := &RepositoryMock{
+		InboxFunc: func(int64, bookmarks.Cursor, int) ([]*bookmarks.Bookmark, bookmarks.Cursor, error) {
+			return nil, "", nil
+		},
+	}
+	ts := httptest.NewServer(New(bookmarks.New(repository, nil), nil, []string{"localhost"}, WithDefaultOwner(42)))
+	defer ts.Close()
+	resp, err := ts.Client().Get(ts.URL + "/inbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if calls := repository.InboxCalls(); len(calls) != 1 || calls[0].OwnerID != 42 {
+		t.Error("default owner not used:", calls)
+	}
+}
+
+func Test_localRedirect(t *testing.T) {
+	tests := []struct {
+		next string
+		want string
+	}{
+		{"", "/"},
+		{"/inbox?cursor=abc", "/inbox?cursor=abc"},
+		{"https://example.com", "/"},
+		{"//example.com", "/"},
+		{"/\\example.com", "/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.next, func(t *testing.T) {
+			if got := localRedirect(tt.next); got != tt.want {
+				t.Errorf("localRedirect() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
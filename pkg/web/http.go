@@ -16,24 +16,29 @@ package web // import "cirello.io/alreadyread/pkg/web"
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"html/template"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"path"
 	"strconv"
 	"strings"
 
 	"cirello.io/alreadyread/frontend"
+	"cirello.io/alreadyread/pkg/auth"
 	"cirello.io/alreadyread/pkg/bookmarks"
+	"cirello.io/alreadyread/pkg/bookmarks/export"
+	"cirello.io/alreadyread/pkg/bookmarks/importer"
 	"github.com/rs/cors"
 )
 
 //go:generate go tool moq -out urltitleloader_mocks_test.go . URLTitleLoader
 type URLTitleLoader interface {
-	Title(url string) string
+	Preview(ctx context.Context, url string) (title string, metadata bookmarks.Metadata)
 }
 
 // Server implements the web interface.
@@ -42,11 +47,14 @@ type Server struct {
 	cors      *cors.Cors
 	handler   http.Handler
 
-	titleLoader URLTitleLoader
+	titleLoader  URLTitleLoader
+	auth         *auth.Auth
+	defaultOwner int64
+	linkHealth   LinkHealth
 }
 
 // New creates a web interface handler.
-func New(bookmarks *bookmarks.Bookmarks, titleLoader URLTitleLoader, allowedOrigins []string) *Server {
+func New(bookmarks *bookmarks.Bookmarks, titleLoader URLTitleLoader, allowedOrigins []string, opts ...Option) *Server {
 	s := &Server{
 		bookmarks: bookmarks,
 		cors: cors.New(cors.Options{
@@ -54,6 +62,9 @@ func New(bookmarks *bookmarks.Bookmarks, titleLoader URLTitleLoader, allowedOrig
 		}),
 		titleLoader: titleLoader,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
 	s.registerRoutes()
 	return s
 }
@@ -65,11 +76,23 @@ func (s *Server) registerRoutes() {
 	router.HandleFunc("/inbox", s.inbox)
 	router.HandleFunc("/duplicated", s.duplicated)
 	router.HandleFunc("/dead", s.dead)
+	router.HandleFunc("/moved", s.moved)
 	router.HandleFunc("/all", s.all)
 	router.HandleFunc("/search", s.search)
+	router.HandleFunc("/tags", s.tags)
+	router.HandleFunc("/tags/{name...}", s.tag)
 	router.HandleFunc("/bookmarks/", s.bookmarkOperations)
+	router.HandleFunc("/import", s.importBookmarks)
+	router.HandleFunc("GET /export/{format}", s.exportBookmarks)
+	s.registerAPIRoutes(router)
+	s.registerAdminRoutes(router)
+	if s.auth != nil {
+		router.HandleFunc("/login", s.login)
+		router.HandleFunc("/signup", s.signup)
+		router.HandleFunc("POST /logout", s.logout)
+	}
 	router.HandleFunc("/", s.index())
-	s.handler = s.cors.Handler(router)
+	s.handler = s.cors.Handler(s.authenticate(router))
 }
 
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -79,107 +102,308 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 func (s *Server) post(w http.ResponseWriter, r *http.Request) {
 	url := r.URL.Query().Get("url")
 	description := r.URL.Query().Get("description")
+	tags := bookmarks.ParseTags(r.URL.Query().Get("tags"))
 	loadTitle := r.URL.Query().Get("loadTitle") == "true"
 	bookmark := &bookmarks.Bookmark{
 		URL:         url,
 		Description: description,
+		Tags:        tags,
 	}
 	if loadTitle {
-		bookmark.Title = s.titleLoader.Title(url)
+		bookmark.Title, bookmark.Metadata = s.titleLoader.Preview(r.Context(), url)
+		if bookmark.Title == "" {
+			bookmark.Title = bookmark.Metadata.Title
+		}
+		if bookmark.Description == "" {
+			bookmark.Description = bookmark.Metadata.Description
+		}
 	}
 	buf := &bytes.Buffer{}
 	frontend.RenderNewLink(buf, bookmark)
 	if r.Header.Get("HX-Request") != "true" {
 		indexBuf := &bytes.Buffer{}
-		frontend.RenderIndex(indexBuf, r.URL.Path, frontend.NoTitle, template.HTML(buf.String()))
+		frontend.RenderIndex(indexBuf, r.URL.Path, frontend.NoTitle, template.HTML(buf.String()), account(r))
 		buf = indexBuf
 	}
 	_, _ = io.Copy(w, buf)
 }
 
 func (s *Server) inbox(w http.ResponseWriter, r *http.Request) {
-	page, err := strconv.Atoi(r.URL.Query().Get("page"))
-	if err != nil || page < 1 {
-		page = 0
-	}
 	lastDate := r.URL.Query().Get("lastDate")
-	list, err := s.bookmarks.Inbox(page)
-	if err != nil {
+	list, next, err := s.bookmarks.Inbox(s.owner(r), bookmarks.Cursor(r.URL.Query().Get("cursor")))
+	if errors.Is(err, bookmarks.ErrInvalidCursor) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
 		log.Println("cannot load bookmarks for inbox:", err)
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
-	s.renderList(w, r, "Inbox", list, page, lastDate)
+	s.renderList(w, r, "Inbox", list, nextCursor(next), lastDate)
 }
 
 func (s *Server) duplicated(w http.ResponseWriter, r *http.Request) {
-	page, err := strconv.Atoi(r.URL.Query().Get("page"))
-	if err != nil || page < 1 {
-		page = 0
-	}
 	lastDate := r.URL.Query().Get("lastDate")
-	list, err := s.bookmarks.Duplicated(page)
-	if err != nil {
+	list, next, err := s.bookmarks.Duplicated(s.owner(r), bookmarks.Cursor(r.URL.Query().Get("cursor")))
+	if errors.Is(err, bookmarks.ErrInvalidCursor) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
 		log.Println("cannot load duplicated bookmarks:", err)
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
-	s.renderList(w, r, "Duplicated", list, page, lastDate)
+	s.renderList(w, r, "Duplicated", list, nextCursor(next), lastDate)
 }
 
 func (s *Server) dead(w http.ResponseWriter, r *http.Request) {
-	page, err := strconv.Atoi(r.URL.Query().Get("page"))
-	if err != nil || page < 1 {
-		page = 0
-	}
 	lastDate := r.URL.Query().Get("lastDate")
-	list, err := s.bookmarks.Dead(page)
+	filter, err := bookmarks.ParseDeadFilter(r.URL.Query().Get("filter"))
 	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	cursor := bookmarks.Cursor(r.URL.Query().Get("cursor"))
+	list, next, err := s.bookmarks.Dead(s.owner(r), filter, cursor)
+	if errors.Is(err, bookmarks.ErrInvalidCursor) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
 		log.Println("cannot load dead bookmarks:", err)
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
-	s.renderList(w, r, "Dead", list, page, lastDate)
+	nextPage := nextCursor(next)
+	if nextPage != nil && filter != bookmarks.AllDead {
+		nextPage.Set("filter", string(filter))
+	}
+	buf := &bytes.Buffer{}
+	if cursor == "" {
+		frontend.RenderDeadFilters(buf, filter)
+	}
+	frontend.RenderLinkTable(buf, list, nextPage, lastDate)
+	s.renderPage(w, r, deadTitles[filter], buf)
+}
+
+// deadTitles names the page of each dead bookmark filter.
+var deadTitles = map[bookmarks.DeadFilter]string{
+	bookmarks.AllDead:  "Dead",
+	bookmarks.SoftDead: "Dead: soft failures",
+	bookmarks.HardDead: "Dead: hard failures",
+}
+
+func (s *Server) moved(w http.ResponseWriter, r *http.Request) {
+	lastDate := r.URL.Query().Get("lastDate")
+	list, next, err := s.bookmarks.Moved(s.owner(r), bookmarks.Cursor(r.URL.Query().Get("cursor")))
+	if errors.Is(err, bookmarks.ErrInvalidCursor) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		log.Println("cannot load moved bookmarks:", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	s.renderList(w, r, "Moved", list, nextCursor(next), lastDate)
 }
 
 func (s *Server) all(w http.ResponseWriter, r *http.Request) {
+	lastDate := r.URL.Query().Get("lastDate")
+	list, next, err := s.bookmarks.All(s.owner(r), bookmarks.Cursor(r.URL.Query().Get("cursor")))
+	if errors.Is(err, bookmarks.ErrInvalidCursor) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		log.Println("cannot load all bookmarks:", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	s.renderList(w, r, "All", list, nextCursor(next), lastDate)
+}
+
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
 	page, err := strconv.Atoi(r.URL.Query().Get("page"))
 	if err != nil || page < 1 {
 		page = 0
 	}
 	lastDate := r.URL.Query().Get("lastDate")
-	list, err := s.bookmarks.All(page)
-	if err != nil {
-		log.Println("cannot load all bookmarks:", err)
+	term := r.URL.Query().Get("term")
+	list, total, err := s.bookmarks.Search(s.owner(r), term, page)
+	if syntaxErr := (*bookmarks.QuerySyntaxError)(nil); errors.As(err, &syntaxErr) {
+		buf := &bytes.Buffer{}
+		frontend.RenderError(buf, syntaxErr.Error())
+		s.renderPage(w, r, "Search", buf)
+		return
+	} else if err != nil {
+		log.Println("cannot search bookmarks:", err)
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
-	s.renderList(w, r, "All", list, page, lastDate)
+	title := fmt.Sprintf("Search: %d results", total)
+	if total == 1 {
+		title = "Search: 1 result"
+	}
+	var next url.Values
+	if (page+1)*s.bookmarks.PageSize() < total {
+		next = url.Values{"term": {term}, "page": {strconv.Itoa(page + 1)}}
+	}
+	s.renderList(w, r, title, list, next, lastDate)
 }
 
-func (s *Server) search(w http.ResponseWriter, r *http.Request) {
-	list, err := s.bookmarks.Search(r.URL.Query().Get("term"))
+func (s *Server) tags(w http.ResponseWriter, r *http.Request) {
+	list, err := s.bookmarks.Tags(s.owner(r))
 	if err != nil {
-		log.Println("cannot load all bookmarks:", err)
+		log.Println("cannot load tags:", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	buf := &bytes.Buffer{}
+	frontend.RenderTags(buf, list)
+	s.renderPage(w, r, "Tags", buf)
+}
+
+func (s *Server) tag(w http.ResponseWriter, r *http.Request) {
+	lastDate := r.URL.Query().Get("lastDate")
+	name := r.PathValue("name")
+	list, next, err := s.bookmarks.ByTag(s.owner(r), name, bookmarks.Cursor(r.URL.Query().Get("cursor")))
+	if errors.Is(err, bookmarks.ErrInvalidCursor) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		log.Println("cannot load tagged bookmarks:", err)
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
-	s.renderList(w, r, "Search", list, -1, "")
+	s.renderList(w, r, "#"+bookmarks.NormalizeTag(name), list, nextCursor(next), lastDate)
 }
 
-func (s *Server) renderList(w http.ResponseWriter, r *http.Request, title string, list []*bookmarks.Bookmark, page int, lastDate string) {
+// maxImportSize limits the size of uploaded bookmark files. Firefox
+// databases include the browsing history, and are the largest of them.
+const maxImportSize = 128 << 20
+
+func (s *Server) importBookmarks(w http.ResponseWriter, r *http.Request) {
 	buf := &bytes.Buffer{}
-	frontend.RenderLinkTable(buf, list, page, lastDate)
+	switch r.Method {
+	case http.MethodGet:
+		frontend.RenderImport(buf, nil, importer.Netscape)
+	case http.MethodPost:
+		r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			log.Println("cannot read uploaded bookmark file:", err)
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		format := importer.Netscape
+		if v := r.FormValue("format"); v != "" {
+			format, err = importer.ParseFormat(v)
+		}
+		var list []*bookmarks.Bookmark
+		if err == nil {
+			list, err = importer.Parse(format, file)
+		}
+		if err != nil {
+			frontend.RenderError(buf, err.Error())
+			frontend.RenderImport(buf, nil, format)
+			s.renderPage(w, r, "Import/Export", buf)
+			return
+		}
+		report, err := s.bookmarks.Import(s.owner(r), list, r.FormValue("dryRun") == "true")
+		if err != nil {
+			log.Println("cannot import bookmarks:", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		frontend.RenderImport(buf, report, format)
+	default:
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	s.renderPage(w, r, "Import/Export", buf)
+}
+
+func (s *Server) exportBookmarks(w http.ResponseWriter, r *http.Request) {
+	format, err := export.ParseFormat(r.PathValue("format"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if err := s.streamExport(w, r, format); err != nil {
+		log.Println("cannot export bookmarks:", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+// streamExport writes all bookmarks of the owner as a file download, as they
+// are read from the repository. It returns an error only if it fails before
+// anything is written; later failures are logged and abort the connection,
+// so that the download fails instead of completing as a truncated file.
+func (s *Server) streamExport(w http.ResponseWriter, r *http.Request, format export.Format) error {
+	enc, err := export.NewEncoder(format, w)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"bookmarks.%s\"", format))
+	started := false
+	err = s.bookmarks.Walk(s.owner(r), func(bookmark *bookmarks.Bookmark) error {
+		started = true
+		return enc.Encode(bookmark)
+	})
+	if err == nil {
+		err = enc.Close()
+	}
+	if err != nil && !started {
+		w.Header().Del("Content-Disposition")
+		return err
+	} else if err != nil {
+		log.Println("cannot finish bookmark export:", err)
+		panic(http.ErrAbortHandler)
+	}
+	return nil
+}
+
+func (s *Server) renderList(w http.ResponseWriter, r *http.Request, title string, list []*bookmarks.Bookmark, next url.Values, lastDate string) {
+	buf := &bytes.Buffer{}
+	frontend.RenderLinkTable(buf, list, next, lastDate)
+	s.renderPage(w, r, title, buf)
+}
+
+// nextCursor prepares the query parameters that load the page after the
+// current one, if any.
+func nextCursor(next bookmarks.Cursor) url.Values {
+	if next == "" {
+		return nil
+	}
+	return url.Values{"cursor": {string(next)}}
+}
+
+// renderPage wraps the rendered content with the index page, unless the
+// request comes from htmx, in which case only the page header is updated.
+func (s *Server) renderPage(w http.ResponseWriter, r *http.Request, title string, buf *bytes.Buffer) {
 	if r.Header.Get("HX-Request") != "true" {
 		indexBuf := &bytes.Buffer{}
-		frontend.RenderIndex(indexBuf, r.URL.Path, title, template.HTML(buf.String()))
+		frontend.RenderIndex(indexBuf, r.URL.Path, title, template.HTML(buf.String()), account(r))
 		buf = indexBuf
 	} else {
-		fmt.Fprintln(buf, "<h2 id=\"header-page-name\" hx-swap-oob=\"true\">", title, "</h2>")
+		fmt.Fprintln(buf, "<h2 id=\"header-page-name\" hx-swap-oob=\"true\">", template.HTMLEscapeString(title), "</h2>")
 	}
 	_, _ = io.Copy(w, buf)
 }
 
+func (s *Server) linkHistory(w http.ResponseWriter, r *http.Request, id int64) {
+	history, err := s.bookmarks.History(s.owner(r), id)
+	if errors.Is(err, bookmarks.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		log.Println("cannot load link history:", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	frontend.RenderLinkHistory(w, history)
+}
+
 func (s *Server) bookmarkOperations(w http.ResponseWriter, r *http.Request) {
 	id, err := extractID("/bookmarks", r.URL.Path)
 	if err != nil {
@@ -188,9 +412,46 @@ func (s *Server) bookmarkOperations(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	switch r.Method {
+	case http.MethodGet:
+		var render func(io.Writer, *bookmarks.Bookmark)
+		switch extractSubresource("/bookmarks", r.URL.Path) {
+		case "edit":
+			render = frontend.RenderEditLink
+		case "card":
+			render = frontend.RenderLink
+		case "history":
+			s.linkHistory(w, r, id)
+			return
+		default:
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		bookmark, err := s.bookmarks.GetByID(s.owner(r), id)
+		if errors.Is(err, bookmarks.ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		} else if err != nil {
+			log.Println("cannot load bookmark:", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		render(w, bookmark)
+		return
+	case http.MethodPut:
+		s.editBookmark(w, r, &bookmarks.Bookmark{
+			ID:          id,
+			Title:       r.FormValue("title"),
+			URL:         r.FormValue("url"),
+			Description: r.FormValue("description"),
+			Tags:        bookmarks.ParseTags(r.FormValue("tags")),
+		})
+		return
 	case http.MethodDelete:
-		err := s.bookmarks.DeleteByID(id)
-		if err != nil {
+		err := s.bookmarks.DeleteByID(s.owner(r), id)
+		if errors.Is(err, bookmarks.ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		} else if err != nil {
 			log.Println("cannot delete bookmark:", err)
 			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 			return
@@ -200,30 +461,70 @@ func (s *Server) bookmarkOperations(w http.ResponseWriter, r *http.Request) {
 	case http.MethodPatch:
 		action := r.URL.Query().Get("action")
 		switch action {
+		case "":
+			s.patchBookmark(w, r, id)
 		case "bump":
-			if err := s.bookmarks.Bump(id); err != nil {
+			if err := s.bookmarks.Bump(s.owner(r), id); err != nil {
 				log.Println("cannot update bookmark:", err)
 				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 				return
 			}
 			w.Header().Set("HX-Redirect", "/inbox")
+		case "move":
+			bookmark, err := s.bookmarks.FollowRedirect(s.owner(r), id)
+			if errors.Is(err, bookmarks.ErrNotFound) {
+				http.NotFound(w, r)
+				return
+			} else if errors.Is(err, bookmarks.ErrNotMoved) || errors.Is(err, bookmarks.ErrDuplicateURL) {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			} else if err != nil {
+				log.Println("cannot update bookmark:", err)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			frontend.RenderLink(w, bookmark)
 		case "update":
 			if inbox := r.URL.Query().Get("inbox"); inbox != "" {
-				if err := s.bookmarks.UpdateInbox(id, inbox); err != nil {
+				if err := s.bookmarks.UpdateInbox(s.owner(r), id, inbox); err != nil {
 					log.Println("cannot update bookmark:", err)
 					http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 					return
 				}
 				w.Header().Set("HX-Reswap", "delete")
 			}
+		case "tag", "untag":
+			tag := r.FormValue("tag")
+			var err error
+			if action == "tag" {
+				err = s.bookmarks.AddTag(s.owner(r), id, tag)
+			} else {
+				err = s.bookmarks.RemoveTag(s.owner(r), id, tag)
+			}
+			if errors.Is(err, bookmarks.ErrEmptyTag) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			} else if err != nil {
+				log.Println("cannot update bookmark tags:", err)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			bookmark, err := s.bookmarks.GetByID(s.owner(r), id)
+			if err != nil {
+				log.Println("cannot load bookmark:", err)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			frontend.RenderLink(w, bookmark)
 		}
 		return
 	case http.MethodPost:
 		title, url, description := r.FormValue("title"), r.FormValue("url"), r.FormValue("description")
-		err := s.bookmarks.Insert(&bookmarks.Bookmark{
+		err := s.bookmarks.Insert(r.Context(), s.owner(r), &bookmarks.Bookmark{
 			Title:       title,
 			URL:         url,
 			Description: description,
+			Tags:        bookmarks.ParseTags(r.FormValue("tags")),
 		})
 		if err != nil {
 			log.Println("cannot store new bookmark:", err)
@@ -238,17 +539,81 @@ func (s *Server) bookmarkOperations(w http.ResponseWriter, r *http.Request) {
 
 }
 
+// patchBookmark edits only the fields present in the form, keeping the
+// others as they are.
+func (s *Server) patchBookmark(w http.ResponseWriter, r *http.Request, id int64) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	bookmark, err := s.bookmarks.GetByID(s.owner(r), id)
+	if errors.Is(err, bookmarks.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		log.Println("cannot load bookmark:", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	edited := &bookmarks.Bookmark{
+		ID:          id,
+		Title:       bookmark.Title,
+		URL:         bookmark.URL,
+		Description: bookmark.Description,
+		Tags:        bookmark.Tags,
+	}
+	if v, ok := r.PostForm["title"]; ok {
+		edited.Title = strings.Join(v, "")
+	}
+	if v, ok := r.PostForm["url"]; ok {
+		edited.URL = strings.Join(v, "")
+	}
+	if v, ok := r.PostForm["description"]; ok {
+		edited.Description = strings.Join(v, "")
+	}
+	if v, ok := r.PostForm["tags"]; ok {
+		edited.Tags = bookmarks.ParseTags(strings.Join(v, ","))
+	}
+	s.editBookmark(w, r, edited)
+}
+
+func (s *Server) editBookmark(w http.ResponseWriter, r *http.Request, edited *bookmarks.Bookmark) {
+	bookmark, err := s.bookmarks.Edit(r.Context(), s.owner(r), edited)
+	if errors.Is(err, &bookmarks.BadURLError{}) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if errors.Is(err, bookmarks.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		log.Println("cannot edit bookmark:", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	frontend.RenderLink(w, bookmark)
+}
+
 func (s *Server) index() func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.String() == "/" {
 			w.Header().Set("Content-Type", "text/html; charset=utf-8")
-			frontend.RenderIndex(w, r.URL.Path, frontend.NoTitle, frontend.EmptyContainer)
+			frontend.RenderIndex(w, r.URL.Path, frontend.NoTitle, frontend.EmptyContainer, account(r))
 			return
 		}
 		http.NotFound(w, r)
 	}
 }
 
+// extractSubresource returns the path segment that follows the ID, if any.
+func extractSubresource(root, urlPath string) string {
+	urlPath = strings.TrimPrefix(path.Clean(urlPath+"/"), path.Clean(root+"/"))
+	urlPathParts := strings.Split(strings.Trim(urlPath, "/"), "/")
+	if len(urlPathParts) < 2 {
+		return ""
+	}
+	return urlPathParts[1]
+}
+
 func extractID(root, urlPath string) (int64, error) {
 	if root == "" {
 		return 0, errors.New("empty root")
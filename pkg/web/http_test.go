@@ -16,11 +16,14 @@ package web
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"slices"
 	"strings"
 	"testing"
 
@@ -56,20 +59,40 @@ func Test_extractID(t *testing.T) {
 	}
 }
 
+func Test_extractSubresource(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"/bookmarks", ""},
+		{"/bookmarks/1", ""},
+		{"/bookmarks/1/", ""},
+		{"/bookmarks/1/edit", "edit"},
+		{"/bookmarks/1/edit/", "edit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if got := extractSubresource("/bookmarks", tt.url); got != tt.want {
+				t.Errorf("extractSubresource() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestServer(t *testing.T) {
 	t.Run("post", func(t *testing.T) {
 		t.Run("emptyTitle", func(t *testing.T) {
 			errDB := errors.New("bad DB")
 			repository := &RepositoryMock{
-				InboxFunc: func(int) ([]*bookmarks.Bookmark, error) {
-					return nil, errDB
+				InboxFunc: func(int64, bookmarks.Cursor, int) ([]*bookmarks.Bookmark, bookmarks.Cursor, error) {
+					return nil, "", errDB
 				},
 			}
 			root := bookmarks.New(repository, nil)
 
 			ts := httptest.NewServer(New(root, &URLCheckerMock{
-				TitleFunc: func(string) string {
-					return "example-title"
+				PreviewFunc: func(context.Context, string) (string, bookmarks.Metadata) {
+					return "example-title", bookmarks.Metadata{}
 				},
 			}, []string{"localhost"}))
 			defer ts.Close()
@@ -91,13 +114,41 @@ func TestServer(t *testing.T) {
 				t.Error("cannot find expected bookmark URL")
 			}
 		})
+		t.Run("metadata", func(t *testing.T) {
+			ts := httptest.NewServer(New(bookmarks.New(&RepositoryMock{}, nil), &URLCheckerMock{
+				PreviewFunc: func(context.Context, string) (string, bookmarks.Metadata) {
+					return "", bookmarks.Metadata{Title: "og-title", Description: "og-description"}
+				},
+			}, []string{"localhost"}))
+			defer ts.Close()
+			for query, want := range map[string][]string{
+				"":                        {"og-title", "og-description"},
+				"&description=my%20words": {"og-title", "my words"},
+			} {
+				resp, err := ts.Client().Post(ts.URL+"/post?loadTitle=true&url="+url.QueryEscape("https://example.com")+query, "", nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				buf := &bytes.Buffer{}
+				_, _ = io.Copy(buf, resp.Body)
+				resp.Body.Close()
+				for _, v := range want {
+					if !strings.Contains(buf.String(), v) {
+						t.Errorf("%q: cannot find %q", query, v)
+					}
+				}
+				if query != "" && strings.Contains(buf.String(), "og-description") {
+					t.Errorf("%q: description overwritten", query)
+				}
+			}
+		})
 	})
 	t.Run("inbox", func(t *testing.T) {
 		t.Run("badDB", func(t *testing.T) {
 			errDB := errors.New("bad DB")
 			repository := &RepositoryMock{
-				InboxFunc: func(int) ([]*bookmarks.Bookmark, error) {
-					return nil, errDB
+				InboxFunc: func(int64, bookmarks.Cursor, int) ([]*bookmarks.Bookmark, bookmarks.Cursor, error) {
+					return nil, "", errDB
 				},
 			}
 			root := bookmarks.New(repository, nil)
@@ -115,16 +166,19 @@ func TestServer(t *testing.T) {
 		t.Run("good", func(t *testing.T) {
 			foundBookmark := &bookmarks.Bookmark{ID: 1, Title: "%FIND-TITLE%", URL: "https://%FIND-%URL.com"}
 			repository := &RepositoryMock{
-				InboxFunc: func(int) ([]*bookmarks.Bookmark, error) {
+				InboxFunc: func(_ int64, after bookmarks.Cursor, _ int) ([]*bookmarks.Bookmark, bookmarks.Cursor, error) {
+					if after != "current" {
+						t.Error("unexpected cursor found:", after)
+					}
 					return []*bookmarks.Bookmark{
 						foundBookmark,
-					}, nil
+					}, "next", nil
 				},
 			}
 			root := bookmarks.New(repository, nil)
 			ts := httptest.NewServer(New(root, nil, []string{"localhost"}))
 			defer ts.Close()
-			resp, err := ts.Client().Get(ts.URL + "/inbox")
+			resp, err := ts.Client().Get(ts.URL + "/inbox?cursor=current")
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -140,14 +194,55 @@ func TestServer(t *testing.T) {
 			if !strings.Contains(buf.String(), foundBookmark.URL) {
 				t.Error("cannot find expected bookmark URL")
 			}
+			if !strings.Contains(buf.String(), "cursor=next") {
+				t.Error("cannot find next page trigger")
+			}
+		})
+		t.Run("badCursor", func(t *testing.T) {
+			repository := &RepositoryMock{
+				InboxFunc: func(int64, bookmarks.Cursor, int) ([]*bookmarks.Bookmark, bookmarks.Cursor, error) {
+					return nil, "", bookmarks.ErrInvalidCursor
+				},
+			}
+			root := bookmarks.New(repository, nil)
+			ts := httptest.NewServer(New(root, nil, []string{"localhost"}))
+			defer ts.Close()
+			resp, err := ts.Client().Get(ts.URL + "/inbox?cursor=garbage")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Fatal("not StatusBadRequest:", resp.StatusCode)
+			}
+		})
+		t.Run("lastPage", func(t *testing.T) {
+			repository := &RepositoryMock{
+				InboxFunc: func(int64, bookmarks.Cursor, int) ([]*bookmarks.Bookmark, bookmarks.Cursor, error) {
+					return []*bookmarks.Bookmark{{ID: 1, Title: "title", URL: "https://example.com"}}, "", nil
+				},
+			}
+			root := bookmarks.New(repository, nil)
+			ts := httptest.NewServer(New(root, nil, []string{"localhost"}))
+			defer ts.Close()
+			resp, err := ts.Client().Get(ts.URL + "/inbox")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			buf := &bytes.Buffer{}
+			_, _ = io.Copy(buf, resp.Body)
+			if strings.Contains(buf.String(), "cursor=") {
+				t.Error("unexpected next page trigger")
+			}
 		})
 	})
 	t.Run("duplicated", func(t *testing.T) {
 		t.Run("badDB", func(t *testing.T) {
 			errDB := errors.New("bad DB")
 			repository := &RepositoryMock{
-				DuplicatedFunc: func(int) ([]*bookmarks.Bookmark, error) {
-					return nil, errDB
+				DuplicatedFunc: func(int64, bookmarks.Cursor, int) ([]*bookmarks.Bookmark, bookmarks.Cursor, error) {
+					return nil, "", errDB
 				},
 			}
 			root := bookmarks.New(repository, nil)
@@ -165,10 +260,10 @@ func TestServer(t *testing.T) {
 		t.Run("good", func(t *testing.T) {
 			foundBookmark := &bookmarks.Bookmark{ID: 1, Title: "%FIND-TITLE%", URL: "https://%FIND-%URL.com"}
 			repository := &RepositoryMock{
-				DuplicatedFunc: func(int) ([]*bookmarks.Bookmark, error) {
+				DuplicatedFunc: func(int64, bookmarks.Cursor, int) ([]*bookmarks.Bookmark, bookmarks.Cursor, error) {
 					return []*bookmarks.Bookmark{
 						foundBookmark,
-					}, nil
+					}, "", nil
 				},
 			}
 			root := bookmarks.New(repository, nil)
@@ -196,8 +291,8 @@ func TestServer(t *testing.T) {
 		t.Run("badDB", func(t *testing.T) {
 			errDB := errors.New("bad DB")
 			repository := &RepositoryMock{
-				DeadFunc: func(int) ([]*bookmarks.Bookmark, error) {
-					return nil, errDB
+				DeadFunc: func(int64, bookmarks.DeadFilter, bookmarks.Cursor, int) ([]*bookmarks.Bookmark, bookmarks.Cursor, error) {
+					return nil, "", errDB
 				},
 			}
 			root := bookmarks.New(repository, nil)
@@ -215,10 +310,10 @@ func TestServer(t *testing.T) {
 		t.Run("good", func(t *testing.T) {
 			foundBookmark := &bookmarks.Bookmark{ID: 1, Title: "%FIND-TITLE%", URL: "https://%FIND-%URL.com"}
 			repository := &RepositoryMock{
-				DeadFunc: func(int) ([]*bookmarks.Bookmark, error) {
+				DeadFunc: func(int64, bookmarks.DeadFilter, bookmarks.Cursor, int) ([]*bookmarks.Bookmark, bookmarks.Cursor, error) {
 					return []*bookmarks.Bookmark{
 						foundBookmark,
-					}, nil
+					}, "", nil
 				},
 			}
 			root := bookmarks.New(repository, nil)
@@ -241,13 +336,98 @@ func TestServer(t *testing.T) {
 				t.Error("cannot find expected bookmark URL")
 			}
 		})
+		t.Run("filter", func(t *testing.T) {
+			var filters []bookmarks.DeadFilter
+			repository := &RepositoryMock{
+				DeadFunc: func(_ int64, filter bookmarks.DeadFilter, _ bookmarks.Cursor, _ int) ([]*bookmarks.Bookmark, bookmarks.Cursor, error) {
+					filters = append(filters, filter)
+					return []*bookmarks.Bookmark{{ID: 1, URL: "https://example.com/parked"}}, "next", nil
+				},
+			}
+			ts := httptest.NewServer(New(bookmarks.New(repository, nil), nil, []string{"localhost"}))
+			defer ts.Close()
+			resp, err := ts.Client().Get(ts.URL + "/dead?filter=soft")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatal("not OK:", resp.StatusCode)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if !slices.Equal(filters, []bookmarks.DeadFilter{bookmarks.SoftDead}) {
+				t.Error("unexpected filters:", filters)
+			}
+			if !strings.Contains(string(body), "Dead: soft failures") || !strings.Contains(string(body), "filter=soft&amp;lastDate=") {
+				t.Error("filter missing from the page:", string(body))
+			}
+
+			resp, err = ts.Client().Get(ts.URL + "/dead?filter=garbage")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Error("not StatusBadRequest:", resp.StatusCode)
+			}
+		})
+	})
+	t.Run("moved", func(t *testing.T) {
+		t.Run("badDB", func(t *testing.T) {
+			errDB := errors.New("bad DB")
+			repository := &RepositoryMock{
+				MovedFunc: func(int64, bookmarks.Cursor, int) ([]*bookmarks.Bookmark, bookmarks.Cursor, error) {
+					return nil, "", errDB
+				},
+			}
+			root := bookmarks.New(repository, nil)
+			ts := httptest.NewServer(New(root, nil, []string{"localhost"}))
+			defer ts.Close()
+			resp, err := ts.Client().Get(ts.URL + "/moved")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusInternalServerError {
+				t.Fatal("not StatusInternalServerError:", resp.StatusCode)
+			}
+		})
+		t.Run("good", func(t *testing.T) {
+			foundBookmark := &bookmarks.Bookmark{ID: 1, Title: "%FIND-TITLE%", URL: "https://%FIND-%URL.com", FinalURL: "https://%FIND-%FINAL.com", RedirectCount: 2, PermanentRedirect: true}
+			repository := &RepositoryMock{
+				MovedFunc: func(int64, bookmarks.Cursor, int) ([]*bookmarks.Bookmark, bookmarks.Cursor, error) {
+					return []*bookmarks.Bookmark{
+						foundBookmark,
+					}, "", nil
+				},
+			}
+			root := bookmarks.New(repository, nil)
+			ts := httptest.NewServer(New(root, nil, []string{"localhost"}))
+			defer ts.Close()
+			resp, err := ts.Client().Get(ts.URL + "/moved")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatal("not OK:", resp.StatusCode)
+			}
+			buf := &bytes.Buffer{}
+			_, _ = io.Copy(buf, resp.Body)
+			if !strings.Contains(buf.String(), foundBookmark.FinalURL) {
+				t.Error("cannot find expected final URL")
+			}
+			if !strings.Contains(buf.String(), "?action=move") {
+				t.Error("cannot find button to follow the redirect")
+			}
+		})
 	})
 	t.Run("all", func(t *testing.T) {
 		t.Run("badDB", func(t *testing.T) {
 			errDB := errors.New("bad DB")
 			repository := &RepositoryMock{
-				AllFunc: func(int) ([]*bookmarks.Bookmark, error) {
-					return nil, errDB
+				AllFunc: func(int64, bookmarks.Cursor, int) ([]*bookmarks.Bookmark, bookmarks.Cursor, error) {
+					return nil, "", errDB
 				},
 			}
 			root := bookmarks.New(repository, nil)
@@ -265,10 +445,10 @@ func TestServer(t *testing.T) {
 		t.Run("good", func(t *testing.T) {
 			foundBookmark := &bookmarks.Bookmark{ID: 1, Title: "%FIND-TITLE%", URL: "https://%FIND-%URL.com"}
 			repository := &RepositoryMock{
-				AllFunc: func(int) ([]*bookmarks.Bookmark, error) {
+				AllFunc: func(int64, bookmarks.Cursor, int) ([]*bookmarks.Bookmark, bookmarks.Cursor, error) {
 					return []*bookmarks.Bookmark{
 						foundBookmark,
-					}, nil
+					}, "", nil
 				},
 			}
 			root := bookmarks.New(repository, nil)
@@ -296,8 +476,8 @@ func TestServer(t *testing.T) {
 		t.Run("badDB", func(t *testing.T) {
 			errDB := errors.New("bad DB")
 			repository := &RepositoryMock{
-				SearchFunc: func(string) ([]*bookmarks.Bookmark, error) {
-					return nil, errDB
+				SearchFunc: func(int64, *bookmarks.Query, int, int) ([]*bookmarks.Bookmark, int, error) {
+					return nil, 0, errDB
 				},
 			}
 			root := bookmarks.New(repository, nil)
@@ -316,19 +496,22 @@ func TestServer(t *testing.T) {
 			foundBookmark := &bookmarks.Bookmark{ID: 1, Title: "%FIND-TITLE%", URL: "https://%FIND-%URL.com"}
 			const expectedTerm = "banana"
 			repository := &RepositoryMock{
-				SearchFunc: func(term string) ([]*bookmarks.Bookmark, error) {
-					if term != expectedTerm {
-						t.Error("unexpected term found:", term)
+				SearchFunc: func(_ int64, query *bookmarks.Query, page, _ int) ([]*bookmarks.Bookmark, int, error) {
+					if len(query.Terms) != 1 || query.Terms[0] != expectedTerm {
+						t.Error("unexpected terms found:", query.Terms)
+					}
+					if page != 2 {
+						t.Error("unexpected page:", page)
 					}
 					return []*bookmarks.Bookmark{
 						foundBookmark,
-					}, nil
+					}, 4321, nil
 				},
 			}
 			root := bookmarks.New(repository, nil)
 			ts := httptest.NewServer(New(root, nil, []string{"localhost"}))
 			defer ts.Close()
-			resp, err := ts.Client().Get(ts.URL + "/search?term=" + expectedTerm)
+			resp, err := ts.Client().Get(ts.URL + "/search?page=2&term=" + expectedTerm)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -344,6 +527,148 @@ func TestServer(t *testing.T) {
 			if !strings.Contains(buf.String(), foundBookmark.URL) {
 				t.Error("cannot find expected bookmark URL")
 			}
+			if !strings.Contains(buf.String(), "4321 results") {
+				t.Error("cannot find result count")
+			}
+			if !strings.Contains(buf.String(), `hx-get="?lastDate=`) || !strings.Contains(buf.String(), "page=3&amp;term=banana") {
+				t.Error("cannot find next page trigger")
+			}
+		})
+		t.Run("lastPage", func(t *testing.T) {
+			repository := &RepositoryMock{
+				SearchFunc: func(int64, *bookmarks.Query, int, int) ([]*bookmarks.Bookmark, int, error) {
+					return []*bookmarks.Bookmark{{ID: 5, Title: "last", URL: "https://example.com/5"}}, 5, nil
+				},
+			}
+			root := bookmarks.New(repository, nil, bookmarks.WithPageSize(2))
+			ts := httptest.NewServer(New(root, nil, []string{"localhost"}))
+			defer ts.Close()
+			resp, err := ts.Client().Get(ts.URL + "/search?page=2&term=banana")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			buf := &bytes.Buffer{}
+			_, _ = io.Copy(buf, resp.Body)
+			if resp.StatusCode != http.StatusOK || !strings.Contains(buf.String(), "5 results") {
+				t.Fatal("unexpected response:", resp.StatusCode, buf.String())
+			}
+			if strings.Contains(buf.String(), "page=3") {
+				t.Error("next page trigger past the last page")
+			}
+		})
+	})
+	t.Run("search/badQuery", func(t *testing.T) {
+		root := bookmarks.New(&RepositoryMock{}, nil)
+		ts := httptest.NewServer(New(root, nil, []string{"localhost"}))
+		defer ts.Close()
+		resp, err := ts.Client().Get(ts.URL + "/search?term=" + url.QueryEscape("is:banana"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal("not OK:", resp.StatusCode)
+		}
+		buf := &bytes.Buffer{}
+		_, _ = io.Copy(buf, resp.Body)
+		if !strings.Contains(buf.String(), "unknown state is:banana") {
+			t.Error("cannot find syntax error message")
+		}
+	})
+	t.Run("tags", func(t *testing.T) {
+		t.Run("badDB", func(t *testing.T) {
+			errDB := errors.New("bad DB")
+			repository := &RepositoryMock{
+				TagsFunc: func(int64) ([]*bookmarks.Tag, error) {
+					return nil, errDB
+				},
+			}
+			root := bookmarks.New(repository, nil)
+			ts := httptest.NewServer(New(root, nil, []string{"localhost"}))
+			defer ts.Close()
+			resp, err := ts.Client().Get(ts.URL + "/tags")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusInternalServerError {
+				t.Fatal("not StatusInternalServerError:", resp.StatusCode)
+			}
+		})
+		t.Run("good", func(t *testing.T) {
+			repository := &RepositoryMock{
+				TagsFunc: func(int64) ([]*bookmarks.Tag, error) {
+					return []*bookmarks.Tag{{Name: "find-tag", Count: 1}}, nil
+				},
+			}
+			root := bookmarks.New(repository, nil)
+			ts := httptest.NewServer(New(root, nil, []string{"localhost"}))
+			defer ts.Close()
+			resp, err := ts.Client().Get(ts.URL + "/tags")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatal("not OK:", resp.StatusCode)
+			}
+			buf := &bytes.Buffer{}
+			_, _ = io.Copy(buf, resp.Body)
+			if !strings.Contains(buf.String(), "#find-tag") {
+				t.Error("cannot find expected tag")
+			}
+		})
+	})
+	t.Run("tag", func(t *testing.T) {
+		t.Run("badDB", func(t *testing.T) {
+			errDB := errors.New("bad DB")
+			repository := &RepositoryMock{
+				ByTagFunc: func(int64, string, bookmarks.Cursor, int) ([]*bookmarks.Bookmark, bookmarks.Cursor, error) {
+					return nil, "", errDB
+				},
+			}
+			root := bookmarks.New(repository, nil)
+			ts := httptest.NewServer(New(root, nil, []string{"localhost"}))
+			defer ts.Close()
+			resp, err := ts.Client().Get(ts.URL + "/tags/banana")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusInternalServerError {
+				t.Fatal("not StatusInternalServerError:", resp.StatusCode)
+			}
+		})
+		t.Run("good", func(t *testing.T) {
+			foundBookmark := &bookmarks.Bookmark{ID: 1, Title: "%FIND-TITLE%", URL: "https://%FIND-%URL.com", Tags: []string{"banana/split"}}
+			const expectedTag = "banana/split"
+			repository := &RepositoryMock{
+				ByTagFunc: func(_ int64, tag string, _ bookmarks.Cursor, _ int) ([]*bookmarks.Bookmark, bookmarks.Cursor, error) {
+					if tag != expectedTag {
+						t.Error("unexpected tag found:", tag)
+					}
+					return []*bookmarks.Bookmark{
+						foundBookmark,
+					}, "", nil
+				},
+			}
+			root := bookmarks.New(repository, nil)
+			ts := httptest.NewServer(New(root, nil, []string{"localhost"}))
+			defer ts.Close()
+			resp, err := ts.Client().Get(ts.URL + "/tags/" + url.PathEscape(expectedTag))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatal("not OK:", resp.StatusCode)
+			}
+			buf := &bytes.Buffer{}
+			_, _ = io.Copy(buf, resp.Body)
+			if !strings.Contains(buf.String(), foundBookmark.Title) {
+				t.Error("cannot find expected bookmark title")
+			}
 		})
 	})
 	t.Run("operations", func(t *testing.T) {
@@ -373,11 +698,177 @@ func TestServer(t *testing.T) {
 				t.Fatal("not StatusMethodNotAllowed:", resp.StatusCode)
 			}
 		})
+		t.Run("methodGet", func(t *testing.T) {
+			foundBookmark := &bookmarks.Bookmark{ID: 1, URL: "https://example.com", Title: "%FIND-TITLE%"}
+			repository := &RepositoryMock{
+				GetByIDFunc: func(_, id int64) (*bookmarks.Bookmark, error) {
+					switch id {
+					case foundBookmark.ID:
+						return foundBookmark, nil
+					case 3:
+						return nil, bookmarks.ErrNotFound
+					}
+					return nil, errors.New("bad DB")
+				},
+				LinkChecksFunc: func(int64, int64, int) ([]*bookmarks.LinkCheck, error) {
+					return []*bookmarks.LinkCheck{
+						{BookmarkID: 1, CheckedAt: 2, Code: http.StatusNotFound, Reason: "%FIND-REASON%"},
+						{BookmarkID: 1, CheckedAt: 1, Code: http.StatusOK},
+					}, nil
+				},
+			}
+			root := bookmarks.New(repository, nil)
+			ts := httptest.NewServer(New(root, nil, []string{"localhost"}))
+			defer ts.Close()
+			tests := []struct {
+				path        string
+				wantCode    int
+				wantPattern string
+			}{
+				{"/bookmarks/1/edit", http.StatusOK, `name="title" value="%FIND-TITLE%"`},
+				{"/bookmarks/1/card", http.StatusOK, `title="%FIND-TITLE%"`},
+				{"/bookmarks/1/history", http.StatusOK, "%FIND-REASON%"},
+				{"/bookmarks/2/edit", http.StatusInternalServerError, ""},
+				{"/bookmarks/2/history", http.StatusInternalServerError, ""},
+				{"/bookmarks/3/edit", http.StatusNotFound, ""},
+				{"/bookmarks/3/history", http.StatusNotFound, ""},
+			}
+			for _, tt := range tests {
+				t.Run(tt.path, func(t *testing.T) {
+					resp, err := ts.Client().Get(ts.URL + tt.path)
+					if err != nil {
+						t.Fatal(err)
+					}
+					defer resp.Body.Close()
+					if resp.StatusCode != tt.wantCode {
+						t.Fatal("unexpected status code:", resp.StatusCode)
+					}
+					buf := &bytes.Buffer{}
+					_, _ = io.Copy(buf, resp.Body)
+					if !strings.Contains(buf.String(), tt.wantPattern) {
+						t.Error("cannot find expected pattern")
+					}
+				})
+			}
+		})
+		t.Run("methodPut", func(t *testing.T) {
+			newServer := func(updateErr error) *httptest.Server {
+				repository := &RepositoryMock{
+					GetByIDFunc: func(_, id int64) (*bookmarks.Bookmark, error) {
+						return &bookmarks.Bookmark{ID: id, URL: "https://example.com", Title: "title"}, nil
+					},
+					UpdateFunc: func(*bookmarks.Bookmark) error { return updateErr },
+					AddTagFunc: func(int64, int64, string) error { return nil },
+				}
+				urlChecker := &URLCheckerMock{
+					CheckFunc: func(_ context.Context, link bookmarks.CheckRequest) bookmarks.CheckResult {
+						return bookmarks.CheckResult{Title: link.Title, Code: http.StatusOK}
+					},
+				}
+				return httptest.NewServer(New(bookmarks.New(repository, urlChecker), nil, []string{"localhost"}))
+			}
+			put := func(t *testing.T, ts *httptest.Server, form url.Values) *http.Response {
+				t.Helper()
+				req, err := http.NewRequest(http.MethodPut, ts.URL+"/bookmarks/1", strings.NewReader(form.Encode()))
+				if err != nil {
+					t.Fatal(err)
+				}
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				resp, err := ts.Client().Do(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Cleanup(func() { resp.Body.Close() })
+				return resp
+			}
+			t.Run("badURL", func(t *testing.T) {
+				ts := newServer(nil)
+				defer ts.Close()
+				resp := put(t, ts, url.Values{"url": {"://"}})
+				if resp.StatusCode != http.StatusBadRequest {
+					t.Fatal("not StatusBadRequest:", resp.StatusCode)
+				}
+			})
+			t.Run("badDB", func(t *testing.T) {
+				ts := newServer(errors.New("bad DB"))
+				defer ts.Close()
+				resp := put(t, ts, url.Values{"url": {"https://example.com"}, "title": {"title"}})
+				if resp.StatusCode != http.StatusInternalServerError {
+					t.Fatal("not StatusInternalServerError:", resp.StatusCode)
+				}
+			})
+			t.Run("good", func(t *testing.T) {
+				ts := newServer(nil)
+				defer ts.Close()
+				resp := put(t, ts, url.Values{"url": {"https://example.org"}, "title": {"%FIND-TITLE%"}, "tags": {"find-tag"}})
+				if resp.StatusCode != http.StatusOK {
+					t.Fatal("not StatusOK:", resp.StatusCode)
+				}
+				buf := &bytes.Buffer{}
+				_, _ = io.Copy(buf, resp.Body)
+				for _, pattern := range []string{"%FIND-TITLE%", "https://example.org", "#find-tag"} {
+					if !strings.Contains(buf.String(), pattern) {
+						t.Error("cannot find expected pattern:", pattern)
+					}
+				}
+			})
+		})
+		t.Run("methodPatch/edit", func(t *testing.T) {
+			foundBookmark := &bookmarks.Bookmark{ID: 1, URL: "https://example.com", Title: "title", Description: "description", Tags: []string{"kept-tag"}}
+			var checked bool
+			repository := &RepositoryMock{
+				GetByIDFunc: func(_, id int64) (*bookmarks.Bookmark, error) {
+					b := *foundBookmark
+					return &b, nil
+				},
+				UpdateFunc: func(b *bookmarks.Bookmark) error {
+					foundBookmark = b
+					return nil
+				},
+			}
+			urlChecker := &URLCheckerMock{
+				CheckFunc: func(_ context.Context, link bookmarks.CheckRequest) bookmarks.CheckResult {
+					checked = true
+					return bookmarks.CheckResult{Title: link.Title, Code: http.StatusOK}
+				},
+			}
+			ts := httptest.NewServer(New(bookmarks.New(repository, urlChecker), nil, []string{"localhost"}))
+			defer ts.Close()
+			req, err := http.NewRequest(http.MethodPatch, ts.URL+"/bookmarks/1", strings.NewReader(url.Values{"title": {"%NEW-TITLE%"}}.Encode()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			resp, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatal("not StatusOK:", resp.StatusCode)
+			}
+			if foundBookmark.Title != "%NEW-TITLE%" {
+				t.Errorf("title not updated: %q", foundBookmark.Title)
+			}
+			if foundBookmark.URL != "https://example.com" || foundBookmark.Description != "description" {
+				t.Errorf("fields absent from the form were changed: %#v", foundBookmark)
+			}
+			if checked {
+				t.Error("link should not be checked again when its URL is kept")
+			}
+			buf := &bytes.Buffer{}
+			_, _ = io.Copy(buf, resp.Body)
+			for _, pattern := range []string{"%NEW-TITLE%", "https://example.com", "#kept-tag"} {
+				if !strings.Contains(buf.String(), pattern) {
+					t.Error("cannot find expected pattern:", pattern)
+				}
+			}
+		})
 		t.Run("methodDelete", func(t *testing.T) {
 			t.Run("badDB", func(t *testing.T) {
 				errDB := errors.New("bad DB")
 				repository := &RepositoryMock{
-					DeleteByIDFunc: func(int64) error {
+					DeleteByIDFunc: func(int64, int64) error {
 						return errDB
 					},
 				}
@@ -399,7 +890,7 @@ func TestServer(t *testing.T) {
 			})
 			t.Run("good", func(t *testing.T) {
 				repository := &RepositoryMock{
-					DeleteByIDFunc: func(int64) error {
+					DeleteByIDFunc: func(int64, int64) error {
 						return nil
 					},
 				}
@@ -442,7 +933,7 @@ func TestServer(t *testing.T) {
 			t.Run("badDB/GetByID", func(t *testing.T) {
 				errDB := errors.New("bad DB")
 				repository := &RepositoryMock{
-					GetByIDFunc: func(int64) (*bookmarks.Bookmark, error) { return nil, errDB },
+					GetByIDFunc: func(int64, int64) (*bookmarks.Bookmark, error) { return nil, errDB },
 				}
 				root := bookmarks.New(repository, nil)
 				ts := httptest.NewServer(New(root, nil, []string{"localhost"}))
@@ -468,7 +959,7 @@ func TestServer(t *testing.T) {
 					Title: "title",
 				}
 				repository := &RepositoryMock{
-					GetByIDFunc: func(int64) (*bookmarks.Bookmark, error) { return foundBookmark, nil },
+					GetByIDFunc: func(int64, int64) (*bookmarks.Bookmark, error) { return foundBookmark, nil },
 					UpdateFunc:  func(*bookmarks.Bookmark) error { return errDB },
 				}
 				root := bookmarks.New(repository, nil)
@@ -494,7 +985,7 @@ func TestServer(t *testing.T) {
 					Title: "title",
 				}
 				repository := &RepositoryMock{
-					GetByIDFunc: func(int64) (*bookmarks.Bookmark, error) { return foundBookmark, nil },
+					GetByIDFunc: func(int64, int64) (*bookmarks.Bookmark, error) { return foundBookmark, nil },
 					UpdateFunc: func(*bookmarks.Bookmark) error {
 						return nil
 					},
@@ -520,16 +1011,189 @@ func TestServer(t *testing.T) {
 				}
 			})
 		})
+		t.Run("methodPatch/move", func(t *testing.T) {
+			moveRequest := func(t *testing.T, repository *RepositoryMock) *http.Response {
+				t.Helper()
+				root := bookmarks.New(repository, nil)
+				ts := httptest.NewServer(New(root, nil, []string{"localhost"}))
+				t.Cleanup(ts.Close)
+				req, err := http.NewRequest(http.MethodPatch, ts.URL+"/bookmarks/1/?action=move", nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				resp, err := ts.Client().Do(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Cleanup(func() { resp.Body.Close() })
+				return resp
+			}
+			t.Run("notFound", func(t *testing.T) {
+				resp := moveRequest(t, &RepositoryMock{
+					GetByIDFunc: func(int64, int64) (*bookmarks.Bookmark, error) { return nil, bookmarks.ErrNotFound },
+				})
+				if resp.StatusCode != http.StatusNotFound {
+					t.Fatal("not StatusNotFound:", resp.StatusCode)
+				}
+			})
+			t.Run("notMoved", func(t *testing.T) {
+				resp := moveRequest(t, &RepositoryMock{
+					GetByIDFunc: func(int64, int64) (*bookmarks.Bookmark, error) {
+						return &bookmarks.Bookmark{URL: "https://example.com"}, nil
+					},
+				})
+				if resp.StatusCode != http.StatusConflict {
+					t.Fatal("not StatusConflict:", resp.StatusCode)
+				}
+			})
+			t.Run("duplicate", func(t *testing.T) {
+				resp := moveRequest(t, &RepositoryMock{
+					GetByIDFunc: func(int64, int64) (*bookmarks.Bookmark, error) {
+						return &bookmarks.Bookmark{URL: "https://example.com", FinalURL: "https://example.org", PermanentRedirect: true}, nil
+					},
+					HasURLFunc: func(int64, string) (bool, error) { return true, nil },
+				})
+				if resp.StatusCode != http.StatusConflict {
+					t.Fatal("not StatusConflict:", resp.StatusCode)
+				}
+			})
+			t.Run("badDB", func(t *testing.T) {
+				errDB := errors.New("bad DB")
+				resp := moveRequest(t, &RepositoryMock{
+					GetByIDFunc: func(int64, int64) (*bookmarks.Bookmark, error) {
+						return &bookmarks.Bookmark{URL: "https://example.com", FinalURL: "https://example.org", PermanentRedirect: true}, nil
+					},
+					HasURLFunc: func(int64, string) (bool, error) { return false, nil },
+					UpdateFunc: func(*bookmarks.Bookmark) error { return errDB },
+				})
+				if resp.StatusCode != http.StatusInternalServerError {
+					t.Fatal("not StatusInternalServerError:", resp.StatusCode)
+				}
+			})
+			t.Run("good", func(t *testing.T) {
+				foundBookmark := &bookmarks.Bookmark{ID: 1, URL: "https://example.com", FinalURL: "https://example.org/new", RedirectCount: 1, PermanentRedirect: true}
+				resp := moveRequest(t, &RepositoryMock{
+					GetByIDFunc: func(int64, int64) (*bookmarks.Bookmark, error) { return foundBookmark, nil },
+					HasURLFunc:  func(int64, string) (bool, error) { return false, nil },
+					UpdateFunc:  func(*bookmarks.Bookmark) error { return nil },
+				})
+				if resp.StatusCode != http.StatusOK {
+					t.Fatal("not StatusOK:", resp.StatusCode)
+				}
+				if foundBookmark.URL != "https://example.org/new" || foundBookmark.FinalURL != "" {
+					t.Errorf("redirect not followed: %#v", foundBookmark)
+				}
+				buf := &bytes.Buffer{}
+				_, _ = io.Copy(buf, resp.Body)
+				if !strings.Contains(buf.String(), "https://example.org/new") {
+					t.Error("cannot find new URL in the card")
+				}
+			})
+		})
+		t.Run("methodPatch/tag", func(t *testing.T) {
+			t.Run("badDB", func(t *testing.T) {
+				errDB := errors.New("bad DB")
+				repository := &RepositoryMock{
+					AddTagFunc: func(int64, int64, string) error { return errDB },
+				}
+				root := bookmarks.New(repository, nil)
+				ts := httptest.NewServer(New(root, nil, []string{"localhost"}))
+				defer ts.Close()
+				req, err := http.NewRequest(http.MethodPatch, ts.URL+"/bookmarks/1/?action=tag", strings.NewReader("tag=banana"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				resp, err := ts.Client().Do(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer resp.Body.Close()
+				if resp.StatusCode != http.StatusInternalServerError {
+					t.Fatal("not StatusInternalServerError:", resp.StatusCode)
+				}
+			})
+			t.Run("emptyTag", func(t *testing.T) {
+				root := bookmarks.New(&RepositoryMock{}, nil)
+				ts := httptest.NewServer(New(root, nil, []string{"localhost"}))
+				defer ts.Close()
+				req, err := http.NewRequest(http.MethodPatch, ts.URL+"/bookmarks/1/?action=tag", strings.NewReader("tag=+++"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				resp, err := ts.Client().Do(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer resp.Body.Close()
+				if resp.StatusCode != http.StatusBadRequest {
+					t.Fatal("not StatusBadRequest:", resp.StatusCode)
+				}
+			})
+			t.Run("good", func(t *testing.T) {
+				foundBookmark := &bookmarks.Bookmark{ID: 1, URL: "https://example.com", Title: "title"}
+				repository := &RepositoryMock{
+					AddTagFunc: func(_, _ int64, tag string) error {
+						foundBookmark.Tags = append(foundBookmark.Tags, tag)
+						return nil
+					},
+					RemoveTagFunc: func(int64, int64, string) error {
+						foundBookmark.Tags = nil
+						return nil
+					},
+					GetByIDFunc: func(int64, int64) (*bookmarks.Bookmark, error) { return foundBookmark, nil },
+				}
+				root := bookmarks.New(repository, nil)
+				ts := httptest.NewServer(New(root, nil, []string{"localhost"}))
+				defer ts.Close()
+				req, err := http.NewRequest(http.MethodPatch, ts.URL+"/bookmarks/1/?action=tag", strings.NewReader("tag=Banana"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				resp, err := ts.Client().Do(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer resp.Body.Close()
+				if resp.StatusCode != http.StatusOK {
+					t.Fatal("not StatusOK:", resp.StatusCode)
+				}
+				buf := &bytes.Buffer{}
+				_, _ = io.Copy(buf, resp.Body)
+				if !strings.Contains(buf.String(), "#banana") {
+					t.Error("cannot find expected tag")
+				}
+
+				req, err = http.NewRequest(http.MethodPatch, ts.URL+"/bookmarks/1/?action=untag&tag=banana", nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				resp, err = ts.Client().Do(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer resp.Body.Close()
+				if resp.StatusCode != http.StatusOK {
+					t.Fatal("not StatusOK:", resp.StatusCode)
+				}
+				if len(foundBookmark.Tags) != 0 {
+					t.Error("tag not removed")
+				}
+			})
+		})
 		t.Run("methodPost", func(t *testing.T) {
 			t.Run("emptyBookmark", func(t *testing.T) {
 				repository := &RepositoryMock{
 					InsertFunc: func(*bookmarks.Bookmark) error {
 						return nil
 					},
+					AddLinkCheckFunc: func(*bookmarks.LinkCheck) error { return nil },
 				}
 				urlChecker := &URLCheckerMock{
-					CheckFunc: func(string, string) (string, int64, int64, string) {
-						return "", 0, 0, ""
+					CheckFunc: func(context.Context, bookmarks.CheckRequest) bookmarks.CheckResult {
+						return bookmarks.CheckResult{}
 					},
 				}
 				root := bookmarks.New(repository, urlChecker)
@@ -561,8 +1225,8 @@ func TestServer(t *testing.T) {
 					},
 				}
 				urlChecker := &URLCheckerMock{
-					CheckFunc: func(_, _ string) (string, int64, int64, string) {
-						return "title", 0, 0, ""
+					CheckFunc: func(context.Context, bookmarks.CheckRequest) bookmarks.CheckResult {
+						return bookmarks.CheckResult{Title: "title"}
 					},
 				}
 				root := bookmarks.New(repository, urlChecker)
@@ -591,15 +1255,16 @@ func TestServer(t *testing.T) {
 					InsertFunc: func(*bookmarks.Bookmark) error {
 						return nil
 					},
-					InboxFunc: func(int) ([]*bookmarks.Bookmark, error) {
+					AddLinkCheckFunc: func(*bookmarks.LinkCheck) error { return nil },
+					InboxFunc: func(int64, bookmarks.Cursor, int) ([]*bookmarks.Bookmark, bookmarks.Cursor, error) {
 						return []*bookmarks.Bookmark{
 							{ID: 1, Title: "title", URL: "https://example.com"},
-						}, nil
+						}, "", nil
 					},
 				}
 				urlChecker := &URLCheckerMock{
-					CheckFunc: func(_, _ string) (string, int64, int64, string) {
-						return "title", 0, 0, ""
+					CheckFunc: func(context.Context, bookmarks.CheckRequest) bookmarks.CheckResult {
+						return bookmarks.CheckResult{Title: "title"}
 					},
 				}
 				root := bookmarks.New(repository, urlChecker)
@@ -625,6 +1290,176 @@ func TestServer(t *testing.T) {
 			})
 		})
 	})
+	t.Run("export", func(t *testing.T) {
+		errDB := errors.New("bad DB")
+		download := func(t *testing.T, walk func(int64, func(*bookmarks.Bookmark) error) error, path string) (*http.Response, string, error) {
+			t.Helper()
+			ts := httptest.NewServer(New(bookmarks.New(&RepositoryMock{WalkFunc: walk}, nil), nil, []string{"localhost"}))
+			defer ts.Close()
+			resp, err := ts.Client().Get(ts.URL + path)
+			if err != nil {
+				return nil, "", err
+			}
+			defer resp.Body.Close()
+			buf := &bytes.Buffer{}
+			_, err = io.Copy(buf, resp.Body)
+			return resp, buf.String(), err
+		}
+		get := func(t *testing.T, walk func(int64, func(*bookmarks.Bookmark) error) error, path string) (*http.Response, string) {
+			t.Helper()
+			resp, body, err := download(t, walk, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return resp, body
+		}
+		bookmark := &bookmarks.Bookmark{URL: "https://example.com/%FIND-ME%"}
+		t.Run("good", func(t *testing.T) {
+			resp, body := get(t, func(_ int64, fn func(*bookmarks.Bookmark) error) error { return fn(bookmark) }, "/export/html")
+			if resp.StatusCode != http.StatusOK {
+				t.Fatal("not OK:", resp.StatusCode)
+			}
+			if cd := resp.Header.Get("Content-Disposition"); cd != `attachment; filename="bookmarks.html"` {
+				t.Error("unexpected content disposition:", cd)
+			}
+			if !strings.Contains(body, "%FIND-ME%") || !strings.HasSuffix(body, "</DL><p>\n") {
+				t.Error("unexpected export:", body)
+			}
+		})
+		t.Run("badFormat", func(t *testing.T) {
+			if resp, _ := get(t, nil, "/export/xml"); resp.StatusCode != http.StatusNotFound {
+				t.Fatal("unexpected status code:", resp.StatusCode)
+			}
+		})
+		t.Run("badDB", func(t *testing.T) {
+			resp, _ := get(t, func(int64, func(*bookmarks.Bookmark) error) error { return errDB }, "/export/csv")
+			if resp.StatusCode != http.StatusInternalServerError || resp.Header.Get("Content-Disposition") != "" {
+				t.Fatal("unexpected response:", resp.StatusCode, resp.Header)
+			}
+		})
+		t.Run("badDB/midway", func(t *testing.T) {
+			_, body, err := download(t, func(_ int64, fn func(*bookmarks.Bookmark) error) error {
+				if err := fn(bookmark); err != nil {
+					return err
+				}
+				return errDB
+			}, "/export/html")
+			if err == nil {
+				t.Fatal("export should fail visibly:", body)
+			}
+			if strings.Contains(body, "</DL>") {
+				t.Error("export should be cut short:", body)
+			}
+		})
+	})
+	t.Run("import", func(t *testing.T) {
+		repository := &RepositoryMock{
+			HasURLFunc: func(_ int64, url string) (bool, error) { return url == "https://example.com/old", nil },
+			InsertFunc: func(b *bookmarks.Bookmark) error {
+				if b.URL == "https://example.com/bad-db" {
+					return errors.New("bad DB")
+				}
+				return nil
+			},
+			AddTagFunc: func(int64, int64, string) error { return nil },
+		}
+		ts := httptest.NewServer(New(bookmarks.New(repository, nil), nil, []string{"localhost"}))
+		defer ts.Close()
+		upload := func(t *testing.T, content string, fields ...string) (int, string) {
+			t.Helper()
+			body := &bytes.Buffer{}
+			mw := multipart.NewWriter(body)
+			for i := 0; i+1 < len(fields); i += 2 {
+				_ = mw.WriteField(fields[i], fields[i+1])
+			}
+			fw, err := mw.CreateFormFile("file", "bookmarks.html")
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, _ = io.WriteString(fw, content)
+			mw.Close()
+			resp, err := ts.Client().Post(ts.URL+"/import", mw.FormDataContentType(), body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			buf := &bytes.Buffer{}
+			_, _ = io.Copy(buf, resp.Body)
+			return resp.StatusCode, buf.String()
+		}
+		t.Run("form", func(t *testing.T) {
+			resp, err := ts.Client().Get(ts.URL + "/import")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			buf := &bytes.Buffer{}
+			_, _ = io.Copy(buf, resp.Body)
+			if resp.StatusCode != http.StatusOK || !strings.Contains(buf.String(), `name="file"`) {
+				t.Fatal("cannot load import form:", resp.StatusCode)
+			}
+		})
+		t.Run("good", func(t *testing.T) {
+			code, body := upload(t, `<DL><p>
+				<DT><A HREF="https://example.com/new" ADD_DATE="1700000000">new</A>
+				<DT><A HREF="https://example.com/old">old</A>
+				<DT><A HREF="place:sort=8">recent</A>
+			</DL>`)
+			if code != http.StatusOK {
+				t.Fatal("not OK:", code)
+			}
+			if !strings.Contains(body, "1 created, 1 skipped as duplicates, 1 failed.") || !strings.Contains(body, "place:sort=8") {
+				t.Error("cannot find import report:", body)
+			}
+		})
+		t.Run("pinboard/dryRun", func(t *testing.T) {
+			inserts := len(repository.InsertCalls())
+			code, body := upload(t, `[{"href":"https://example.com/pinned","toread":"yes"}]`, "format", "pinboard", "dryRun", "true")
+			if code != http.StatusOK {
+				t.Fatal("not OK:", code)
+			}
+			if !strings.Contains(body, "1 would be created") || !strings.Contains(body, `<option value="pinboard" selected>`) {
+				t.Error("cannot find dry run report:", body)
+			}
+			if len(repository.InsertCalls()) != inserts {
+				t.Error("dry run stored bookmarks")
+			}
+		})
+		t.Run("badFormat", func(t *testing.T) {
+			for _, format := range []string{"xml", "pinboard"} {
+				code, body := upload(t, "not a bookmark file", "format", format)
+				if code != http.StatusOK || (!strings.Contains(body, "cannot parse") && !strings.Contains(body, "unknown import format")) {
+					t.Error(format, "unexpected response:", code, body)
+				}
+			}
+		})
+		t.Run("missingFile", func(t *testing.T) {
+			resp, err := ts.Client().Post(ts.URL+"/import", "application/x-www-form-urlencoded", strings.NewReader(""))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Fatal("unexpected status code:", resp.StatusCode)
+			}
+		})
+		t.Run("badDB", func(t *testing.T) {
+			if code, _ := upload(t, `<DT><A HREF="https://example.com/bad-db">bad</A>`); code != http.StatusInternalServerError {
+				t.Fatal("unexpected status code:", code)
+			}
+		})
+		t.Run("badMethod", func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodDelete, ts.URL+"/import", nil)
+			resp, err := ts.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusMethodNotAllowed {
+				t.Fatal("unexpected status code:", resp.StatusCode)
+			}
+		})
+	})
 	t.Run("index", func(t *testing.T) {
 		root := bookmarks.New(nil, nil)
 		ts := httptest.NewServer(New(root, nil, []string{"localhost"}))
@@ -640,7 +1475,7 @@ func TestServer(t *testing.T) {
 		respBuf := &bytes.Buffer{}
 		_, _ = io.Copy(respBuf, resp.Body)
 		tplBuf := &bytes.Buffer{}
-		frontend.RenderIndex(tplBuf, "/inbox", frontend.NoTitle, "")
+		frontend.RenderIndex(tplBuf, "/inbox", frontend.NoTitle, "", "")
 		if tplBuf.String() != respBuf.String() {
 			t.Fatal("index page not rendering correctly")
 		}
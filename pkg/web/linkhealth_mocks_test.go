@@ -0,0 +1,105 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package web
+
+import (
+	"cirello.io/alreadyread/pkg/bookmarks/linkhealth"
+	"sync"
+)
+
+// Ensure, that LinkHealthMock does implement LinkHealth.
+// If this is not the case, regenerate this file with moq.
+var _ LinkHealth = &LinkHealthMock{}
+
+// LinkHealthMock is a mock implementation of LinkHealth.
+//
+//	func TestSomethingThatUsesLinkHealth(t *testing.T) {
+//
+//		// make and configure a mocked LinkHealth
+//		mockedLinkHealth := &LinkHealthMock{
+//			StatusFunc: func() linkhealth.Status {
+//				panic("mock out the Status method")
+//			},
+//			TriggerFunc: func() bool {
+//				panic("mock out the Trigger method")
+//			},
+//		}
+//
+//		// use mockedLinkHealth in code that requires LinkHealth
+//		// and then make assertions.
+//
+//	}
+type LinkHealthMock struct {
+	// StatusFunc mocks the Status method.
+	StatusFunc func() linkhealth.Status
+
+	// TriggerFunc mocks the Trigger method.
+	TriggerFunc func() bool
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Status holds details about calls to the Status method.
+		Status []struct {
+		}
+		// Trigger holds details about calls to the Trigger method.
+		Trigger []struct {
+		}
+	}
+	lockStatus  sync.RWMutex
+	lockTrigger sync.RWMutex
+}
+
+// Status calls StatusFunc.
+func (mock *LinkHealthMock) Status() linkhealth.Status {
+	if mock.StatusFunc == nil {
+		panic("LinkHealthMock.StatusFunc: method is nil but LinkHealth.Status was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockStatus.Lock()
+	mock.calls.Status = append(mock.calls.Status, callInfo)
+	mock.lockStatus.Unlock()
+	return mock.StatusFunc()
+}
+
+// StatusCalls gets all the calls that were made to Status.
+// Check the length with:
+//
+//	len(mockedLinkHealth.StatusCalls())
+func (mock *LinkHealthMock) StatusCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockStatus.RLock()
+	calls = mock.calls.Status
+	mock.lockStatus.RUnlock()
+	return calls
+}
+
+// Trigger calls TriggerFunc.
+func (mock *LinkHealthMock) Trigger() bool {
+	if mock.TriggerFunc == nil {
+		panic("LinkHealthMock.TriggerFunc: method is nil but LinkHealth.Trigger was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockTrigger.Lock()
+	mock.calls.Trigger = append(mock.calls.Trigger, callInfo)
+	mock.lockTrigger.Unlock()
+	return mock.TriggerFunc()
+}
+
+// TriggerCalls gets all the calls that were made to Trigger.
+// Check the length with:
+//
+//	len(mockedLinkHealth.TriggerCalls())
+func (mock *LinkHealthMock) TriggerCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockTrigger.RLock()
+	calls = mock.calls.Trigger
+	mock.lockTrigger.RUnlock()
+	return calls
+}
@@ -0,0 +1,44 @@
+// Copyright 2023 cirello.io
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import "cirello.io/alreadyread/pkg/auth"
+
+// Option configures the web interface.
+type Option func(*Server)
+
+// WithAuth requires a token for API requests and a login session for
+// everything else. Without it, the server is open to anyone who can reach it.
+func WithAuth(a *auth.Auth) Option {
+	return func(s *Server) {
+		s.auth = a
+	}
+}
+
+// WithDefaultOwner sets the user whose bookmarks are served when
+// authentication is disabled.
+func WithDefaultOwner(userID int64) Option {
+	return func(s *Server) {
+		s.defaultOwner = userID
+	}
+}
+
+// WithLinkHealth lets the administrator follow the link checker and start it
+// on demand.
+func WithLinkHealth(linkHealth LinkHealth) Option {
+	return func(s *Server) {
+		s.linkHealth = linkHealth
+	}
+}
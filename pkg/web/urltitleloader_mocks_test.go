@@ -4,6 +4,8 @@
 package web
 
 import (
+	"cirello.io/alreadyread/pkg/bookmarks"
+	"context"
 	"sync"
 )
 
@@ -17,8 +19,8 @@ var _ URLTitleLoader = &URLTitleLoaderMock{}
 //
 //		// make and configure a mocked URLTitleLoader
 //		mockedURLTitleLoader := &URLTitleLoaderMock{
-//			TitleFunc: func(url string) string {
-//				panic("mock out the Title method")
+//			PreviewFunc: func(ctx context.Context, url string) (string, bookmarks.Metadata) {
+//				panic("mock out the Preview method")
 //			},
 //		}
 //
@@ -27,48 +29,54 @@ var _ URLTitleLoader = &URLTitleLoaderMock{}
 //
 //	}
 type URLTitleLoaderMock struct {
-	// TitleFunc mocks the Title method.
-	TitleFunc func(url string) string
+	// PreviewFunc mocks the Preview method.
+	PreviewFunc func(ctx context.Context, url string) (string, bookmarks.Metadata)
 
 	// calls tracks calls to the methods.
 	calls struct {
-		// Title holds details about calls to the Title method.
-		Title []struct {
+		// Preview holds details about calls to the Preview method.
+		Preview []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
 			// URL is the url argument value.
 			URL string
 		}
 	}
-	lockTitle sync.RWMutex
+	lockPreview sync.RWMutex
 }
 
-// Title calls TitleFunc.
-func (mock *URLTitleLoaderMock) Title(url string) string {
-	if mock.TitleFunc == nil {
-		panic("URLTitleLoaderMock.TitleFunc: method is nil but URLTitleLoader.Title was just called")
+// Preview calls PreviewFunc.
+func (mock *URLTitleLoaderMock) Preview(ctx context.Context, url string) (string, bookmarks.Metadata) {
+	if mock.PreviewFunc == nil {
+		panic("URLTitleLoaderMock.PreviewFunc: method is nil but URLTitleLoader.Preview was just called")
 	}
 	callInfo := struct {
+		Ctx context.Context
 		URL string
 	}{
+		Ctx: ctx,
 		URL: url,
 	}
-	mock.lockTitle.Lock()
-	mock.calls.Title = append(mock.calls.Title, callInfo)
-	mock.lockTitle.Unlock()
-	return mock.TitleFunc(url)
+	mock.lockPreview.Lock()
+	mock.calls.Preview = append(mock.calls.Preview, callInfo)
+	mock.lockPreview.Unlock()
+	return mock.PreviewFunc(ctx, url)
 }
 
-// TitleCalls gets all the calls that were made to Title.
+// PreviewCalls gets all the calls that were made to Preview.
 // Check the length with:
 //
-//	len(mockedURLTitleLoader.TitleCalls())
-func (mock *URLTitleLoaderMock) TitleCalls() []struct {
+//	len(mockedURLTitleLoader.PreviewCalls())
+func (mock *URLTitleLoaderMock) PreviewCalls() []struct {
+	Ctx context.Context
 	URL string
 } {
 	var calls []struct {
+		Ctx context.Context
 		URL string
 	}
-	mock.lockTitle.RLock()
-	calls = mock.calls.Title
-	mock.lockTitle.RUnlock()
+	mock.lockPreview.RLock()
+	calls = mock.calls.Preview
+	mock.lockPreview.RUnlock()
 	return calls
 }